@@ -0,0 +1,272 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/go-audio/aiff"
+	"github.com/go-audio/wav"
+	"github.com/jfreymuth/oggvorbis"
+	"github.com/mewkiz/flac"
+	"github.com/pkg/errors"
+)
+
+// 裸 pcm 默认按 OpenAI TTS 的参数处理：24kHz、16-bit、单声道
+const DefaultPCMSampleRate = 24000
+
+var ErrAudioFormatUnsupported = errors.New("audio format not supported for transcoding")
+
+// PCMAudio 解码后的 16-bit PCM 音频，多声道时交错存储
+type PCMAudio struct {
+	Samples    []int16
+	SampleRate int
+	Channels   int
+}
+
+// NormalizeAudioFormat 统一音频格式名称，兼容文件扩展名与 MIME 子类型
+func NormalizeAudioFormat(format string) string {
+	format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+	switch format {
+	case "wave", "x-wav":
+		return "wav"
+	case "aif", "x-aiff":
+		return "aiff"
+	case "oga", "vorbis":
+		return "ogg"
+	case "pcm16", "s16le":
+		return "pcm"
+	case "mpeg", "mpga":
+		return "mp3"
+	}
+	return format
+}
+
+// CanDecodeAudio 是否支持本地解码该格式
+func CanDecodeAudio(format string) bool {
+	switch NormalizeAudioFormat(format) {
+	case "wav", "pcm", "flac", "ogg", "aiff":
+		return true
+	}
+	return false
+}
+
+// CanEncodeAudio 是否支持本地编码该格式
+func CanEncodeAudio(format string) bool {
+	switch NormalizeAudioFormat(format) {
+	case "wav", "pcm":
+		return true
+	}
+	return false
+}
+
+// TranscodeAudio 纯 Go 转码：解码为 PCM，按需重采样后编码为目标格式。
+// sampleRate 为 0 时保留源采样率（输出 pcm 时默认 24kHz），pcm 输出固定为单声道
+func TranscodeAudio(data []byte, from string, to string, sampleRate int) ([]byte, error) {
+	from = NormalizeAudioFormat(from)
+	to = NormalizeAudioFormat(to)
+	if !CanEncodeAudio(to) {
+		return nil, errors.Wrapf(ErrAudioFormatUnsupported, "encode %s", to)
+	}
+	pcm, err := DecodeAudio(data, from)
+	if err != nil {
+		return nil, err
+	}
+	if to == "pcm" {
+		pcm = pcm.ToMono()
+		if sampleRate <= 0 {
+			sampleRate = DefaultPCMSampleRate
+		}
+	}
+	if sampleRate > 0 {
+		pcm = pcm.Resample(sampleRate)
+	}
+	return EncodeAudio(pcm, to)
+}
+
+// DecodeAudio 将 wav/pcm/flac/ogg(vorbis)/aiff 解码为 16-bit PCM
+func DecodeAudio(data []byte, format string) (*PCMAudio, error) {
+	switch NormalizeAudioFormat(format) {
+	case "pcm":
+		samples := make([]int16, len(data)/2)
+		for i := range samples {
+			samples[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+		}
+		return &PCMAudio{Samples: samples, SampleRate: DefaultPCMSampleRate, Channels: 1}, nil
+	case "wav":
+		decoder := wav.NewDecoder(bytes.NewReader(data))
+		if !decoder.IsValidFile() {
+			return nil, errors.New("invalid wav file")
+		}
+		// 仅支持整数 PCM（1）及其扩展格式（0xFFFE），浮点 wav 暂不处理
+		if decoder.WavAudioFormat != 1 && decoder.WavAudioFormat != 0xFFFE {
+			return nil, errors.Wrapf(ErrAudioFormatUnsupported, "wav audio format %d", decoder.WavAudioFormat)
+		}
+		buf, err := decoder.FullPCMBuffer()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode wav")
+		}
+		return intSamplesToPCM(buf.Data, buf.Format.SampleRate, buf.Format.NumChannels, buf.SourceBitDepth, true), nil
+	case "aiff":
+		decoder := aiff.NewDecoder(bytes.NewReader(data))
+		if !decoder.IsValidFile() {
+			return nil, errors.New("invalid aiff file")
+		}
+		buf, err := decoder.FullPCMBuffer()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode aiff")
+		}
+		return intSamplesToPCM(buf.Data, buf.Format.SampleRate, buf.Format.NumChannels, buf.SourceBitDepth, false), nil
+	case "flac":
+		return decodeFLAC(data)
+	case "ogg":
+		samples, format, err := oggvorbis.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode ogg vorbis")
+		}
+		pcm := &PCMAudio{Samples: make([]int16, len(samples)), SampleRate: format.SampleRate, Channels: format.Channels}
+		for i, s := range samples {
+			pcm.Samples[i] = floatToInt16(float64(s))
+		}
+		return pcm, nil
+	}
+	return nil, errors.Wrapf(ErrAudioFormatUnsupported, "decode %s", format)
+}
+
+// EncodeAudio 将 PCM 编码为 wav 或裸 pcm（16-bit 小端）
+func EncodeAudio(pcm *PCMAudio, format string) ([]byte, error) {
+	switch NormalizeAudioFormat(format) {
+	case "pcm":
+		out := make([]byte, len(pcm.Samples)*2)
+		for i, s := range pcm.Samples {
+			binary.LittleEndian.PutUint16(out[i*2:], uint16(s))
+		}
+		return out, nil
+	case "wav":
+		dataSize := len(pcm.Samples) * 2
+		blockAlign := pcm.Channels * 2
+		buf := bytes.NewBuffer(make([]byte, 0, 44+dataSize))
+		buf.WriteString("RIFF")
+		_ = binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+		buf.WriteString("WAVEfmt ")
+		_ = binary.Write(buf, binary.LittleEndian, uint32(16))
+		_ = binary.Write(buf, binary.LittleEndian, uint16(1))
+		_ = binary.Write(buf, binary.LittleEndian, uint16(pcm.Channels))
+		_ = binary.Write(buf, binary.LittleEndian, uint32(pcm.SampleRate))
+		_ = binary.Write(buf, binary.LittleEndian, uint32(pcm.SampleRate*blockAlign))
+		_ = binary.Write(buf, binary.LittleEndian, uint16(blockAlign))
+		_ = binary.Write(buf, binary.LittleEndian, uint16(16))
+		buf.WriteString("data")
+		_ = binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+		_ = binary.Write(buf, binary.LittleEndian, pcm.Samples)
+		return buf.Bytes(), nil
+	}
+	return nil, errors.Wrapf(ErrAudioFormatUnsupported, "encode %s", format)
+}
+
+// ToMono 多声道取平均混为单声道
+func (p *PCMAudio) ToMono() *PCMAudio {
+	if p.Channels <= 1 {
+		return p
+	}
+	frames := len(p.Samples) / p.Channels
+	mono := make([]int16, frames)
+	for i := 0; i < frames; i++ {
+		sum := 0
+		for ch := 0; ch < p.Channels; ch++ {
+			sum += int(p.Samples[i*p.Channels+ch])
+		}
+		mono[i] = int16(sum / p.Channels)
+	}
+	return &PCMAudio{Samples: mono, SampleRate: p.SampleRate, Channels: 1}
+}
+
+// Resample 线性插值重采样，语音场景下音质足够
+func (p *PCMAudio) Resample(sampleRate int) *PCMAudio {
+	if sampleRate <= 0 || sampleRate == p.SampleRate || p.SampleRate <= 0 || p.Channels <= 0 {
+		return p
+	}
+	frames := len(p.Samples) / p.Channels
+	if frames == 0 {
+		return &PCMAudio{SampleRate: sampleRate, Channels: p.Channels}
+	}
+	outFrames := int(int64(frames) * int64(sampleRate) / int64(p.SampleRate))
+	out := make([]int16, outFrames*p.Channels)
+	ratio := float64(p.SampleRate) / float64(sampleRate)
+	for i := 0; i < outFrames; i++ {
+		pos := float64(i) * ratio
+		idx := int(pos)
+		frac := pos - float64(idx)
+		next := idx + 1
+		if next >= frames {
+			next = frames - 1
+		}
+		for ch := 0; ch < p.Channels; ch++ {
+			a := float64(p.Samples[idx*p.Channels+ch])
+			b := float64(p.Samples[next*p.Channels+ch])
+			out[i*p.Channels+ch] = int16(math.Round(a + (b-a)*frac))
+		}
+	}
+	return &PCMAudio{Samples: out, SampleRate: sampleRate, Channels: p.Channels}
+}
+
+func intSamplesToPCM(data []int, sampleRate int, channels int, bitDepth int, unsigned8 bool) *PCMAudio {
+	pcm := &PCMAudio{Samples: make([]int16, len(data)), SampleRate: sampleRate, Channels: channels}
+	for i, v := range data {
+		pcm.Samples[i] = scaleToInt16(v, bitDepth, unsigned8)
+	}
+	return pcm
+}
+
+func decodeFLAC(data []byte) (*PCMAudio, error) {
+	stream, err := flac.New(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode flac")
+	}
+	defer stream.Close()
+	channels := int(stream.Info.NChannels)
+	bitDepth := int(stream.Info.BitsPerSample)
+	pcm := &PCMAudio{SampleRate: int(stream.Info.SampleRate), Channels: channels}
+	for {
+		frame, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "failed to decode flac frame")
+		}
+		if len(frame.Subframes) != channels {
+			return nil, fmt.Errorf("flac frame has %d channels, expected %d", len(frame.Subframes), channels)
+		}
+		for i := 0; i < int(frame.BlockSize); i++ {
+			for ch := 0; ch < channels; ch++ {
+				pcm.Samples = append(pcm.Samples, scaleToInt16(int(frame.Subframes[ch].Samples[i]), bitDepth, false))
+			}
+		}
+	}
+	return pcm, nil
+}
+
+func scaleToInt16(v int, bitDepth int, unsigned8 bool) int16 {
+	switch {
+	case bitDepth == 8 && unsigned8:
+		return int16((v - 128) << 8)
+	case bitDepth < 16:
+		return int16(v << (16 - bitDepth))
+	case bitDepth > 16:
+		return int16(v >> (bitDepth - 16))
+	}
+	return int16(v)
+}
+
+func floatToInt16(v float64) int16 {
+	if v > 1 {
+		v = 1
+	} else if v < -1 {
+		v = -1
+	}
+	return int16(math.Round(v * math.MaxInt16))
+}
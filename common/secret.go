@@ -0,0 +1,179 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 敏感字段采用信封加密：每个值使用随机数据密钥 AES-256-GCM 加密，数据密钥再由主密钥加密后与密文一同保存。
+// 存储格式为 enc:v1:<主密钥 ID>:<加密的数据密钥>:<密文>，未配置主密钥时按明文存储。
+const secretPrefix = "enc:v1:"
+
+var (
+	secretCurrentKeyId string
+	secretMasterKeys   = map[string][]byte{}
+)
+
+// InitSecretEncryption 读取主密钥：SECRET_ENCRYPTION_KEY 或 SECRET_ENCRYPTION_KEY_FILE 的第一行为当前密钥，
+// 密钥文件的其余行与 SECRET_ENCRYPTION_OLD_KEYS（逗号分隔）为轮换前的旧密钥，仅用于解密
+func InitSecretEncryption() error {
+	var keys []string
+	if key := strings.TrimSpace(os.Getenv("SECRET_ENCRYPTION_KEY")); key != "" {
+		keys = append(keys, key)
+	}
+	if path := os.Getenv("SECRET_ENCRYPTION_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read SECRET_ENCRYPTION_KEY_FILE: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				keys = append(keys, line)
+			}
+		}
+	}
+	for _, key := range strings.Split(os.Getenv("SECRET_ENCRYPTION_OLD_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	for i, key := range keys {
+		if len(key) < 16 {
+			return errors.New("secret encryption key must be at least 16 characters")
+		}
+		// 任意长度的主密钥经 SHA-256 派生为 AES-256 密钥
+		derived := sha256.Sum256([]byte(key))
+		id := secretKeyId(derived[:])
+		secretMasterKeys[id] = derived[:]
+		if i == 0 {
+			secretCurrentKeyId = id
+		}
+	}
+	return nil
+}
+
+func secretKeyId(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// SecretEncryptionEnabled 是否配置了主密钥
+func SecretEncryptionEnabled() bool {
+	return secretCurrentKeyId != ""
+}
+
+// IsEncryptedSecret 判断值是否为加密格式
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// EncryptSecret 使用当前主密钥加密，未配置主密钥、空值或已加密的值原样返回
+func EncryptSecret(plaintext string) (string, error) {
+	if !SecretEncryptionEnabled() || plaintext == "" || IsEncryptedSecret(plaintext) {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	ciphertext, err := sealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := sealAESGCM(secretMasterKeys[secretCurrentKeyId], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + secretCurrentKeyId + ":" + base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptSecret 解密加密格式的值，明文原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, secretPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("invalid encrypted secret format")
+	}
+	masterKey, ok := secretMasterKeys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("secret encryption key %s is not configured", parts[0])
+	}
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := openAESGCM(masterKey, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	plaintext, err := openAESGCM(dataKey, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// RotateSecret 将明文或旧主密钥加密的值改用当前主密钥加密，返回值未变化时 changed 为 false
+func RotateSecret(value string) (rotated string, changed bool, err error) {
+	if !SecretEncryptionEnabled() || value == "" || strings.HasPrefix(value, secretPrefix+secretCurrentKeyId+":") {
+		return value, false, nil
+	}
+	plaintext, err := DecryptSecret(value)
+	if err != nil {
+		return value, false, err
+	}
+	rotated, err = EncryptSecret(plaintext)
+	if err != nil {
+		return value, false, err
+	}
+	return rotated, true, nil
+}
+
+func sealAESGCM(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openAESGCM(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,161 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecretKeyOld = "old-master-key-0123456789"
+	testSecretKeyNew = "new-master-key-0123456789"
+)
+
+// setSecretKeys 按环境变量重新加载主密钥，测试结束后恢复为未配置
+func setSecretKeys(t *testing.T, current string, old string) {
+	t.Helper()
+	resetSecretKeys := func() {
+		secretCurrentKeyId = ""
+		secretMasterKeys = map[string][]byte{}
+	}
+	resetSecretKeys()
+	t.Cleanup(resetSecretKeys)
+	t.Setenv("SECRET_ENCRYPTION_KEY", current)
+	t.Setenv("SECRET_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("SECRET_ENCRYPTION_OLD_KEYS", old)
+	require.NoError(t, InitSecretEncryption())
+}
+
+func TestEncryptSecret_RoundTrip(t *testing.T) {
+	setSecretKeys(t, testSecretKeyNew, "")
+
+	tests := []string{"sk-abcdef", "中文密钥", strings.Repeat("x", 4096), "line1\nline2:with:colons"}
+	for _, plaintext := range tests {
+		encrypted, err := EncryptSecret(plaintext)
+		require.NoError(t, err)
+		require.True(t, IsEncryptedSecret(encrypted))
+		require.NotContains(t, encrypted, plaintext)
+
+		decrypted, err := DecryptSecret(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	}
+}
+
+func TestEncryptSecret_Passthrough(t *testing.T) {
+	setSecretKeys(t, "", "")
+	require.False(t, SecretEncryptionEnabled())
+
+	value, err := EncryptSecret("plain")
+	require.NoError(t, err)
+	require.Equal(t, "plain", value)
+
+	setSecretKeys(t, testSecretKeyNew, "")
+	value, err = EncryptSecret("")
+	require.NoError(t, err)
+	require.Equal(t, "", value)
+
+	// 已加密的值不会重复加密
+	encrypted, err := EncryptSecret("plain")
+	require.NoError(t, err)
+	again, err := EncryptSecret(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, again)
+
+	// 明文原样返回，兼容加密前的数据
+	decrypted, err := DecryptSecret("plain")
+	require.NoError(t, err)
+	require.Equal(t, "plain", decrypted)
+}
+
+func TestDecryptSecret_Invalid(t *testing.T) {
+	setSecretKeys(t, testSecretKeyNew, "")
+	encrypted, err := EncryptSecret("sk-abcdef")
+	require.NoError(t, err)
+	parts := strings.Split(strings.TrimPrefix(encrypted, secretPrefix), ":")
+
+	tamper := func(s string) string {
+		b := []byte(s)
+		if b[len(b)/2] == 'A' {
+			b[len(b)/2] = 'B'
+		} else {
+			b[len(b)/2] = 'A'
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "missing parts", value: secretPrefix + parts[0] + ":" + parts[1]},
+		{name: "unknown key id", value: secretPrefix + "deadbeef:" + parts[1] + ":" + parts[2]},
+		{name: "bad base64", value: secretPrefix + parts[0] + ":!!!:" + parts[2]},
+		{name: "tampered data key", value: secretPrefix + parts[0] + ":" + tamper(parts[1]) + ":" + parts[2]},
+		{name: "tampered ciphertext", value: secretPrefix + parts[0] + ":" + parts[1] + ":" + tamper(parts[2])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptSecret(tt.value)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDecryptSecret_WrongMasterKey(t *testing.T) {
+	setSecretKeys(t, testSecretKeyOld, "")
+	encrypted, err := EncryptSecret("sk-abcdef")
+	require.NoError(t, err)
+
+	// 未配置旧密钥时无法解密
+	setSecretKeys(t, testSecretKeyNew, "")
+	_, err = DecryptSecret(encrypted)
+	require.Error(t, err)
+}
+
+func TestRotateSecret(t *testing.T) {
+	setSecretKeys(t, testSecretKeyOld, "")
+	oldEncrypted, err := EncryptSecret("sk-abcdef")
+	require.NoError(t, err)
+
+	// 轮换后旧密钥仅用于解密
+	setSecretKeys(t, testSecretKeyNew, testSecretKeyOld)
+
+	tests := []struct {
+		name        string
+		value       string
+		wantChanged bool
+	}{
+		{name: "old key ciphertext", value: oldEncrypted, wantChanged: true},
+		{name: "plaintext", value: "sk-abcdef", wantChanged: true},
+		{name: "empty", value: "", wantChanged: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotated, changed, err := RotateSecret(tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantChanged, changed)
+			if !changed {
+				require.Equal(t, tt.value, rotated)
+				return
+			}
+			require.True(t, strings.HasPrefix(rotated, secretPrefix+secretCurrentKeyId+":"))
+			decrypted, err := DecryptSecret(rotated)
+			require.NoError(t, err)
+			require.Equal(t, "sk-abcdef", decrypted)
+
+			// 已使用当前密钥加密的值不再变化
+			again, changed, err := RotateSecret(rotated)
+			require.NoError(t, err)
+			require.False(t, changed)
+			require.Equal(t, rotated, again)
+		})
+	}
+}
+
+func TestInitSecretEncryption_ShortKey(t *testing.T) {
+	setSecretKeys(t, "", "")
+	t.Setenv("SECRET_ENCRYPTION_KEY", "short")
+	require.Error(t, InitSecretEncryption())
+}
@@ -13,12 +13,19 @@ const (
 	/* token related keys */
 	ContextKeyTokenUnlimited         ContextKey = "token_unlimited_quota"
 	ContextKeyTokenKey               ContextKey = "token_key"
+	ContextKeyTokenKeyHash           ContextKey = "token_key_hash"
 	ContextKeyTokenId                ContextKey = "token_id"
 	ContextKeyTokenGroup             ContextKey = "token_group"
 	ContextKeyTokenSpecificChannelId ContextKey = "specific_channel_id"
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
+	ContextKeyTokenScopes            ContextKey = "token_scopes"
+	ContextKeyTokenCallbackUrl       ContextKey = "token_callback_url"
+	ContextKeyDerivedTokenId         ContextKey = "derived_token_id"
+	ContextKeyDerivedTokenMetadata   ContextKey = "derived_token_metadata"
+	ContextKeyDerivedTokenMaxSpend   ContextKey = "derived_token_max_spend"
+	ContextKeyDerivedTokenExpiresAt  ContextKey = "derived_token_expires_at"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -52,6 +59,8 @@ const (
 	ContextKeyUsingGroup  ContextKey = "group"
 	ContextKeyUserName    ContextKey = "username"
 
+	ContextKeyUserCreditLimit ContextKey = "user_credit_limit"
+
 	ContextKeyLocalCountTokens ContextKey = "local_count_tokens"
 
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
@@ -59,4 +68,10 @@ const (
 	// ContextKeyAdminRejectReason stores an admin-only reject/block reason extracted from upstream responses.
 	// It is not returned to end users, but can be persisted into consume/error logs for debugging.
 	ContextKeyAdminRejectReason ContextKey = "admin_reject_reason"
+
+	// ContextKeyQueuedTask 后台调度排队任务时存放对应的 *model.Task
+	ContextKeyQueuedTask ContextKey = "queued_task"
+
+	// ContextKeyMessageBatch 后台执行消息批处理中的请求，按批处理倍率计费
+	ContextKeyMessageBatch ContextKey = "message_batch"
 )
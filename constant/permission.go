@@ -0,0 +1,54 @@
+package constant
+
+// 管理后台资源权限，可组合为自定义角色分配给用户
+const (
+	PermissionChannelRead     = "channel.read"
+	PermissionChannelWrite    = "channel.write"
+	PermissionChannelKey      = "channel.key"
+	PermissionUserRead        = "user.read"
+	PermissionUserWrite       = "user.write"
+	PermissionBillingRead     = "billing.read"
+	PermissionBillingWrite    = "billing.write"
+	PermissionModelRead       = "model.read"
+	PermissionModelWrite      = "model.write"
+	PermissionOptionRead      = "option.read"
+	PermissionOptionWrite     = "option.write"
+	PermissionLogRead         = "log.read"
+	PermissionLogWrite        = "log.write"
+	PermissionDeploymentRead  = "deployment.read"
+	PermissionDeploymentWrite = "deployment.write"
+)
+
+var Permissions = []string{
+	PermissionChannelRead,
+	PermissionChannelWrite,
+	PermissionChannelKey,
+	PermissionUserRead,
+	PermissionUserWrite,
+	PermissionBillingRead,
+	PermissionBillingWrite,
+	PermissionModelRead,
+	PermissionModelWrite,
+	PermissionOptionRead,
+	PermissionOptionWrite,
+	PermissionLogRead,
+	PermissionLogWrite,
+	PermissionDeploymentRead,
+	PermissionDeploymentWrite,
+}
+
+// RootOnlyPermissions 未分配自定义角色的管理员不具备的权限，与原先仅限超级管理员的接口保持一致
+var RootOnlyPermissions = map[string]bool{
+	PermissionChannelKey:  true,
+	PermissionOptionRead:  true,
+	PermissionOptionWrite: true,
+}
+
+func IsValidPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package constant
+
+// 令牌权限范围，令牌未配置任何范围时允许访问全部接口
+const (
+	TokenScopeChat       = "chat"
+	TokenScopeEmbeddings = "embeddings"
+	TokenScopeImages     = "images"
+	TokenScopeAudio      = "audio"
+	TokenScopeRealtime   = "realtime"
+	TokenScopeTasks      = "tasks"
+	TokenScopeMidjourney = "midjourney"
+	TokenScopeModelsList = "models.list"
+	TokenScopeUsageRead  = "usage.read"
+)
+
+var TokenScopes = []string{
+	TokenScopeChat,
+	TokenScopeEmbeddings,
+	TokenScopeImages,
+	TokenScopeAudio,
+	TokenScopeRealtime,
+	TokenScopeTasks,
+	TokenScopeMidjourney,
+	TokenScopeModelsList,
+	TokenScopeUsageRead,
+}
+
+func IsValidTokenScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
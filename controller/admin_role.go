@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type adminRoleRequest struct {
+	Id          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type adminRoleResponse struct {
+	*model.AdminRole
+	Permissions []string `json:"permissions"`
+}
+
+func toAdminRoleResponse(role *model.AdminRole) adminRoleResponse {
+	return adminRoleResponse{AdminRole: role, Permissions: role.GetPermissions()}
+}
+
+// validateAdminRoleRequest 校验角色名称与权限，返回规范化后的权限列表
+func validateAdminRoleRequest(req *adminRoleRequest) ([]string, string) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, "角色名称不能为空"
+	}
+	seen := make(map[string]bool)
+	permissions := make([]string, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		if !constant.IsValidPermission(p) {
+			return nil, "无效的权限：" + p
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+	if dup, err := model.IsAdminRoleNameDuplicated(req.Id, req.Name); err != nil {
+		return nil, err.Error()
+	} else if dup {
+		return nil, "角色名称已存在"
+	}
+	return permissions, ""
+}
+
+// GetAdminRoles 获取全部自定义管理角色
+func GetAdminRoles(c *gin.Context) {
+	roles, err := model.GetAllAdminRoles()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	data := make([]adminRoleResponse, 0, len(roles))
+	for _, role := range roles {
+		data = append(data, toAdminRoleResponse(role))
+	}
+	common.ApiSuccess(c, data)
+}
+
+// GetAdminPermissions 获取可分配的权限列表
+func GetAdminPermissions(c *gin.Context) {
+	common.ApiSuccess(c, constant.Permissions)
+}
+
+func CreateAdminRole(c *gin.Context) {
+	var req adminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	req.Id = 0
+	permissions, msg := validateAdminRoleRequest(&req)
+	if msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	role := &model.AdminRole{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	role.SetPermissions(permissions)
+	if err := role.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, toAdminRoleResponse(role))
+}
+
+func UpdateAdminRole(c *gin.Context) {
+	var req adminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Id == 0 {
+		common.ApiErrorMsg(c, "缺少角色 ID")
+		return
+	}
+	role, err := model.GetAdminRoleById(req.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	permissions, msg := validateAdminRoleRequest(&req)
+	if msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	role.Name = req.Name
+	role.Description = req.Description
+	role.SetPermissions(permissions)
+	if err := role.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, toAdminRoleResponse(role))
+}
+
+func DeleteAdminRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteAdminRoleById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// SetUserAdminRole 为用户分配或取消自定义管理角色
+func SetUserAdminRole(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req struct {
+		AdminRoleId int `json:"admin_role_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if user.Role >= common.RoleRootUser {
+		common.ApiErrorMsg(c, "无法为超级管理员分配角色")
+		return
+	}
+	if req.AdminRoleId > 0 {
+		if _, err := model.GetAdminRoleById(req.AdminRoleId); err != nil {
+			common.ApiErrorMsg(c, "角色不存在")
+			return
+		}
+	}
+	if err := model.SetUserAdminRole(userId, req.AdminRoleId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
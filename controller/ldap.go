@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func LdapLogin(c *gin.Context) {
+	setting := system_setting.GetLDAPSettings()
+	if !setting.Enabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未开启通过 LDAP 登录",
+		})
+		return
+	}
+	var loginRequest LoginRequest
+	err := json.NewDecoder(c.Request.Body).Decode(&loginRequest)
+	if err != nil || loginRequest.Username == "" || loginRequest.Password == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	entry, err := service.LdapAuthenticate(loginRequest.Username, loginRequest.Password)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	group := service.ResolveLdapGroup(entry.Groups)
+	user := model.User{
+		LdapId: entry.Username,
+	}
+	if model.IsLdapIdAlreadyTaken(user.LdapId) {
+		err := user.FillUserByLdapId()
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		// 每次登录时同步目录中的分组与角色
+		role, ldapAdmin := service.ResolveLdapRole(entry.Groups, user.Role, user.LdapAdmin)
+		if group == "" {
+			group = user.Group
+		}
+		if group != user.Group || role != user.Role || ldapAdmin != user.LdapAdmin {
+			if err := model.UpdateUserDirectoryAttrs(user.Id, user.Status, group, role, ldapAdmin); err != nil {
+				common.ApiError(c, err)
+				return
+			}
+			user.Group = group
+			user.Role = role
+			user.LdapAdmin = ldapAdmin
+		}
+	} else {
+		if !common.RegisterEnabled || !setting.AutoRegister {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "管理员关闭了新用户注册",
+			})
+			return
+		}
+		user.Username = entry.Username
+		if exist, err := model.CheckUserExistOrDeleted(user.Username, ""); err != nil || exist || len(user.Username) > 20 {
+			user.Username = "ldap_" + strconv.Itoa(model.GetMaxUserId()+1)
+		}
+		user.Email = entry.Email
+		user.DisplayName = entry.DisplayName
+		if user.DisplayName == "" {
+			user.DisplayName = "LDAP User"
+		}
+		user.Group = group
+		user.Role, user.LdapAdmin = service.ResolveLdapRole(entry.Groups, common.RoleCommonUser, false)
+		if err := user.Insert(0); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if err := user.FillUserByLdapId(); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+
+	if user.Status != common.UserStatusEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "用户已被封禁",
+			"success": false,
+		})
+		return
+	}
+	setupLoginWith2FA(&user, c)
+}
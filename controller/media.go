@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mediaArchiveBatchSize   = 20
+	mediaArchiveMaxAttempts = 3
+	mediaArchiveRetryDelay  = 300
+	// 仅转存最近一天内完成的任务，避免开启后回溯全部历史任务
+	mediaArchiveLookback = 24 * 3600
+)
+
+var (
+	mediaArchiveOnce    sync.Once
+	mediaArchiveRunning atomic.Bool
+)
+
+// StartMediaArchiveTask 定期将成功任务的生成结果转存到媒体存储，并清理超过保留期限的文件
+func StartMediaArchiveTask() {
+	mediaArchiveOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(30 * time.Second)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				runMediaArchiveOnce()
+			}
+		})
+	})
+}
+
+func runMediaArchiveOnce() {
+	if !mediaArchiveRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer mediaArchiveRunning.Store(false)
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	// 关闭转存后仍需按保留期限清理已转存的文件
+	cleanupExpiredMedia(ctx, now)
+
+	if !system_setting.GetMediaStorageSettings().Enabled {
+		return
+	}
+
+	tasks, err := model.GetUnarchivedTasks(now-mediaArchiveLookback, mediaArchiveBatchSize)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: query tasks failed: %v", err))
+	}
+	for _, task := range tasks {
+		obj := newMediaObject(task.UserId, task.Group, model.MediaSourceTask, task.TaskID)
+		archiveTaskMedia(ctx, obj, task)
+	}
+
+	mjTasks, err := model.GetUnarchivedMidjourneys((now-mediaArchiveLookback)*1000, mediaArchiveBatchSize)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: query midjourney tasks failed: %v", err))
+	}
+	for _, task := range mjTasks {
+		group, _ := model.GetUserGroup(task.UserId, false)
+		obj := newMediaObject(task.UserId, group, model.MediaSourceMidjourney, task.MjId)
+		archiveMidjourneyMedia(ctx, obj, task)
+	}
+
+	objs, err := model.GetRetryableMediaObjects(now, mediaArchiveMaxAttempts, mediaArchiveBatchSize)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: query retryable objects failed: %v", err))
+	}
+	for _, obj := range objs {
+		switch obj.SourceType {
+		case model.MediaSourceTask:
+			task, exist, err := model.GetByOnlyTaskId(obj.SourceId)
+			if err != nil || !exist {
+				obj.Attempts = mediaArchiveMaxAttempts
+				_ = obj.Update()
+				continue
+			}
+			archiveTaskMedia(ctx, obj, task)
+		case model.MediaSourceMidjourney:
+			task := model.GetByOnlyMJId(obj.SourceId)
+			if task == nil {
+				obj.Attempts = mediaArchiveMaxAttempts
+				_ = obj.Update()
+				continue
+			}
+			archiveMidjourneyMedia(ctx, obj, task)
+		}
+	}
+}
+
+func newMediaObject(userId int, group string, sourceType string, sourceId string) *model.MediaObject {
+	return &model.MediaObject{
+		PublicId:   common.GetUUID(),
+		UserId:     userId,
+		Group:      group,
+		SourceType: sourceType,
+		SourceId:   sourceId,
+	}
+}
+
+func archiveTaskMedia(ctx context.Context, obj *model.MediaObject, task *model.Task) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	client, req, reqErr := newVideoContentRequest(ctx, task)
+	var err error
+	var upstreamUrl string
+	if reqErr != nil {
+		err = reqErr
+	} else {
+		// Gemini 渠道的下载地址带有 key=<渠道密钥>，存储与替换时均去除
+		upstreamUrl = stripUrlApiKey(*req.URL)
+		sourceUrl := *req.URL
+		sourceUrl.RawQuery = ""
+		sourceUrl.Fragment = ""
+		obj.SourceUrl = sourceUrl.String()
+		err = storeMediaObject(ctx, obj, client, req)
+	}
+	if !saveMediaObject(ctx, obj, err) {
+		return
+	}
+
+	// 将任务结果中的上游地址替换为固定访问地址
+	stableUrl := service.MediaObjectStableUrl(obj)
+	data := task.Data
+	if upstreamUrl != "" && len(data) > 0 {
+		replaced := strings.ReplaceAll(string(data), upstreamUrl, stableUrl)
+		if escaped, err := common.Marshal(upstreamUrl); err == nil {
+			stableEscaped, _ := common.Marshal(stableUrl)
+			replaced = strings.ReplaceAll(replaced, strings.Trim(string(escaped), `"`), strings.Trim(string(stableEscaped), `"`))
+		}
+		data = []byte(replaced)
+	}
+	if err := model.UpdateTaskMediaUrl(task.ID, stableUrl, data); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: update task %s failed: %v", task.TaskID, err))
+	}
+}
+
+// stripUrlApiKey 去除地址中的 key 参数，其余参数保持原有顺序，以便与任务结果中的原始地址匹配
+func stripUrlApiKey(u url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	params := strings.Split(u.RawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		if !strings.HasPrefix(param, "key=") {
+			kept = append(kept, param)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
+
+func archiveMidjourneyMedia(ctx context.Context, obj *model.MediaObject, task *model.Midjourney) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	obj.SourceUrl = task.ImageUrl
+	err := func() error {
+		proxy := ""
+		if channel, err := model.CacheGetChannel(task.ChannelId); err == nil {
+			proxy = channel.GetSetting().Proxy
+		}
+		client, err := service.GetHttpClientWithProxy(proxy)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, task.ImageUrl, nil)
+		if err != nil {
+			return err
+		}
+		return storeMediaObject(ctx, obj, client, req)
+	}()
+	if !saveMediaObject(ctx, obj, err) {
+		return
+	}
+	if err := model.UpdateMidjourneyImageUrl(task.Id, service.MediaObjectStableUrl(obj)); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: update midjourney task %s failed: %v", task.MjId, err))
+	}
+}
+
+// storeMediaObject 下载上游文件到临时文件后写入媒体存储
+func storeMediaObject(ctx context.Context, obj *model.MediaObject, client *http.Client, req *http.Request) error {
+	setting := system_setting.GetMediaStorageSettings()
+	store, err := service.GetMediaStore("")
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upstream returned status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "new-api-media-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	maxSize := int64(setting.MaxFileSizeMB) << 20
+	// 未限制大小（MaxFileSizeMB 为 0）时不截断文件
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	size, err := io.Copy(tmp, body)
+	if err != nil {
+		return err
+	}
+	if maxSize > 0 && size > maxSize {
+		return fmt.Errorf("file exceeds %d MB", setting.MaxFileSizeMB)
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ext := ""
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	key := time.Now().Format("2006/01/02/") + obj.PublicId + ext
+	if err = store.Put(ctx, key, contentType, tmp, size); err != nil {
+		return err
+	}
+
+	obj.Driver = setting.Driver
+	obj.StorageKey = key
+	obj.ContentType = contentType
+	obj.Size = size
+	return nil
+}
+
+// saveMediaObject 记录转存结果，返回是否转存成功
+func saveMediaObject(ctx context.Context, obj *model.MediaObject, archiveErr error) bool {
+	obj.Attempts++
+	if archiveErr != nil {
+		errMsg := []rune(archiveErr.Error())
+		if len(errMsg) > 200 {
+			errMsg = errMsg[:200]
+		}
+		obj.Status = model.MediaObjectStatusFailed
+		obj.LastError = string(errMsg)
+		obj.NextRetryAt = time.Now().Unix() + mediaArchiveRetryDelay
+		logger.LogWarn(ctx, fmt.Sprintf("media archive: %s %s attempt %d failed: %v", obj.SourceType, obj.SourceId, obj.Attempts, archiveErr))
+	} else {
+		obj.Status = model.MediaObjectStatusStored
+		obj.LastError = ""
+		if days := system_setting.GetMediaStorageSettings().GetRetentionDays(obj.Group); days > 0 {
+			obj.ExpiresAt = time.Now().Unix() + int64(days)*86400
+		}
+	}
+
+	var err error
+	if obj.Id == 0 {
+		err = obj.Insert()
+	} else {
+		err = obj.Update()
+	}
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: save %s %s failed: %v", obj.SourceType, obj.SourceId, err))
+		if archiveErr == nil {
+			if store, storeErr := service.GetMediaStore(obj.Driver); storeErr == nil {
+				_ = store.Delete(ctx, obj.StorageKey)
+			}
+		}
+		return false
+	}
+	return archiveErr == nil
+}
+
+func cleanupExpiredMedia(ctx context.Context, now int64) {
+	objs, err := model.GetExpiredMediaObjects(now, mediaArchiveBatchSize)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("media archive: query expired objects failed: %v", err))
+		return
+	}
+	for _, obj := range objs {
+		store, err := service.GetMediaStore(obj.Driver)
+		if err == nil {
+			err = store.Delete(ctx, obj.StorageKey)
+		}
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("media archive: delete %s failed: %v", obj.PublicId, err))
+			continue
+		}
+		obj.Status = model.MediaObjectStatusDeleted
+		if err := obj.Update(); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("media archive: update %s failed: %v", obj.PublicId, err))
+		}
+	}
+}
+
+// GetMedia 转存文件的固定访问地址，校验文件归属后跳转到带有效期的签名链接
+func GetMedia(c *gin.Context) {
+	obj, err := model.GetMediaObjectByPublicId(c.Param("id"))
+	// 非所属用户与文件不存在返回相同结果，避免泄露文件是否存在
+	if err != nil || (obj.UserId != c.GetInt("id") && c.GetInt("role") < common.RoleAdminUser) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": "Media not found",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	if obj.Status != model.MediaObjectStatusStored || (obj.ExpiresAt > 0 && obj.ExpiresAt <= time.Now().Unix()) {
+		c.JSON(http.StatusGone, gin.H{
+			"error": gin.H{
+				"message": "Media has expired",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	signedURL, err := service.GetMediaObjectSignedUrl(c.Request.Context(), obj)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to sign media %s: %s", obj.PublicId, err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to sign media url",
+				"type":    "server_error",
+			},
+		})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=60")
+	c.Redirect(http.StatusFound, signedURL)
+}
+
+// GetMediaRaw 本地存储的签名下载链接
+func GetMediaRaw(c *gin.Context) {
+	publicId := c.Param("id")
+	if !service.VerifyMediaLinkSignature(publicId, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": gin.H{
+				"message": "Invalid or expired signature",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	obj, err := model.GetMediaObjectByPublicId(publicId)
+	if err != nil || obj.Status != model.MediaObjectStatusStored || obj.Driver != system_setting.MediaStorageDriverLocal {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": "Media not found",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	path := service.LocalMediaPath(obj.StorageKey)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": "Media not found",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	c.Header("Content-Type", obj.ContentType)
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(path)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMedia_Ownership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupControllerTestDB(t, &model.MediaObject{})
+	setting := system_setting.GetMediaStorageSettings()
+	orig := setting.PublicBaseURL
+	t.Cleanup(func() { setting.PublicBaseURL = orig })
+	setting.PublicBaseURL = "https://cdn.example.com"
+
+	obj := &model.MediaObject{
+		PublicId:   common.GetUUID(),
+		UserId:     1,
+		SourceType: model.MediaSourceTask,
+		SourceId:   "task-1",
+		StorageKey: "2026/01/01/video.mp4",
+		Status:     model.MediaObjectStatusStored,
+	}
+	require.NoError(t, obj.Insert())
+
+	tests := []struct {
+		name     string
+		id       int
+		role     int
+		wantCode int
+	}{
+		{name: "owner", id: 1, role: common.RoleCommonUser, wantCode: http.StatusFound},
+		{name: "other user", id: 2, role: common.RoleCommonUser, wantCode: http.StatusNotFound},
+		{name: "admin", id: 3, role: common.RoleAdminUser, wantCode: http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/media/"+obj.PublicId, nil)
+			c.Params = gin.Params{{Key: "id", Value: obj.PublicId}}
+			c.Set("id", tt.id)
+			c.Set("role", tt.role)
+			GetMedia(c)
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusFound {
+				require.Equal(t, "https://cdn.example.com/"+obj.StorageKey, w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	messageBatchMaxRequests  = 100000
+	messageBatchExpireTime   = 24 * time.Hour
+	messageBatchMaxAttempts  = 3
+	messageBatchResultsPage  = 200
+	messageBatchRequestLimit = 10 * time.Minute
+)
+
+var (
+	messageBatchCustomIdRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	messageBatchOnce          sync.Once
+	messageBatchRunning       atomic.Bool
+)
+
+func writeClaudeError(c *gin.Context, statusCode int, errorType string, message string) {
+	c.JSON(statusCode, gin.H{
+		"type": "error",
+		"error": types.ClaudeError{
+			Type:    errorType,
+			Message: message,
+		},
+	})
+}
+
+func formatMessageBatchTime(timestamp int64) *string {
+	if timestamp == 0 {
+		return nil
+	}
+	formatted := time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+// buildClaudeMessageBatch 转换为 Anthropic 批处理对象，执行中与已领取的请求统一计为 processing
+func buildClaudeMessageBatch(batch *model.MessageBatch) (*dto.ClaudeMessageBatch, error) {
+	counts, err := model.CountMessageBatchRequests(batch.BatchId)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.ClaudeMessageBatch{
+		Id:               batch.BatchId,
+		Type:             "message_batch",
+		ProcessingStatus: batch.Status,
+		RequestCounts: dto.ClaudeMessageBatchRequestCounts{
+			Processing: counts[model.MessageBatchRequestStatusPending] + counts[model.MessageBatchRequestStatusProcessing],
+			Succeeded:  counts[model.MessageBatchRequestStatusSucceeded],
+			Errored:    counts[model.MessageBatchRequestStatusErrored],
+			Canceled:   counts[model.MessageBatchRequestStatusCanceled],
+			Expired:    counts[model.MessageBatchRequestStatusExpired],
+		},
+		EndedAt:           formatMessageBatchTime(batch.EndedAt),
+		CreatedAt:         *formatMessageBatchTime(batch.CreatedAt),
+		ExpiresAt:         *formatMessageBatchTime(batch.ExpiresAt),
+		CancelInitiatedAt: formatMessageBatchTime(batch.CancelInitiatedAt),
+	}
+	if batch.Status == model.MessageBatchStatusEnded {
+		resultsUrl := fmt.Sprintf("%s/v1/messages/batches/%s/results", system_setting.ServerAddress, batch.BatchId)
+		result.ResultsUrl = &resultsUrl
+	}
+	return result, nil
+}
+
+// getUserMessageBatch 查询当前用户的批处理，不存在时写入 404 错误并返回 nil
+func getUserMessageBatch(c *gin.Context) *model.MessageBatch {
+	batch, err := model.GetUserMessageBatch(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeClaudeError(c, http.StatusNotFound, "not_found_error", "message batch not found")
+		} else {
+			writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+		}
+		return nil
+	}
+	return batch
+}
+
+func writeClaudeMessageBatch(c *gin.Context, batch *model.MessageBatch) {
+	result, err := buildClaudeMessageBatch(batch)
+	if err != nil {
+		writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateMessageBatch 创建 Claude 消息批处理，请求由后台按提交顺序执行
+func CreateMessageBatch(c *gin.Context) {
+	if common.GetContextKeyString(c, constant.ContextKeyDerivedTokenId) != "" {
+		writeClaudeError(c, http.StatusForbidden, "permission_error", "derived tokens cannot create message batches")
+		return
+	}
+	var request dto.ClaudeMessageBatchCreateRequest
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+		return
+	}
+	if len(request.Requests) == 0 || len(request.Requests) > messageBatchMaxRequests {
+		writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests: must contain between 1 and %d items", messageBatchMaxRequests))
+		return
+	}
+
+	customIds := make(map[string]bool, len(request.Requests))
+	requests := make([]*model.MessageBatchRequest, 0, len(request.Requests))
+	for i, item := range request.Requests {
+		if !messageBatchCustomIdRegex.MatchString(item.CustomId) {
+			writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.custom_id: must be 1-64 characters of letters, digits, '_' or '-'", i))
+			return
+		}
+		if customIds[item.CustomId] {
+			writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.custom_id: duplicate custom_id %s", i, item.CustomId))
+			return
+		}
+		customIds[item.CustomId] = true
+
+		var params dto.ClaudeRequest
+		if err := common.Unmarshal(item.Params, &params); err != nil {
+			writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.params: %s", i, err.Error()))
+			return
+		}
+		if params.Model == "" {
+			writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.params.model: field required", i))
+			return
+		}
+		if params.Stream {
+			writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.params.stream: streaming is not supported in message batches", i))
+			return
+		}
+		requests = append(requests, &model.MessageBatchRequest{
+			CustomId: item.CustomId,
+			Model:    params.Model,
+			Params:   item.Params,
+		})
+	}
+
+	now := time.Now()
+	batch := &model.MessageBatch{
+		BatchId:   "msgbatch_" + common.GetRandomString(24),
+		UserId:    c.GetInt("id"),
+		TokenId:   c.GetInt("token_id"),
+		Group:     common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		Status:    model.MessageBatchStatusInProgress,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(messageBatchExpireTime).Unix(),
+	}
+	if err := model.CreateMessageBatch(batch, requests); err != nil {
+		writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	writeClaudeMessageBatch(c, batch)
+}
+
+func GetMessageBatch(c *gin.Context) {
+	batch := getUserMessageBatch(c)
+	if batch == nil {
+		return
+	}
+	writeClaudeMessageBatch(c, batch)
+}
+
+// ListMessageBatches 按创建时间倒序列出批处理，支持 before_id / after_id 分页
+func ListMessageBatches(c *gin.Context) {
+	userId := c.GetInt("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 1000 {
+		limit = 20
+	}
+	var beforeId, afterId int64
+	for key, target := range map[string]*int64{"before_id": &beforeId, "after_id": &afterId} {
+		if batchId := c.Query(key); batchId != "" {
+			batch, err := model.GetUserMessageBatch(userId, batchId)
+			if err != nil {
+				writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", key+": message batch not found")
+				return
+			}
+			*target = batch.Id
+		}
+	}
+
+	batches, err := model.GetUserMessageBatches(userId, beforeId, afterId, limit+1)
+	if err != nil {
+		writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	list := &dto.ClaudeMessageBatchList{Data: make([]*dto.ClaudeMessageBatch, 0, len(batches))}
+	if len(batches) > limit {
+		list.HasMore = true
+		if beforeId > 0 {
+			batches = batches[1:]
+		} else {
+			batches = batches[:limit]
+		}
+	}
+	for _, batch := range batches {
+		item, err := buildClaudeMessageBatch(batch)
+		if err != nil {
+			writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+			return
+		}
+		list.Data = append(list.Data, item)
+	}
+	if len(list.Data) > 0 {
+		list.FirstId = &list.Data[0].Id
+		list.LastId = &list.Data[len(list.Data)-1].Id
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CancelMessageBatch 取消批处理，尚未执行的请求标记为已取消，执行中的请求完成后批处理结束
+func CancelMessageBatch(c *gin.Context) {
+	batch := getUserMessageBatch(c)
+	if batch == nil {
+		return
+	}
+	if batch.Status == model.MessageBatchStatusInProgress {
+		batch.CancelInitiatedAt = common.GetTimestamp()
+		if err := model.CancelMessageBatch(batch); err != nil {
+			writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+			return
+		}
+		batch.Status = model.MessageBatchStatusCanceling
+	}
+	writeClaudeMessageBatch(c, batch)
+}
+
+func DeleteMessageBatch(c *gin.Context) {
+	batch := getUserMessageBatch(c)
+	if batch == nil {
+		return
+	}
+	if batch.Status != model.MessageBatchStatusEnded {
+		writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", "message batch must be ended before it can be deleted")
+		return
+	}
+	if err := model.DeleteMessageBatch(batch); err != nil {
+		writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":   batch.BatchId,
+		"type": "message_batch_deleted",
+	})
+}
+
+// GetMessageBatchResults 以 JSONL 格式按提交顺序返回批处理结果
+func GetMessageBatchResults(c *gin.Context) {
+	batch := getUserMessageBatch(c)
+	if batch == nil {
+		return
+	}
+	if batch.Status != model.MessageBatchStatusEnded {
+		writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", "message batch is still processing, results are available after it has ended")
+		return
+	}
+
+	c.Header("Content-Type", "application/x-jsonl")
+	c.Status(http.StatusOK)
+	var afterId int64
+	for {
+		requests, err := model.GetMessageBatchResults(batch.BatchId, afterId, messageBatchResultsPage)
+		if err != nil {
+			logger.LogError(c, fmt.Sprintf("message batch %s: read results failed: %v", batch.BatchId, err))
+			return
+		}
+		for _, request := range requests {
+			result := request.Result
+			if len(result) == 0 {
+				result, _ = common.Marshal(gin.H{"type": request.Status})
+			}
+			line, _ := common.Marshal(dto.ClaudeMessageBatchResult{
+				CustomId: request.CustomId,
+				Result:   result,
+			})
+			_, _ = c.Writer.Write(append(line, '\n'))
+			afterId = request.Id
+		}
+		c.Writer.Flush()
+		if len(requests) < messageBatchResultsPage {
+			return
+		}
+	}
+}
+
+// StartMessageBatchTask 在主节点后台执行消息批处理中的请求
+func StartMessageBatchTask() {
+	messageBatchOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		// 服务重启时执行中的请求没有结果，重新执行
+		if err := model.ResetProcessingMessageBatchRequests(); err != nil {
+			common.SysError("message batch: reset processing requests failed: " + err.Error())
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(5 * time.Second)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				runMessageBatchOnce()
+			}
+		})
+	})
+}
+
+func runMessageBatchOnce() {
+	if !messageBatchRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer messageBatchRunning.Store(false)
+
+	ctx := context.Background()
+	concurrency := max(model_setting.GetClaudeSettings().BatchConcurrency, 1)
+	for {
+		finishMessageBatches(ctx)
+		requests, err := model.GetPendingMessageBatchRequests(concurrency)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("message batch: query pending requests failed: %v", err))
+			return
+		}
+		if len(requests) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		var released atomic.Bool
+		for _, request := range requests {
+			wg.Add(1)
+			gopool.Go(func() {
+				defer wg.Done()
+				if !processMessageBatchRequest(ctx, request) {
+					released.Store(true)
+				}
+			})
+		}
+		wg.Wait()
+		// 有请求因上游繁忙放回队列时等待下一轮，避免立即重试
+		if released.Load() {
+			return
+		}
+	}
+}
+
+// finishMessageBatches 过期未执行的请求，并结束所有请求均已完成的批处理
+func finishMessageBatches(ctx context.Context) {
+	batches, err := model.GetUnfinishedMessageBatches(100)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("message batch: query unfinished batches failed: %v", err))
+		return
+	}
+	now := common.GetTimestamp()
+	for _, batch := range batches {
+		if batch.ExpiresAt < now {
+			if err := model.ExpireMessageBatchRequests(batch.BatchId); err != nil {
+				logger.LogError(ctx, fmt.Sprintf("message batch %s: expire requests failed: %v", batch.BatchId, err))
+				continue
+			}
+		}
+		counts, err := model.CountMessageBatchRequests(batch.BatchId)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("message batch %s: count requests failed: %v", batch.BatchId, err))
+			continue
+		}
+		if counts[model.MessageBatchRequestStatusPending]+counts[model.MessageBatchRequestStatusProcessing] > 0 {
+			continue
+		}
+		if err := model.EndMessageBatch(batch.Id, now); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("message batch %s: end batch failed: %v", batch.BatchId, err))
+		}
+	}
+}
+
+// processMessageBatchRequest 执行单个请求并保存结果，返回 false 表示上游繁忙已放回队列
+func processMessageBatchRequest(ctx context.Context, request *model.MessageBatchRequest) bool {
+	claimed, err := model.ClaimMessageBatchRequest(request.Id)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("message batch: claim request %d failed: %v", request.Id, err))
+		return true
+	}
+	if !claimed {
+		return true
+	}
+	batch, err := model.GetMessageBatchByBatchId(request.BatchId)
+	if err != nil {
+		saveMessageBatchError(ctx, request, "api_error", "message batch not found")
+		return true
+	}
+
+	statusCode, body := relayMessageBatchRequest(ctx, batch, request)
+	if statusCode == http.StatusOK {
+		result, _ := common.Marshal(gin.H{
+			"type":    model.MessageBatchRequestStatusSucceeded,
+			"message": json.RawMessage(body),
+		})
+		if err := model.FinishMessageBatchRequest(request.Id, model.MessageBatchRequestStatusSucceeded, result); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("message batch: save result of request %d failed: %v", request.Id, err))
+		}
+		return true
+	}
+	if (statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError) && request.Attempts+1 < messageBatchMaxAttempts {
+		if err := model.ReleaseMessageBatchRequest(request.Id); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("message batch: release request %d failed: %v", request.Id, err))
+		}
+		return false
+	}
+
+	// Claude 格式错误为 {"type":"error","error":{...}}，分发阶段的错误为 OpenAI 格式 {"error":{...}}
+	var errResponse struct {
+		Error types.ClaudeError `json:"error"`
+	}
+	_ = common.Unmarshal(body, &errResponse)
+	saveMessageBatchError(ctx, request, common.GetStringIfEmpty(errResponse.Error.Type, "api_error"), common.GetStringIfEmpty(errResponse.Error.Message, fmt.Sprintf("request failed with status code %d", statusCode)))
+	return true
+}
+
+func saveMessageBatchError(ctx context.Context, request *model.MessageBatchRequest, errorType string, message string) {
+	result, _ := common.Marshal(gin.H{
+		"type": model.MessageBatchRequestStatusErrored,
+		"error": gin.H{
+			"type": "error",
+			"error": types.ClaudeError{
+				Type:    errorType,
+				Message: message,
+			},
+		},
+	})
+	if err := model.FinishMessageBatchRequest(request.Id, model.MessageBatchRequestStatusErrored, result); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("message batch: save result of request %d failed: %v", request.Id, err))
+	}
+}
+
+// relayMessageBatchRequest 以提交批处理时的令牌身份重放请求，经过与 /v1/messages 相同的分发与计费流程
+func relayMessageBatchRequest(ctx context.Context, batch *model.MessageBatch, request *model.MessageBatchRequest) (int, []byte) {
+	ctx, cancel := context.WithTimeout(ctx, messageBatchRequestLimit)
+	defer cancel()
+	requestId := common.GetTimeString() + common.GetRandomString(8)
+	ctx = context.WithValue(ctx, common.RequestIdKey, requestId)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/messages", bytes.NewReader(request.Params))
+	if err != nil {
+		return http.StatusInternalServerError, nil
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httpReq
+	c.Set(common.RequestIdKey, requestId)
+	c.Set(common.KeyRequestBody, request.Params)
+	common.SetContextKey(c, constant.ContextKeyRequestStartTime, time.Now())
+	if err := setupMessageBatchContext(c, batch); err != nil {
+		body, _ := common.Marshal(gin.H{"error": types.ClaudeError{Type: "permission_error", Message: err.Error()}})
+		return http.StatusForbidden, body
+	}
+	common.SetContextKey(c, constant.ContextKeyMessageBatch, true)
+
+	middleware.Distribute()(c)
+	if !c.IsAborted() {
+		Relay(c, types.RelayFormatClaude)
+	}
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+// setupMessageBatchContext 按提交批处理时的令牌重建鉴权上下文，令牌或用户状态变化后请求将失败
+func setupMessageBatchContext(c *gin.Context, batch *model.MessageBatch) error {
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil || token.UserId != batch.UserId {
+		return errors.New("令牌不存在")
+	}
+	if token.Status != common.TokenStatusEnabled || (token.ExpiredTime != -1 && token.ExpiredTime < common.GetTimestamp()) {
+		return errors.New("该令牌状态不可用")
+	}
+	userCache, err := model.GetUserCache(token.UserId)
+	if err != nil {
+		return err
+	}
+	if userCache.Status != common.UserStatusEnabled {
+		return errors.New("用户已被封禁")
+	}
+	if userCache.BillingSuspended {
+		return errors.New("账单已逾期，服务已暂停，请结清账单后继续使用")
+	}
+	userCache.WriteContext(c)
+	common.SetContextKey(c, constant.ContextKeyUsingGroup, batch.Group)
+	return middleware.SetupContextForToken(c, token)
+}
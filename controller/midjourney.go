@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
@@ -77,6 +78,11 @@ func UpdateMidjourneyTaskBulk() {
 				}
 				continue
 			}
+			if midjourneyChannel.Type != constant.ChannelTypeMidjourney && midjourneyChannel.Type != constant.ChannelTypeMidjourneyPlus {
+				// 模拟任务在后台生成，仅处理服务重启等原因遗留的超时任务
+				failStaleEmulatedMjTasks(ctx, taskIds, taskM)
+				continue
+			}
 			requestUrl := fmt.Sprintf("%s/mj/task/list-by-condition", *midjourneyChannel.BaseURL)
 
 			body, _ := json.Marshal(map[string]any{
@@ -130,6 +136,8 @@ func UpdateMidjourneyTaskBulk() {
 				if !checkMjTaskNeedUpdate(task, responseItem) {
 					continue
 				}
+				preStatus := task.Status
+				preProgress := task.Progress
 				task.Code = 1
 				task.Progress = responseItem.Progress
 				task.PromptEn = responseItem.PromptEn
@@ -168,13 +176,16 @@ func UpdateMidjourneyTaskBulk() {
 				if (task.Progress != "100%" && responseItem.FailReason != "") || (task.Progress == "100%" && task.Status == "FAILURE") {
 					logger.LogInfo(ctx, task.MjId+" 构建失败，"+task.FailReason)
 					task.Progress = "100%"
-					if task.Quota != 0 {
+					if task.Quota != 0 && preStatus != "FAILURE" {
 						shouldReturnQuota = true
 					}
 				}
-				err = task.Update()
+				// 仅在状态未被对账等其他流程修改时更新，避免重复退款
+				updated, err := task.UpdateWithStatus(preStatus, preProgress)
 				if err != nil {
 					logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
+				} else if !updated {
+					logger.LogWarn(ctx, fmt.Sprintf("Midjourney task %s was updated by another process, skip", task.MjId))
 				} else {
 					if shouldReturnQuota {
 						err = model.IncreaseUserQuota(task.UserId, task.Quota, false)
@@ -182,7 +193,7 @@ func UpdateMidjourneyTaskBulk() {
 							logger.LogError(ctx, "fail to increase user quota: "+err.Error())
 						}
 						logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, logger.LogQuota(task.Quota))
-						model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+						model.RecordTaskRefundLog(task.UserId, task.MjId, task.Quota, logContent)
 					}
 				}
 			}
@@ -190,6 +201,37 @@ func UpdateMidjourneyTaskBulk() {
 	}
 }
 
+// failStaleEmulatedMjTasks 将超过 1 小时仍未完成的模拟任务标记为失败并退还额度
+func failStaleEmulatedMjTasks(ctx context.Context, taskIds []string, taskM map[string]*model.Midjourney) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		if now-task.SubmitTime <= 3600000 {
+			continue
+		}
+		preStatus, preProgress := task.Status, task.Progress
+		task.Status = "FAILURE"
+		task.Progress = "100%"
+		task.FailReason = "任务超时（超过1小时）"
+		task.FinishTime = now
+		updated, err := task.UpdateWithStatus(preStatus, preProgress)
+		if err != nil {
+			logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
+			continue
+		}
+		if !updated {
+			continue
+		}
+		if task.Quota != 0 {
+			if err := model.IncreaseUserQuota(task.UserId, task.Quota, false); err != nil {
+				logger.LogError(ctx, "fail to increase user quota: "+err.Error())
+			}
+			logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, logger.LogQuota(task.Quota))
+			model.RecordTaskRefundLog(task.UserId, task.MjId, task.Quota, logContent)
+		}
+	}
+}
+
 func checkMjTaskNeedUpdate(oldTask *model.Midjourney, newTask dto.MidjourneyDto) bool {
 	if oldTask.Code != 1 {
 		return true
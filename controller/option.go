@@ -10,6 +10,7 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/setting"
 	"github.com/QuantumNous/new-api/setting/console_setting"
+	"github.com/QuantumNous/new-api/setting/model_setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/setting/system_setting"
@@ -57,6 +58,14 @@ func UpdateOption(c *gin.Context) {
 		})
 		return
 	}
+	// 修改令牌哈希盐会使所有已有令牌失效
+	if model.IsInternalOption(option.Key) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "该配置项由系统维护，不能修改",
+		})
+		return
+	}
 	switch option.Value.(type) {
 	case bool:
 		option.Value = common.Interface2String(option.Value.(bool))
@@ -92,6 +101,40 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "MjEmulationEnabled":
+		// 模拟生成的图片需转存后以链接返回，不能写入 data URL
+		if option.Value == "true" && !system_setting.GetMediaStorageSettings().Enabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无法启用 Midjourney 模拟，请先启用媒体转存！",
+			})
+			return
+		}
+	case "media_storage.enabled":
+		if option.Value == "false" && setting.MjEmulationEnabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "已启用 Midjourney 模拟时，不能关闭媒体转存",
+			})
+			return
+		}
+	case "oidc_relay.enabled":
+		relaySetting := system_setting.GetOIDCRelaySettings()
+		if option.Value == "true" && (relaySetting.Issuer == "" || relaySetting.Audience == "") {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无法启用 OIDC/JWT 中继认证，请先填入 Issuer 以及 Audience！",
+			})
+			return
+		}
+	case "oidc_relay.issuer", "oidc_relay.audience":
+		if option.Value == "" && system_setting.GetOIDCRelaySettings().Enabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "已启用 OIDC/JWT 中继认证时，Issuer 与 Audience 不能为空",
+			})
+			return
+		}
 	case "LinuxDOOAuthEnabled":
 		if option.Value == "true" && common.LinuxDOClientId == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -169,6 +212,51 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "PromptTierRatio":
+		err = ratio_setting.CheckPromptTierRatio(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "上下文阶梯倍率设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "VolumeTierRatio":
+		err = ratio_setting.CheckVolumeTierRatio(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "用量阶梯倍率设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "claude.batch_ratio":
+		err = model_setting.CheckBatchRatio(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "schedule_ratio_setting.rules":
+		err = ratio_setting.CheckScheduleRatioRules(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "schedule_ratio_setting.timezone":
+		err = ratio_setting.CheckScheduleRatioTimezone(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	case "ModelRequestRateLimitGroup":
 		err = setting.CheckModelRequestRateLimitGroup(option.Value.(string))
 		if err != nil {
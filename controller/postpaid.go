@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSelfPostpaidBills 获取当前用户的后付费账单
+func GetSelfPostpaidBills(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	bills, total, err := model.GetUserPostpaidBills(c.GetInt("id"), pageInfo)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(bills)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetAllPostpaidBills 管理员获取后付费账单，支持 user_id 与 status 筛选
+func GetAllPostpaidBills(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	bills, total, err := model.GetAllPostpaidBills(userId, c.Query("status"), pageInfo)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(bills)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// PayPostpaidBill 管理员确认账单已付款，欠款返还至用户余额并恢复服务
+func PayPostpaidBill(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("bill_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	bill, err := model.PayPostpaidBill(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, bill)
+}
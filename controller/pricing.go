@@ -46,6 +46,8 @@ func GetPricing(c *gin.Context) {
 		"usable_group":       usableGroup,
 		"supported_endpoint": model.GetSupportedEndpointMap(),
 		"auto_groups":        service.GetUserAutoGroup(group),
+		"volume_tiers":       ratio_setting.GetVolumeTiersCopy(),
+		"schedule_ratio":     ratio_setting.GetScheduleRatioSetting(),
 	})
 }
 
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func writeRealtimeError(c *gin.Context, newAPIError *types.NewAPIError) {
+	logger.LogError(c, fmt.Sprintf("relay error: %s", newAPIError.Error()))
+	newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), c.GetString(common.RequestIdKey)))
+	c.JSON(newAPIError.StatusCode, gin.H{
+		"error": newAPIError.ToOpenAIError(),
+	})
+}
+
+// RelayRealtimeSession 为 WebRTC 客户端签发临时密钥，临时密钥为仅限 realtime 与当前模型的派生令牌
+func RelayRealtimeSession(c *gin.Context) {
+	if common.GetContextKeyString(c, constant.ContextKeyDerivedTokenId) != "" {
+		writeRealtimeError(c, types.NewErrorWithStatusCode(errors.New("派生令牌不能申请临时密钥"), types.ErrorCodeAccessDenied, http.StatusForbidden, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	relayInfo, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAIRealtime, &dto.BaseRequest{}, nil)
+	if err != nil {
+		writeRealtimeError(c, types.NewError(err, types.ErrorCodeGenRelayInfoFailed))
+		return
+	}
+	// 未配置价格的模型不向上游申请会话
+	if _, err := helper.ModelPriceHelper(c, relayInfo, 0, &types.TokenCountMeta{}); err != nil {
+		writeRealtimeError(c, types.NewError(err, types.ErrorCodeModelPriceError))
+		return
+	}
+	if newAPIError := relay.RealtimeSessionHelper(c, relayInfo); newAPIError != nil {
+		writeRealtimeError(c, newAPIError)
+	}
+}
+
+// RelayRealtimeCall 使用临时密钥交换 WebRTC SDP，渠道固定为签发临时密钥时选择的渠道
+func RelayRealtimeCall(c *gin.Context) {
+	session, ok := service.GetRealtimeEphemeralSession(common.GetContextKeyString(c, constant.ContextKeyDerivedTokenId))
+	if !ok {
+		writeRealtimeError(c, types.NewErrorWithStatusCode(errors.New("请使用 /v1/realtime/sessions 签发的临时密钥"), types.ErrorCodeAccessDenied, http.StatusUnauthorized, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	channel, err := model.GetChannelById(session.ChannelId, true)
+	if err != nil || channel.Status != common.ChannelStatusEnabled {
+		writeRealtimeError(c, types.NewError(errors.New("临时密钥对应的渠道不可用"), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry()))
+		return
+	}
+	if newAPIError := middleware.SetupContextForSelectedChannel(c, channel, session.OriginModel); newAPIError != nil {
+		writeRealtimeError(c, newAPIError)
+		return
+	}
+	// 多密钥渠道需使用签发临时密钥时的密钥
+	if keys := channel.GetKeys(); channel.ChannelInfo.IsMultiKey && session.KeyIndex < len(keys) {
+		common.SetContextKey(c, constant.ContextKeyChannelKey, keys[session.KeyIndex])
+		common.SetContextKey(c, constant.ContextKeyChannelMultiKeyIndex, session.KeyIndex)
+	}
+
+	relayInfo, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAIRealtime, &dto.BaseRequest{}, nil)
+	if err != nil {
+		writeRealtimeError(c, types.NewError(err, types.ErrorCodeGenRelayInfoFailed))
+		return
+	}
+	priceData, err := helper.ModelPriceHelper(c, relayInfo, 0, &types.TokenCountMeta{})
+	if err != nil {
+		writeRealtimeError(c, types.NewError(err, types.ErrorCodeModelPriceError))
+		return
+	}
+	if !priceData.FreeModel {
+		if newAPIError := service.PreConsumeQuota(c, priceData.QuotaToPreConsume, relayInfo); newAPIError != nil {
+			writeRealtimeError(c, newAPIError)
+			return
+		}
+	}
+	if newAPIError := relay.RealtimeCallHelper(c, relayInfo, session); newAPIError != nil {
+		if relayInfo.FinalPreConsumedQuota != 0 {
+			service.ReturnPreConsumedQuota(c, relayInfo)
+		}
+		writeRealtimeError(c, newAPIError)
+	}
+}
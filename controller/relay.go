@@ -33,7 +33,7 @@ import (
 func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
 	var err *types.NewAPIError
 	switch info.RelayMode {
-	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		err = relay.ImageHelper(c, info)
 	case relayconstant.RelayModeAudioSpeech:
 		fallthrough
@@ -401,7 +401,13 @@ func RelayMidjourney(c *gin.Context) {
 	case relayconstant.RelayModeSwapFace:
 		mjErr = relay.RelaySwapFace(c, relayInfo)
 	default:
-		mjErr = relay.RelayMidjourneySubmit(c, relayInfo)
+		channelType := common.GetContextKeyInt(c, constant.ContextKeyChannelType)
+		if channelType != constant.ChannelTypeMidjourney && channelType != constant.ChannelTypeMidjourneyPlus {
+			// 非 Midjourney 渠道由图片模型模拟
+			mjErr = relay.RelayMidjourneyEmulation(c, relayInfo)
+		} else {
+			mjErr = relay.RelayMidjourneySubmit(c, relayInfo)
+		}
 	}
 	//err = relayMidjourneySubmit(c, relayMode)
 	log.Println(mjErr)
@@ -421,6 +427,26 @@ func RelayMidjourney(c *gin.Context) {
 	}
 }
 
+// RelayClaudeCountTokens 统计 Claude 请求的输入 token 数，不计费
+func RelayClaudeCountTokens(c *gin.Context) {
+	request := &dto.ClaudeRequest{}
+	if err := common.UnmarshalBodyReusable(c, request); err != nil {
+		writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+		return
+	}
+	relayInfo, err := relaycommon.GenRelayInfo(c, types.RelayFormatClaude, request, nil)
+	if err != nil {
+		writeClaudeError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	if newAPIError := relay.ClaudeCountTokensHelper(c, relayInfo, request); newAPIError != nil {
+		c.JSON(newAPIError.StatusCode, gin.H{
+			"type":  "error",
+			"error": newAPIError.ToClaudeError(),
+		})
+	}
+}
+
 func RelayNotImplemented(c *gin.Context) {
 	err := types.OpenAIError{
 		Message: "API not implemented",
@@ -0,0 +1,701 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scimDefaultCount = 100
+	scimMaxCount     = 200
+	scimDefaultGroup = "default"
+)
+
+var scimFilterRegexp = regexp.MustCompile(`(?i)^\s*([a-zA-Z.]+)\s+eq\s+"(.*)"\s*$`)
+
+// SCIM 响应使用 application/scim+json
+func scimJSON(c *gin.Context, status int, obj any) {
+	data, _ := json.Marshal(obj)
+	c.Data(status, "application/scim+json", data)
+}
+
+func ScimErrorResponse(c *gin.Context, status int, scimType string, detail string) {
+	scimJSON(c, status, dto.ScimError{
+		Schemas:  []string{dto.ScimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func scimLocation(resource string, id string) string {
+	return fmt.Sprintf("%s/scim/v2/%s/%s", strings.TrimSuffix(system_setting.ServerAddress, "/"), resource, id)
+}
+
+func scimPage(c *gin.Context) (startIndex int, count int) {
+	startIndex, _ = strconv.Atoi(c.Query("startIndex"))
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.Query("count"))
+	if err != nil || count < 0 {
+		count = scimDefaultCount
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+	return startIndex, count
+}
+
+func scimListResponse(resources []any, total int, startIndex int) dto.ScimListResponse {
+	if resources == nil {
+		resources = []any{}
+	}
+	return dto.ScimListResponse{
+		Schemas:      []string{dto.ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func toScimUser(user *model.User) dto.ScimUser {
+	active := user.Status == common.UserStatusEnabled
+	userName := user.ScimUserName
+	if userName == "" {
+		userName = user.Username
+	}
+	scimUser := dto.ScimUser{
+		Schemas:     []string{dto.ScimSchemaUser},
+		Id:          strconv.Itoa(user.Id),
+		ExternalId:  user.ExternalId,
+		UserName:    userName,
+		DisplayName: user.DisplayName,
+		Name:        &dto.ScimName{Formatted: user.DisplayName},
+		Active:      &active,
+		Meta: &dto.ScimMeta{
+			ResourceType: "User",
+			Location:     scimLocation("Users", strconv.Itoa(user.Id)),
+		},
+	}
+	if user.Email != "" {
+		scimUser.Emails = []dto.ScimEmail{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.Group != "" {
+		scimUser.Groups = []dto.ScimMember{{Value: user.Group, Display: user.Group}}
+	}
+	return scimUser
+}
+
+func scimDisplayName(req *dto.ScimUser) string {
+	if req.DisplayName != "" {
+		return req.DisplayName
+	}
+	if req.Name != nil {
+		if req.Name.Formatted != "" {
+			return req.Name.Formatted
+		}
+		return strings.TrimSpace(req.Name.GivenName + " " + req.Name.FamilyName)
+	}
+	return ""
+}
+
+func getScimUser(c *gin.Context) (*model.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ScimErrorResponse(c, http.StatusNotFound, "", "用户不存在")
+		return nil, false
+	}
+	// 非 SCIM 创建的用户与管理员对 SCIM 不可见
+	user, err := model.GetScimUserById(id)
+	if err != nil {
+		ScimErrorResponse(c, http.StatusNotFound, "", "用户不存在")
+		return nil, false
+	}
+	return user, true
+}
+
+// applyScimActive 停用用户时同时吊销其全部令牌
+func applyScimActive(user *model.User, active bool) error {
+	if active {
+		user.Status = common.UserStatusEnabled
+		return nil
+	}
+	if user.Role == common.RoleRootUser {
+		return errors.New("无法禁用超级管理员用户")
+	}
+	if user.Status != common.UserStatusDisabled {
+		if err := model.RevokeUserTokens(user.Id); err != nil {
+			return err
+		}
+	}
+	user.Status = common.UserStatusDisabled
+	return nil
+}
+
+func ScimListUsers(c *gin.Context) {
+	startIndex, count := scimPage(c)
+	var attr, value string
+	if filter := c.Query("filter"); filter != "" {
+		matches := scimFilterRegexp.FindStringSubmatch(filter)
+		if matches == nil {
+			ScimErrorResponse(c, http.StatusBadRequest, "invalidFilter", "仅支持 eq 过滤条件")
+			return
+		}
+		switch strings.ToLower(matches[1]) {
+		case "username":
+			attr = "username"
+		case "externalid":
+			attr = "externalid"
+		case "emails.value", "emails":
+			attr = "email"
+		default:
+			ScimErrorResponse(c, http.StatusBadRequest, "invalidFilter", "不支持的过滤属性 "+matches[1])
+			return
+		}
+		value = matches[2]
+	}
+	users, total, err := model.SearchScimUsers(attr, value, startIndex-1, count)
+	if err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resources := make([]any, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, toScimUser(user))
+	}
+	scimJSON(c, http.StatusOK, scimListResponse(resources, int(total), startIndex))
+}
+
+func ScimGetUser(c *gin.Context) {
+	user, ok := getScimUser(c)
+	if !ok {
+		return
+	}
+	scimJSON(c, http.StatusOK, toScimUser(user))
+}
+
+func ScimCreateUser(c *gin.Context) {
+	var req dto.ScimUser
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil || req.UserName == "" {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidValue", "userName 不能为空")
+		return
+	}
+	if req.ExternalId == "" {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidValue", "externalId 不能为空")
+		return
+	}
+	if users, _, err := model.SearchScimUsers("username", req.UserName, 0, 1); err == nil && len(users) > 0 {
+		ScimErrorResponse(c, http.StatusConflict, "uniqueness", "用户已存在")
+		return
+	}
+	user := model.User{
+		Username:     req.UserName,
+		ScimUserName: req.UserName,
+		ExternalId:   req.ExternalId,
+		DisplayName:  scimDisplayName(&req),
+		Email:        req.PrimaryEmail(),
+		Password:     req.Password,
+		Role:         common.RoleCommonUser,
+	}
+	// SCIM userName 常为邮箱，超过用户名长度限制或已被占用时生成用户名
+	if exist, err := model.CheckUserExistOrDeleted(user.Username, ""); err != nil || exist || len(user.Username) > 20 {
+		user.Username = "scim_" + strconv.Itoa(model.GetMaxUserId()+1)
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+	if err := user.Insert(0); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if err := model.DB.Where("username = ?", user.Username).First(&user).Error; err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if req.Active != nil && !*req.Active {
+		if err := applyScimActive(&user, false); err == nil {
+			_ = model.UpdateScimUser(&user)
+		}
+	}
+	scimJSON(c, http.StatusCreated, toScimUser(&user))
+}
+
+func ScimReplaceUser(c *gin.Context) {
+	user, ok := getScimUser(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimUser
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", "无效的参数")
+		return
+	}
+	if req.UserName != "" {
+		user.ScimUserName = req.UserName
+	}
+	// 请求中未包含的属性保持不变，externalId 为空会使用户脱离 SCIM 管理
+	if req.ExternalId != "" {
+		user.ExternalId = req.ExternalId
+	}
+	if name := scimDisplayName(&req); name != "" {
+		user.DisplayName = name
+	}
+	if email := req.PrimaryEmail(); email != "" {
+		user.Email = email
+	}
+	if req.Active != nil {
+		if err := applyScimActive(user, *req.Active); err != nil {
+			ScimErrorResponse(c, http.StatusBadRequest, "mutability", err.Error())
+			return
+		}
+	}
+	if err := model.UpdateScimUser(user); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scimJSON(c, http.StatusOK, toScimUser(user))
+}
+
+// scimBool 兼容部分 IdP 以字符串形式传递布尔值
+func scimBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+func applyScimUserAttr(user *model.User, path string, value any) error {
+	str, _ := value.(string)
+	switch strings.ToLower(path) {
+	case "active":
+		active, ok := scimBool(value)
+		if !ok {
+			return errors.New("active 必须为布尔值")
+		}
+		return applyScimActive(user, active)
+	case "username":
+		user.ScimUserName = str
+	case "externalid":
+		if str == "" {
+			return errors.New("externalId 不能为空")
+		}
+		user.ExternalId = str
+	case "displayname", "name.formatted":
+		user.DisplayName = str
+	case "emails", `emails[type eq "work"].value`, `emails[primary eq true].value`:
+		if emails, ok := value.([]any); ok {
+			for _, e := range emails {
+				if m, ok := e.(map[string]any); ok {
+					if v, ok := m["value"].(string); ok {
+						user.Email = v
+						break
+					}
+				}
+			}
+		} else {
+			user.Email = str
+		}
+	}
+	return nil
+}
+
+func ScimPatchUser(c *gin.Context) {
+	user, ok := getScimUser(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimPatchRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", "无效的参数")
+		return
+	}
+	for _, op := range req.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		case "remove":
+			op.Value = ""
+		default:
+			ScimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", "不支持的操作 "+op.Op)
+			return
+		}
+		var err error
+		if op.Path == "" {
+			// 无 path 时 value 为属性集合
+			attrs, ok := op.Value.(map[string]any)
+			if !ok {
+				ScimErrorResponse(c, http.StatusBadRequest, "invalidValue", "无效的参数")
+				return
+			}
+			for path, value := range attrs {
+				if err = applyScimUserAttr(user, path, value); err != nil {
+					break
+				}
+			}
+		} else {
+			err = applyScimUserAttr(user, op.Path, op.Value)
+		}
+		if err != nil {
+			ScimErrorResponse(c, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+	if err := model.UpdateScimUser(user); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scimJSON(c, http.StatusOK, toScimUser(user))
+}
+
+func ScimDeleteUser(c *gin.Context) {
+	user, ok := getScimUser(c)
+	if !ok {
+		return
+	}
+	if user.Role == common.RoleRootUser {
+		ScimErrorResponse(c, http.StatusBadRequest, "mutability", "无法删除超级管理员用户")
+		return
+	}
+	if err := model.RevokeUserTokens(user.Id); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if err := user.Delete(); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SCIM 组对应本系统的用户分组，组成员即该分组下的用户
+
+func scimGroupNames() []string {
+	names := make([]string, 0)
+	for name := range ratio_setting.GetGroupRatioCopy() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func toScimGroup(name string, withMembers bool) (dto.ScimGroup, error) {
+	group := dto.ScimGroup{
+		Schemas:     []string{dto.ScimSchemaGroup},
+		Id:          name,
+		DisplayName: name,
+		Meta: &dto.ScimMeta{
+			ResourceType: "Group",
+			Location:     scimLocation("Groups", name),
+		},
+	}
+	if !withMembers {
+		return group, nil
+	}
+	users, err := model.GetUsersByGroup(name)
+	if err != nil {
+		return group, err
+	}
+	for _, user := range users {
+		display := user.ScimUserName
+		if display == "" {
+			display = user.Username
+		}
+		group.Members = append(group.Members, dto.ScimMember{Value: strconv.Itoa(user.Id), Display: display})
+	}
+	return group, nil
+}
+
+func getScimGroupName(c *gin.Context) (string, bool) {
+	name := c.Param("id")
+	if !ratio_setting.ContainsGroupRatio(name) {
+		ScimErrorResponse(c, http.StatusNotFound, "", "分组不存在")
+		return "", false
+	}
+	return name, true
+}
+
+func ScimListGroups(c *gin.Context) {
+	startIndex, count := scimPage(c)
+	names := scimGroupNames()
+	if filter := c.Query("filter"); filter != "" {
+		matches := scimFilterRegexp.FindStringSubmatch(filter)
+		if matches == nil || !strings.EqualFold(matches[1], "displayName") {
+			ScimErrorResponse(c, http.StatusBadRequest, "invalidFilter", "仅支持 displayName eq 过滤条件")
+			return
+		}
+		names = []string{}
+		if ratio_setting.ContainsGroupRatio(matches[2]) {
+			names = []string{matches[2]}
+		}
+	}
+	total := len(names)
+	start := startIndex - 1
+	if start > total {
+		start = total
+	}
+	end := start + count
+	if end > total {
+		end = total
+	}
+	withMembers := !strings.Contains(c.Query("excludedAttributes"), "members")
+	resources := make([]any, 0, end-start)
+	for _, name := range names[start:end] {
+		group, err := toScimGroup(name, withMembers)
+		if err != nil {
+			ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		resources = append(resources, group)
+	}
+	scimJSON(c, http.StatusOK, scimListResponse(resources, total, startIndex))
+}
+
+func ScimGetGroup(c *gin.Context) {
+	name, ok := getScimGroupName(c)
+	if !ok {
+		return
+	}
+	group, err := toScimGroup(name, true)
+	if err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scimJSON(c, http.StatusOK, group)
+}
+
+// ScimCreateGroup 分组由倍率设置维护，只允许关联已存在的分组
+func ScimCreateGroup(c *gin.Context) {
+	var req dto.ScimGroup
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil || req.DisplayName == "" {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidValue", "displayName 不能为空")
+		return
+	}
+	if !ratio_setting.ContainsGroupRatio(req.DisplayName) {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidValue", "分组不存在，请先在分组倍率中添加该分组")
+		return
+	}
+	if err := setScimGroupMembers(req.DisplayName, req.Members, true); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	group, err := toScimGroup(req.DisplayName, true)
+	if err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scimJSON(c, http.StatusCreated, group)
+}
+
+func scimMemberIds(members []dto.ScimMember) []int {
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.Atoi(member.Value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func setScimUserGroup(userId int, group string) error {
+	user, err := model.GetScimUserById(userId)
+	if err != nil || user.Group == group {
+		return nil
+	}
+	return model.UpdateUserGroup(userId, group)
+}
+
+// removeScimGroupMember 移出分组的用户回到默认分组
+func removeScimGroupMember(userId int, group string) error {
+	user, err := model.GetScimUserById(userId)
+	if err != nil || user.Group != group {
+		return nil
+	}
+	return model.UpdateUserGroup(userId, scimDefaultGroup)
+}
+
+// setScimGroupMembers replace 为 true 时，不在 members 中的现有成员会被移出分组
+func setScimGroupMembers(group string, members []dto.ScimMember, replace bool) error {
+	ids := scimMemberIds(members)
+	keep := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+		if err := setScimUserGroup(id, group); err != nil {
+			return err
+		}
+	}
+	if !replace {
+		return nil
+	}
+	current, err := model.GetUsersByGroup(group)
+	if err != nil {
+		return err
+	}
+	for _, user := range current {
+		if !keep[user.Id] {
+			if err := model.UpdateUserGroup(user.Id, scimDefaultGroup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ScimReplaceGroup(c *gin.Context) {
+	name, ok := getScimGroupName(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimGroup
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", "无效的参数")
+		return
+	}
+	if err := setScimGroupMembers(name, req.Members, true); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	group, err := toScimGroup(name, true)
+	if err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	scimJSON(c, http.StatusOK, group)
+}
+
+var scimMemberPathRegexp = regexp.MustCompile(`(?i)^members\[value eq "(.+)"\]$`)
+
+func parseScimMembers(value any) []dto.ScimMember {
+	var members []dto.ScimMember
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var wrapper struct {
+			Members []dto.ScimMember `json:"members"`
+		}
+		_ = json.Unmarshal(data, &wrapper)
+		return wrapper.Members
+	}
+	_ = json.Unmarshal(data, &members)
+	return members
+}
+
+func ScimPatchGroup(c *gin.Context) {
+	name, ok := getScimGroupName(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimPatchRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		ScimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", "无效的参数")
+		return
+	}
+	for _, op := range req.Operations {
+		var err error
+		path := strings.ToLower(op.Path)
+		switch strings.ToLower(op.Op) {
+		case "add":
+			err = setScimGroupMembers(name, parseScimMembers(op.Value), false)
+		case "replace":
+			if path == "" || path == "members" {
+				err = setScimGroupMembers(name, parseScimMembers(op.Value), true)
+			}
+		case "remove":
+			if matches := scimMemberPathRegexp.FindStringSubmatch(op.Path); matches != nil {
+				if id, convErr := strconv.Atoi(matches[1]); convErr == nil {
+					err = removeScimGroupMember(id, name)
+				}
+			} else if path == "members" {
+				members := parseScimMembers(op.Value)
+				if len(members) == 0 {
+					err = setScimGroupMembers(name, nil, true)
+				}
+				for _, id := range scimMemberIds(members) {
+					if err = removeScimGroupMember(id, name); err != nil {
+						break
+					}
+				}
+			}
+		default:
+			ScimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", "不支持的操作 "+op.Op)
+			return
+		}
+		if err != nil {
+			ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ScimDeleteGroup 分组不可通过 SCIM 删除，仅将组内用户移回默认分组
+func ScimDeleteGroup(c *gin.Context) {
+	name, ok := getScimGroupName(c)
+	if !ok {
+		return
+	}
+	if err := setScimGroupMembers(name, nil, true); err != nil {
+		ScimErrorResponse(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func ScimServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication scheme using the OAuth Bearer Token Standard",
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "created": time.Unix(0, 0).UTC().Format(time.RFC3339)},
+	})
+}
+
+func ScimResourceTypes(c *gin.Context) {
+	resources := []any{
+		gin.H{
+			"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   dto.ScimSchemaUser,
+		},
+		gin.H{
+			"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   dto.ScimSchemaGroup,
+		},
+	}
+	scimJSON(c, http.StatusOK, scimListResponse(resources, len(resources), 1))
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func serveScimUser(handler gin.HandlerFunc, method string, userId int, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/scim/v2/Users/"+strconv.Itoa(userId), bytes.NewBufferString(body))
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(userId)}}
+	handler(c)
+	return w
+}
+
+func TestScimUserScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupControllerTestDB(t, &model.User{}, &model.Token{})
+
+	provisioned := createControllerTestUser(t, "scim", common.RoleCommonUser)
+	local := createControllerTestUser(t, "local", common.RoleCommonUser)
+	admin := createControllerTestUser(t, "admin", common.RoleAdminUser)
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id IN ?", []int{provisioned.Id, admin.Id}).
+		Updates(map[string]any{"external_id": "ext", "email": "old@example.com"}).Error)
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		method  string
+		body    string
+	}{
+		{name: "get", handler: ScimGetUser, method: http.MethodGet},
+		{name: "replace", handler: ScimReplaceUser, method: http.MethodPut, body: `{"userName":"x","emails":[{"value":"evil@example.com"}]}`},
+		{name: "patch", handler: ScimPatchUser, method: http.MethodPatch, body: `{"Operations":[{"op":"replace","path":"active","value":false}]}`},
+		{name: "delete", handler: ScimDeleteUser, method: http.MethodDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 非 SCIM 创建的用户与管理员均不可见
+			for _, user := range []*model.User{local, admin} {
+				w := serveScimUser(tt.handler, tt.method, user.Id, tt.body)
+				require.Equal(t, http.StatusNotFound, w.Code)
+			}
+		})
+	}
+	unchanged, err := model.GetUserById(admin.Id, false)
+	require.NoError(t, err)
+	require.Equal(t, "old@example.com", unchanged.Email)
+	require.Equal(t, common.UserStatusEnabled, unchanged.Status)
+
+	w := serveScimUser(ScimGetUser, http.MethodGet, provisioned.Id, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	users, total, err := model.SearchScimUsers("", "", 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, provisioned.Id, users[0].Id)
+}
+
+func TestScimReplaceUser_KeepsOmittedAttributes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupControllerTestDB(t, &model.User{}, &model.Token{})
+
+	user := createControllerTestUser(t, "scim", common.RoleCommonUser)
+	require.NoError(t, model.DB.Model(user).Updates(map[string]any{"external_id": "ext", "email": "user@example.com"}).Error)
+
+	w := serveScimUser(ScimReplaceUser, http.MethodPut, user.Id, `{"userName":"scim","displayName":"Renamed"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	updated, err := model.GetUserById(user.Id, false)
+	require.NoError(t, err)
+	require.Equal(t, "Renamed", updated.DisplayName)
+	require.Equal(t, "user@example.com", updated.Email)
+	require.Equal(t, "ext", updated.ExternalId)
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func renderStatement(c *gin.Context, userId int) {
+	month := c.Query("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	statement, err := model.GetUserStatement(userId, month)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	filename := fmt.Sprintf("statement-%s-%d", month, userId)
+	switch c.Query("format") {
+	case "csv":
+		data, err := service.RenderStatementCSV(statement)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	case "pdf":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", filename))
+		c.Data(http.StatusOK, "application/pdf", service.RenderStatementPDF(statement))
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data": gin.H{
+				"statement": statement,
+				"summary":   service.SummarizeStatement(statement),
+			},
+		})
+	}
+}
+
+// GetSelfStatement 获取当前用户的月度账单，format 支持 json（默认）、csv、pdf
+func GetSelfStatement(c *gin.Context) {
+	renderStatement(c, c.GetInt("id"))
+}
+
+// GetUserStatement 管理员获取指定用户的月度账单
+func GetUserStatement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	myRole := c.GetInt("role")
+	if myRole <= user.Role && myRole != common.RoleRootUser {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无权获取同级或更高等级用户的信息",
+		})
+		return
+	}
+	renderStatement(c, id)
+}
+
+// SendUserStatement 管理员手动向用户发送账单邮件
+func SendUserStatement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	month := c.Query("month")
+	if month == "" {
+		month = model.PreviousStatementMonth(time.Now())
+	}
+	if err := service.SendUserStatementEmail(id, month); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
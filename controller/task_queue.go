@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const taskQueueDispatchBatch = 20
+
+// DispatchQueuedTasks 清理排队超时的任务，并为有空闲槽位的渠道提交排队任务，由 UpdateTaskBulk 轮询驱动
+func DispatchQueuedTasks(ctx context.Context) {
+	setting := operation_setting.GetTaskQueueSetting()
+	if setting.QueueTimeoutMinute > 0 {
+		before := time.Now().Unix() - int64(setting.QueueTimeoutMinute)*60
+		items, err := model.GetExpiredQueueItems(before, 100)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("task queue: query expired items failed: %v", err))
+		}
+		for _, item := range items {
+			if task, err := model.GetTaskById(item.TaskId); err == nil {
+				failQueuedTask(ctx, task, "排队超时")
+			} else {
+				_ = model.DeleteTaskQueueItem(item.Id)
+			}
+		}
+	}
+
+	channelIds, err := model.GetQueuedChannelIds()
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("task queue: query queued channels failed: %v", err))
+		return
+	}
+	for _, channelId := range channelIds {
+		dispatchChannelQueue(ctx, channelId)
+	}
+}
+
+func dispatchChannelQueue(ctx context.Context, channelId int) {
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		// 渠道已删除，排队任务无法提交
+		items, _ := model.GetChannelQueueItems(channelId, taskQueueDispatchBatch)
+		for _, item := range items {
+			if task, err := model.GetTaskById(item.TaskId); err == nil {
+				failQueuedTask(ctx, task, "渠道不存在")
+			} else {
+				_ = model.DeleteTaskQueueItem(item.Id)
+			}
+		}
+		return
+	}
+	if channel.Status != common.ChannelStatusEnabled {
+		// 渠道被禁用时继续排队，直到恢复或超时
+		return
+	}
+
+	free := taskQueueDispatchBatch
+	if limit := channel.GetOtherSettings().TaskConcurrency; limit > 0 {
+		active, err := model.CountChannelActiveTasks(channelId)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("task queue: count active tasks of channel #%d failed: %v", channelId, err))
+			return
+		}
+		free = min(limit-int(active), taskQueueDispatchBatch)
+	}
+	if free <= 0 {
+		return
+	}
+	items, err := model.GetChannelQueueItems(channelId, free)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("task queue: query items of channel #%d failed: %v", channelId, err))
+		return
+	}
+	for _, item := range items {
+		if !dispatchQueuedTask(ctx, channel, item) {
+			break
+		}
+	}
+}
+
+// dispatchQueuedTask 重放排队任务的原始请求提交上游，返回 false 表示上游繁忙，本轮停止调度该渠道
+func dispatchQueuedTask(ctx context.Context, channel *model.Channel, item *model.TaskQueueItem) bool {
+	task, err := model.GetTaskById(item.TaskId)
+	if err != nil || task.Status != model.TaskStatusWaiting {
+		_ = model.DeleteTaskQueueItem(item.Id)
+		return true
+	}
+	var req relay.TaskQueueRequest
+	if err := common.UnmarshalJsonStr(item.Request, &req); err != nil {
+		failQueuedTask(ctx, task, "排队请求解析失败")
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	c, err := newQueuedTaskContext(ctx, task, &req, item.Body)
+	if err != nil {
+		// 令牌或用户在排队期间被禁用时同样失败并退还额度
+		failQueuedTask(ctx, task, err.Error())
+		return true
+	}
+	if apiErr := middleware.SetupContextForSelectedChannel(c, channel, req.OriginModel); apiErr != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("task queue: setup channel #%d for task %s failed: %s", channel.Id, task.TaskID, apiErr.Error()))
+		return false
+	}
+	info, err := relaycommon.GenRelayInfo(c, types.RelayFormatTask, nil, nil)
+	if err != nil {
+		failQueuedTask(ctx, task, "排队请求重建失败")
+		return true
+	}
+
+	taskErr := relay.RelayTaskSubmit(c, info)
+	if taskErr == nil {
+		logger.LogInfo(ctx, fmt.Sprintf("task queue: queued task %s submitted to channel #%d", task.QueueId, channel.Id))
+		return true
+	}
+	if !taskErr.LocalError && (taskErr.StatusCode == http.StatusTooManyRequests || taskErr.StatusCode/100 == 5) {
+		// 上游繁忙或暂时不可用，保留在队列中等待下一轮
+		logger.LogWarn(ctx, fmt.Sprintf("task queue: channel #%d busy for task %s: %s", channel.Id, task.QueueId, taskErr.Message))
+		return false
+	}
+	failQueuedTask(ctx, task, taskErr.Message)
+	return true
+}
+
+// newQueuedTaskContext 根据入队时保存的请求与上下文重建 gin 上下文，出队时重新校验令牌与用户状态
+func newQueuedTaskContext(ctx context.Context, task *model.Task, req *relay.TaskQueueRequest, body []byte) (*gin.Context, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New("排队请求重建失败")
+	}
+	if req.ContentType != "" {
+		httpReq.Header.Set("Content-Type", req.ContentType)
+	}
+	token, err := model.GetTokenById(req.TokenId)
+	if err != nil || token.UserId != task.UserId {
+		return nil, errors.New("令牌不存在")
+	}
+	if token.Status != common.TokenStatusEnabled || (token.ExpiredTime != -1 && token.ExpiredTime < common.GetTimestamp()) {
+		return nil, errors.New("该令牌状态不可用")
+	}
+	userCache, err := model.GetUserCache(task.UserId)
+	if err != nil {
+		return nil, errors.New("排队请求重建失败")
+	}
+	if userCache.Status != common.UserStatusEnabled {
+		return nil, errors.New("用户已被封禁")
+	}
+	if userCache.BillingSuspended {
+		return nil, errors.New("账单已逾期，服务已暂停，请结清账单后继续使用")
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httpReq
+	for key, value := range req.Params {
+		c.Params = append(c.Params, gin.Param{Key: key, Value: value})
+	}
+	c.Set(common.KeyRequestBody, body)
+	c.Set("platform", req.Platform)
+	if req.Action != "" {
+		c.Set("action", req.Action)
+	}
+	if req.RelayMode != 0 {
+		c.Set("relay_mode", req.RelayMode)
+	}
+	c.Set("token_name", req.TokenName)
+	common.SetContextKey(c, constant.ContextKeyUserId, task.UserId)
+	common.SetContextKey(c, constant.ContextKeyUserGroup, req.UserGroup)
+	common.SetContextKey(c, constant.ContextKeyUsingGroup, req.UsingGroup)
+	common.SetContextKey(c, constant.ContextKeyTokenId, req.TokenId)
+	common.SetContextKey(c, constant.ContextKeyTokenKeyHash, token.KeyHash)
+	common.SetContextKey(c, constant.ContextKeyTokenUnlimited, token.UnlimitedQuota)
+	common.SetContextKey(c, constant.ContextKeyTokenGroup, req.TokenGroup)
+	common.SetContextKey(c, constant.ContextKeyRequestStartTime, time.Now())
+	common.SetContextKey(c, constant.ContextKeyQueuedTask, task)
+	return c, nil
+}
+
+// failQueuedTask 将排队任务标记为失败并退还额度，任务已提交上游时返回 false
+func failQueuedTask(ctx context.Context, task *model.Task, reason string) bool {
+	updated, err := model.FailQueuedTask(task.ID, reason)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("task queue: fail task %s error: %v", task.QueueId, err))
+		return false
+	}
+	if !updated {
+		return false
+	}
+	if task.Quota != 0 {
+		if err := model.IncreaseUserQuota(task.UserId, task.Quota, false); err != nil {
+			logger.LogWarn(ctx, "Failed to increase user quota: "+err.Error())
+		}
+		logContent := fmt.Sprintf("Queued task %s failed (%s), refund %s", task.QueueId, reason, logger.LogQuota(task.Quota))
+		model.RecordTaskRefundLog(task.UserId, task.TaskID, task.Quota, logContent)
+	}
+	return true
+}
+
+// CancelTask 用户取消仍在排队的任务并退还预扣额度
+func CancelTask(c *gin.Context) {
+	userId := c.GetInt("id")
+	task, exist, err := model.GetByTaskId(userId, c.Param("task_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !exist {
+		common.ApiErrorMsg(c, "任务不存在")
+		return
+	}
+	if task.Status != model.TaskStatusWaiting || !failQueuedTask(c.Request.Context(), task, "用户取消") {
+		common.ApiErrorMsg(c, "任务已提交上游，无法取消")
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQueuedTaskContext_RechecksStatus(t *testing.T) {
+	setupControllerTestDB(t, &model.User{}, &model.Token{})
+
+	tests := []struct {
+		name    string
+		token   func(token *model.Token)
+		user    func(user *model.User)
+		wantErr string
+	}{
+		{name: "valid"},
+		{name: "token disabled", token: func(token *model.Token) { token.Status = common.TokenStatusDisabled }, wantErr: "该令牌状态不可用"},
+		{name: "token expired", token: func(token *model.Token) { token.ExpiredTime = common.GetTimestamp() - 60 }, wantErr: "该令牌状态不可用"},
+		{name: "user disabled", user: func(user *model.User) { user.Status = common.UserStatusDisabled }, wantErr: "用户已被封禁"},
+		{name: "billing suspended", user: func(user *model.User) { user.BillingSuspended = true }, wantErr: "账单已逾期，服务已暂停，请结清账单后继续使用"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createControllerTestUser(t, "queued_"+common.GetRandomString(6), common.RoleCommonUser)
+			if tt.user != nil {
+				tt.user(user)
+				require.NoError(t, model.DB.Save(user).Error)
+			}
+			token := &model.Token{
+				UserId:      user.Id,
+				Name:        "queued",
+				Status:      common.TokenStatusEnabled,
+				ExpiredTime: -1,
+			}
+			token.SetKey(common.GetRandomString(48))
+			if tt.token != nil {
+				tt.token(token)
+			}
+			require.NoError(t, model.DB.Create(token).Error)
+
+			task := &model.Task{UserId: user.Id}
+			req := &relay.TaskQueueRequest{Method: http.MethodPost, Url: "/v1/video/generations", TokenId: token.Id}
+			c, err := newQueuedTaskContext(context.Background(), task, req, []byte("{}"))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, c)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const taskReconcileReportLimit = 1000
+
+// GetTaskReconcileReport 比对任务额度与消费、退款日志，默认检查最近 7 天提交的任务
+func GetTaskReconcileReport(c *gin.Context) {
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if endTimestamp <= 0 {
+		endTimestamp = time.Now().Unix()
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	if startTimestamp <= 0 {
+		startTimestamp = endTimestamp - 7*24*3600
+	}
+	if startTimestamp >= endTimestamp {
+		common.ApiErrorMsg(c, "开始时间必须早于结束时间")
+		return
+	}
+	items, err := model.GetTaskQuotaDiscrepancies(startTimestamp, endTimestamp, taskReconcileReportLimit)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"start_timestamp":     startTimestamp,
+		"end_timestamp":       endTimestamp,
+		"consume_log_enabled": common.LogConsumeEnabled,
+		"items":               items,
+	})
+}
+
+// RunTaskReconcile 立即处理超时未完成的任务
+func RunTaskReconcile(c *gin.Context) {
+	result, err := service.ReconcileStuckTasks(c.Request.Context())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, result)
+}
@@ -128,6 +128,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 	shouldRefund := false
 	quota := task.Quota
 	preStatus := task.Status
+	preProgress := task.Progress
 
 	task.Status = model.TaskStatus(taskResult.Status)
 	switch taskResult.Status {
@@ -226,7 +227,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 									logContent := fmt.Sprintf("视频任务成功退还多扣费用，模型倍率 %.2f，分组倍率 %.2f，tokens %d，预扣费 %s，实际扣费 %s，退还 %s",
 										modelRatio, finalGroupRatio, taskResult.TotalTokens,
 										logger.LogQuota(preConsumedQuota), logger.LogQuota(actualQuota), logger.LogQuota(refundQuota))
-									model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+									model.RecordTaskRefundLog(task.UserId, task.TaskID, refundQuota, logContent)
 								}
 							} else {
 								// quotaDelta == 0, 预扣费刚好准确
@@ -261,9 +262,14 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 	if taskResult.Progress != "" {
 		task.Progress = taskResult.Progress
 	}
-	if err := task.Update(); err != nil {
+	// 仅在状态未被对账等其他流程修改时更新，避免重复退款
+	updated, err := task.UpdateWithStatus(preStatus, preProgress)
+	if err != nil {
 		common.SysLog("UpdateVideoTask task error: " + err.Error())
 		shouldRefund = false
+	} else if !updated {
+		logger.LogWarn(ctx, fmt.Sprintf("Task %s was updated by another process, skip", task.TaskID))
+		shouldRefund = false
 	}
 
 	if shouldRefund {
@@ -272,7 +278,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 			logger.LogWarn(ctx, "Failed to increase user quota: "+err.Error())
 		}
 		logContent := fmt.Sprintf("Video async task failed %s, refund %s", task.TaskID, logger.LogQuota(quota))
-		model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+		model.RecordTaskRefundLog(task.UserId, task.TaskID, quota, logContent)
 	}
 
 	return nil
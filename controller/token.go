@@ -7,7 +7,9 @@ import (
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -136,6 +138,27 @@ func GetTokenUsage(c *gin.Context) {
 	})
 }
 
+// normalizeTokenScopes 校验并规范化逗号分隔的令牌权限范围
+func normalizeTokenScopes(scopes string) (string, error) {
+	if strings.TrimSpace(scopes) == "" {
+		return "", nil
+	}
+	seen := make(map[string]bool)
+	normalized := make([]string, 0)
+	for _, scope := range strings.Split(scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !constant.IsValidTokenScope(scope) {
+			return "", fmt.Errorf("无效的令牌权限范围: %s", scope)
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
 func AddToken(c *gin.Context) {
 	token := model.Token{}
 	err := c.ShouldBindJSON(&token)
@@ -168,6 +191,15 @@ func AddToken(c *gin.Context) {
 			return
 		}
 	}
+	token.Scopes, err = normalizeTokenScopes(token.Scopes)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = service.ValidateTaskCallbackUrl(token.CallbackUrl); err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -180,7 +212,6 @@ func AddToken(c *gin.Context) {
 	cleanToken := model.Token{
 		UserId:             c.GetInt("id"),
 		Name:               token.Name,
-		Key:                key,
 		CreatedTime:        common.GetTimestamp(),
 		AccessedTime:       common.GetTimestamp(),
 		ExpiredTime:        token.ExpiredTime,
@@ -188,18 +219,23 @@ func AddToken(c *gin.Context) {
 		UnlimitedQuota:     token.UnlimitedQuota,
 		ModelLimitsEnabled: token.ModelLimitsEnabled,
 		ModelLimits:        token.ModelLimits,
+		Scopes:             token.Scopes,
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
 		CrossGroupRetry:    token.CrossGroupRetry,
+		CallbackUrl:        token.CallbackUrl,
 	}
+	cleanToken.SetKey(key)
 	err = cleanToken.Insert()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	// 令牌明文只在创建时返回一次
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data":    cleanToken,
 	})
 	return
 }
@@ -252,6 +288,15 @@ func UpdateToken(c *gin.Context) {
 			return
 		}
 	}
+	token.Scopes, err = normalizeTokenScopes(token.Scopes)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = service.ValidateTaskCallbackUrl(token.CallbackUrl); err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		common.ApiError(c, err)
@@ -283,9 +328,11 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.ModelLimitsEnabled = token.ModelLimitsEnabled
 		cleanToken.ModelLimits = token.ModelLimits
+		cleanToken.Scopes = token.Scopes
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.CallbackUrl = token.CallbackUrl
 	}
 	err = cleanToken.Update()
 	if err != nil {
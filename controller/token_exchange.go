@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeToken 使用令牌本身换取短期派生令牌
+func ExchangeToken(c *gin.Context) {
+	if common.GetContextKeyString(c, constant.ContextKeyDerivedTokenId) != "" {
+		common.ApiErrorMsg(c, "派生令牌不能再次签发派生令牌")
+		return
+	}
+	parent, err := model.GetTokenByKeyHash(common.GetContextKeyString(c, constant.ContextKeyTokenKeyHash), false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	issueDerivedToken(c, parent)
+}
+
+// ExchangeUserToken 登录用户为自己的令牌签发短期派生令牌
+func ExchangeUserToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	parent, err := model.GetTokenByIds(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	issueDerivedToken(c, parent)
+}
+
+func issueDerivedToken(c *gin.Context, parent *model.Token) {
+	var req service.DerivedTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+			common.ApiError(c, errors.New("无效的参数"))
+			return
+		}
+	}
+	token, err := service.IssueDerivedToken(parent, req)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, token)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -65,8 +66,11 @@ func Login(c *gin.Context) {
 		})
 		return
 	}
+	setupLoginWith2FA(&user, c)
+}
 
-	// 检查是否启用2FA
+// setupLoginWith2FA 启用了两步验证的用户先进入待验证状态，否则直接登录
+func setupLoginWith2FA(user *model.User, c *gin.Context) {
 	if model.IsTwoFAEnabled(user.Id) {
 		// 设置pending session，等待2FA验证
 		session := sessions.Default(c)
@@ -91,7 +95,7 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	setupLogin(&user, c)
+	setupLogin(user, c)
 }
 
 // setup session & cookies and then return user info
@@ -120,6 +124,8 @@ func setupLogin(user *model.User, c *gin.Context) {
 			"role":         user.Role,
 			"status":       user.Status,
 			"group":        user.Group,
+			// 管理后台权限，前端据此展示可访问的管理页面
+			"admin_permissions": model.GetEffectivePermissions(user.Role, user.AdminRoleId),
 		},
 	})
 }
@@ -245,7 +251,6 @@ func Register(c *gin.Context) {
 		token := model.Token{
 			UserId:             insertedUser.Id, // 使用插入后的用户ID
 			Name:               cleanUser.Username + "的初始令牌",
-			Key:                key,
 			CreatedTime:        common.GetTimestamp(),
 			AccessedTime:       common.GetTimestamp(),
 			ExpiredTime:        -1,     // 永不过期
@@ -253,6 +258,7 @@ func Register(c *gin.Context) {
 			UnlimitedQuota:     true,
 			ModelLimitsEnabled: false,
 		}
+		token.SetKey(key)
 		if setting.DefaultUseAutoGroup {
 			token.Group = "auto"
 		}
@@ -442,6 +448,11 @@ func GetSelf(c *gin.Context) {
 
 	// 获取用户设置并提取sidebar_modules
 	userSetting := user.GetSetting()
+	// 返回解密后的设置，数据库中的 webhook 密钥为密文
+	userSettingJSON := user.Setting
+	if settingBytes, err := common.Marshal(userSetting); err == nil {
+		userSettingJSON = string(settingBytes)
+	}
 
 	// 构建响应数据，包含用户信息和权限
 	responseData := map[string]interface{}{
@@ -466,10 +477,12 @@ func GetSelf(c *gin.Context) {
 		"aff_history_quota": user.AffHistoryQuota,
 		"inviter_id":        user.InviterId,
 		"linux_do_id":       user.LinuxDOId,
-		"setting":           user.Setting,
+		"setting":           userSettingJSON,
 		"stripe_customer":   user.StripeCustomer,
 		"sidebar_modules":   userSetting.SidebarModules, // 正确提取sidebar_modules字段
 		"permissions":       permissions,                // 新增权限字段
+		"admin_role_id":     user.AdminRoleId,
+		"admin_permissions": model.GetEffectivePermissions(user.Role, user.AdminRoleId),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -597,9 +610,34 @@ func GetUserModels(c *gin.Context) {
 	return
 }
 
+// checkManageTarget 管理操作只能作用于权限等级严格低于自己真实角色的其他用户，
+// 自定义角色只授予接口权限，不能用于修改自己或同级用户；超级管理员不受限制
+func checkManageTarget(c *gin.Context, target *model.User) string {
+	if c.GetInt("role") == common.RoleRootUser {
+		return ""
+	}
+	if target.Id == c.GetInt("id") {
+		return "无法通过管理接口修改自己的账户"
+	}
+	if c.GetInt("role") <= target.Role {
+		return "无权更新同权限等级或更高权限等级的用户信息"
+	}
+	return ""
+}
+
 func UpdateUser(c *gin.Context) {
 	var updatedUser model.User
-	err := json.NewDecoder(c.Request.Body).Decode(&updatedUser)
+	// 信用额度仅在请求中显式提供时更新
+	var billingFields struct {
+		CreditLimit *int `json:"credit_limit"`
+	}
+	body, err := common.GetRequestBody(c)
+	if err == nil {
+		err = common.Unmarshal(body, &updatedUser)
+	}
+	if err == nil {
+		err = common.Unmarshal(body, &billingFields)
+	}
 	if err != nil || updatedUser.Id == 0 {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -623,10 +661,10 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 	myRole := c.GetInt("role")
-	if myRole <= originUser.Role && myRole != common.RoleRootUser {
+	if msg := checkManageTarget(c, originUser); msg != "" {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "无权更新同权限等级或更高权限等级的用户信息",
+			"message": msg,
 		})
 		return
 	}
@@ -637,6 +675,23 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	updateCreditLimit := billingFields.CreditLimit != nil && *billingFields.CreditLimit != originUser.CreditLimit
+	if updateCreditLimit {
+		if !slices.Contains(c.GetStringSlice("admin_permissions"), constant.PermissionBillingWrite) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无权修改用户的信用额度",
+			})
+			return
+		}
+		if *billingFields.CreditLimit < 0 {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "信用额度不能为负数",
+			})
+			return
+		}
+	}
 	if updatedUser.Password == "$I_LOVE_U" {
 		updatedUser.Password = "" // rollback to what it should be
 	}
@@ -645,6 +700,13 @@ func UpdateUser(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	if updateCreditLimit {
+		if err := model.UpdateUserCreditLimit(originUser.Id, *billingFields.CreditLimit); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户信用额度从 %s修改为 %s", logger.LogQuota(originUser.CreditLimit), logger.LogQuota(*billingFields.CreditLimit)))
+	}
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", logger.LogQuota(originUser.Quota), logger.LogQuota(updatedUser.Quota)))
 	}
@@ -684,7 +746,10 @@ func UpdateSelf(c *gin.Context) {
 		}
 
 		// 保存更新后的设置
-		user.SetSetting(currentSetting)
+		if err := user.SetSetting(currentSetting); err != nil {
+			common.ApiError(c, err)
+			return
+		}
 		if err := user.Update(false); err != nil {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
@@ -788,11 +853,10 @@ func DeleteUser(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	myRole := c.GetInt("role")
-	if myRole <= originUser.Role {
+	if msg := checkManageTarget(c, originUser); msg != "" {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "无权删除同权限等级或更高权限等级的用户",
+			"message": msg,
 		})
 		return
 	}
@@ -908,10 +972,10 @@ func ManageUser(c *gin.Context) {
 		return
 	}
 	myRole := c.GetInt("role")
-	if myRole <= user.Role && myRole != common.RoleRootUser {
+	if msg := checkManageTarget(c, &user); msg != "" {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "无权更新同权限等级或更高权限等级的用户信息",
+			"message": msg,
 		})
 		return
 	}
@@ -1241,20 +1305,21 @@ func UpdateUserSetting(c *gin.Context) {
 		return
 	}
 
-	// 构建设置
+	// 构建设置，Webhook 密钥同时用于任务回调签名，未提供新密钥时保留原密钥
 	settings := dto.UserSetting{
 		NotifyType:            req.QuotaWarningType,
 		QuotaWarningThreshold: req.QuotaWarningThreshold,
 		AcceptUnsetRatioModel: req.AcceptUnsetModelRatioModel,
 		RecordIpLog:           req.RecordIpLog,
+		WebhookSecret:         user.GetSetting().WebhookSecret,
+	}
+	if req.WebhookSecret != "" {
+		settings.WebhookSecret = req.WebhookSecret
 	}
 
 	// 如果是webhook类型,添加webhook相关设置
 	if req.QuotaWarningType == dto.NotifyTypeWebhook {
 		settings.WebhookUrl = req.WebhookUrl
-		if req.WebhookSecret != "" {
-			settings.WebhookSecret = req.WebhookSecret
-		}
 	}
 
 	// 如果提供了通知邮箱，添加到设置中
@@ -1280,7 +1345,10 @@ func UpdateUserSetting(c *gin.Context) {
 	}
 
 	// 更新用户设置
-	user.SetSetting(settings)
+	if err := user.SetSetting(settings); err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	if err := user.Update(false); err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCheckManageTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		myId       int
+		myRole     int
+		targetId   int
+		targetRole int
+		allowed    bool
+	}{
+		{name: "root edits itself", myId: 1, myRole: common.RoleRootUser, targetId: 1, targetRole: common.RoleRootUser, allowed: true},
+		{name: "root edits another root", myId: 1, myRole: common.RoleRootUser, targetId: 2, targetRole: common.RoleRootUser, allowed: true},
+		{name: "root edits admin", myId: 1, myRole: common.RoleRootUser, targetId: 2, targetRole: common.RoleAdminUser, allowed: true},
+		{name: "admin edits common user", myId: 2, myRole: common.RoleAdminUser, targetId: 3, targetRole: common.RoleCommonUser, allowed: true},
+		{name: "admin edits itself", myId: 2, myRole: common.RoleAdminUser, targetId: 2, targetRole: common.RoleAdminUser, allowed: false},
+		{name: "admin edits another admin", myId: 2, myRole: common.RoleAdminUser, targetId: 4, targetRole: common.RoleAdminUser, allowed: false},
+		{name: "admin edits root", myId: 2, myRole: common.RoleAdminUser, targetId: 1, targetRole: common.RoleRootUser, allowed: false},
+		// 持有自定义角色的普通用户不能管理同级用户
+		{name: "role holder edits common user", myId: 3, myRole: common.RoleCommonUser, targetId: 5, targetRole: common.RoleCommonUser, allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("id", tt.myId)
+			c.Set("role", tt.myRole)
+			msg := checkManageTarget(c, &model.User{Id: tt.targetId, Role: tt.targetRole})
+			if tt.allowed {
+				require.Empty(t, msg)
+			} else {
+				require.NotEmpty(t, msg)
+			}
+		})
+	}
+}
+
+// setupControllerTestDB 使用内存 SQLite 替换全局 DB，测试结束后恢复
+func setupControllerTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// 内存库每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	origDB, origLogDB, origRedis := model.DB, model.LOG_DB, common.RedisEnabled
+	t.Cleanup(func() {
+		model.DB = origDB
+		model.LOG_DB = origLogDB
+		common.RedisEnabled = origRedis
+	})
+	model.DB = db
+	model.LOG_DB = db
+	common.RedisEnabled = false
+}
+
+func createControllerTestUser(t *testing.T, username string, role int) *model.User {
+	t.Helper()
+	user := &model.User{
+		Username:    username,
+		Password:    "password",
+		DisplayName: username,
+		Role:        role,
+		Status:      common.UserStatusEnabled,
+		AffCode:     common.GetRandomString(4),
+	}
+	require.NoError(t, model.DB.Create(user).Error)
+	return user
+}
+
+func TestUpdateUser_RootAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupControllerTestDB(t, &model.User{})
+	root := createControllerTestUser(t, "root", common.RoleRootUser)
+	otherRoot := createControllerTestUser(t, "root2", common.RoleRootUser)
+
+	for _, target := range []*model.User{root, otherRoot} {
+		body, err := common.Marshal(map[string]any{
+			"id":           target.Id,
+			"username":     target.Username,
+			"display_name": "renamed",
+			"role":         common.RoleRootUser,
+		})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/api/user/", bytes.NewReader(body))
+		c.Set("id", root.Id)
+		c.Set("role", common.RoleRootUser)
+
+		UpdateUser(c)
+
+		require.Contains(t, w.Body.String(), `"success":true`)
+		updated, err := model.GetUserById(target.Id, false)
+		require.NoError(t, err)
+		require.Equal(t, "renamed", updated.DisplayName)
+	}
+}
+
+func TestUpdateUser_CreditLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupControllerTestDB(t, &model.User{}, &model.Log{})
+	admin := createControllerTestUser(t, "admin", common.RoleAdminUser)
+	target := createControllerTestUser(t, "postpaid", common.RoleCommonUser)
+	require.NoError(t, model.UpdateUserCreditLimit(target.Id, 1000))
+
+	tests := []struct {
+		name        string
+		creditLimit any
+		permissions []string
+		wantSuccess bool
+		wantLimit   int
+	}{
+		{name: "omitted keeps limit", permissions: []string{constant.PermissionUserWrite}, wantSuccess: true, wantLimit: 1000},
+		{name: "unchanged without billing permission", creditLimit: 1000, permissions: []string{constant.PermissionUserWrite}, wantSuccess: true, wantLimit: 1000},
+		{name: "change without billing permission", creditLimit: 5000, permissions: []string{constant.PermissionUserWrite}, wantSuccess: false, wantLimit: 1000},
+		{name: "change with billing permission", creditLimit: 0, permissions: []string{constant.PermissionUserWrite, constant.PermissionBillingWrite}, wantSuccess: true, wantLimit: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := map[string]any{
+				"id":           target.Id,
+				"username":     target.Username,
+				"display_name": target.DisplayName,
+				"role":         common.RoleCommonUser,
+			}
+			if tt.creditLimit != nil {
+				req["credit_limit"] = tt.creditLimit
+			}
+			body, err := common.Marshal(req)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/api/user/", bytes.NewReader(body))
+			c.Set("id", admin.Id)
+			c.Set("role", common.RoleAdminUser)
+			c.Set("admin_permissions", tt.permissions)
+
+			UpdateUser(c)
+
+			require.Contains(t, w.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
+			updated, err := model.GetUserById(target.Id, false)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantLimit, updated.CreditLimit)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -60,95 +61,27 @@ func VideoProxy(c *gin.Context) {
 		return
 	}
 
-	channel, err := model.CacheGetChannel(task.ChannelId)
-	if err != nil {
-		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to get task %s: not found", taskID))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"message": "Failed to retrieve channel information",
-				"type":    "server_error",
-			},
-		})
-		return
-	}
-	baseURL := channel.GetBaseURL()
-	if baseURL == "" {
-		baseURL = "https://api.openai.com"
-	}
-
-	var videoURL string
-	proxy := channel.GetSetting().Proxy
-	client, err := service.GetHttpClientWithProxy(proxy)
-	if err != nil {
-		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to create proxy client for task %s: %s", taskID, err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"message": "Failed to create proxy client",
-				"type":    "server_error",
-			},
-		})
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
-	defer cancel()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "", nil)
-	if err != nil {
-		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to create request: %s", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"message": "Failed to create proxy request",
-				"type":    "server_error",
-			},
-		})
-		return
-	}
-
-	switch channel.Type {
-	case constant.ChannelTypeGemini:
-		apiKey := task.PrivateData.Key
-		if apiKey == "" {
-			logger.LogError(c.Request.Context(), fmt.Sprintf("Missing stored API key for Gemini task %s", taskID))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": gin.H{
-					"message": "API key not stored for task",
-					"type":    "server_error",
-				},
-			})
+	if obj := model.GetStoredMediaObject(model.MediaSourceTask, task.TaskID); obj != nil {
+		if signedURL, err := service.GetMediaObjectSignedUrl(c.Request.Context(), obj); err == nil {
+			c.Redirect(http.StatusFound, signedURL)
 			return
 		}
-
-		videoURL, err = getGeminiVideoURL(channel, task, apiKey)
-		if err != nil {
-			logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to resolve Gemini video URL for task %s: %s", taskID, err.Error()))
-			c.JSON(http.StatusBadGateway, gin.H{
-				"error": gin.H{
-					"message": "Failed to resolve Gemini video URL",
-					"type":    "server_error",
-				},
-			})
-			return
-		}
-		req.Header.Set("x-goog-api-key", apiKey)
-	case constant.ChannelTypeOpenAI, constant.ChannelTypeSora:
-		videoURL = fmt.Sprintf("%s/v1/videos/%s/content", baseURL, task.TaskID)
-		req.Header.Set("Authorization", "Bearer "+channel.Key)
-	default:
-		// Video URL is directly in task.FailReason
-		videoURL = task.FailReason
 	}
 
-	req.URL, err = url.Parse(videoURL)
-	if err != nil {
-		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to parse URL %s: %s", videoURL, err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	client, req, reqErr := newVideoContentRequest(ctx, task)
+	if reqErr != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to build video request for task %s: %s", taskID, reqErr.Error()))
+		c.JSON(reqErr.status, gin.H{
 			"error": gin.H{
-				"message": "Failed to create proxy request",
+				"message": reqErr.message,
 				"type":    "server_error",
 			},
 		})
 		return
 	}
+	videoURL := req.URL.String()
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -187,3 +120,64 @@ func VideoProxy(c *gin.Context) {
 		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to stream video content: %s", err.Error()))
 	}
 }
+
+// videoContentRequestError 构造上游视频请求失败时返回给客户端的状态码与信息
+type videoContentRequestError struct {
+	status  int
+	message string
+	err     error
+}
+
+func (e *videoContentRequestError) Error() string {
+	return fmt.Sprintf("%s: %v", e.message, e.err)
+}
+
+// newVideoContentRequest 根据渠道类型构造获取任务视频内容的上游请求
+func newVideoContentRequest(ctx context.Context, task *model.Task) (*http.Client, *http.Request, *videoContentRequestError) {
+	channel, err := model.CacheGetChannel(task.ChannelId)
+	if err != nil {
+		return nil, nil, &videoContentRequestError{http.StatusInternalServerError, "Failed to retrieve channel information", err}
+	}
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" {
+		baseURL = "https://api.openai.com"
+	}
+
+	var videoURL string
+	proxy := channel.GetSetting().Proxy
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, nil, &videoContentRequestError{http.StatusInternalServerError, "Failed to create proxy client", err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "", nil)
+	if err != nil {
+		return nil, nil, &videoContentRequestError{http.StatusInternalServerError, "Failed to create proxy request", err}
+	}
+
+	switch channel.Type {
+	case constant.ChannelTypeGemini:
+		apiKey := task.PrivateData.Key
+		if apiKey == "" {
+			return nil, nil, &videoContentRequestError{http.StatusInternalServerError, "API key not stored for task", errors.New("missing stored API key")}
+		}
+
+		videoURL, err = getGeminiVideoURL(channel, task, apiKey)
+		if err != nil {
+			return nil, nil, &videoContentRequestError{http.StatusBadGateway, "Failed to resolve Gemini video URL", err}
+		}
+		req.Header.Set("x-goog-api-key", apiKey)
+	case constant.ChannelTypeOpenAI, constant.ChannelTypeSora:
+		videoURL = fmt.Sprintf("%s/v1/videos/%s/content", baseURL, task.TaskID)
+		req.Header.Set("Authorization", "Bearer "+channel.Key)
+	default:
+		// Video URL is directly in task.FailReason
+		videoURL = task.FailReason
+	}
+
+	req.URL, err = url.Parse(videoURL)
+	if err != nil {
+		return nil, nil, &videoContentRequestError{http.StatusInternalServerError, "Failed to create proxy request", err}
+	}
+	return client, req, nil
+}
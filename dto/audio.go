@@ -17,6 +17,7 @@ type AudioRequest struct {
 	ResponseFormat string          `json:"response_format,omitempty"`
 	Speed          float64         `json:"speed,omitempty"`
 	StreamFormat   string          `json:"stream_format,omitempty"`
+	Stream         json.RawMessage `json:"stream,omitempty"` // 转写接口的表单字段 stream=true，值可能是布尔或字符串
 	Metadata       json.RawMessage `json:"metadata,omitempty"`
 }
 
@@ -32,7 +33,10 @@ func (r *AudioRequest) GetTokenCountMeta() *types.TokenCountMeta {
 }
 
 func (r *AudioRequest) IsStream(c *gin.Context) bool {
-	return r.StreamFormat == "sse"
+	if r.StreamFormat == "sse" {
+		return true
+	}
+	return strings.Trim(string(r.Stream), `"`) == "true"
 }
 
 func (r *AudioRequest) SetModelName(modelName string) {
@@ -65,3 +69,25 @@ type Segment struct {
 	CompressionRatio float64 `json:"compression_ratio"`
 	NoSpeechProb     float64 `json:"no_speech_prob"`
 }
+
+const (
+	AudioSpeechStreamEventDelta     = "speech.audio.delta"
+	AudioSpeechStreamEventDone      = "speech.audio.done"
+	AudioTranscriptStreamEventDelta = "transcript.text.delta"
+	AudioTranscriptStreamEventDone  = "transcript.text.done"
+)
+
+// AudioStreamEvent 语音合成 stream_format=sse 与转写 stream=true 的 SSE 事件
+type AudioStreamEvent struct {
+	Type  string            `json:"type"`
+	Audio string            `json:"audio,omitempty"`
+	Delta string            `json:"delta,omitempty"`
+	Text  string            `json:"text,omitempty"`
+	Usage *AudioStreamUsage `json:"usage,omitempty"`
+}
+
+type AudioStreamUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
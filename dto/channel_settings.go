@@ -31,6 +31,9 @@ type ChannelOtherSettings struct {
 	DisableStore          bool          `json:"disable_store,omitempty"`           // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
 	AllowSafetyIdentifier bool          `json:"allow_safety_identifier,omitempty"` // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
 	AwsKeyType            AwsKeyType    `json:"aws_key_type,omitempty"`
+	TaskConcurrency       int           `json:"task_concurrency,omitempty"`     // 异步任务并发上限，超出后进入排队，0 表示不限制
+	AudioInputFormats     []string      `json:"audio_input_formats,omitempty"`  // 上游语音识别接受的上传格式，为空表示不转码
+	AudioOutputFormats    []string      `json:"audio_output_formats,omitempty"` // 上游语音合成可返回的格式，为空表示不转码
 }
 
 func (s *ChannelOtherSettings) IsOpenRouterEnterprise() bool {
@@ -0,0 +1,48 @@
+package dto
+
+import "encoding/json"
+
+// ClaudeMessageBatchCreateRequest POST /v1/messages/batches 请求体
+type ClaudeMessageBatchCreateRequest struct {
+	Requests []ClaudeMessageBatchRequestItem `json:"requests"`
+}
+
+type ClaudeMessageBatchRequestItem struct {
+	CustomId string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+type ClaudeMessageBatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// ClaudeMessageBatch 批处理对象，时间均为 RFC 3339 格式
+type ClaudeMessageBatch struct {
+	Id                string                          `json:"id"`
+	Type              string                          `json:"type"`
+	ProcessingStatus  string                          `json:"processing_status"`
+	RequestCounts     ClaudeMessageBatchRequestCounts `json:"request_counts"`
+	EndedAt           *string                         `json:"ended_at"`
+	CreatedAt         string                          `json:"created_at"`
+	ExpiresAt         string                          `json:"expires_at"`
+	ArchivedAt        *string                         `json:"archived_at"`
+	CancelInitiatedAt *string                         `json:"cancel_initiated_at"`
+	ResultsUrl        *string                         `json:"results_url"`
+}
+
+type ClaudeMessageBatchList struct {
+	Data    []*ClaudeMessageBatch `json:"data"`
+	HasMore bool                  `json:"has_more"`
+	FirstId *string               `json:"first_id"`
+	LastId  *string               `json:"last_id"`
+}
+
+// ClaudeMessageBatchResult 结果文件中的一行
+type ClaudeMessageBatchResult struct {
+	CustomId string          `json:"custom_id"`
+	Result   json.RawMessage `json:"result"`
+}
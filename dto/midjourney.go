@@ -22,6 +22,7 @@ type MidjourneyRequest struct {
 	State       string   `json:"state"`
 	TaskId      string   `json:"taskId"`
 	Base64Array []string `json:"base64Array"`
+	Base64      string   `json:"base64"`
 	Content     string   `json:"content"`
 	MaskBase64  string   `json:"maskBase64"`
 }
@@ -170,6 +170,7 @@ type ImageResponse struct {
 	Data     []ImageData     `json:"data"`
 	Created  int64           `json:"created"`
 	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Usage    json.RawMessage `json:"usage,omitempty"`
 }
 type ImageData struct {
 	Url           string `json:"url"`
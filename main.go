@@ -106,6 +106,27 @@ func main() {
 	// Codex credential auto-refresh check every 10 minutes, refresh when expires within 1 day
 	service.StartCodexCredentialAutoRefreshTask()
 
+	// 月度账单邮件
+	service.StartStatementEmailTask()
+
+	// 后付费账单出账与逾期暂停
+	service.StartPostpaidBillingTask()
+
+	// LDAP 目录用户同步
+	service.StartLdapSyncTask()
+
+	// 异步任务完成回调投递
+	service.StartTaskCallbackTask()
+
+	// 异步任务超时对账
+	service.StartTaskReconcileTask()
+
+	// 生成结果转存与保留期清理
+	controller.StartMediaArchiveTask()
+
+	// Claude 消息批处理执行
+	controller.StartMessageBatchTask()
+
 	if common.IsMasterNode && constant.UpdateTask {
 		gopool.Go(func() {
 			controller.UpdateMidjourneyTaskBulk()
@@ -31,6 +31,13 @@ func validUserInfo(username string, role int) bool {
 }
 
 func authHelper(c *gin.Context, minRole int) {
+	authHelperWith(c, func(role int, userId int) bool {
+		return role >= minRole
+	})
+}
+
+// authHelperWith 完成登录态校验后由 allow 判断是否放行
+func authHelperWith(c *gin.Context, allow func(role int, userId int) bool) {
 	session := sessions.Default(c)
 	username := session.Get("username")
 	role := session.Get("role")
@@ -109,7 +116,7 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
-	if role.(int) < minRole {
+	if !allow(role.(int), id.(int)) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "无权进行此操作，权限不足",
@@ -157,6 +164,31 @@ func TryUserAuth() func(c *gin.Context) {
 	}
 }
 
+// MediaAuth 媒体文件访问鉴权：浏览器直接打开链接时使用登录会话，API 调用方使用令牌
+func MediaAuth() func(c *gin.Context) {
+	tokenAuth := TokenAuth()
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		id, ok := session.Get("id").(int)
+		if !ok {
+			tokenAuth(c)
+			return
+		}
+		user, err := model.GetUserCache(id)
+		if err != nil || user.Status != common.UserStatusEnabled {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "用户已被封禁",
+			})
+			c.Abort()
+			return
+		}
+		c.Set("id", id)
+		c.Set("role", session.Get("role"))
+		c.Next()
+	}
+}
+
 func UserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		authHelper(c, common.RoleCommonUser)
@@ -175,6 +207,29 @@ func RootAuth() func(c *gin.Context) {
 	}
 }
 
+// PermissionAuth 要求用户拥有任一指定的管理权限。
+// 自定义角色只授予接口访问权限，不提升权限等级，"role" 始终为用户的真实角色，
+// 已授予的权限另存于 "admin_permissions"
+func PermissionAuth(permissions ...string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		authHelperWith(c, func(role int, userId int) bool {
+			if role >= common.RoleRootUser {
+				c.Set("admin_permissions", model.GetEffectivePermissions(role, 0))
+				return true
+			}
+			userCache, err := model.GetUserCache(userId)
+			if err != nil {
+				return false
+			}
+			if !model.HasAnyPermission(role, userCache.AdminRoleId, permissions...) {
+				return false
+			}
+			c.Set("admin_permissions", model.GetEffectivePermissions(role, userCache.AdminRoleId))
+			return true
+		})
+	}
+}
+
 func WssAuth(c *gin.Context) {
 
 }
@@ -227,15 +282,21 @@ func TokenAuth() func(c *gin.Context) {
 			if strings.HasPrefix(key, "Bearer ") || strings.HasPrefix(key, "bearer ") {
 				key = strings.TrimSpace(key[7:])
 			}
-			key = strings.TrimPrefix(key, "sk-")
-			parts = strings.Split(key, "-")
-			key = parts[0]
+		}
+		key = strings.TrimPrefix(key, "sk-")
+		var token *model.Token
+		var err error
+		if service.IsDerivedTokenKey(key) {
+			// 派生令牌为自包含的 JWT，不支持 -channelId 后缀
+			token, err = service.ValidateDerivedToken(c, key)
+		} else if service.IsOIDCRelayToken(key) {
+			// IdP 签发的 JWT，映射到用户的系统令牌
+			token, err = service.ValidateOIDCRelayToken(key)
 		} else {
-			key = strings.TrimPrefix(key, "sk-")
 			parts = strings.Split(key, "-")
 			key = parts[0]
+			token, err = model.ValidateUserToken(key)
 		}
-		token, err := model.ValidateUserToken(key)
 		if token != nil {
 			id := c.GetInt("id")
 			if id == 0 {
@@ -273,6 +334,10 @@ func TokenAuth() func(c *gin.Context) {
 			abortWithOpenAiMessage(c, http.StatusForbidden, "用户已被封禁")
 			return
 		}
+		if userCache.BillingSuspended {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "账单已逾期，服务已暂停，请结清账单后继续使用", types.ErrorCodeBillingSuspended)
+			return
+		}
 
 		userCache.WriteContext(c)
 
@@ -310,6 +375,7 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	c.Set("id", token.UserId)
 	c.Set("token_id", token.Id)
 	c.Set("token_key", token.Key)
+	common.SetContextKey(c, constant.ContextKeyTokenKeyHash, token.KeyHash)
 	c.Set("token_name", token.Name)
 	c.Set("token_unlimited_quota", token.UnlimitedQuota)
 	if !token.UnlimitedQuota {
@@ -323,6 +389,8 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	common.SetContextKey(c, constant.ContextKeyTokenScopes, token.GetScopes())
+	common.SetContextKey(c, constant.ContextKeyTokenCallbackUrl, token.CallbackUrl)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
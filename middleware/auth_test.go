@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupPermissionAuthTest 使用内存 SQLite 替换全局 DB，并创建一个只有渠道读权限的角色
+func setupPermissionAuthTest(t *testing.T) int {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// 内存库每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.AdminRole{}))
+	origDB, origRedis := model.DB, common.RedisEnabled
+	t.Cleanup(func() {
+		model.DB = origDB
+		common.RedisEnabled = origRedis
+	})
+	model.DB = db
+	common.RedisEnabled = false
+
+	role := &model.AdminRole{Name: "channel-reader"}
+	role.SetPermissions([]string{constant.PermissionChannelRead})
+	require.NoError(t, role.Insert())
+	return role.Id
+}
+
+func createPermissionAuthUser(t *testing.T, username string, role int, adminRoleId int) *model.User {
+	t.Helper()
+	user := &model.User{
+		Username:    username,
+		Password:    "password",
+		DisplayName: username,
+		Role:        role,
+		Status:      common.UserStatusEnabled,
+		AdminRoleId: adminRoleId,
+		AffCode:     common.GetRandomString(4),
+	}
+	user.SetAccessToken(common.GetRandomString(32))
+	require.NoError(t, model.DB.Create(user).Error)
+	return user
+}
+
+func TestPermissionAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	roleId := setupPermissionAuthTest(t)
+
+	root := createPermissionAuthUser(t, "root", common.RoleRootUser, 0)
+	admin := createPermissionAuthUser(t, "admin", common.RoleAdminUser, 0)
+	reader := createPermissionAuthUser(t, "reader", common.RoleCommonUser, roleId)
+	plain := createPermissionAuthUser(t, "plain", common.RoleCommonUser, 0)
+
+	tests := []struct {
+		name       string
+		user       *model.User
+		permission string
+		allowed    bool
+	}{
+		{name: "root passes root-only", user: root, permission: constant.PermissionOptionWrite, allowed: true},
+		{name: "admin passes legacy permission", user: admin, permission: constant.PermissionChannelWrite, allowed: true},
+		{name: "admin blocked from root-only", user: admin, permission: constant.PermissionChannelKey, allowed: false},
+		{name: "role holder passes granted permission", user: reader, permission: constant.PermissionChannelRead, allowed: true},
+		{name: "role holder blocked outside role", user: reader, permission: constant.PermissionChannelWrite, allowed: false},
+		{name: "role holder blocked from user management", user: reader, permission: constant.PermissionUserWrite, allowed: false},
+		{name: "plain user blocked", user: plain, permission: constant.PermissionChannelRead, allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRole int
+			var gotPermissions []string
+			router := gin.New()
+			router.Use(sessions.Sessions("session", cookie.NewStore([]byte("secret"))))
+			router.GET("/", PermissionAuth(tt.permission), func(c *gin.Context) {
+				gotRole = c.GetInt("role")
+				gotPermissions = c.GetStringSlice("admin_permissions")
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tt.user.GetAccessToken())
+			req.Header.Set("New-Api-User", strconv.Itoa(tt.user.Id))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if !tt.allowed {
+				require.NotEqual(t, http.StatusNoContent, w.Code)
+				return
+			}
+			require.Equal(t, http.StatusNoContent, w.Code)
+			// 自定义角色只授予接口权限，请求上下文中的角色保持用户的真实角色
+			require.Equal(t, tt.user.Role, gotRole)
+			require.Contains(t, gotPermissions, tt.permission)
+		})
+	}
+}
@@ -202,6 +202,10 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 				}
 			}
 			modelRequest.Model = midjourneyModel
+			// Midjourney 模拟：按图片或识图模型选择渠道
+			if emulationModel := service.GetMjEmulationModel(common.GetContextKeyInt(c, constant.ContextKeyUserId), midjourneyModel, &midjourneyRequest); emulationModel != "" {
+				modelRequest.Model = emulationModel
+			}
 		}
 		c.Set("relay_mode", relayMode)
 	} else if strings.Contains(c.Request.URL.Path, "/suno/") {
@@ -273,7 +277,8 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/realtime") {
 		//wss://api.openai.com/v1/realtime?model=gpt-4o-realtime-preview-2024-10-01
-		modelRequest.Model = c.Query("model")
+		// /v1/realtime/sessions 的模型在请求体中
+		modelRequest.Model = common.GetStringIfEmpty(c.Query("model"), modelRequest.Model)
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/moderations") {
 		if modelRequest.Model == "" {
@@ -287,7 +292,7 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/images/generations") {
 		modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "dall-e")
-	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") {
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") || strings.HasPrefix(c.Request.URL.Path, "/v1/images/variations") {
 		//modelRequest.Model = common.GetStringIfEmpty(c.PostForm("model"), "gpt-image-1")
 		contentType := c.ContentType()
 		if slices.Contains([]string{gin.MIMEPOSTForm, gin.MIMEMultipartPOSTForm}, contentType) {
@@ -296,6 +301,10 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 				modelRequest.Model = req.Model
 			}
 		}
+		if strings.HasPrefix(c.Request.URL.Path, "/v1/images/variations") {
+			// OpenAI 的变体接口仅支持 dall-e-2
+			modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "dall-e-2")
+		}
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/audio") {
 		relayMode := relayconstant.RelayModeAudioSpeech
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithScimError(c *gin.Context, status int, detail string) {
+	c.JSON(status, dto.ScimError{
+		Schemas: []string{dto.ScimSchemaError},
+		Status:  strconv.Itoa(status),
+		Detail:  detail,
+	})
+	c.Abort()
+}
+
+// ScimAuth 校验 IdP 调用 SCIM 接口时携带的 Bearer 令牌
+func ScimAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setting := system_setting.GetScimSettings()
+		if !setting.Enabled || setting.BearerSecret == "" {
+			abortWithScimError(c, http.StatusForbidden, "SCIM 未启用")
+			return
+		}
+		auth := c.Request.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") && !strings.HasPrefix(auth, "bearer ") {
+			abortWithScimError(c, http.StatusUnauthorized, "未提供 SCIM 令牌")
+			return
+		}
+		key := strings.TrimSpace(auth[7:])
+		if subtle.ConstantTimeCompare([]byte(key), []byte(setting.BearerSecret)) != 1 {
+			abortWithScimError(c, http.StatusUnauthorized, "无效的 SCIM 令牌")
+			return
+		}
+		c.Next()
+	}
+}
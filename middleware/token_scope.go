@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenHasScope 判断当前令牌是否拥有指定权限范围，未配置范围的令牌拥有全部权限
+func tokenHasScope(c *gin.Context, scope string) bool {
+	scopes := common.GetContextKeyStringSlice(c, constant.ContextKeyTokenScopes)
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenScopeDeniedMessage(scope string) string {
+	return fmt.Sprintf("该令牌未授权访问此接口，缺少权限范围: %s", scope)
+}
+
+// TokenScope 校验令牌是否拥有访问当前路由所需的权限范围，需在 TokenAuth 之后使用
+func TokenScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tokenHasScope(c, scope) {
+			abortWithOpenAiMessage(c, http.StatusForbidden, tokenScopeDeniedMessage(scope), types.ErrorCodeTokenScopeDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+// MidjourneyTokenScope Midjourney 路由使用 Midjourney 格式返回错误
+func MidjourneyTokenScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tokenHasScope(c, constant.TokenScopeMidjourney) {
+			abortWithMidjourneyMessage(c, http.StatusForbidden, constant.MjRequestError, tokenScopeDeniedMessage(constant.TokenScopeMidjourney))
+			return
+		}
+		c.Next()
+	}
+}
+
+// GeminiTokenScope Gemini 原生接口通过路径中的 action 区分权限范围
+func GeminiTokenScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := geminiActionScope(c.Request.URL.Path)
+		if !tokenHasScope(c, scope) {
+			abortWithOpenAiMessage(c, http.StatusForbidden, tokenScopeDeniedMessage(scope), types.ErrorCodeTokenScopeDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+func geminiActionScope(path string) string {
+	idx := strings.LastIndex(path, ":")
+	if idx == -1 {
+		return constant.TokenScopeChat
+	}
+	switch path[idx+1:] {
+	case "embedContent", "batchEmbedContents":
+		return constant.TokenScopeEmbeddings
+	case "predict":
+		return constant.TokenScopeImages
+	case "predictLongRunning":
+		return constant.TokenScopeTasks
+	default:
+		return constant.TokenScopeChat
+	}
+}
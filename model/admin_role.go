@@ -0,0 +1,202 @@
+package model
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"gorm.io/gorm"
+)
+
+// AdminRole 自定义管理角色，Permissions 以逗号分隔保存 constant.Permissions 中的权限
+type AdminRole struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Description string `json:"description" gorm:"type:varchar(255)"`
+	Permissions string `json:"permissions" gorm:"type:text"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64  `json:"updated_time" gorm:"bigint"`
+}
+
+// 角色权限缓存，多节点部署时最长一分钟后生效
+const adminRoleCacheTTL = time.Minute
+
+var (
+	adminRoleCacheLock     sync.RWMutex
+	adminRoleCache         map[int]map[string]bool
+	adminRoleCacheLoadedAt time.Time
+)
+
+func (r *AdminRole) GetPermissions() []string {
+	permissions := make([]string, 0)
+	for _, p := range strings.Split(r.Permissions, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+func (r *AdminRole) SetPermissions(permissions []string) {
+	r.Permissions = strings.Join(permissions, ",")
+}
+
+func GetAllAdminRoles() ([]*AdminRole, error) {
+	var roles []*AdminRole
+	err := DB.Order("id asc").Find(&roles).Error
+	return roles, err
+}
+
+func GetAdminRoleById(id int) (*AdminRole, error) {
+	var role AdminRole
+	err := DB.First(&role, "id = ?", id).Error
+	return &role, err
+}
+
+func IsAdminRoleNameDuplicated(id int, name string) (bool, error) {
+	var cnt int64
+	err := DB.Model(&AdminRole{}).Where("name = ? AND id <> ?", name, id).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func (r *AdminRole) Insert() error {
+	now := common.GetTimestamp()
+	r.CreatedTime = now
+	r.UpdatedTime = now
+	if err := DB.Create(r).Error; err != nil {
+		return err
+	}
+	resetAdminRoleCache()
+	return nil
+}
+
+func (r *AdminRole) Update() error {
+	r.UpdatedTime = common.GetTimestamp()
+	err := DB.Model(r).Select("name", "description", "permissions", "updated_time").Updates(r).Error
+	if err != nil {
+		return err
+	}
+	resetAdminRoleCache()
+	return nil
+}
+
+// DeleteAdminRoleById 删除角色，并解除已分配该角色的用户
+func DeleteAdminRoleById(id int) error {
+	var userIds []int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("admin_role_id = ?", id).Pluck("id", &userIds).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("admin_role_id = ?", id).Update("admin_role_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&AdminRole{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, userId := range userIds {
+		_ = invalidateUserCache(userId)
+	}
+	resetAdminRoleCache()
+	return nil
+}
+
+// SetUserAdminRole 为用户分配自定义管理角色，roleId 为 0 时取消分配
+func SetUserAdminRole(userId int, roleId int) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Update("admin_role_id", roleId).Error
+	if err != nil {
+		return err
+	}
+	return invalidateUserCache(userId)
+}
+
+func resetAdminRoleCache() {
+	adminRoleCacheLock.Lock()
+	adminRoleCacheLoadedAt = time.Time{}
+	adminRoleCacheLock.Unlock()
+}
+
+func getAdminRolePermissionSet(roleId int) map[string]bool {
+	adminRoleCacheLock.RLock()
+	if adminRoleCache != nil && time.Since(adminRoleCacheLoadedAt) < adminRoleCacheTTL {
+		set := adminRoleCache[roleId]
+		adminRoleCacheLock.RUnlock()
+		return set
+	}
+	adminRoleCacheLock.RUnlock()
+
+	roles, err := GetAllAdminRoles()
+	if err != nil {
+		common.SysLog("failed to load admin roles: " + err.Error())
+		adminRoleCacheLock.RLock()
+		defer adminRoleCacheLock.RUnlock()
+		return adminRoleCache[roleId]
+	}
+	cache := make(map[int]map[string]bool, len(roles))
+	for _, role := range roles {
+		set := make(map[string]bool)
+		for _, p := range role.GetPermissions() {
+			set[p] = true
+		}
+		cache[role.Id] = set
+	}
+	adminRoleCacheLock.Lock()
+	adminRoleCache = cache
+	adminRoleCacheLoadedAt = time.Now()
+	adminRoleCacheLock.Unlock()
+	return cache[roleId]
+}
+
+// GetEffectivePermissions 计算用户实际拥有的管理权限：
+// 超级管理员拥有全部权限；分配了自定义角色的用户仅拥有角色中的权限；
+// 未分配角色的管理员保持原有权限（不含仅限超级管理员的权限）
+func GetEffectivePermissions(role int, adminRoleId int) []string {
+	permissions := make([]string, 0)
+	switch {
+	case role >= common.RoleRootUser:
+		permissions = append(permissions, constant.Permissions...)
+	case adminRoleId > 0:
+		set := getAdminRolePermissionSet(adminRoleId)
+		for _, p := range constant.Permissions {
+			if set[p] {
+				permissions = append(permissions, p)
+			}
+		}
+	case role >= common.RoleAdminUser:
+		for _, p := range constant.Permissions {
+			if !constant.RootOnlyPermissions[p] {
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return permissions
+}
+
+// HasAnyPermission 判断用户是否拥有任一指定权限
+func HasAnyPermission(role int, adminRoleId int, permissions ...string) bool {
+	if role >= common.RoleRootUser {
+		return true
+	}
+	if adminRoleId > 0 {
+		set := getAdminRolePermissionSet(adminRoleId)
+		for _, p := range permissions {
+			if set[p] {
+				return true
+			}
+		}
+		return false
+	}
+	if role >= common.RoleAdminUser {
+		for _, p := range permissions {
+			if !constant.RootOnlyPermissions[p] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setupAdminRoleTest 创建一个只有渠道读写权限的角色，返回角色 ID
+func setupAdminRoleTest(t *testing.T) int {
+	t.Helper()
+	setupTestDB(t, &AdminRole{}, &User{})
+	resetAdminRoleCache()
+	t.Cleanup(resetAdminRoleCache)
+
+	role := &AdminRole{Name: "channel-operator"}
+	role.SetPermissions([]string{constant.PermissionChannelRead, constant.PermissionChannelWrite})
+	require.NoError(t, role.Insert())
+	return role.Id
+}
+
+func TestHasAnyPermission(t *testing.T) {
+	roleId := setupAdminRoleTest(t)
+
+	tests := []struct {
+		name        string
+		role        int
+		adminRoleId int
+		permissions []string
+		want        bool
+	}{
+		{name: "root has root-only permission", role: common.RoleRootUser, permissions: []string{constant.PermissionOptionWrite}, want: true},
+		{name: "admin keeps legacy permission", role: common.RoleAdminUser, permissions: []string{constant.PermissionUserWrite}, want: true},
+		{name: "admin lacks root-only permission", role: common.RoleAdminUser, permissions: []string{constant.PermissionChannelKey}, want: false},
+		{name: "common user without role", role: common.RoleCommonUser, permissions: []string{constant.PermissionChannelRead}, want: false},
+		{name: "common user with role permission", role: common.RoleCommonUser, adminRoleId: roleId, permissions: []string{constant.PermissionChannelWrite}, want: true},
+		{name: "common user outside role", role: common.RoleCommonUser, adminRoleId: roleId, permissions: []string{constant.PermissionUserWrite}, want: false},
+		// 分配角色后管理员只保留角色中的权限，不能借角色叠加原有权限
+		{name: "admin with role restricted to role", role: common.RoleAdminUser, adminRoleId: roleId, permissions: []string{constant.PermissionUserWrite}, want: false},
+		{name: "any of several permissions", role: common.RoleCommonUser, adminRoleId: roleId, permissions: []string{constant.PermissionUserWrite, constant.PermissionChannelRead}, want: true},
+		{name: "unknown role", role: common.RoleCommonUser, adminRoleId: roleId + 100, permissions: []string{constant.PermissionChannelRead}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, HasAnyPermission(tt.role, tt.adminRoleId, tt.permissions...))
+		})
+	}
+}
+
+func TestGetEffectivePermissions(t *testing.T) {
+	roleId := setupAdminRoleTest(t)
+
+	require.Equal(t, constant.Permissions, GetEffectivePermissions(common.RoleRootUser, 0))
+	require.Equal(t, []string{constant.PermissionChannelRead, constant.PermissionChannelWrite},
+		GetEffectivePermissions(common.RoleCommonUser, roleId))
+	require.Empty(t, GetEffectivePermissions(common.RoleCommonUser, 0))
+
+	adminPermissions := GetEffectivePermissions(common.RoleAdminUser, 0)
+	for p := range constant.RootOnlyPermissions {
+		require.NotContains(t, adminPermissions, p)
+	}
+}
+
+func TestAdminRoleUpdate_RevokesPermission(t *testing.T) {
+	roleId := setupAdminRoleTest(t)
+	require.True(t, HasAnyPermission(common.RoleCommonUser, roleId, constant.PermissionChannelWrite))
+
+	role, err := GetAdminRoleById(roleId)
+	require.NoError(t, err)
+	role.SetPermissions([]string{constant.PermissionChannelRead})
+	require.NoError(t, role.Update())
+
+	// 更新后立即失效缓存，不需要等待缓存过期
+	require.False(t, HasAnyPermission(common.RoleCommonUser, roleId, constant.PermissionChannelWrite))
+	require.True(t, HasAnyPermission(common.RoleCommonUser, roleId, constant.PermissionChannelRead))
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DerivedTokenSpend 未启用 Redis 时派生令牌的消费记录，多节点通过数据库共享
+type DerivedTokenSpend struct {
+	Id        string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	Spend     int    `json:"spend"`
+	ExpiresAt int64  `json:"expires_at" gorm:"bigint;index"`
+}
+
+func GetDerivedTokenSpend(id string) int {
+	var record DerivedTokenSpend
+	if err := DB.Where("id = ?", id).Limit(1).Find(&record).Error; err != nil {
+		return 0
+	}
+	return record.Spend
+}
+
+// ReserveDerivedTokenSpend 在不超过 maxSpend 的前提下原子地累加消费额度，返回 false 表示额度不足
+func ReserveDerivedTokenSpend(id string, maxSpend int, quota int, expiresAt int64) (bool, error) {
+	if err := ensureDerivedTokenSpend(id, expiresAt); err != nil {
+		return false, err
+	}
+	result := DB.Model(&DerivedTokenSpend{}).
+		Where("id = ? AND spend + ? <= ?", id, quota, maxSpend).
+		Update("spend", gorm.Expr("spend + ?", quota))
+	return result.RowsAffected > 0, result.Error
+}
+
+// AddDerivedTokenSpend 无条件累加消费额度，quota 为负数时返还
+func AddDerivedTokenSpend(id string, quota int, expiresAt int64) error {
+	if err := ensureDerivedTokenSpend(id, expiresAt); err != nil {
+		return err
+	}
+	return DB.Model(&DerivedTokenSpend{}).Where("id = ?", id).
+		Update("spend", gorm.Expr("spend + ?", quota)).Error
+}
+
+func ensureDerivedTokenSpend(id string, expiresAt int64) error {
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&DerivedTokenSpend{Id: id, ExpiresAt: expiresAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		// 新建记录时顺带清理已过期的记录
+		DB.Where("expires_at < ?", common.GetTimestamp()).Delete(&DerivedTokenSpend{})
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func (user *User) FillUserByLdapId() error {
+	if user.LdapId == "" {
+		return errors.New("ldap id 为空！")
+	}
+	DB.Where(User{LdapId: user.LdapId}).First(user)
+	return nil
+}
+
+func IsLdapIdAlreadyTaken(ldapId string) bool {
+	return DB.Where("ldap_id = ?", ldapId).Find(&User{}).RowsAffected == 1
+}
+
+// GetLdapUsers 分页获取通过 LDAP 登录的用户，用于目录同步
+func GetLdapUsers(offset int, limit int) ([]*User, error) {
+	var users []*User
+	err := DB.Where("ldap_id <> ''").Order("id asc").Offset(offset).Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// UpdateUserDirectoryAttrs 按目录同步结果更新用户的状态、分组与角色，ldapAdmin 表示管理员角色由目录组授予
+func UpdateUserDirectoryAttrs(userId int, status int, group string, role int, ldapAdmin bool) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"status":     status,
+		"group":      group,
+		"role":       role,
+		"ldap_admin": ldapAdmin,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := invalidateUserCache(userId); err != nil {
+		common.SysLog("failed to invalidate user cache: " + err.Error())
+	}
+	return nil
+}
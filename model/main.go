@@ -196,14 +196,26 @@ func InitDB() (err error) {
 		sqlDB.SetConnMaxLifetime(time.Second * time.Duration(common.GetEnvOrDefault("SQL_MAX_LIFETIME", 60)))
 
 		if !common.IsMasterNode {
-			return nil
+			return initTokenKeySalt()
 		}
 		if common.UsingMySQL {
 			//_, _ = sqlDB.Exec("ALTER TABLE channels MODIFY model_mapping TEXT;") // TODO: delete this line when most users have upgraded
 		}
 		common.SysLog("database migration started")
 		err = migrateDB()
-		return err
+		if err != nil {
+			return err
+		}
+		if err = initTokenKeySalt(); err != nil {
+			return err
+		}
+		if err = migrateTokenKeys(); err != nil {
+			return err
+		}
+		if err = scrubMediaObjectSourceUrls(); err != nil {
+			return err
+		}
+		return migrateSecrets()
 	} else {
 		common.FatalLog(err)
 	}
@@ -268,6 +280,14 @@ func migrateDB() error {
 		&TwoFA{},
 		&TwoFABackupCode{},
 		&Checkin{},
+		&PostpaidBill{},
+		&AdminRole{},
+		&MediaObject{},
+		&TaskQueueItem{},
+		&MessageBatch{},
+		&MessageBatchRequest{},
+		&DerivedTokenSpend{},
+		&OIDCRelayIdentity{},
 	)
 	if err != nil {
 		return err
@@ -302,6 +322,14 @@ func migrateDBFast() error {
 		{&TwoFA{}, "TwoFA"},
 		{&TwoFABackupCode{}, "TwoFABackupCode"},
 		{&Checkin{}, "Checkin"},
+		{&PostpaidBill{}, "PostpaidBill"},
+		{&AdminRole{}, "AdminRole"},
+		{&MediaObject{}, "MediaObject"},
+		{&TaskQueueItem{}, "TaskQueueItem"},
+		{&MessageBatch{}, "MessageBatch"},
+		{&MessageBatchRequest{}, "MessageBatchRequest"},
+		{&DerivedTokenSpend{}, "DerivedTokenSpend"},
+		{&OIDCRelayIdentity{}, "OIDCRelayIdentity"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"gorm.io/gorm"
+)
+
+// 转存对象来源
+const (
+	MediaSourceTask       = "task"
+	MediaSourceMidjourney = "midjourney"
+	MediaSourceImage      = "image"
+)
+
+// 转存对象状态
+const (
+	MediaObjectStatusStored  = "stored"
+	MediaObjectStatusFailed  = "failed"
+	MediaObjectStatusDeleted = "deleted"
+)
+
+// MediaObject 转存到本地或 S3 的生成结果，对外通过 /media/{public_id} 访问
+type MediaObject struct {
+	Id          int64  `json:"id"`
+	PublicId    string `json:"public_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	Group       string `json:"group" gorm:"type:varchar(64)"`
+	SourceType  string `json:"source_type" gorm:"type:varchar(20);uniqueIndex:idx_media_source"`
+	SourceId    string `json:"source_id" gorm:"type:varchar(191);uniqueIndex:idx_media_source"`
+	SourceUrl   string `json:"-" gorm:"type:text"`
+	Driver      string `json:"driver" gorm:"type:varchar(20)"`
+	StorageKey  string `json:"-" gorm:"type:varchar(255)"`
+	ContentType string `json:"content_type" gorm:"type:varchar(100)"`
+	Size        int64  `json:"size"`
+	Status      string `json:"status" gorm:"type:varchar(20);index"`
+	Attempts    int    `json:"attempts" gorm:"default:0"`
+	NextRetryAt int64  `json:"next_retry_at" gorm:"bigint;default:0"`
+	LastError   string `json:"last_error" gorm:"type:varchar(255)"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+	ExpiresAt   int64  `json:"expires_at" gorm:"bigint;index"` // 0 表示永久保留
+}
+
+func (m *MediaObject) Insert() error {
+	m.CreatedAt = common.GetTimestamp()
+	return DB.Create(m).Error
+}
+
+func (m *MediaObject) Update() error {
+	return DB.Save(m).Error
+}
+
+func GetMediaObjectByPublicId(publicId string) (*MediaObject, error) {
+	var obj MediaObject
+	err := DB.Where("public_id = ?", publicId).First(&obj).Error
+	return &obj, err
+}
+
+// GetStoredMediaObject 获取来源对应的已转存对象，不存在时返回 nil
+func GetStoredMediaObject(sourceType string, sourceId string) *MediaObject {
+	var obj MediaObject
+	err := DB.Where("source_type = ? AND source_id = ? AND status = ?", sourceType, sourceId, MediaObjectStatusStored).First(&obj).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			common.SysLog("failed to query media object: " + err.Error())
+		}
+		return nil
+	}
+	if obj.ExpiresAt > 0 && obj.ExpiresAt <= common.GetTimestamp() {
+		return nil
+	}
+	return &obj
+}
+
+// GetUnarchivedTasks 获取指定时间后成功、尚未转存的视频任务
+func GetUnarchivedTasks(since int64, limit int) ([]*Task, error) {
+	var tasks []*Task
+	err := DB.Where("status = ? AND updated_at >= ? AND platform <> ?", TaskStatusSuccess, since, constant.TaskPlatformSuno).
+		Where("NOT EXISTS (SELECT 1 FROM media_objects WHERE media_objects.source_type = ? AND media_objects.source_id = tasks.task_id)", MediaSourceTask).
+		Order("id").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// GetUnarchivedMidjourneys 获取指定时间（毫秒）后成功、尚未转存的 Midjourney 任务
+func GetUnarchivedMidjourneys(sinceMs int64, limit int) ([]*Midjourney, error) {
+	var tasks []*Midjourney
+	err := DB.Where("status = ? AND image_url <> '' AND finish_time >= ?", "SUCCESS", sinceMs).
+		Where("NOT EXISTS (SELECT 1 FROM media_objects WHERE media_objects.source_type = ? AND media_objects.source_id = midjourneys.mj_id)", MediaSourceMidjourney).
+		Order("id").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// GetRetryableMediaObjects 获取转存失败且到达重试时间的记录
+func GetRetryableMediaObjects(now int64, maxAttempts int, limit int) ([]*MediaObject, error) {
+	var objs []*MediaObject
+	err := DB.Where("status = ? AND attempts < ? AND next_retry_at <= ?", MediaObjectStatusFailed, maxAttempts, now).
+		Order("id").Limit(limit).Find(&objs).Error
+	return objs, err
+}
+
+// GetExpiredMediaObjects 获取超过保留期限的已转存对象
+func GetExpiredMediaObjects(now int64, limit int) ([]*MediaObject, error) {
+	var objs []*MediaObject
+	err := DB.Where("status = ? AND expires_at > 0 AND expires_at <= ?", MediaObjectStatusStored, now).
+		Order("id").Limit(limit).Find(&objs).Error
+	return objs, err
+}
+
+// UpdateTaskMediaUrl 将任务结果地址替换为转存后的地址
+func UpdateTaskMediaUrl(id int64, failReason string, data json.RawMessage) error {
+	updates := map[string]any{"fail_reason": failReason}
+	if len(data) > 0 {
+		updates["data"] = data
+	}
+	return DB.Model(&Task{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// UpdateMidjourneyImageUrl 将 Midjourney 图片地址替换为转存后的地址
+func UpdateMidjourneyImageUrl(id int, imageUrl string) error {
+	return DB.Model(&Midjourney{}).Where("id = ?", id).Update("image_url", imageUrl).Error
+}
+
+// scrubMediaObjectSourceUrls 去除旧版本记录在来源地址中的查询参数（Gemini 渠道密钥）
+func scrubMediaObjectSourceUrls() error {
+	var objs []*MediaObject
+	if err := DB.Select("id", "source_url").Where("source_url LIKE ?", "%key=%").Find(&objs).Error; err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		sourceUrl, _, _ := strings.Cut(obj.SourceUrl, "?")
+		if err := DB.Model(&MediaObject{}).Where("id = ?", obj.Id).Update("source_url", sourceUrl).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
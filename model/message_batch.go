@@ -0,0 +1,208 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// 批处理状态，与 Anthropic Message Batches 的 processing_status 一致
+const (
+	MessageBatchStatusInProgress = "in_progress"
+	MessageBatchStatusCanceling  = "canceling"
+	MessageBatchStatusEnded      = "ended"
+)
+
+// 批处理中单个请求的状态，pending 与 processing 对外统一显示为处理中
+const (
+	MessageBatchRequestStatusPending    = "pending"
+	MessageBatchRequestStatusProcessing = "processing"
+	MessageBatchRequestStatusSucceeded  = "succeeded"
+	MessageBatchRequestStatusErrored    = "errored"
+	MessageBatchRequestStatusCanceled   = "canceled"
+	MessageBatchRequestStatusExpired    = "expired"
+)
+
+// MessageBatch Claude 消息批处理，请求由后台逐个转发，结果在全部完成后下载
+type MessageBatch struct {
+	Id                int64  `json:"id"`
+	BatchId           string `json:"batch_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId            int    `json:"user_id" gorm:"index"`
+	TokenId           int    `json:"token_id"`
+	Group             string `json:"group" gorm:"type:varchar(64)"`
+	Status            string `json:"status" gorm:"type:varchar(20);index"`
+	CreatedAt         int64  `json:"created_at" gorm:"bigint"`
+	ExpiresAt         int64  `json:"expires_at" gorm:"bigint"`
+	CancelInitiatedAt int64  `json:"cancel_initiated_at" gorm:"bigint"`
+	EndedAt           int64  `json:"ended_at" gorm:"bigint"`
+}
+
+// MessageBatchRequest 批处理中的单个请求，保存请求参数与执行结果
+type MessageBatchRequest struct {
+	Id        int64  `json:"id"`
+	BatchId   string `json:"batch_id" gorm:"type:varchar(64);index"`
+	CustomId  string `json:"custom_id" gorm:"type:varchar(64)"`
+	Model     string `json:"model" gorm:"type:varchar(255)"`
+	Params    []byte `json:"-"`
+	Status    string `json:"status" gorm:"type:varchar(20);index"`
+	Attempts  int    `json:"attempts"`
+	Result    []byte `json:"-"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// CreateMessageBatch 创建批处理及其全部请求
+func CreateMessageBatch(batch *MessageBatch, requests []*MessageBatchRequest) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		now := common.GetTimestamp()
+		for _, request := range requests {
+			request.BatchId = batch.BatchId
+			request.Status = MessageBatchRequestStatusPending
+			request.UpdatedAt = now
+		}
+		return tx.CreateInBatches(requests, 200).Error
+	})
+}
+
+func GetUserMessageBatch(userId int, batchId string) (*MessageBatch, error) {
+	var batch MessageBatch
+	err := DB.Where("user_id = ? AND batch_id = ?", userId, batchId).First(&batch).Error
+	return &batch, err
+}
+
+func GetMessageBatchByBatchId(batchId string) (*MessageBatch, error) {
+	var batch MessageBatch
+	err := DB.Where("batch_id = ?", batchId).First(&batch).Error
+	return &batch, err
+}
+
+// GetUserMessageBatches 按创建时间倒序分页，afterId 向更早翻页，beforeId 向更新翻页
+func GetUserMessageBatches(userId int, beforeId int64, afterId int64, limit int) ([]*MessageBatch, error) {
+	var batches []*MessageBatch
+	query := DB.Where("user_id = ?", userId)
+	if beforeId > 0 {
+		err := query.Where("id > ?", beforeId).Order("id asc").Limit(limit).Find(&batches).Error
+		for i, j := 0, len(batches)-1; i < j; i, j = i+1, j-1 {
+			batches[i], batches[j] = batches[j], batches[i]
+		}
+		return batches, err
+	}
+	if afterId > 0 {
+		query = query.Where("id < ?", afterId)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+// CountMessageBatchRequests 按状态统计批处理中的请求数
+func CountMessageBatchRequests(batchId string) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	err := DB.Model(&MessageBatchRequest{}).Select("status, count(*) as count").Where("batch_id = ?", batchId).Group("status").Scan(&rows).Error
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, err
+}
+
+// CancelMessageBatch 标记批处理为取消中，尚未开始的请求直接取消
+func CancelMessageBatch(batch *MessageBatch) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&MessageBatch{}).Where("id = ? AND status = ?", batch.Id, MessageBatchStatusInProgress).Updates(map[string]any{
+			"status":              MessageBatchStatusCanceling,
+			"cancel_initiated_at": batch.CancelInitiatedAt,
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&MessageBatchRequest{}).Where("batch_id = ? AND status = ?", batch.BatchId, MessageBatchRequestStatusPending).Updates(map[string]any{
+			"status":     MessageBatchRequestStatusCanceled,
+			"updated_at": batch.CancelInitiatedAt,
+		}).Error
+	})
+}
+
+func DeleteMessageBatch(batch *MessageBatch) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("batch_id = ?", batch.BatchId).Delete(&MessageBatchRequest{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&MessageBatch{}, batch.Id).Error
+	})
+}
+
+// GetMessageBatchResults 按提交顺序分页读取批处理结果
+func GetMessageBatchResults(batchId string, afterId int64, limit int) ([]*MessageBatchRequest, error) {
+	var requests []*MessageBatchRequest
+	err := DB.Select("id, custom_id, status, result").Where("batch_id = ? AND id > ?", batchId, afterId).Order("id asc").Limit(limit).Find(&requests).Error
+	return requests, err
+}
+
+// GetPendingMessageBatchRequests 按提交顺序获取待执行的请求
+func GetPendingMessageBatchRequests(limit int) ([]*MessageBatchRequest, error) {
+	var requests []*MessageBatchRequest
+	err := DB.Where("status = ?", MessageBatchRequestStatusPending).Order("id asc").Limit(limit).Find(&requests).Error
+	return requests, err
+}
+
+// ClaimMessageBatchRequest 将请求标记为执行中，返回 false 表示已被取消或处理
+func ClaimMessageBatchRequest(id int64) (bool, error) {
+	result := DB.Model(&MessageBatchRequest{}).Where("id = ? AND status = ?", id, MessageBatchRequestStatusPending).Updates(map[string]any{
+		"status":     MessageBatchRequestStatusProcessing,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"updated_at": common.GetTimestamp(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FinishMessageBatchRequest 保存请求的最终状态与结果
+func FinishMessageBatchRequest(id int64, status string, result []byte) error {
+	return DB.Model(&MessageBatchRequest{}).Where("id = ?", id).Updates(map[string]any{
+		"status":     status,
+		"result":     result,
+		"updated_at": common.GetTimestamp(),
+	}).Error
+}
+
+// ReleaseMessageBatchRequest 上游暂时不可用时放回待执行队列
+func ReleaseMessageBatchRequest(id int64) error {
+	return DB.Model(&MessageBatchRequest{}).Where("id = ? AND status = ?", id, MessageBatchRequestStatusProcessing).Updates(map[string]any{
+		"status":     MessageBatchRequestStatusPending,
+		"updated_at": common.GetTimestamp(),
+	}).Error
+}
+
+// ResetProcessingMessageBatchRequests 服务重启后将中断的请求放回待执行队列
+func ResetProcessingMessageBatchRequests() error {
+	return DB.Model(&MessageBatchRequest{}).Where("status = ?", MessageBatchRequestStatusProcessing).Updates(map[string]any{
+		"status":     MessageBatchRequestStatusPending,
+		"updated_at": common.GetTimestamp(),
+	}).Error
+}
+
+// GetUnfinishedMessageBatches 获取尚未结束的批处理
+func GetUnfinishedMessageBatches(limit int) ([]*MessageBatch, error) {
+	var batches []*MessageBatch
+	err := DB.Where("status <> ?", MessageBatchStatusEnded).Order("id asc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+// ExpireMessageBatchRequests 批处理超过有效期后，未开始的请求标记为过期
+func ExpireMessageBatchRequests(batchId string) error {
+	return DB.Model(&MessageBatchRequest{}).Where("batch_id = ? AND status = ?", batchId, MessageBatchRequestStatusPending).Updates(map[string]any{
+		"status":     MessageBatchRequestStatusExpired,
+		"updated_at": common.GetTimestamp(),
+	}).Error
+}
+
+func EndMessageBatch(id int64, endedAt int64) error {
+	return DB.Model(&MessageBatch{}).Where("id = ?", id).Updates(map[string]any{
+		"status":   MessageBatchStatusEnded,
+		"ended_at": endedAt,
+	}).Error
+}
@@ -16,6 +16,7 @@ type Midjourney struct {
 	ImageUrl    string `json:"image_url"`
 	VideoUrl    string `json:"video_url"`
 	VideoUrls   string `json:"video_urls"`
+	ImageUrls   string `json:"image_urls"` // 模拟任务生成的单张图片地址（JSON 数组）
 	Status      string `json:"status" gorm:"type:varchar(20);index"`
 	Progress    string `json:"progress" gorm:"type:varchar(30);index"`
 	FailReason  string `json:"fail_reason"`
@@ -23,6 +24,7 @@ type Midjourney struct {
 	Quota       int    `json:"quota"`
 	Buttons     string `json:"buttons"`
 	Properties  string `json:"properties"`
+	TaskCallback
 }
 
 // TaskQueryParams 用于包含所有搜索条件的结构体，可以根据需求添加更多字段
@@ -153,10 +155,17 @@ func (midjourney *Midjourney) Insert() error {
 
 func (midjourney *Midjourney) Update() error {
 	var err error
-	err = DB.Save(midjourney).Error
+	err = DB.Omit(taskCallbackColumns...).Save(midjourney).Error
 	return err
 }
 
+// UpdateWithStatus 仅在任务状态与进度仍为读取时的值时更新，返回 false 表示任务已被其他流程修改
+func (midjourney *Midjourney) UpdateWithStatus(fromStatus string, fromProgress string) (bool, error) {
+	result := DB.Model(midjourney).Select("*").Omit(taskCallbackColumns...).
+		Where("status = ? AND progress = ?", fromStatus, fromProgress).Updates(midjourney)
+	return result.RowsAffected > 0, result.Error
+}
+
 func MjBulkUpdate(mjIds []string, params map[string]any) error {
 	return DB.Model(&Midjourney{}).
 		Where("mj_id in (?)", mjIds).
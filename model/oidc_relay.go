@@ -0,0 +1,68 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// OIDCRelayTokenName 通过 OIDC/JWT 访问中继接口时，为用户自动维护的系统令牌名称
+const OIDCRelayTokenName = "OIDC Relay"
+
+// GetUserTokenByName 获取用户指定名称的令牌，不存在时返回 nil
+func GetUserTokenByName(userId int, name string) (*Token, error) {
+	var token Token
+	err := DB.Where("user_id = ? AND name = ?", userId, name).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UpdateUserGroup 更新用户分组并同步缓存
+func UpdateUserGroup(userId int, group string) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Update("group", group).Error
+	if err != nil {
+		return err
+	}
+	if err := updateUserGroupCache(userId, group); err != nil {
+		common.SysLog("failed to update user group cache: " + err.Error())
+	}
+	return nil
+}
+
+// OIDCRelayIdentity JWT 中继身份与用户的绑定，以 iss + sub 唯一标识，与网页 OIDC 登录的 OIDC ID 相互独立
+type OIDCRelayIdentity struct {
+	Id          int    `json:"id"`
+	Issuer      string `json:"issuer" gorm:"type:varchar(255);uniqueIndex:idx_oidc_relay_identity"`
+	Subject     string `json:"subject" gorm:"type:varchar(255);uniqueIndex:idx_oidc_relay_identity"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// GetOIDCRelayIdentityUserId 按 iss + sub 查询绑定的用户 ID，未绑定时返回 0
+func GetOIDCRelayIdentityUserId(issuer string, subject string) (int, error) {
+	var identity OIDCRelayIdentity
+	err := DB.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return identity.UserId, nil
+}
+
+func CreateOIDCRelayIdentity(issuer string, subject string, userId int) error {
+	return DB.Create(&OIDCRelayIdentity{
+		Issuer:      issuer,
+		Subject:     subject,
+		UserId:      userId,
+		CreatedTime: common.GetTimestamp(),
+	}).Error
+}
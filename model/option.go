@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -19,11 +20,24 @@ type Option struct {
 	Value string `json:"value"`
 }
 
+// AllOption 读取全部配置，解密失败的配置项不会返回，错误一并返回
 func AllOption() ([]*Option, error) {
 	var options []*Option
-	var err error
-	err = DB.Find(&options).Error
-	return options, err
+	if err := DB.Find(&options).Error; err != nil {
+		return nil, err
+	}
+	var errs []error
+	decrypted := options[:0]
+	for _, option := range options {
+		value, err := decryptOptionValue(option.Key, option.Value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		option.Value = value
+		decrypted = append(decrypted, option)
+	}
+	return decrypted, errors.Join(errs...)
 }
 
 func InitOptionMap() {
@@ -122,6 +136,8 @@ func InitOptionMap() {
 	common.OptionMap["ImageRatio"] = ratio_setting.ImageRatio2JSONString()
 	common.OptionMap["AudioRatio"] = ratio_setting.AudioRatio2JSONString()
 	common.OptionMap["AudioCompletionRatio"] = ratio_setting.AudioCompletionRatio2JSONString()
+	common.OptionMap["PromptTierRatio"] = ratio_setting.PromptTierRatio2JSONString()
+	common.OptionMap["VolumeTierRatio"] = ratio_setting.VolumeTierRatio2JSONString()
 	common.OptionMap["TopUpLink"] = common.TopUpLink
 	//common.OptionMap["ChatLink"] = common.ChatLink
 	//common.OptionMap["ChatLink2"] = common.ChatLink2
@@ -135,6 +151,9 @@ func InitOptionMap() {
 	common.OptionMap["MjModeClearEnabled"] = strconv.FormatBool(setting.MjModeClearEnabled)
 	common.OptionMap["MjForwardUrlEnabled"] = strconv.FormatBool(setting.MjForwardUrlEnabled)
 	common.OptionMap["MjActionCheckSuccessEnabled"] = strconv.FormatBool(setting.MjActionCheckSuccessEnabled)
+	common.OptionMap["MjEmulationEnabled"] = strconv.FormatBool(setting.MjEmulationEnabled)
+	common.OptionMap["MjEmulationImageModel"] = setting.MjEmulationImageModel
+	common.OptionMap["MjEmulationDescribeModel"] = setting.MjEmulationDescribeModel
 	common.OptionMap["CheckSensitiveEnabled"] = strconv.FormatBool(setting.CheckSensitiveEnabled)
 	common.OptionMap["DemoSiteEnabled"] = strconv.FormatBool(operation_setting.DemoSiteEnabled)
 	common.OptionMap["SelfUseModeEnabled"] = strconv.FormatBool(operation_setting.SelfUseModeEnabled)
@@ -159,7 +178,10 @@ func InitOptionMap() {
 }
 
 func loadOptionsFromDatabase() {
-	options, _ := AllOption()
+	options, err := AllOption()
+	if err != nil {
+		common.SysError("failed to load options: " + err.Error())
+	}
 	for _, option := range options {
 		err := updateOptionMap(option.Key, option.Value)
 		if err != nil {
@@ -183,7 +205,11 @@ func UpdateOption(key string, value string) error {
 	}
 	// https://gorm.io/docs/update.html#Save-All-Fields
 	DB.FirstOrCreate(&option, Option{Key: key})
-	option.Value = value
+	encrypted, err := encryptOptionValue(key, value)
+	if err != nil {
+		return err
+	}
+	option.Value = encrypted
 	// Save is a combination function.
 	// If save value does not contain primary key, it will execute Create,
 	// otherwise it will execute Update (with all fields).
@@ -277,6 +303,8 @@ func updateOptionMap(key string, value string) (err error) {
 			setting.MjForwardUrlEnabled = boolValue
 		case "MjActionCheckSuccessEnabled":
 			setting.MjActionCheckSuccessEnabled = boolValue
+		case "MjEmulationEnabled":
+			setting.MjEmulationEnabled = boolValue
 		case "CheckSensitiveEnabled":
 			setting.CheckSensitiveEnabled = boolValue
 		case "DemoSiteEnabled":
@@ -304,6 +332,10 @@ func updateOptionMap(key string, value string) (err error) {
 		common.EmailDomainWhitelist = strings.Split(value, ",")
 	case "SMTPServer":
 		common.SMTPServer = value
+	case "MjEmulationImageModel":
+		setting.MjEmulationImageModel = value
+	case "MjEmulationDescribeModel":
+		setting.MjEmulationDescribeModel = value
 	case "SMTPPort":
 		intValue, _ := strconv.Atoi(value)
 		common.SMTPPort = intValue
@@ -433,6 +465,10 @@ func updateOptionMap(key string, value string) (err error) {
 		err = ratio_setting.UpdateAudioRatioByJSONString(value)
 	case "AudioCompletionRatio":
 		err = ratio_setting.UpdateAudioCompletionRatioByJSONString(value)
+	case "PromptTierRatio":
+		err = ratio_setting.UpdatePromptTierRatioByJSONString(value)
+	case "VolumeTierRatio":
+		err = ratio_setting.UpdateVolumeTierRatioByJSONString(value)
 	case "TopUpLink":
 		common.TopUpLink = value
 	//case "ChatLink":
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PostpaidBillStatusUnpaid = "unpaid"
+	PostpaidBillStatusPaid   = "paid"
+)
+
+// PostpaidBill 后付费用户的月度账单
+type PostpaidBill struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"user_id" gorm:"not null;uniqueIndex:idx_user_bill_month"`
+	Month       string `json:"month" gorm:"type:varchar(7);not null;uniqueIndex:idx_user_bill_month"` // 格式: YYYY-MM
+	UsageQuota  int64  `json:"usage_quota" gorm:"bigint;default:0"`                                   // 当月消费额度
+	OwedQuota   int    `json:"owed_quota" gorm:"type:int;default:0"`                                  // 本期新增的透支额度（不含之前未付账单），付款后返还至用户余额
+	Status      string `json:"status" gorm:"type:varchar(16);index;default:'unpaid'"`
+	DueTime     int64  `json:"due_time" gorm:"bigint;index"`
+	PaidTime    int64  `json:"paid_time" gorm:"bigint"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// GetPostpaidUsers 分页获取设置了信用额度的用户
+func GetPostpaidUsers(startIdx int, num int) ([]*User, error) {
+	var users []*User
+	err := DB.Select("id", "username", "email", "quota", "credit_limit", "setting").
+		Where("credit_limit > ?", 0).
+		Order("id asc").
+		Limit(num).
+		Offset(startIdx).
+		Find(&users).Error
+	return users, err
+}
+
+// UpdateUserCreditLimit 修改用户的后付费信用额度
+func UpdateUserCreditLimit(userId int, creditLimit int) error {
+	if err := DB.Model(&User{}).Where("id = ?", userId).Update("credit_limit", creditLimit).Error; err != nil {
+		return err
+	}
+	return invalidateUserCache(userId)
+}
+
+// CreatePostpaidBill 为后付费用户生成指定月份的账单，当月无消费且无欠款时不生成
+func CreatePostpaidBill(user *User, month string, dueTime int64) (*PostpaidBill, error) {
+	startTime, endTime, err := ParseStatementMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	var existing PostpaidBill
+	err = DB.Where("user_id = ? AND month = ?", user.Id, month).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var usage int64
+	err = LOG_DB.Table("logs").
+		Select("COALESCE(SUM(quota), 0)").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", user.Id, LogTypeConsume, startTime, endTime).
+		Scan(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+	// 透支余额中已由之前未付账单计入的部分不重复出账，否则结清时会重复返还
+	var pendingOwed int64
+	err = DB.Model(&PostpaidBill{}).
+		Select("COALESCE(SUM(owed_quota), 0)").
+		Where("user_id = ? AND status = ?", user.Id, PostpaidBillStatusUnpaid).
+		Scan(&pendingOwed).Error
+	if err != nil {
+		return nil, err
+	}
+	owed := postpaidNewDebt(user.Quota, pendingOwed)
+	if usage == 0 && owed == 0 {
+		return nil, nil
+	}
+
+	bill := &PostpaidBill{
+		UserId:      user.Id,
+		Month:       month,
+		UsageQuota:  usage,
+		OwedQuota:   owed,
+		Status:      PostpaidBillStatusUnpaid,
+		DueTime:     dueTime,
+		CreatedTime: common.GetTimestamp(),
+	}
+	// 无欠款的账单直接视为已结清
+	if owed == 0 {
+		bill.Status = PostpaidBillStatusPaid
+		bill.PaidTime = bill.CreatedTime
+	}
+	if err := DB.Create(bill).Error; err != nil {
+		return nil, err
+	}
+	return bill, nil
+}
+
+// postpaidNewDebt 计算本期新增欠款：当前透支额度扣除之前未付账单已计入的欠款
+func postpaidNewDebt(quota int, pendingOwed int64) int {
+	if quota >= 0 {
+		return 0
+	}
+	owed := int64(-quota) - pendingOwed
+	if owed <= 0 {
+		return 0
+	}
+	return int(owed)
+}
+
+func queryPostpaidBills(tx *gorm.DB, pageInfo *common.PageInfo) (bills []*PostpaidBill, total int64, err error) {
+	// 新建会话，保证 Count 与 Find 复用同一组查询条件
+	tx = tx.Session(&gorm.Session{})
+	if err = tx.Model(&PostpaidBill{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(pageInfo.GetPageSize()).Offset(pageInfo.GetStartIdx()).Find(&bills).Error
+	return bills, total, err
+}
+
+// GetUserPostpaidBills 分页获取用户的后付费账单
+func GetUserPostpaidBills(userId int, pageInfo *common.PageInfo) ([]*PostpaidBill, int64, error) {
+	return queryPostpaidBills(DB.Where("user_id = ?", userId), pageInfo)
+}
+
+// GetAllPostpaidBills 管理员分页获取后付费账单，可按用户与状态筛选
+func GetAllPostpaidBills(userId int, status string, pageInfo *common.PageInfo) ([]*PostpaidBill, int64, error) {
+	tx := DB.Model(&PostpaidBill{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	return queryPostpaidBills(tx, pageInfo)
+}
+
+// GetOverdueBillUserIds 获取存在逾期未付账单的用户
+func GetOverdueBillUserIds(now int64) ([]int, error) {
+	var userIds []int
+	err := DB.Model(&PostpaidBill{}).
+		Where("status = ? AND due_time > 0 AND due_time < ?", PostpaidBillStatusUnpaid, now).
+		Distinct("user_id").
+		Pluck("user_id", &userIds).Error
+	return userIds, err
+}
+
+func hasOverdueBill(userId int, now int64) (bool, error) {
+	var count int64
+	err := DB.Model(&PostpaidBill{}).
+		Where("user_id = ? AND status = ? AND due_time > 0 AND due_time < ?", userId, PostpaidBillStatusUnpaid, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SetUserBillingSuspended 设置用户因账单逾期导致的服务暂停状态
+func SetUserBillingSuspended(userId int, suspended bool) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Update("billing_suspended", suspended).Error
+	if err != nil {
+		return err
+	}
+	return updateUserBillingSuspendedCache(userId, suspended)
+}
+
+// PayPostpaidBill 将账单标记为已付款，欠款额度返还至用户余额，无其他逾期账单时恢复服务
+func PayPostpaidBill(billId int) (*PostpaidBill, error) {
+	var bill PostpaidBill
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&bill, "id = ?", billId).Error; err != nil {
+			return err
+		}
+		if bill.Status != PostpaidBillStatusUnpaid {
+			return errors.New("账单已结清")
+		}
+		bill.Status = PostpaidBillStatusPaid
+		bill.PaidTime = common.GetTimestamp()
+		result := tx.Model(&PostpaidBill{}).
+			Where("id = ? AND status = ?", bill.Id, PostpaidBillStatusUnpaid).
+			Updates(map[string]interface{}{"status": bill.Status, "paid_time": bill.PaidTime})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("账单已结清")
+		}
+		return tx.Model(&User{}).Where("id = ?", bill.UserId).Update("quota", gorm.Expr("quota + ?", bill.OwedQuota)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := cacheIncrUserQuota(bill.UserId, int64(bill.OwedQuota)); err != nil {
+		common.SysLog("failed to increase user quota cache: " + err.Error())
+	}
+	RecordLog(bill.UserId, LogTypeManage, fmt.Sprintf("后付费账单 %s 已结清，返还额度 %s", bill.Month, logger.FormatQuota(bill.OwedQuota)))
+
+	overdue, err := hasOverdueBill(bill.UserId, common.GetTimestamp())
+	if err != nil {
+		return &bill, err
+	}
+	if !overdue {
+		if err := SetUserBillingSuspended(bill.UserId, false); err != nil {
+			return &bill, err
+		}
+	}
+	return &bill, nil
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostpaidNewDebt(t *testing.T) {
+	tests := []struct {
+		name        string
+		quota       int
+		pendingOwed int64
+		want        int
+	}{
+		{name: "positive balance", quota: 100, pendingOwed: 0, want: 0},
+		{name: "zero balance", quota: 0, pendingOwed: 0, want: 0},
+		{name: "overdraft without pending bills", quota: -500, pendingOwed: 0, want: 500},
+		{name: "overdraft partly billed", quota: -500, pendingOwed: 200, want: 300},
+		{name: "overdraft fully billed", quota: -500, pendingOwed: 500, want: 0},
+		// 未付账单已计入的欠款多于当前透支（期间有充值）时不产生新欠款
+		{name: "pending exceeds overdraft", quota: -300, pendingOwed: 500, want: 0},
+		{name: "positive balance with pending bills", quota: 100, pendingOwed: 500, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, postpaidNewDebt(tt.quota, tt.pendingOwed))
+		})
+	}
+}
@@ -27,6 +27,7 @@ type Pricing struct {
 	CompletionRatio        float64                 `json:"completion_ratio"`
 	EnableGroup            []string                `json:"enable_groups"`
 	SupportedEndpointTypes []constant.EndpointType `json:"supported_endpoint_types"`
+	PromptTiers            []types.PriceTier       `json:"prompt_tiers,omitempty"`
 }
 
 type PricingVendor struct {
@@ -290,6 +291,7 @@ func updatePricing() {
 			pricing.ModelRatio = modelRatio
 			pricing.CompletionRatio = ratio_setting.GetCompletionRatio(model)
 			pricing.QuotaType = 0
+			pricing.PromptTiers = ratio_setting.GetPromptTiers(model)
 		}
 		pricingMap = append(pricingMap, pricing)
 	}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+// scimManagedUsers SCIM 只能管理由 SCIM 创建（externalId 非空）且不是管理员的用户
+func scimManagedUsers(query *gorm.DB) *gorm.DB {
+	return query.Where("external_id <> '' AND role < ?", common.RoleAdminUser)
+}
+
+// GetScimUserById 获取 SCIM 可管理的用户
+func GetScimUserById(id int) (*User, error) {
+	var user User
+	err := scimManagedUsers(DB.Omit("password")).Where("id = ?", id).First(&user).Error
+	return &user, err
+}
+
+// SearchScimUsers 按 SCIM 过滤条件分页查询 SCIM 可管理的用户，attr 为空时返回全部
+func SearchScimUsers(attr string, value string, offset int, limit int) ([]*User, int64, error) {
+	query := scimManagedUsers(DB.Model(&User{}))
+	switch attr {
+	case "username":
+		query = query.Where("scim_user_name = ? OR (scim_user_name = '' AND username = ?)", value, value)
+	case "externalid":
+		query = query.Where("external_id = ?", value)
+	case "email":
+		query = query.Where("email = ?", value)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []*User
+	err := query.Order("id asc").Offset(offset).Limit(limit).Omit("password").Find(&users).Error
+	return users, total, err
+}
+
+// GetUsersByGroup 获取指定分组下 SCIM 可管理的用户
+func GetUsersByGroup(group string) ([]*User, error) {
+	var users []*User
+	err := scimManagedUsers(DB.Select("id", "username", "scim_user_name")).Where(commonGroupCol+" = ?", group).Find(&users).Error
+	return users, err
+}
+
+// RevokeUserTokens 禁用用户的全部令牌，用于 SCIM 停用或删除用户
+func RevokeUserTokens(userId int) error {
+	var tokens []Token
+	if err := DB.Where("user_id = ?", userId).Find(&tokens).Error; err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	err := DB.Model(&Token{}).Where("user_id = ?", userId).Update("status", common.TokenStatusDisabled).Error
+	if err != nil {
+		return err
+	}
+	if common.RedisEnabled {
+		gopool.Go(func() {
+			for _, t := range tokens {
+				_ = cacheDeleteToken(t.KeyHash)
+			}
+		})
+	}
+	return nil
+}
+
+// UpdateUserStatus 更新用户状态并同步缓存
+func UpdateUserStatus(userId int, status int) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Update("status", status).Error
+	if err != nil {
+		return err
+	}
+	return updateUserStatusCache(userId, status == common.UserStatusEnabled)
+}
+
+// UpdateScimUser 按 SCIM 资源更新用户属性，可写入空值
+func UpdateScimUser(user *User) error {
+	err := DB.Model(&User{}).Where("id = ?", user.Id).Updates(map[string]interface{}{
+		"scim_user_name": user.ScimUserName,
+		"external_id":    user.ExternalId,
+		"display_name":   user.DisplayName,
+		"email":          user.Email,
+		"status":         user.Status,
+		"group":          user.Group,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return invalidateUserCache(user.Id)
+}
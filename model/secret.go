@@ -0,0 +1,219 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const secretMigrateBatch = 500
+
+// secretOptionSuffixes 需要加密存储的配置项后缀，与 GetOptions 中不下发到前端的规则一致
+var secretOptionSuffixes = []string{"Token", "Secret", "Key", "secret", "api_key"}
+
+func isSecretOptionKey(key string) bool {
+	for _, suffix := range secretOptionSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	schema.RegisterSerializer("secret", SecretSerializer{})
+}
+
+// SecretSerializer 写入时加密、读取时解密的字段序列化器，字段标签为 serializer:secret
+type SecretSerializer struct{}
+
+func (SecretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("failed to scan secret value: %#v", dbValue)
+	}
+	plaintext, err := common.DecryptSecret(value)
+	if err != nil {
+		// 不能把密文当作密钥使用，直接返回错误
+		common.SysError(fmt.Sprintf("failed to decrypt %s.%s: %s", field.Schema.Table, field.DBName, err.Error()))
+		return fmt.Errorf("failed to decrypt %s.%s: %w", field.Schema.Table, field.DBName, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+func (SecretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	return common.EncryptSecret(value)
+}
+
+// UpdateChannelKey 单独更新渠道密钥，按列更新不经过序列化器，需显式加密
+func UpdateChannelKey(id int, key string) error {
+	encrypted, err := common.EncryptSecret(key)
+	if err != nil {
+		return err
+	}
+	return DB.Model(&Channel{}).Where("id = ?", id).Update("key", encrypted).Error
+}
+
+func encryptOptionValue(key string, value string) (string, error) {
+	if !isSecretOptionKey(key) {
+		return value, nil
+	}
+	return common.EncryptSecret(value)
+}
+
+// decryptOptionValue 解密失败时返回错误，不能把密文当作配置值使用
+func decryptOptionValue(key string, value string) (string, error) {
+	if !isSecretOptionKey(key) {
+		return value, nil
+	}
+	plaintext, err := common.DecryptSecret(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt option %s: %w", key, err)
+	}
+	return plaintext, nil
+}
+
+// decryptWebhookSecret 解密失败时视为未设置密钥，不能把密文当作签名密钥使用
+func decryptWebhookSecret(secret string) string {
+	plaintext, err := common.DecryptSecret(secret)
+	if err != nil {
+		common.SysError("failed to decrypt webhook secret: " + err.Error())
+		return ""
+	}
+	return plaintext
+}
+
+type rawSecretRow struct {
+	Id     int
+	Secret string
+}
+
+// migrateSecrets 启动时将明文或旧主密钥加密的敏感字段改用当前主密钥加密
+func migrateSecrets() error {
+	if !common.SecretEncryptionEnabled() {
+		var count int64
+		DB.Table("channels").Where(commonKeyCol+" LIKE ?", "enc:v1:%").Count(&count)
+		if count > 0 {
+			common.SysError(fmt.Sprintf("%d channel keys are encrypted but SECRET_ENCRYPTION_KEY is not configured", count))
+		}
+		return nil
+	}
+	channels, err := migrateChannelSecrets()
+	if err != nil {
+		return err
+	}
+	options, err := migrateOptionSecrets()
+	if err != nil {
+		return err
+	}
+	users, err := migrateUserSecrets()
+	if err != nil {
+		return err
+	}
+	if channels+options+users > 0 {
+		common.SysLog(fmt.Sprintf("encrypted secrets at rest: %d channel keys, %d options, %d user webhook secrets", channels, options, users))
+	}
+	return nil
+}
+
+func migrateChannelSecrets() (int, error) {
+	var rows []rawSecretRow
+	var migrated int
+	err := DB.Table("channels").
+		Select("id", commonKeyCol+" AS secret").
+		Where(commonKeyCol+" <> ''").
+		FindInBatches(&rows, secretMigrateBatch, func(tx *gorm.DB, batch int) error {
+			for _, row := range rows {
+				rotated, changed, err := common.RotateSecret(row.Secret)
+				if err != nil {
+					common.SysError(fmt.Sprintf("failed to encrypt key of channel #%d: %s", row.Id, err.Error()))
+					continue
+				}
+				if !changed {
+					continue
+				}
+				if err := DB.Table("channels").Where("id = ?", row.Id).Update("key", rotated).Error; err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		}).Error
+	return migrated, err
+}
+
+func migrateOptionSecrets() (int, error) {
+	var options []*Option
+	if err := DB.Find(&options).Error; err != nil {
+		return 0, err
+	}
+	var migrated int
+	for _, option := range options {
+		if !isSecretOptionKey(option.Key) {
+			continue
+		}
+		rotated, changed, err := common.RotateSecret(option.Value)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to encrypt option %s: %s", option.Key, err.Error()))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if err := DB.Model(&Option{}).Where(commonKeyCol+" = ?", option.Key).Update("value", rotated).Error; err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+func migrateUserSecrets() (int, error) {
+	var rows []rawSecretRow
+	var migrated int
+	err := DB.Table("users").
+		Select("id", "setting AS secret").
+		Where("setting LIKE ?", "%webhook_secret%").
+		FindInBatches(&rows, secretMigrateBatch, func(tx *gorm.DB, batch int) error {
+			for _, row := range rows {
+				// 使用 map 保留设置中的其他字段
+				var settings map[string]any
+				if err := common.Unmarshal([]byte(row.Secret), &settings); err != nil {
+					continue
+				}
+				secret, _ := settings["webhook_secret"].(string)
+				rotated, changed, err := common.RotateSecret(secret)
+				if err != nil {
+					common.SysError(fmt.Sprintf("failed to encrypt webhook secret of user #%d: %s", row.Id, err.Error()))
+					continue
+				}
+				if !changed {
+					continue
+				}
+				settings["webhook_secret"] = rotated
+				settingBytes, err := common.Marshal(settings)
+				if err != nil {
+					return err
+				}
+				if err := DB.Table("users").Where("id = ?", row.Id).Update("setting", string(settingBytes)).Error; err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		}).Error
+	return migrated, err
+}
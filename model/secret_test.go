@@ -0,0 +1,81 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSecretOptionKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: tokenKeySaltOption, want: true},
+		{key: "StripeApiSecret", want: true},
+		{key: "SMTPToken", want: true},
+		{key: "TurnstileSecretKey", want: true},
+		{key: "media_storage.s3_access_secret", want: true},
+		{key: "some_setting.api_key", want: true},
+		{key: "SMTPServer", want: false},
+		{key: "media_storage.s3_access_key_id", want: false},
+		{key: "TokenExpireTime", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			require.Equal(t, tt.want, isSecretOptionKey(tt.key))
+		})
+	}
+}
+
+func TestIsInternalOption(t *testing.T) {
+	require.True(t, IsInternalOption(tokenKeySaltOption))
+	require.False(t, IsInternalOption("StripeApiSecret"))
+}
+
+// 无法解密的密文（例如主密钥未配置）
+const undecryptableSecret = "enc:v1:deadbeef:AAAA:AAAA"
+
+func TestAllOption_SkipsUndecryptable(t *testing.T) {
+	setupTestDB(t, &Option{})
+	require.NoError(t, DB.Create(&Option{Key: "SMTPToken", Value: undecryptableSecret}).Error)
+	require.NoError(t, DB.Create(&Option{Key: "SMTPServer", Value: "smtp.example.com"}).Error)
+
+	options, err := AllOption()
+	require.Error(t, err)
+	require.Len(t, options, 1)
+	require.Equal(t, "SMTPServer", options[0].Key)
+}
+
+func TestGetSetting_UndecryptableWebhookSecret(t *testing.T) {
+	user := &User{}
+	require.NoError(t, user.SetSetting(dto.UserSetting{WebhookSecret: "plain"}))
+	require.Equal(t, "plain", user.GetSetting().WebhookSecret)
+
+	user.Setting = `{"webhook_secret":"` + undecryptableSecret + `"}`
+	require.Empty(t, user.GetSetting().WebhookSecret)
+}
+
+func TestEnsureUserWebhookSecret(t *testing.T) {
+	setupTestDB(t, &User{})
+	origRedis := common.RedisEnabled
+	t.Cleanup(func() { common.RedisEnabled = origRedis })
+	common.RedisEnabled = false
+
+	user := &User{Username: "callback", Password: "password", AffCode: common.GetRandomString(4)}
+	require.NoError(t, DB.Create(user).Error)
+
+	secret, generated, err := EnsureUserWebhookSecret(user.Id)
+	require.NoError(t, err)
+	require.True(t, generated)
+	require.NotEmpty(t, secret)
+
+	// 已有密钥时保持不变
+	again, generated, err := EnsureUserWebhookSecret(user.Id)
+	require.NoError(t, err)
+	require.False(t, generated)
+	require.Equal(t, secret, again)
+}
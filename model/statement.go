@@ -0,0 +1,187 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// StatementModelItem 账单中按模型汇总的消费明细
+type StatementModelItem struct {
+	ModelName        string `json:"model_name"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	Quota            int64  `json:"quota"`
+}
+
+type StatementTopUpItem struct {
+	TradeNo       string  `json:"trade_no"`
+	PaymentMethod string  `json:"payment_method"`
+	Amount        int64   `json:"amount"`
+	Money         float64 `json:"money"`
+	CompleteTime  int64   `json:"complete_time"`
+}
+
+type StatementRedemptionItem struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	Quota        int    `json:"quota"`
+	RedeemedTime int64  `json:"redeemed_time"`
+}
+
+// UserStatement 用户月度账单
+type UserStatement struct {
+	UserId          int                       `json:"user_id"`
+	Username        string                    `json:"username"`
+	Email           string                    `json:"email"`
+	Month           string                    `json:"month"`
+	StartTime       int64                     `json:"start_time"`
+	EndTime         int64                     `json:"end_time"`
+	Items           []StatementModelItem      `json:"items"`
+	TopUps          []StatementTopUpItem      `json:"top_ups"`
+	Redemptions     []StatementRedemptionItem `json:"redemptions"`
+	Checkins        []CheckinRecord           `json:"checkins"`
+	UsageQuota      int64                     `json:"usage_quota"`
+	RefundQuota     int64                     `json:"refund_quota"`
+	TopUpMoney      float64                   `json:"top_up_money"`
+	RedemptionQuota int64                     `json:"redemption_quota"`
+	CheckinQuota    int64                     `json:"checkin_quota"`
+}
+
+// ParseStatementMonth 解析 YYYY-MM 格式的账单月份，返回该月的起止时间戳（左闭右开）
+func ParseStatementMonth(month string) (int64, int64, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return 0, 0, errors.New("invalid month, expected format YYYY-MM")
+	}
+	return start.Unix(), start.AddDate(0, 1, 0).Unix(), nil
+}
+
+// PreviousStatementMonth 返回上一个自然月（YYYY-MM），以当月 1 日为基准避免月末日期被规范化到当月
+func PreviousStatementMonth(now time.Time) string {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0).Format("2006-01")
+}
+
+func getStatementModelItems(userId int, startTime, endTime int64) ([]StatementModelItem, error) {
+	items := make([]StatementModelItem, 0)
+	err := LOG_DB.Table("logs").
+		Select("model_name, count(*) as requests, COALESCE(sum(prompt_tokens), 0) as prompt_tokens, COALESCE(sum(completion_tokens), 0) as completion_tokens, COALESCE(sum(quota), 0) as quota").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeConsume, startTime, endTime).
+		Group("model_name").
+		Order("quota desc").
+		Scan(&items).Error
+	if err != nil || len(items) > 0 || !common.DataExportEnabled {
+		return items, err
+	}
+	// 日志已被清理时回退到数据看板的汇总数据
+	var quotaData []*QuotaData
+	err = DB.Table("quota_data").
+		Select("model_name, sum(count) as count, sum(quota) as quota, sum(token_used) as token_used").
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userId, startTime, endTime).
+		Group("model_name").
+		Find(&quotaData).Error
+	if err != nil {
+		return items, err
+	}
+	for _, data := range quotaData {
+		items = append(items, StatementModelItem{
+			ModelName:    data.ModelName,
+			Requests:     int64(data.Count),
+			PromptTokens: int64(data.TokenUsed),
+			Quota:        int64(data.Quota),
+		})
+	}
+	return items, nil
+}
+
+// GetUserStatement 汇总用户指定月份的消费、充值、兑换与签到记录
+func GetUserStatement(userId int, month string) (*UserStatement, error) {
+	startTime, endTime, err := ParseStatementMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	user, err := GetUserById(userId, false)
+	if err != nil {
+		return nil, err
+	}
+	statement := &UserStatement{
+		UserId:    user.Id,
+		Username:  user.Username,
+		Email:     user.Email,
+		Month:     month,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	statement.Items, err = getStatementModelItems(userId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range statement.Items {
+		statement.UsageQuota += item.Quota
+	}
+
+	err = LOG_DB.Table("logs").
+		Select("COALESCE(sum(quota), 0)").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeRefund, startTime, endTime).
+		Scan(&statement.RefundQuota).Error
+	if err != nil {
+		return nil, err
+	}
+
+	statement.TopUps = make([]StatementTopUpItem, 0)
+	err = DB.Model(&TopUp{}).
+		Select("trade_no, payment_method, amount, money, complete_time").
+		Where("user_id = ? AND status = ? AND complete_time >= ? AND complete_time < ?", userId, common.TopUpStatusSuccess, startTime, endTime).
+		Order("complete_time asc").
+		Scan(&statement.TopUps).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, topUp := range statement.TopUps {
+		statement.TopUpMoney += topUp.Money
+	}
+
+	statement.Redemptions = make([]StatementRedemptionItem, 0)
+	err = DB.Model(&Redemption{}).
+		Select("id, name, quota, redeemed_time").
+		Where("used_user_id = ? AND redeemed_time >= ? AND redeemed_time < ?", userId, startTime, endTime).
+		Order("redeemed_time asc").
+		Scan(&statement.Redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, redemption := range statement.Redemptions {
+		statement.RedemptionQuota += int64(redemption.Quota)
+	}
+
+	startDate := time.Unix(startTime, 0).Format("2006-01-02")
+	endDate := time.Unix(endTime-1, 0).Format("2006-01-02")
+	checkins, err := GetUserCheckinRecords(userId, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	statement.Checkins = make([]CheckinRecord, len(checkins))
+	for i, checkin := range checkins {
+		statement.Checkins[i] = CheckinRecord{
+			CheckinDate:  checkin.CheckinDate,
+			QuotaAwarded: checkin.QuotaAwarded,
+		}
+		statement.CheckinQuota += int64(checkin.QuotaAwarded)
+	}
+	return statement, nil
+}
+
+// GetStatementRecipients 分页获取有邮箱的正常用户，用于月度账单邮件
+func GetStatementRecipients(startIdx int, num int) ([]*User, error) {
+	var users []*User
+	err := DB.Select("id", "username", "email").
+		Where("status = ? AND email <> ''", common.UserStatusEnabled).
+		Order("id asc").
+		Limit(num).
+		Offset(startIdx).
+		Find(&users).Error
+	return users, err
+}
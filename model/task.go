@@ -15,7 +15,7 @@ type TaskStatus string
 func (t TaskStatus) ToVideoStatus() string {
 	var status string
 	switch t {
-	case TaskStatusQueued, TaskStatusSubmitted:
+	case TaskStatusWaiting, TaskStatusQueued, TaskStatusSubmitted:
 		status = dto.VideoStatusQueued
 	case TaskStatusInProgress:
 		status = dto.VideoStatusInProgress
@@ -37,6 +37,7 @@ const (
 	TaskStatusFailure               = "FAILURE"
 	TaskStatusSuccess               = "SUCCESS"
 	TaskStatusUnknown               = "UNKNOWN"
+	TaskStatusWaiting               = "WAITING" // 在本地队列中等待上游空闲槽位，尚未提交上游
 )
 
 type Task struct {
@@ -60,6 +61,9 @@ type Task struct {
 	// 禁止返回给用户，内部可能包含key等隐私信息
 	PrivateData TaskPrivateData `json:"-" gorm:"column:private_data;type:json"`
 	Data        json.RawMessage `json:"data" gorm:"type:json"`
+	// 排队时分配的本地任务 id，提交上游后仍可用于查询
+	QueueId string `json:"queue_id,omitempty" gorm:"type:varchar(64);index"`
+	TaskCallback
 }
 
 func (t *Task) SetData(data any) {
@@ -240,7 +244,7 @@ func GetAllUnFinishSyncTasks(limit int) []*Task {
 	var tasks []*Task
 	var err error
 	// get all tasks progress is not 100%
-	err = DB.Where("progress != ?", "100%").Where("status != ?", TaskStatusFailure).Where("status != ?", TaskStatusSuccess).Limit(limit).Order("id").Find(&tasks).Error
+	err = DB.Where("progress != ?", "100%").Where("status != ?", TaskStatusFailure).Where("status != ?", TaskStatusSuccess).Where("status != ?", TaskStatusWaiting).Limit(limit).Order("id").Find(&tasks).Error
 	if err != nil {
 		return nil
 	}
@@ -253,7 +257,7 @@ func GetByOnlyTaskId(taskId string) (*Task, bool, error) {
 	}
 	var task *Task
 	var err error
-	err = DB.Where("task_id = ?", taskId).First(&task).Error
+	err = DB.Where("task_id = ? OR queue_id = ?", taskId, taskId).First(&task).Error
 	exist, err := RecordExist(err)
 	if err != nil {
 		return nil, false, err
@@ -267,7 +271,7 @@ func GetByTaskId(userId int, taskId string) (*Task, bool, error) {
 	}
 	var task *Task
 	var err error
-	err = DB.Where("user_id = ? and task_id = ?", userId, taskId).
+	err = DB.Where("user_id = ? and (task_id = ? or queue_id = ?)", userId, taskId, taskId).
 		First(&task).Error
 	exist, err := RecordExist(err)
 	if err != nil {
@@ -302,10 +306,17 @@ func (Task *Task) Insert() error {
 
 func (Task *Task) Update() error {
 	var err error
-	err = DB.Save(Task).Error
+	err = DB.Omit(taskCallbackColumns...).Save(Task).Error
 	return err
 }
 
+// UpdateWithStatus 仅在任务状态与进度仍为读取时的值时更新，返回 false 表示任务已被其他流程修改
+func (Task *Task) UpdateWithStatus(fromStatus TaskStatus, fromProgress string) (bool, error) {
+	result := DB.Model(Task).Select("*").Omit(taskCallbackColumns...).
+		Where("status = ? AND progress = ?", fromStatus, fromProgress).Updates(Task)
+	return result.RowsAffected > 0, result.Error
+}
+
 func TaskBulkUpdate(TaskIds []string, params map[string]any) error {
 	if len(TaskIds) == 0 {
 		return nil
@@ -0,0 +1,64 @@
+package model
+
+// 异步任务回调投递状态
+const (
+	TaskCallbackStatusPending = "pending"
+	TaskCallbackStatusSuccess = "success"
+	TaskCallbackStatusFailed  = "failed"
+)
+
+// taskCallbackColumns 回调状态由投递任务单独维护，任务整体保存时需忽略，避免覆盖投递结果
+var taskCallbackColumns = []string{"callback_status", "callback_attempts", "callback_next_at", "callback_error"}
+
+// TaskCallback 任务完成后的回调配置与投递记录，嵌入 Task 与 Midjourney
+type TaskCallback struct {
+	CallbackUrl      string `json:"callback_url,omitempty" gorm:"type:varchar(512);default:''"`
+	CallbackStatus   string `json:"callback_status,omitempty" gorm:"type:varchar(20);default:'';index"`
+	CallbackAttempts int    `json:"callback_attempts,omitempty" gorm:"default:0"`
+	CallbackNextAt   int64  `json:"callback_next_at,omitempty" gorm:"bigint;default:0"`
+	CallbackError    string `json:"callback_error,omitempty" gorm:"type:varchar(255);default:''"`
+}
+
+// SetCallbackUrl 设置回调地址，任务进入终态后由后台投递
+func (cb *TaskCallback) SetCallbackUrl(url string) {
+	if url == "" {
+		return
+	}
+	cb.CallbackUrl = url
+	cb.CallbackStatus = TaskCallbackStatusPending
+}
+
+func (cb *TaskCallback) callbackUpdates() map[string]any {
+	return map[string]any{
+		"callback_status":   cb.CallbackStatus,
+		"callback_attempts": cb.CallbackAttempts,
+		"callback_next_at":  cb.CallbackNextAt,
+		"callback_error":    cb.CallbackError,
+	}
+}
+
+// GetPendingCallbackTasks 获取已进入终态且到达投递时间的任务
+func GetPendingCallbackTasks(now int64, limit int) ([]*Task, error) {
+	var tasks []*Task
+	err := DB.Where("callback_status = ? AND callback_next_at <= ?", TaskCallbackStatusPending, now).
+		Where("status IN ?", []TaskStatus{TaskStatusSuccess, TaskStatusFailure}).
+		Order("id").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+func (t *Task) UpdateCallback() error {
+	return DB.Model(&Task{}).Where("id = ?", t.ID).Updates(t.callbackUpdates()).Error
+}
+
+// GetPendingCallbackMidjourneys 获取已进入终态且到达投递时间的 Midjourney 任务
+func GetPendingCallbackMidjourneys(now int64, limit int) ([]*Midjourney, error) {
+	var tasks []*Midjourney
+	err := DB.Where("callback_status = ? AND callback_next_at <= ?", TaskCallbackStatusPending, now).
+		Where("status IN ?", []string{"SUCCESS", "FAILURE"}).
+		Order("id").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+func (midjourney *Midjourney) UpdateCallback() error {
+	return DB.Model(&Midjourney{}).Where("id = ?", midjourney.Id).Updates(midjourney.callbackUpdates()).Error
+}
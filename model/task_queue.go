@@ -0,0 +1,125 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// TaskQueueItem 等待提交上游的排队任务，保存原始请求以便调度时重放
+type TaskQueueItem struct {
+	Id        int64  `json:"id"`
+	TaskId    int64  `json:"task_id" gorm:"uniqueIndex"` // 对应 tasks.id
+	UserId    int    `json:"user_id" gorm:"index"`
+	ChannelId int    `json:"channel_id" gorm:"index"`
+	Priority  int    `json:"priority" gorm:"index"`
+	Request   string `json:"-" gorm:"type:text"` // 请求方法、地址、请求头及上下文，JSON 格式
+	Body      []byte `json:"-"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+// taskActiveStatuses 已提交上游、占用渠道并发槽位的任务状态
+var taskActiveStatuses = []TaskStatus{TaskStatusNotStart, TaskStatusSubmitted, TaskStatusQueued, TaskStatusInProgress}
+
+// EnqueueTask 创建排队中的任务及其队列记录
+func EnqueueTask(task *Task, item *TaskQueueItem) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		item.TaskId = task.ID
+		item.CreatedAt = common.GetTimestamp()
+		return tx.Create(item).Error
+	})
+}
+
+// CountChannelActiveTasks 统计渠道上已提交上游且未完成的任务数
+func CountChannelActiveTasks(channelId int) (int64, error) {
+	var cnt int64
+	err := DB.Model(&Task{}).Where("channel_id = ? AND status IN ? AND progress != ?", channelId, taskActiveStatuses, "100%").Count(&cnt).Error
+	return cnt, err
+}
+
+func CountChannelQueuedTasks(channelId int) (int64, error) {
+	var cnt int64
+	err := DB.Model(&TaskQueueItem{}).Where("channel_id = ?", channelId).Count(&cnt).Error
+	return cnt, err
+}
+
+func CountUserQueuedTasks(userId int) (int64, error) {
+	var cnt int64
+	err := DB.Model(&TaskQueueItem{}).Where("user_id = ?", userId).Count(&cnt).Error
+	return cnt, err
+}
+
+// GetQueuedChannelIds 获取存在排队任务的渠道
+func GetQueuedChannelIds() ([]int, error) {
+	var channelIds []int
+	err := DB.Model(&TaskQueueItem{}).Distinct("channel_id").Pluck("channel_id", &channelIds).Error
+	return channelIds, err
+}
+
+// GetChannelQueueItems 按优先级和排队先后获取渠道的排队任务
+func GetChannelQueueItems(channelId int, limit int) ([]*TaskQueueItem, error) {
+	var items []*TaskQueueItem
+	err := DB.Where("channel_id = ?", channelId).Order("priority desc, id asc").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+// GetExpiredQueueItems 获取排队超时的任务
+func GetExpiredQueueItems(before int64, limit int) ([]*TaskQueueItem, error) {
+	var items []*TaskQueueItem
+	err := DB.Where("created_at < ?", before).Order("id").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+func DeleteTaskQueueItem(id int64) error {
+	return DB.Delete(&TaskQueueItem{}, id).Error
+}
+
+func GetTaskById(id int64) (*Task, error) {
+	var task Task
+	err := DB.First(&task, "id = ?", id).Error
+	return &task, err
+}
+
+// MarkQueuedTaskSubmitted 排队任务提交上游成功后写入上游任务信息，任务已被取消时返回 false
+func MarkQueuedTaskSubmitted(task *Task) (bool, error) {
+	var updated bool
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Task{}).Where("id = ? AND status = ?", task.ID, TaskStatusWaiting).Updates(map[string]any{
+			"task_id":      task.TaskID,
+			"status":       task.Status,
+			"channel_id":   task.ChannelId,
+			"data":         task.Data,
+			"private_data": task.PrivateData,
+			"properties":   task.Properties,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected > 0
+		return tx.Where("task_id = ?", task.ID).Delete(&TaskQueueItem{}).Error
+	})
+	return updated, err
+}
+
+// FailQueuedTask 将仍在排队的任务标记为失败并移出队列，返回是否由本次调用完成状态变更
+func FailQueuedTask(id int64, reason string) (bool, error) {
+	var updated bool
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := common.GetTimestamp()
+		result := tx.Model(&Task{}).Where("id = ? AND status = ?", id, TaskStatusWaiting).Updates(map[string]any{
+			"status":      TaskStatusFailure,
+			"progress":    "100%",
+			"fail_reason": reason,
+			"finish_time": now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected > 0
+		return tx.Where("task_id = ?", id).Delete(&TaskQueueItem{}).Error
+	})
+	return updated, err
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"gorm.io/gorm"
+)
+
+// 对账时跳过的任务状态，排队中的任务由排队超时处理
+var taskReconcileSkipStatuses = []TaskStatus{TaskStatusSuccess, TaskStatusFailure, TaskStatusWaiting}
+
+// GetUnfinishedTaskPlatforms 获取存在未完成任务的平台
+func GetUnfinishedTaskPlatforms() ([]string, error) {
+	var platforms []string
+	err := DB.Model(&Task{}).Where("progress != ?", "100%").Where("status NOT IN ?", taskReconcileSkipStatuses).
+		Distinct("platform").Pluck("platform", &platforms).Error
+	return platforms, err
+}
+
+// GetStuckTasks 获取指定平台提交时间早于 before 且仍未完成的任务
+func GetStuckTasks(platform string, before int64, limit int) ([]*Task, error) {
+	var tasks []*Task
+	err := DB.Where("platform = ? AND submit_time < ?", platform, before).
+		Where("progress != ?", "100%").Where("status NOT IN ?", taskReconcileSkipStatuses).
+		Order("id").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// FailStuckTask 仅在任务状态未变化时标记失败，避免与轮询更新冲突导致重复退款
+func FailStuckTask(task *Task, reason string) (bool, error) {
+	now := common.GetTimestamp()
+	result := DB.Model(&Task{}).Where("id = ? AND status = ? AND progress = ?", task.ID, task.Status, task.Progress).
+		Updates(map[string]any{
+			"status":      TaskStatusFailure,
+			"progress":    "100%",
+			"fail_reason": reason,
+			"finish_time": now,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetStuckMidjourneys 获取提交时间早于 before（毫秒）且仍未完成的 Midjourney 任务
+func GetStuckMidjourneys(before int64, limit int) ([]*Midjourney, error) {
+	var tasks []*Midjourney
+	err := DB.Where("submit_time < ?", before).Where("progress != ?", "100%").
+		Where("status NOT IN ?", []string{"SUCCESS", "FAILURE"}).
+		Order("id").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+func FailStuckMidjourney(task *Midjourney, reason string) (bool, error) {
+	result := DB.Model(&Midjourney{}).Where("id = ? AND status = ? AND progress = ?", task.Id, task.Status, task.Progress).
+		Updates(map[string]any{
+			"status":      "FAILURE",
+			"progress":    "100%",
+			"fail_reason": reason,
+			"finish_time": time.Now().UnixMilli(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TaskQuotaDiscrepancy 任务额度与消费、退款日志不一致的记录
+type TaskQuotaDiscrepancy struct {
+	Platform      string `json:"platform"`
+	TaskId        string `json:"task_id"`
+	UserId        int    `json:"user_id"`
+	Status        string `json:"status"`
+	SubmitTime    int64  `json:"submit_time"`
+	TaskQuota     int    `json:"task_quota"`
+	ConsumedQuota int    `json:"consumed_quota"`
+	RefundedQuota int    `json:"refunded_quota"`
+	Issue         string `json:"issue"`
+}
+
+// 对账问题类型
+const (
+	TaskQuotaIssueMissingConsume = "missing_consume_log"
+	TaskQuotaIssueQuotaMismatch  = "quota_mismatch"
+	TaskQuotaIssueMissingRefund  = "missing_refund"
+	TaskQuotaIssueOverRefund     = "over_refund"
+)
+
+const taskQuotaLogBatch = 1000
+
+type taskQuotaLogSum struct {
+	consumed int
+	refunded int
+}
+
+// sumTaskQuotaLogs 按任务 id 汇总时间范围内的消费与退款日志额度，任务完成后的退款可能晚于提交时间，
+// 日志量可能很大，分批读取只保留汇总结果
+func sumTaskQuotaLogs(startTime int64, endTime int64) (map[string]*taskQuotaLogSum, error) {
+	var logs []*Log
+	sums := make(map[string]*taskQuotaLogSum)
+	err := LOG_DB.Select("id", "type", "quota", "other").
+		Where("type IN ? AND created_at >= ? AND created_at < ?", []int{LogTypeConsume, LogTypeRefund}, startTime, endTime).
+		Where("other LIKE ?", "%\"task_id\"%").
+		FindInBatches(&logs, taskQuotaLogBatch, func(tx *gorm.DB, batch int) error {
+			for _, log := range logs {
+				other, _ := common.StrToMap(log.Other)
+				taskId, _ := other["task_id"].(string)
+				if taskId == "" {
+					continue
+				}
+				sum, ok := sums[taskId]
+				if !ok {
+					sum = &taskQuotaLogSum{}
+					sums[taskId] = sum
+				}
+				if log.Type == LogTypeRefund {
+					sum.refunded += log.Quota
+				} else {
+					sum.consumed += log.Quota
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// checkTaskQuota 比对单个任务的额度，成功任务净消费应等于任务额度，失败任务应全额退还
+func checkTaskQuota(d *TaskQuotaDiscrepancy, finished bool, failed bool) bool {
+	net := d.ConsumedQuota - d.RefundedQuota
+	switch {
+	case d.ConsumedQuota == 0:
+		d.Issue = TaskQuotaIssueMissingConsume
+	case d.RefundedQuota > d.ConsumedQuota:
+		d.Issue = TaskQuotaIssueOverRefund
+	case failed && net > 0:
+		d.Issue = TaskQuotaIssueMissingRefund
+	case finished && !failed && net != d.TaskQuota:
+		d.Issue = TaskQuotaIssueQuotaMismatch
+	default:
+		return false
+	}
+	return true
+}
+
+// GetTaskQuotaDiscrepancies 比对提交时间在 [startTime, endTime) 内的任务额度与消费、退款日志
+func GetTaskQuotaDiscrepancies(startTime int64, endTime int64, limit int) ([]*TaskQuotaDiscrepancy, error) {
+	sums, err := sumTaskQuotaLogs(startTime, common.GetTimestamp()+1)
+	if err != nil {
+		return nil, err
+	}
+	getSum := func(ids ...string) taskQuotaLogSum {
+		var total taskQuotaLogSum
+		for _, id := range ids {
+			if sum, ok := sums[id]; ok && id != "" {
+				total.consumed += sum.consumed
+				total.refunded += sum.refunded
+			}
+		}
+		return total
+	}
+
+	discrepancies := make([]*TaskQuotaDiscrepancy, 0)
+	var tasks []*Task
+	err = DB.Where("submit_time >= ? AND submit_time < ? AND quota > 0", startTime, endTime).
+		Order("id").Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		// 排队任务提交上游后任务 id 会变化，消费日志记录的是排队 id
+		ids := []string{task.TaskID}
+		if task.QueueId != "" && task.QueueId != task.TaskID {
+			ids = append(ids, task.QueueId)
+		}
+		sum := getSum(ids...)
+		d := &TaskQuotaDiscrepancy{
+			Platform:      string(task.Platform),
+			TaskId:        task.TaskID,
+			UserId:        task.UserId,
+			Status:        string(task.Status),
+			SubmitTime:    task.SubmitTime,
+			TaskQuota:     task.Quota,
+			ConsumedQuota: sum.consumed,
+			RefundedQuota: sum.refunded,
+		}
+		finished := task.Status == TaskStatusSuccess || task.Status == TaskStatusFailure
+		if checkTaskQuota(d, finished, task.Status == TaskStatusFailure) {
+			discrepancies = append(discrepancies, d)
+			if len(discrepancies) >= limit {
+				return discrepancies, nil
+			}
+		}
+	}
+
+	var mjTasks []*Midjourney
+	err = DB.Where("submit_time >= ? AND submit_time < ? AND quota > 0", startTime*1000, endTime*1000).
+		Order("id").Find(&mjTasks).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range mjTasks {
+		sum := getSum(task.MjId)
+		d := &TaskQuotaDiscrepancy{
+			Platform:      constant.TaskPlatformMidjourney,
+			TaskId:        task.MjId,
+			UserId:        task.UserId,
+			Status:        task.Status,
+			SubmitTime:    task.SubmitTime / 1000,
+			TaskQuota:     task.Quota,
+			ConsumedQuota: sum.consumed,
+			RefundedQuota: sum.refunded,
+		}
+		finished := task.Status == "SUCCESS" || task.Status == "FAILURE"
+		if checkTaskQuota(d, finished, task.Status == "FAILURE") {
+			discrepancies = append(discrepancies, d)
+			if len(discrepancies) >= limit {
+				break
+			}
+		}
+	}
+	return discrepancies, nil
+}
@@ -13,7 +13,9 @@ import (
 type Token struct {
 	Id                 int            `json:"id"`
 	UserId             int            `json:"user_id" gorm:"index"`
-	Key                string         `json:"key" gorm:"type:char(48);uniqueIndex"`
+	Key                string         `json:"key,omitempty" gorm:"-:all"` // 令牌明文，仅在创建时返回，不落库
+	KeyPrefix          string         `json:"key_prefix" gorm:"type:varchar(16);index"`
+	KeyHash            string         `json:"-" gorm:"type:char(64);uniqueIndex"`
 	Status             int            `json:"status" gorm:"default:1"`
 	Name               string         `json:"name" gorm:"index" `
 	CreatedTime        int64          `json:"created_time" gorm:"bigint"`
@@ -23,10 +25,12 @@ type Token struct {
 	UnlimitedQuota     bool           `json:"unlimited_quota"`
 	ModelLimitsEnabled bool           `json:"model_limits_enabled"`
 	ModelLimits        string         `json:"model_limits" gorm:"type:varchar(1024);default:''"`
+	Scopes             string         `json:"scopes" gorm:"type:varchar(255);default:''"` // 权限范围，逗号分隔，为空时不限制
 	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
+	CrossGroupRetry    bool           `json:"cross_group_retry"`                                // 跨分组重试，仅auto分组有效
+	CallbackUrl        string         `json:"callback_url" gorm:"type:varchar(512);default:''"` // 异步任务完成回调地址，请求未指定 callback_url 时使用
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -64,10 +68,11 @@ func GetAllUserTokens(userId int, startIdx int, num int) ([]*Token, error) {
 }
 
 func SearchUserTokens(userId int, keyword string, token string) (tokens []*Token, err error) {
+	// 数据库中只保存令牌前缀，按前缀匹配
 	if token != "" {
-		token = strings.Trim(token, "sk-")
+		token = TokenKeyPrefix(strings.TrimPrefix(token, "sk-"))
 	}
-	err = DB.Where("user_id = ?", userId).Where("name LIKE ?", "%"+keyword+"%").Where(commonKeyCol+" LIKE ?", "%"+token+"%").Find(&tokens).Error
+	err = DB.Where("user_id = ?", userId).Where("name LIKE ?", "%"+keyword+"%").Where("key_prefix LIKE ?", "%"+token+"%").Find(&tokens).Error
 	return tokens, err
 }
 
@@ -167,7 +172,35 @@ func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
 		// Don't return error - fall through to DB
 	}
 	fromDB = true
-	err = DB.Where(commonKeyCol+" = ?", key).First(&token).Error
+	err = DB.Where("key_prefix = ? AND key_hash = ?", TokenKeyPrefix(key), HashTokenKey(key)).First(&token).Error
+	if err == nil {
+		token.Key = key
+	}
+	return token, err
+}
+
+// GetTokenByKeyHash 通过令牌哈希获取令牌，用于无法取得令牌明文的场景（如派生令牌）
+func GetTokenByKeyHash(keyHash string, fromDB bool) (token *Token, err error) {
+	if keyHash == "" {
+		return nil, errors.New("令牌哈希为空")
+	}
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) && token != nil {
+			gopool.Go(func() {
+				if err := cacheSetToken(*token); err != nil {
+					common.SysLog("failed to update token cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if !fromDB && common.RedisEnabled {
+		token, err := cacheGetTokenByHash(keyHash)
+		if err == nil {
+			return token, nil
+		}
+	}
+	fromDB = true
+	err = DB.Where("key_hash = ?", keyHash).First(&token).Error
 	return token, err
 }
 
@@ -190,7 +223,7 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry").Updates(token).Error
+		"model_limits_enabled", "model_limits", "scopes", "allow_ips", "group", "cross_group_retry", "callback_url").Updates(token).Error
 	return err
 }
 
@@ -213,7 +246,7 @@ func (token *Token) Delete() (err error) {
 	defer func() {
 		if shouldUpdateRedis(true, err) {
 			gopool.Go(func() {
-				err := cacheDeleteToken(token.Key)
+				err := cacheDeleteToken(token.KeyHash)
 				if err != nil {
 					common.SysLog("failed to delete token cache: " + err.Error())
 				}
@@ -244,6 +277,26 @@ func (token *Token) GetModelLimitsMap() map[string]bool {
 	return limitsMap
 }
 
+func (token *Token) GetScopes() []string {
+	if token.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(token.Scopes, ",")
+}
+
+// HasScope 判断令牌是否拥有指定权限范围，未配置范围的令牌拥有全部权限
+func (token *Token) HasScope(scope string) bool {
+	if token.Scopes == "" {
+		return true
+	}
+	for _, s := range token.GetScopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 func DisableModelLimits(tokenId int) error {
 	token, err := GetTokenById(tokenId)
 	if err != nil {
@@ -267,13 +320,13 @@ func DeleteTokenById(id int, userId int) (err error) {
 	return token.Delete()
 }
 
-func IncreaseTokenQuota(id int, key string, quota int) (err error) {
+func IncreaseTokenQuota(id int, keyHash string, quota int) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
 	}
 	if common.RedisEnabled {
 		gopool.Go(func() {
-			err := cacheIncrTokenQuota(key, int64(quota))
+			err := cacheIncrTokenQuota(keyHash, int64(quota))
 			if err != nil {
 				common.SysLog("failed to increase token quota: " + err.Error())
 			}
@@ -297,13 +350,13 @@ func increaseTokenQuota(id int, quota int) (err error) {
 	return err
 }
 
-func DecreaseTokenQuota(id int, key string, quota int) (err error) {
+func DecreaseTokenQuota(id int, keyHash string, quota int) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
 	}
 	if common.RedisEnabled {
 		gopool.Go(func() {
-			err := cacheDecrTokenQuota(key, int64(quota))
+			err := cacheDecrTokenQuota(keyHash, int64(quota))
 			if err != nil {
 				common.SysLog("failed to decrease token quota: " + err.Error())
 			}
@@ -360,7 +413,7 @@ func BatchDeleteTokens(ids []int, userId int) (int, error) {
 	if common.RedisEnabled {
 		gopool.Go(func() {
 			for _, t := range tokens {
-				_ = cacheDeleteToken(t.Key)
+				_ = cacheDeleteToken(t.KeyHash)
 			}
 		})
 	}
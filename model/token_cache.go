@@ -8,8 +8,12 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 )
 
+// 令牌缓存以 KeyHash 为键，明文不写入缓存
 func cacheSetToken(token Token) error {
-	key := common.GenerateHMAC(token.Key)
+	key := token.KeyHash
+	if key == "" {
+		return nil
+	}
 	token.Clean()
 	err := common.RedisHSetObj(fmt.Sprintf("token:%s", key), &token, time.Duration(common.RedisKeyCacheSeconds())*time.Second)
 	if err != nil {
@@ -19,7 +23,6 @@ func cacheSetToken(token Token) error {
 }
 
 func cacheDeleteToken(key string) error {
-	key = common.GenerateHMAC(key)
 	err := common.RedisDelKey(fmt.Sprintf("token:%s", key))
 	if err != nil {
 		return err
@@ -28,7 +31,6 @@ func cacheDeleteToken(key string) error {
 }
 
 func cacheIncrTokenQuota(key string, increment int64) error {
-	key = common.GenerateHMAC(key)
 	err := common.RedisHIncrBy(fmt.Sprintf("token:%s", key), constant.TokenFiledRemainQuota, increment)
 	if err != nil {
 		return err
@@ -41,7 +43,6 @@ func cacheDecrTokenQuota(key string, decrement int64) error {
 }
 
 func cacheSetTokenField(key string, field string, value string) error {
-	key = common.GenerateHMAC(key)
 	err := common.RedisHSetField(fmt.Sprintf("token:%s", key), field, value)
 	if err != nil {
 		return err
@@ -51,15 +52,22 @@ func cacheSetTokenField(key string, field string, value string) error {
 
 // CacheGetTokenByKey 从缓存中获取 token，如果缓存中不存在，则从数据库中获取
 func cacheGetTokenByKey(key string) (*Token, error) {
-	hmacKey := common.GenerateHMAC(key)
+	token, err := cacheGetTokenByHash(HashTokenKey(key))
+	if err != nil {
+		return nil, err
+	}
+	token.Key = key
+	return token, nil
+}
+
+func cacheGetTokenByHash(keyHash string) (*Token, error) {
 	if !common.RedisEnabled {
 		return nil, fmt.Errorf("redis is not enabled")
 	}
 	var token Token
-	err := common.RedisHGetObj(fmt.Sprintf("token:%s", hmacKey), &token)
+	err := common.RedisHGetObj(fmt.Sprintf("token:%s", keyHash), &token)
 	if err != nil {
 		return nil, err
 	}
-	token.Key = key
 	return &token, nil
 }
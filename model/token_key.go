@@ -0,0 +1,116 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 令牌在数据库中只保存可见前缀与加盐哈希，明文仅在创建时返回一次
+const (
+	TokenKeyPrefixLength = 8
+	tokenKeySaltOption   = "TokenKeyHashSecret" // 以 Secret 结尾，不会通过 GetOptions 暴露，并加密存储
+	tokenMigrateBatch    = 500
+)
+
+var tokenKeySalt string
+
+// IsInternalOption 由系统生成与维护的配置项，不允许通过配置接口修改
+func IsInternalOption(key string) bool {
+	return key == tokenKeySaltOption
+}
+
+// initTokenKeySalt 读取或生成全局令牌哈希盐，多节点通过数据库共享同一个盐
+func initTokenKeySalt() error {
+	salt, err := encryptOptionValue(tokenKeySaltOption, common.GetRandomString(64))
+	if err != nil {
+		return err
+	}
+	option := Option{Key: tokenKeySaltOption, Value: salt}
+	err = DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&option).Error
+	if err != nil {
+		return err
+	}
+	option = Option{}
+	if err := DB.Where(commonKeyCol+" = ?", tokenKeySaltOption).First(&option).Error; err != nil {
+		return err
+	}
+	// 盐解密失败时不能退回密文，否则所有令牌哈希都会失配
+	salt, err = common.DecryptSecret(option.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt token key salt: %w", err)
+	}
+	if salt == "" {
+		return errors.New("token key salt is empty")
+	}
+	tokenKeySalt = salt
+	return nil
+}
+
+// TokenKeyPrefix 返回令牌的可见前缀，用于展示与索引查找
+func TokenKeyPrefix(key string) string {
+	if len(key) <= TokenKeyPrefixLength {
+		return key
+	}
+	return key[:TokenKeyPrefixLength]
+}
+
+// HashTokenKey 计算令牌的加盐哈希
+func HashTokenKey(key string) string {
+	return common.GenerateHMACWithKey([]byte(tokenKeySalt), key)
+}
+
+// SetKey 设置令牌明文，同时生成前缀与哈希
+func (token *Token) SetKey(key string) {
+	token.Key = key
+	token.KeyPrefix = TokenKeyPrefix(key)
+	token.KeyHash = HashTokenKey(key)
+}
+
+type legacyTokenKey struct {
+	Id        int
+	LegacyKey string
+}
+
+// migrateTokenKeys 将旧版明文存储的令牌转换为前缀 + 哈希，并清空明文列
+func migrateTokenKeys() error {
+	if !DB.Migrator().HasColumn("tokens", "key") {
+		return nil
+	}
+	var legacy []legacyTokenKey
+	var migrated int
+	err := DB.Table("tokens").
+		Select("id", commonKeyCol+" AS legacy_key").
+		Where(commonKeyCol+" IS NOT NULL AND "+commonKeyCol+" <> ''").
+		FindInBatches(&legacy, tokenMigrateBatch, func(tx *gorm.DB, batch int) error {
+			for _, row := range legacy {
+				err := DB.Table("tokens").Where("id = ?", row.Id).Updates(map[string]interface{}{
+					"key_prefix": TokenKeyPrefix(row.LegacyKey),
+					"key_hash":   HashTokenKey(row.LegacyKey),
+					"key":        gorm.Expr("NULL"),
+				}).Error
+				if err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+	if migrated > 0 {
+		common.SysLog(fmt.Sprintf("migrated %d plaintext token keys to hashed storage", migrated))
+	}
+	return nil
+}
+
+// DerivedTokenSecret 派生令牌的签名密钥，由 CRYPTO_SECRET 派生而非数据库中的哈希盐，
+// 仅持有数据库备份无法伪造派生令牌；多节点部署需配置相同的 CRYPTO_SECRET（或 SESSION_SECRET）
+func DerivedTokenSecret() []byte {
+	return []byte(common.GenerateHMACWithKey([]byte(common.CryptoSecret), "derived_token"))
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 使用内存 SQLite 替换全局 DB，测试结束后恢复
+func setupTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// 内存库每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	origDB := DB
+	t.Cleanup(func() { DB = origDB })
+	DB = db
+	initCol()
+}
+
+func setTokenKeySalt(t *testing.T, salt string) {
+	t.Helper()
+	orig := tokenKeySalt
+	t.Cleanup(func() { tokenKeySalt = orig })
+	tokenKeySalt = salt
+}
+
+func TestTokenKeyPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "long key", key: "abcdefghijklmnop", want: "abcdefgh"},
+		{name: "exact length", key: "abcdefgh", want: "abcdefgh"},
+		{name: "short key", key: "abc", want: "abc"},
+		{name: "empty key", key: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, TokenKeyPrefix(tt.key))
+		})
+	}
+}
+
+func TestHashTokenKey(t *testing.T) {
+	setTokenKeySalt(t, "salt-a")
+	hash := HashTokenKey("key-1")
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashTokenKey("key-1"))
+	require.NotEqual(t, hash, HashTokenKey("key-2"))
+
+	tokenKeySalt = "salt-b"
+	require.NotEqual(t, hash, HashTokenKey("key-1"))
+}
+
+func TestTokenSetKey(t *testing.T) {
+	setTokenKeySalt(t, "salt")
+	token := &Token{}
+	token.SetKey("abcdefghijklmnop")
+	require.Equal(t, "abcdefghijklmnop", token.Key)
+	require.Equal(t, "abcdefgh", token.KeyPrefix)
+	require.Equal(t, HashTokenKey("abcdefghijklmnop"), token.KeyHash)
+}
+
+func TestGetTokenByKey_RoundTrip(t *testing.T) {
+	setupTestDB(t, &Token{})
+	setTokenKeySalt(t, "salt")
+
+	token := &Token{UserId: 1, Name: "test"}
+	token.SetKey("abcdefghijklmnop")
+	require.NoError(t, DB.Create(token).Error)
+
+	found, err := GetTokenByKey("abcdefghijklmnop", true)
+	require.NoError(t, err)
+	require.Equal(t, token.Id, found.Id)
+	require.Equal(t, "abcdefghijklmnop", found.Key)
+
+	// 前缀相同但其余部分不同的密钥不能命中
+	_, err = GetTokenByKey("abcdefghXXXXXXXX", true)
+	require.Error(t, err)
+}
+
+func TestInitTokenKeySalt_Persists(t *testing.T) {
+	setupTestDB(t, &Option{})
+	setTokenKeySalt(t, "")
+
+	require.NoError(t, initTokenKeySalt())
+	first := tokenKeySalt
+	require.Len(t, first, 64)
+
+	// 再次初始化（如其他节点启动）应复用数据库中的盐
+	tokenKeySalt = ""
+	require.NoError(t, initTokenKeySalt())
+	require.Equal(t, first, tokenKeySalt)
+}
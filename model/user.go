@@ -31,6 +31,10 @@ type User struct {
 	OidcId           string         `json:"oidc_id" gorm:"column:oidc_id;index"`
 	WeChatId         string         `json:"wechat_id" gorm:"column:wechat_id;index"`
 	TelegramId       string         `json:"telegram_id" gorm:"column:telegram_id;index"`
+	LdapId           string         `json:"ldap_id" gorm:"column:ldap_id;index"`
+	LdapAdmin        bool           `json:"-" gorm:"default:false"`                                            // 管理员角色由 LDAP 目录组授予，仅此类管理员会随目录组变化被降级
+	ExternalId       string         `json:"external_id" gorm:"column:external_id;index"`                       // SCIM externalId
+	ScimUserName     string         `json:"scim_user_name" gorm:"column:scim_user_name;index"`                 // SCIM userName，可能超过用户名长度限制
 	VerificationCode string         `json:"verification_code" gorm:"-:all"`                                    // this field is only for Email verification, don't save it to database!
 	AccessToken      *string        `json:"access_token" gorm:"type:char(32);column:access_token;uniqueIndex"` // this token is for system management
 	Quota            int            `json:"quota" gorm:"type:int;default:0"`
@@ -47,6 +51,9 @@ type User struct {
 	Setting          string         `json:"setting" gorm:"type:text;column:setting"`
 	Remark           string         `json:"remark,omitempty" gorm:"type:varchar(255)" validate:"max=255"`
 	StripeCustomer   string         `json:"stripe_customer" gorm:"type:varchar(64);column:stripe_customer;index"`
+	CreditLimit      int            `json:"credit_limit" gorm:"type:int;default:0"`        // 后付费信用额度，大于 0 时余额允许透支至 -CreditLimit
+	BillingSuspended bool           `json:"billing_suspended" gorm:"default:false"`        // 账单逾期导致的服务暂停
+	AdminRoleId      int            `json:"admin_role_id" gorm:"type:int;default:0;index"` // 自定义管理角色，0 表示未分配
 }
 
 func (user *User) ToBaseUser() *UserBase {
@@ -58,6 +65,10 @@ func (user *User) ToBaseUser() *UserBase {
 		Username: user.Username,
 		Setting:  user.Setting,
 		Email:    user.Email,
+
+		AdminRoleId:      user.AdminRoleId,
+		CreditLimit:      user.CreditLimit,
+		BillingSuspended: user.BillingSuspended,
 	}
 	return cache
 }
@@ -81,16 +92,23 @@ func (user *User) GetSetting() dto.UserSetting {
 			common.SysLog("failed to unmarshal setting: " + err.Error())
 		}
 	}
+	setting.WebhookSecret = decryptWebhookSecret(setting.WebhookSecret)
 	return setting
 }
 
-func (user *User) SetSetting(setting dto.UserSetting) {
+// SetSetting 加密 Webhook 密钥后写入设置，失败时不修改用户设置
+func (user *User) SetSetting(setting dto.UserSetting) error {
+	encrypted, err := common.EncryptSecret(setting.WebhookSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	setting.WebhookSecret = encrypted
 	settingBytes, err := json.Marshal(setting)
 	if err != nil {
-		common.SysLog("failed to marshal setting: " + err.Error())
-		return
+		return fmt.Errorf("failed to marshal setting: %w", err)
 	}
 	user.Setting = string(settingBytes)
+	return nil
 }
 
 // 根据用户角色生成默认的边栏配置
@@ -389,7 +407,9 @@ func (user *User) Insert(inviterId int) error {
 	if user.Setting == "" {
 		defaultSetting := dto.UserSetting{}
 		// 这里暂时不设置SidebarModules，因为需要在用户创建后根据角色设置
-		user.SetSetting(defaultSetting)
+		if err := user.SetSetting(defaultSetting); err != nil {
+			return err
+		}
 	}
 
 	result := DB.Create(user)
@@ -406,9 +426,12 @@ func (user *User) Insert(inviterId int) error {
 		if defaultSidebarConfig != "" {
 			currentSetting := createdUser.GetSetting()
 			currentSetting.SidebarModules = defaultSidebarConfig
-			createdUser.SetSetting(currentSetting)
-			createdUser.Update(false)
-			common.SysLog(fmt.Sprintf("为新用户 %s (角色: %d) 初始化边栏配置", createdUser.Username, createdUser.Role))
+			if err := createdUser.SetSetting(currentSetting); err != nil {
+				common.SysError(fmt.Sprintf("为新用户 %s 初始化边栏配置失败: %s", createdUser.Username, err.Error()))
+			} else {
+				createdUser.Update(false)
+				common.SysLog(fmt.Sprintf("为新用户 %s (角色: %d) 初始化边栏配置", createdUser.Username, createdUser.Role))
+			}
 		}
 	}
 
@@ -763,6 +786,32 @@ func GetUserSetting(id int, fromDB bool) (settingMap dto.UserSetting, err error)
 	return userBase.GetSetting(), nil
 }
 
+// EnsureUserWebhookSecret 获取用户的 Webhook 密钥，未设置时自动生成并保存，返回密钥以及是否为新生成
+func EnsureUserWebhookSecret(id int) (string, bool, error) {
+	if setting, err := GetUserSetting(id, false); err == nil && setting.WebhookSecret != "" {
+		return setting.WebhookSecret, false, nil
+	}
+	user, err := GetUserById(id, true)
+	if err != nil {
+		return "", false, err
+	}
+	setting := user.GetSetting()
+	if setting.WebhookSecret != "" {
+		return setting.WebhookSecret, false, nil
+	}
+	setting.WebhookSecret = common.GetRandomString(32)
+	if err := user.SetSetting(setting); err != nil {
+		return "", false, err
+	}
+	if err := DB.Model(&User{}).Where("id = ?", id).Update("setting", user.Setting).Error; err != nil {
+		return "", false, err
+	}
+	if err := updateUserSettingCache(id, user.Setting); err != nil {
+		common.SysLog("failed to update user setting cache: " + err.Error())
+	}
+	return setting.WebhookSecret, true, nil
+}
+
 func IncreaseUserQuota(id int, quota int, db bool) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
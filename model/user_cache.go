@@ -22,6 +22,10 @@ type UserBase struct {
 	Status   int    `json:"status"`
 	Username string `json:"username"`
 	Setting  string `json:"setting"`
+
+	AdminRoleId      int  `json:"admin_role_id"`
+	CreditLimit      int  `json:"credit_limit"`
+	BillingSuspended bool `json:"billing_suspended"`
 }
 
 func (user *UserBase) WriteContext(c *gin.Context) {
@@ -31,6 +35,7 @@ func (user *UserBase) WriteContext(c *gin.Context) {
 	common.SetContextKey(c, constant.ContextKeyUserEmail, user.Email)
 	common.SetContextKey(c, constant.ContextKeyUserName, user.Username)
 	common.SetContextKey(c, constant.ContextKeyUserSetting, user.GetSetting())
+	common.SetContextKey(c, constant.ContextKeyUserCreditLimit, user.CreditLimit)
 }
 
 func (user *UserBase) GetSetting() dto.UserSetting {
@@ -41,6 +46,7 @@ func (user *UserBase) GetSetting() dto.UserSetting {
 			common.SysLog("failed to unmarshal setting: " + err.Error())
 		}
 	}
+	setting.WebhookSecret = decryptWebhookSecret(setting.WebhookSecret)
 	return setting
 }
 
@@ -99,15 +105,7 @@ func GetUserCache(userId int) (userCache *UserBase, err error) {
 	}
 
 	// Create cache object from user data
-	userCache = &UserBase{
-		Id:       user.Id,
-		Group:    user.Group,
-		Quota:    user.Quota,
-		Status:   user.Status,
-		Username: user.Username,
-		Setting:  user.Setting,
-		Email:    user.Email,
-	}
+	userCache = user.ToBaseUser()
 
 	return userCache, nil
 }
@@ -217,3 +215,10 @@ func updateUserSettingCache(userId int, setting string) error {
 	}
 	return common.RedisHSetField(getUserCacheKey(userId), "Setting", setting)
 }
+
+func updateUserBillingSuspendedCache(userId int, suspended bool) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisHSetField(getUserCacheKey(userId), "BillingSuspended", fmt.Sprintf("%t", suspended))
+}
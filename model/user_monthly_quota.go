@@ -0,0 +1,53 @@
+package model
+
+import (
+	"sync"
+	"time"
+)
+
+// 用户当月消费额度缓存，用于用量阶梯计费，允许数分钟的统计延迟
+const userMonthlyQuotaCacheTTL = 5 * time.Minute
+
+type userMonthlyQuotaEntry struct {
+	quota      int
+	monthStart int64
+	expiresAt  time.Time
+}
+
+var userMonthlyQuotaCache sync.Map
+
+func currentMonthStart() int64 {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix()
+}
+
+// SumUserConsumeQuota 统计用户自 startTimestamp 起的消费额度
+func SumUserConsumeQuota(userId int, startTimestamp int64) (int, error) {
+	var quota int64
+	err := LOG_DB.Table("logs").
+		Select("COALESCE(SUM(quota), 0)").
+		Where("user_id = ? AND type = ? AND created_at >= ?", userId, LogTypeConsume, startTimestamp).
+		Scan(&quota).Error
+	return int(quota), err
+}
+
+// GetUserMonthlyConsumeQuota 返回用户当月已消费额度（带缓存）
+func GetUserMonthlyConsumeQuota(userId int) (int, error) {
+	monthStart := currentMonthStart()
+	if v, ok := userMonthlyQuotaCache.Load(userId); ok {
+		entry := v.(userMonthlyQuotaEntry)
+		if entry.monthStart == monthStart && time.Now().Before(entry.expiresAt) {
+			return entry.quota, nil
+		}
+	}
+	quota, err := SumUserConsumeQuota(userId, monthStart)
+	if err != nil {
+		return 0, err
+	}
+	userMonthlyQuotaCache.Store(userId, userMonthlyQuotaEntry{
+		quota:      quota,
+		monthStart: monthStart,
+		expiresAt:  time.Now().Add(userMonthlyQuotaCacheTTL),
+	})
+	return quota, nil
+}
@@ -92,7 +92,7 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 			} else {
 				fullRequestURL = fmt.Sprintf("%s/api/v1/services/aigc/text2image/image-synthesis", info.ChannelBaseUrl)
 			}
-		case constant.RelayModeImagesEdits:
+		case constant.RelayModeImagesEdits, constant.RelayModeImagesVariations:
 			if isOldWanModel(info.OriginModelName) {
 				fullRequestURL = fmt.Sprintf("%s/api/v1/services/aigc/image2image/image-synthesis", info.ChannelBaseUrl)
 			} else if isWanModel(info.OriginModelName) {
@@ -126,7 +126,7 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *rel
 			req.Set("X-DashScope-Async", "enable")
 		}
 	}
-	if info.RelayMode == constant.RelayModeImagesEdits {
+	if info.RelayMode == constant.RelayModeImagesEdits || info.RelayMode == constant.RelayModeImagesVariations {
 		if isWanModel(info.OriginModelName) {
 			req.Set("X-DashScope-Async", "enable")
 		}
@@ -168,7 +168,11 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 			return nil, fmt.Errorf("convert image request to async ali image request failed: %w", err)
 		}
 		return aliRequest, nil
-	} else if info.RelayMode == constant.RelayModeImagesEdits {
+	} else if info.RelayMode == constant.RelayModeImagesEdits || info.RelayMode == constant.RelayModeImagesVariations {
+		if info.RelayMode == constant.RelayModeImagesVariations && request.Prompt == "" {
+			// 变体接口没有提示词，使用图生图模拟
+			request.Prompt = relaycommon.ImageVariationPrompt
+		}
 		if isOldWanModel(info.OriginModelName) {
 			return oaiFormEdit2WanxImageEdit(c, info, request)
 		}
@@ -236,7 +240,7 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		switch info.RelayMode {
 		case constant.RelayModeImagesGenerations:
 			err, usage = aliImageHandler(a, c, resp, info)
-		case constant.RelayModeImagesEdits:
+		case constant.RelayModeImagesEdits, constant.RelayModeImagesVariations:
 			err, usage = aliImageHandler(a, c, resp, info)
 		case constant.RelayModeRerank:
 			err, usage = RerankHandler(c, resp, info)
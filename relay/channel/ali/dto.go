@@ -198,6 +198,14 @@ type WanImageInput struct {
 	NegativePrompt string   `json:"negative_prompt,omitempty"` // 可选：反向提示词，描述不希望在画面中看到的内容
 }
 
+// WanImageEditInput 万相通用图像编辑（wanx2.1-imageedit）的输入
+type WanImageEditInput struct {
+	Function     string `json:"function"`
+	Prompt       string `json:"prompt"`
+	BaseImageUrl string `json:"base_image_url"`
+	MaskImageUrl string `json:"mask_image_url,omitempty"`
+}
+
 type WanImageParameters struct {
 	N         int     `json:"n,omitempty"`         // 生成图片数量，取值范围1-4，默认4
 	Watermark *bool   `json:"watermark,omitempty"` // 是否添加水印标识，默认false
@@ -12,7 +12,6 @@ import (
 )
 
 func oaiFormEdit2WanxImageEdit(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (*AliImageRequest, error) {
-	var err error
 	var imageRequest AliImageRequest
 	imageRequest.Model = request.Model
 	imageRequest.ResponseFormat = request.ResponseFormat
@@ -23,17 +22,34 @@ func oaiFormEdit2WanxImageEdit(c *gin.Context, info *relaycommon.RelayInfo, requ
 	if err := common.UnmarshalBodyReusable(c, &wanInput); err != nil {
 		return nil, err
 	}
-	if wanInput.Images, err = getImageBase64sFromForm(c, "image"); err != nil {
-		return nil, fmt.Errorf("get image base64s from form failed: %w", err)
+	if wanInput.Prompt == "" {
+		wanInput.Prompt = request.Prompt
+	}
+	images, mask, err := relaycommon.GetImageFormFiles(c)
+	if err != nil {
+		return nil, fmt.Errorf("get images from form failed: %w", err)
 	}
-	//wanParams := WanImageParameters{
-	//	N: int(request.N),
-	//}
-	imageRequest.Input = wanInput
 	imageRequest.Parameters = AliImageParameters{
 		N: int(request.N),
 	}
-	info.PriceData.AddOtherRatio("n", float64(imageRequest.Parameters.N))
+	if mask != nil && strings.Contains(request.Model, "imageedit") {
+		// 万相通用图像编辑的局部重绘，蒙版白色区域为待编辑区域
+		binaryMask, err := mask.BinaryMask()
+		if err != nil {
+			return nil, err
+		}
+		imageRequest.Input = WanImageEditInput{
+			Function:     "description_edit_with_mask",
+			Prompt:       wanInput.Prompt,
+			BaseImageUrl: images[0].DataURL(),
+			MaskImageUrl: binaryMask.DataURL(),
+		}
+		return &imageRequest, nil
+	}
+	for _, image := range images {
+		wanInput.Images = append(wanInput.Images, image.DataURL())
+	}
+	imageRequest.Input = wanInput
 
 	return &imageRequest, nil
 }
@@ -0,0 +1,717 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// OpenAI Realtime 的 pcm16 为 24kHz 单声道，Gemini Live 输出同样为 24kHz
+const geminiLiveInputAudioMimeType = "audio/pcm;rate=24000"
+
+// Gemini Live 预置音色，OpenAI 音色名无法对应时使用上游默认音色
+var geminiLiveVoices = []string{"Puck", "Charon", "Kore", "Fenrir", "Aoede", "Leda", "Orus", "Zephyr"}
+
+// geminiLiveSession 将 OpenAI Realtime 协议转换为 Gemini Live 协议的会话状态
+type geminiLiveSession struct {
+	c          *gin.Context
+	info       *relaycommon.RelayInfo
+	clientConn *websocket.Conn
+	targetConn *websocket.Conn
+
+	// 客户端连接同时被两个 goroutine 写入
+	clientMu sync.Mutex
+
+	session        *dto.RealtimeSession
+	audioOutput    bool
+	manualActivity bool
+	setupSent      bool
+	setupDone      chan struct{}
+	setupOnce      sync.Once
+
+	// 以下字段被两个 goroutine 共享
+	mu              sync.Mutex
+	activityStarted bool
+	pendingTurns    []dto.GeminiChatContent
+	callNames       map[string]string
+	toolResponded   bool
+	responseId      string
+	responseItemId  string
+	responseAudio   bool
+	responseOutput  []dto.RealtimeItem
+	turnUsage       *dto.RealtimeUsage
+	localUsage      *dto.RealtimeUsage
+	sumUsage        *dto.RealtimeUsage
+}
+
+// GeminiLiveRealtimeHandler 桥接 /v1/realtime 与 Gemini Live，计费沿用 Realtime 的预扣与结算流程
+func GeminiLiveRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.RealtimeUsage) {
+	if info == nil || info.ClientWs == nil || info.TargetWs == nil {
+		return types.NewError(fmt.Errorf("invalid websocket connection"), types.ErrorCodeBadResponse), nil
+	}
+
+	info.IsStream = true
+	info.InputAudioFormat = "pcm16"
+	info.OutputAudioFormat = "pcm16"
+	s := &geminiLiveSession{
+		c:          c,
+		info:       info,
+		clientConn: info.ClientWs,
+		targetConn: info.TargetWs,
+		session:    defaultGeminiLiveSession(),
+		setupDone:  make(chan struct{}),
+		callNames:  make(map[string]string),
+		localUsage: &dto.RealtimeUsage{},
+		sumUsage:   &dto.RealtimeUsage{},
+	}
+
+	clientClosed := make(chan struct{})
+	targetClosed := make(chan struct{})
+	errChan := make(chan error, 2)
+
+	// 与 OpenAI 一致，连接建立后立即下发 session.created，上游 setup 延迟到客户端首条消息
+	if err := s.sendEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeSessionCreated, Session: s.session}); err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponse), nil
+	}
+
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic in client reader: %v", r)
+			}
+		}()
+		for {
+			select {
+			case <-c.Done():
+				return
+			default:
+				_, message, err := s.clientConn.ReadMessage()
+				if err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						errChan <- fmt.Errorf("error reading from client: %v", err)
+					}
+					close(clientClosed)
+					return
+				}
+				if err := s.handleClientMessage(message, targetClosed); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}
+	})
+
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic in target reader: %v", r)
+			}
+		}()
+		for {
+			select {
+			case <-c.Done():
+				return
+			default:
+				_, message, err := s.targetConn.ReadMessage()
+				if err != nil {
+					var closeErr *websocket.CloseError
+					if ce, ok := err.(*websocket.CloseError); ok {
+						closeErr = ce
+					}
+					if closeErr != nil && closeErr.Code != websocket.CloseNormalClosure && closeErr.Text != "" {
+						// setup 参数错误等情况上游会在关闭帧中给出原因
+						helper.WssError(c, s.clientConn, types.OpenAIError{
+							Message: closeErr.Text,
+							Type:    "upstream_error",
+							Code:    closeErr.Code,
+						})
+					}
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						errChan <- fmt.Errorf("error reading from target: %v", err)
+					}
+					close(targetClosed)
+					return
+				}
+				info.SetFirstResponseTime()
+				if err := s.handleServerMessage(message); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}
+	})
+
+	select {
+	case <-clientClosed:
+	case <-targetClosed:
+	case err := <-errChan:
+		logger.LogError(c, "gemini live realtime error: "+err.Error())
+	case <-c.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consumeTurnUsage()
+	return nil, s.sumUsage
+}
+
+func defaultGeminiLiveSession() *dto.RealtimeSession {
+	return &dto.RealtimeSession{
+		Modalities:        []string{"text", "audio"},
+		InputAudioFormat:  "pcm16",
+		OutputAudioFormat: "pcm16",
+		TurnDetection:     map[string]any{"type": "server_vad"},
+		Tools:             []dto.RealTimeTool{},
+	}
+}
+
+func (s *geminiLiveSession) sendEvent(event *dto.RealtimeEvent) error {
+	if event.EventId == "" {
+		event.EventId = "evt_" + common.GetRandomString(16)
+	}
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	return helper.WssObject(s.c, s.clientConn, event)
+}
+
+func (s *geminiLiveSession) sendUpstream(msg *dto.GeminiLiveClientMessage) error {
+	if err := helper.WssObject(s.c, s.targetConn, msg); err != nil {
+		return fmt.Errorf("error writing to target: %v", err)
+	}
+	return nil
+}
+
+func (s *geminiLiveSession) sendClientError(message string) error {
+	return s.sendEvent(&dto.RealtimeEvent{
+		Type: dto.RealtimeEventTypeError,
+		Error: &types.OpenAIError{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	})
+}
+
+func (s *geminiLiveSession) countLocalUsage(event *dto.RealtimeEvent, input bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.countLocalUsageLocked(event, input)
+}
+
+// countLocalUsageLocked 上游未返回用量时按 OpenAI 事件在本地估算，调用方需持有 s.mu
+func (s *geminiLiveSession) countLocalUsageLocked(event *dto.RealtimeEvent, input bool) {
+	textToken, audioToken, err := service.CountTokenRealtime(s.info, *event, s.info.UpstreamModelName)
+	if err != nil {
+		logger.LogWarn(s.c, fmt.Sprintf("gemini live count token error: %v", err))
+		return
+	}
+	s.localUsage.TotalTokens += textToken + audioToken
+	if input {
+		s.localUsage.InputTokens += textToken + audioToken
+		s.localUsage.InputTokenDetails.TextTokens += textToken
+		s.localUsage.InputTokenDetails.AudioTokens += audioToken
+	} else {
+		s.localUsage.OutputTokens += textToken + audioToken
+		s.localUsage.OutputTokenDetails.TextTokens += textToken
+		s.localUsage.OutputTokenDetails.AudioTokens += audioToken
+	}
+}
+
+// consumeTurnUsage 结算一轮对话的用量，优先使用上游返回的用量，调用方需持有 s.mu
+func (s *geminiLiveSession) consumeTurnUsage() *dto.RealtimeUsage {
+	usage := s.turnUsage
+	if usage == nil || usage.TotalTokens == 0 {
+		usage = s.localUsage
+	}
+	s.turnUsage = nil
+	s.localUsage = &dto.RealtimeUsage{}
+	if usage.TotalTokens == 0 {
+		return usage
+	}
+
+	s.sumUsage.TotalTokens += usage.TotalTokens
+	s.sumUsage.InputTokens += usage.InputTokens
+	s.sumUsage.OutputTokens += usage.OutputTokens
+	s.sumUsage.InputTokenDetails.CachedTokens += usage.InputTokenDetails.CachedTokens
+	s.sumUsage.InputTokenDetails.TextTokens += usage.InputTokenDetails.TextTokens
+	s.sumUsage.InputTokenDetails.AudioTokens += usage.InputTokenDetails.AudioTokens
+	s.sumUsage.OutputTokenDetails.TextTokens += usage.OutputTokenDetails.TextTokens
+	s.sumUsage.OutputTokenDetails.AudioTokens += usage.OutputTokenDetails.AudioTokens
+	if err := service.PreWssConsumeQuota(s.c, s.info, usage); err != nil {
+		logger.LogError(s.c, "gemini live consume quota error: "+err.Error())
+	}
+	return usage
+}
+
+func (s *geminiLiveSession) handleClientMessage(message []byte, targetClosed <-chan struct{}) error {
+	event := &dto.RealtimeEvent{}
+	if err := common.Unmarshal(message, event); err != nil {
+		return fmt.Errorf("error unmarshalling message: %v", err)
+	}
+
+	if !s.setupSent {
+		if event.Type == dto.RealtimeEventTypeSessionUpdate && event.Session != nil {
+			s.applySession(event.Session, message)
+		}
+		if err := s.sendUpstream(&dto.GeminiLiveClientMessage{Setup: s.buildSetup()}); err != nil {
+			return err
+		}
+		s.setupSent = true
+		// 上游返回 setupComplete 前不能发送其他消息
+		select {
+		case <-s.setupDone:
+		case <-targetClosed:
+			return nil
+		case <-s.c.Done():
+			return nil
+		}
+		if event.Type == dto.RealtimeEventTypeSessionUpdate {
+			s.countLocalUsage(event, true)
+			return s.sendEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeSessionUpdated, Session: s.session})
+		}
+	}
+
+	switch event.Type {
+	case dto.RealtimeEventTypeSessionUpdate:
+		// Gemini Live 会话建立后不支持修改配置，返回当前生效的配置
+		logger.LogWarn(s.c, "gemini live does not support updating session after setup, ignored")
+		return s.sendEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeSessionUpdated, Session: s.session})
+	case dto.RealtimeEventInputAudioBufferAppend:
+		s.countLocalUsage(event, true)
+		s.mu.Lock()
+		startActivity := s.manualActivity && !s.activityStarted
+		s.activityStarted = true
+		s.mu.Unlock()
+		if startActivity {
+			if err := s.sendUpstream(&dto.GeminiLiveClientMessage{
+				RealtimeInput: &dto.GeminiLiveRealtimeInput{ActivityStart: &struct{}{}},
+			}); err != nil {
+				return err
+			}
+		}
+		return s.sendUpstream(&dto.GeminiLiveClientMessage{
+			RealtimeInput: &dto.GeminiLiveRealtimeInput{
+				Audio: &dto.GeminiInlineData{MimeType: geminiLiveInputAudioMimeType, Data: event.Audio},
+			},
+		})
+	case dto.RealtimeEventInputAudioBufferCommit:
+		s.mu.Lock()
+		activityStarted := s.activityStarted
+		s.activityStarted = false
+		s.mu.Unlock()
+		input := &dto.GeminiLiveRealtimeInput{AudioStreamEnd: true}
+		if s.manualActivity {
+			input = &dto.GeminiLiveRealtimeInput{ActivityEnd: &struct{}{}}
+		}
+		if activityStarted {
+			if err := s.sendUpstream(&dto.GeminiLiveClientMessage{RealtimeInput: input}); err != nil {
+				return err
+			}
+		}
+		return s.sendEvent(&dto.RealtimeEvent{
+			Type:   dto.RealtimeEventInputAudioBufferCommitted,
+			ItemId: "item_" + common.GetRandomString(16),
+		})
+	case dto.RealtimeEventInputAudioBufferClear:
+		// 已发送给上游的音频无法撤回
+		return s.sendEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferCleared})
+	case dto.RealtimeEventTypeConversationCreate:
+		return s.handleConversationItem(event)
+	case dto.RealtimeEventTypeResponseCreate:
+		s.mu.Lock()
+		turns := s.pendingTurns
+		s.pendingTurns = nil
+		toolResponded := s.toolResponded
+		s.toolResponded = false
+		s.mu.Unlock()
+		// 提交工具结果后上游会自动继续生成，无需再次触发
+		if len(turns) == 0 && toolResponded {
+			return nil
+		}
+		return s.sendUpstream(&dto.GeminiLiveClientMessage{
+			ClientContent: &dto.GeminiLiveClientContent{Turns: turns, TurnComplete: true},
+		})
+	case dto.RealtimeEventTypeResponseCancel:
+		// Gemini Live 没有取消生成的消息，由客户端开始说话时上游自动打断
+		return nil
+	default:
+		logger.LogDebug(s.c, "gemini live ignored client event: "+event.Type)
+		return nil
+	}
+}
+
+func (s *geminiLiveSession) handleConversationItem(event *dto.RealtimeEvent) error {
+	item := event.Item
+	if item == nil {
+		return s.sendClientError("conversation.item.create requires item")
+	}
+	if item.Id == "" {
+		item.Id = "item_" + common.GetRandomString(16)
+	}
+
+	switch item.Type {
+	case "message":
+		content := dto.GeminiChatContent{Role: "user"}
+		if item.Role == "assistant" {
+			content.Role = "model"
+		}
+		for _, part := range item.Content {
+			switch part.Type {
+			case "input_text", "text":
+				content.Parts = append(content.Parts, dto.GeminiPart{Text: part.Text})
+			case "input_audio":
+				content.Parts = append(content.Parts, dto.GeminiPart{
+					InlineData: &dto.GeminiInlineData{MimeType: geminiLiveInputAudioMimeType, Data: part.Audio},
+				})
+			}
+		}
+		if len(content.Parts) > 0 {
+			s.mu.Lock()
+			s.pendingTurns = append(s.pendingTurns, content)
+			s.mu.Unlock()
+		}
+	case "function_call_output":
+		s.mu.Lock()
+		name := s.callNames[item.CallId]
+		delete(s.callNames, item.CallId)
+		s.toolResponded = true
+		s.mu.Unlock()
+		response := map[string]any{"output": item.Output}
+		var parsed map[string]any
+		if err := common.UnmarshalJsonStr(item.Output, &parsed); err == nil && parsed != nil {
+			response = parsed
+		}
+		if err := s.sendUpstream(&dto.GeminiLiveClientMessage{
+			ToolResponse: &dto.GeminiLiveToolResponse{
+				FunctionResponses: []dto.GeminiLiveFunctionResponse{{Id: item.CallId, Name: name, Response: response}},
+			},
+		}); err != nil {
+			return err
+		}
+	default:
+		return s.sendClientError(fmt.Sprintf("unsupported conversation item type: %s", item.Type))
+	}
+
+	created := &dto.RealtimeEvent{Type: dto.RealtimeEventConversationItemCreated, Item: item}
+	s.countLocalUsage(created, true)
+	return s.sendEvent(created)
+}
+
+// applySession 将 session.update 合并到当前会话配置，turn_detection 显式为 null 时改为手动提交音频
+func (s *geminiLiveSession) applySession(update *dto.RealtimeSession, message []byte) {
+	if len(update.Modalities) > 0 {
+		s.session.Modalities = update.Modalities
+	}
+	s.session.Instructions = update.Instructions
+	s.session.Voice = update.Voice
+	s.session.InputAudioTranscription = update.InputAudioTranscription
+	s.session.Tools = update.Tools
+	s.session.ToolChoice = update.ToolChoice
+	s.session.Temperature = update.Temperature
+	if len(update.Tools) > 0 {
+		s.info.RealtimeTools = update.Tools
+	}
+	if update.InputAudioFormat != "" && update.InputAudioFormat != "pcm16" {
+		_ = s.sendClientError("gemini live only supports pcm16 audio format")
+	}
+
+	var raw struct {
+		Session map[string]json.RawMessage `json:"session"`
+	}
+	if err := common.Unmarshal(message, &raw); err == nil {
+		if td, ok := raw.Session["turn_detection"]; ok {
+			s.session.TurnDetection = update.TurnDetection
+			s.manualActivity = strings.TrimSpace(string(td)) == "null"
+		}
+	}
+}
+
+func (s *geminiLiveSession) buildSetup() *dto.GeminiLiveSetup {
+	setup := &dto.GeminiLiveSetup{
+		Model:            "models/" + s.info.UpstreamModelName,
+		GenerationConfig: &dto.GeminiLiveGenerationConfig{},
+	}
+
+	// Gemini Live 每个会话只能输出一种模态，有音频时优先音频并通过转写返回文本
+	audioOutput := false
+	for _, modality := range s.session.Modalities {
+		if modality == "audio" {
+			audioOutput = true
+		}
+	}
+	s.mu.Lock()
+	s.audioOutput = audioOutput
+	s.mu.Unlock()
+	if audioOutput {
+		setup.GenerationConfig.ResponseModalities = []string{"AUDIO"}
+		setup.OutputAudioTranscription = &struct{}{}
+	} else {
+		setup.GenerationConfig.ResponseModalities = []string{"TEXT"}
+	}
+	if s.session.Temperature > 0 {
+		temperature := s.session.Temperature
+		setup.GenerationConfig.Temperature = &temperature
+	}
+	for _, voice := range geminiLiveVoices {
+		if strings.EqualFold(voice, s.session.Voice) {
+			setup.GenerationConfig.SpeechConfig = &dto.GeminiLiveSpeechConfig{
+				VoiceConfig: dto.GeminiLiveVoiceConfig{
+					PrebuiltVoiceConfig: dto.GeminiLivePrebuiltVoiceConfig{VoiceName: voice},
+				},
+			}
+			break
+		}
+	}
+
+	if s.session.Instructions != "" {
+		setup.SystemInstruction = &dto.GeminiChatContent{
+			Parts: []dto.GeminiPart{{Text: s.session.Instructions}},
+		}
+	}
+	if len(s.session.Tools) > 0 && s.session.ToolChoice != "none" {
+		declarations := make([]dto.GeminiLiveFunctionDeclaration, 0, len(s.session.Tools))
+		for _, tool := range s.session.Tools {
+			if tool.Type != "" && tool.Type != "function" {
+				continue
+			}
+			declarations = append(declarations, dto.GeminiLiveFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  cleanFunctionParameters(tool.Parameters),
+			})
+		}
+		if len(declarations) > 0 {
+			setup.Tools = []dto.GeminiLiveTool{{FunctionDeclarations: declarations}}
+		}
+	}
+	if s.session.InputAudioTranscription.Model != "" {
+		setup.InputAudioTranscription = &struct{}{}
+	}
+	if s.manualActivity {
+		setup.RealtimeInputConfig = &dto.GeminiLiveRealtimeInputConfig{
+			AutomaticActivityDetection: &dto.GeminiLiveActivityDetection{Disabled: true},
+		}
+	}
+	return setup
+}
+
+// ensureResponse 每轮回复开始时下发 response.created，调用方需持有 s.mu
+func (s *geminiLiveSession) ensureResponse() error {
+	if s.responseId != "" {
+		return nil
+	}
+	s.responseId = "resp_" + common.GetRandomString(16)
+	s.responseItemId = "item_" + common.GetRandomString(16)
+	s.responseOutput = nil
+	return s.sendEvent(&dto.RealtimeEvent{
+		Type: dto.RealtimeEventResponseCreated,
+		Response: &dto.RealtimeResponse{
+			Id:     s.responseId,
+			Object: "realtime.response",
+			Status: "in_progress",
+		},
+	})
+}
+
+// finishResponse 结束当前回复并结算用量，调用方需持有 s.mu
+func (s *geminiLiveSession) finishResponse(status string) error {
+	if s.responseId == "" {
+		return nil
+	}
+	if s.responseAudio {
+		if err := s.sendEvent(&dto.RealtimeEvent{
+			Type:       dto.RealtimeEventResponseAudioDone,
+			ResponseId: s.responseId,
+			ItemId:     s.responseItemId,
+		}); err != nil {
+			return err
+		}
+	}
+	if s.turnUsage == nil || s.turnUsage.TotalTokens == 0 {
+		// 与 OpenAI 本地计费一致，非首轮回复计入工具定义的 token
+		s.countLocalUsageLocked(&dto.RealtimeEvent{Type: dto.RealtimeEventTypeResponseDone}, true)
+		s.info.IsFirstRequest = false
+	}
+	usage := s.consumeTurnUsage()
+	event := &dto.RealtimeEvent{
+		Type: dto.RealtimeEventTypeResponseDone,
+		Response: &dto.RealtimeResponse{
+			Id:     s.responseId,
+			Object: "realtime.response",
+			Status: status,
+			Output: s.responseOutput,
+			Usage:  usage,
+		},
+	}
+	s.responseId = ""
+	s.responseItemId = ""
+	s.responseAudio = false
+	s.responseOutput = nil
+	return s.sendEvent(event)
+}
+
+func (s *geminiLiveSession) handleServerMessage(message []byte) error {
+	msg := &dto.GeminiLiveServerMessage{}
+	if err := common.Unmarshal(message, msg); err != nil {
+		return fmt.Errorf("error unmarshalling message: %v", err)
+	}
+
+	if msg.SetupComplete != nil {
+		s.setupOnce.Do(func() { close(s.setupDone) })
+	}
+	if len(msg.GoAway) > 0 {
+		logger.LogInfo(s.c, "gemini live upstream will close the session: "+string(msg.GoAway))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 上游用量可能先于 turnComplete 单独返回，取一轮中最后一次上报的值
+	if msg.UsageMetadata != nil {
+		s.turnUsage = geminiLiveUsageToRealtime(msg.UsageMetadata, s.audioOutput)
+	}
+
+	if msg.ToolCall != nil {
+		if err := s.ensureResponse(); err != nil {
+			return err
+		}
+		for _, call := range msg.ToolCall.FunctionCalls {
+			s.callNames[call.Id] = call.Name
+			arguments := "{}"
+			if len(call.Args) > 0 {
+				arguments = string(call.Args)
+			}
+			itemId := "item_" + common.GetRandomString(16)
+			if err := s.sendEvent(&dto.RealtimeEvent{
+				Type:       dto.RealtimeEventResponseFunctionCallArgumentsDone,
+				ResponseId: s.responseId,
+				ItemId:     itemId,
+				CallId:     call.Id,
+				Name:       call.Name,
+				Arguments:  arguments,
+			}); err != nil {
+				return err
+			}
+			name := call.Name
+			item := dto.RealtimeItem{
+				Id:        itemId,
+				Type:      "function_call",
+				Status:    "completed",
+				Name:      &name,
+				CallId:    call.Id,
+				Arguments: arguments,
+			}
+			s.responseOutput = append(s.responseOutput, item)
+			if err := s.sendEvent(&dto.RealtimeEvent{
+				Type:       dto.RealtimeEventResponseOutputItemDone,
+				ResponseId: s.responseId,
+				Item:       &item,
+			}); err != nil {
+				return err
+			}
+		}
+		// 客户端需在收到 response.done 后提交工具结果
+		return s.finishResponse("completed")
+	}
+
+	content := msg.ServerContent
+	if content == nil {
+		return nil
+	}
+	if content.InputTranscription != nil && content.InputTranscription.Text != "" {
+		if err := s.sendEvent(&dto.RealtimeEvent{
+			Type:  dto.RealtimeEventInputAudioTranscriptionDelta,
+			Delta: content.InputTranscription.Text,
+		}); err != nil {
+			return err
+		}
+	}
+	if content.Interrupted {
+		if err := s.sendEvent(&dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferSpeechStarted}); err != nil {
+			return err
+		}
+		return s.finishResponse("cancelled")
+	}
+	if content.ModelTurn != nil {
+		if err := s.ensureResponse(); err != nil {
+			return err
+		}
+		for _, part := range content.ModelTurn.Parts {
+			var event *dto.RealtimeEvent
+			switch {
+			case part.Thought:
+				continue
+			case part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/"):
+				event = &dto.RealtimeEvent{Type: dto.RealtimeEventResponseAudioDelta, Delta: part.InlineData.Data}
+				s.responseAudio = true
+			case part.Text != "":
+				event = &dto.RealtimeEvent{Type: dto.RealtimeEventResponseTextDelta, Delta: part.Text}
+			default:
+				continue
+			}
+			event.ResponseId = s.responseId
+			event.ItemId = s.responseItemId
+			if err := s.sendEvent(event); err != nil {
+				return err
+			}
+			s.countLocalUsageLocked(event, false)
+		}
+	}
+	if content.OutputTranscription != nil && content.OutputTranscription.Text != "" {
+		if err := s.ensureResponse(); err != nil {
+			return err
+		}
+		if err := s.sendEvent(&dto.RealtimeEvent{
+			Type:       dto.RealtimeEventResponseAudioTranscriptionDelta,
+			ResponseId: s.responseId,
+			ItemId:     s.responseItemId,
+			Delta:      content.OutputTranscription.Text,
+		}); err != nil {
+			return err
+		}
+	}
+	if content.TurnComplete {
+		return s.finishResponse("completed")
+	}
+	return nil
+}
+
+// geminiLiveUsageToRealtime 按模态拆分上游用量，缺少明细时文本输入、输出按会话模态计入
+func geminiLiveUsageToRealtime(metadata *dto.GeminiLiveUsageMetadata, audioOutput bool) *dto.RealtimeUsage {
+	usage := &dto.RealtimeUsage{
+		InputTokens:  metadata.PromptTokenCount + metadata.ToolUsePromptTokenCount,
+		OutputTokens: metadata.ResponseTokenCount + metadata.ThoughtsTokenCount,
+	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	usage.InputTokenDetails.CachedTokens = metadata.CachedContentTokenCount
+
+	for _, detail := range metadata.PromptTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.InputTokenDetails.AudioTokens += detail.TokenCount
+		}
+	}
+	usage.InputTokenDetails.TextTokens = usage.InputTokens - usage.InputTokenDetails.AudioTokens
+
+	if len(metadata.ResponseTokensDetails) == 0 && audioOutput {
+		usage.OutputTokenDetails.AudioTokens = metadata.ResponseTokenCount
+	}
+	for _, detail := range metadata.ResponseTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.OutputTokenDetails.AudioTokens += detail.TokenCount
+		}
+	}
+	usage.OutputTokenDetails.TextTokens = usage.OutputTokens - usage.OutputTokenDetails.AudioTokens
+	return usage
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/relay/channel/openai"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
@@ -1583,6 +1584,113 @@ func GeminiImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.
 	return usage, nil
 }
 
+// convertImageRequest2GeminiChat 通过 Gemini 图像模型的 generateContent 模拟图片生成、编辑与变体，
+// 蒙版作为第二张图片传入并附加说明
+func convertImageRequest2GeminiChat(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (*dto.GeminiChatRequest, error) {
+	prompt := request.Prompt
+	var parts []dto.GeminiPart
+	if info.RelayMode == relayconstant.RelayModeImagesEdits || info.RelayMode == relayconstant.RelayModeImagesVariations {
+		images, mask, err := relaycommon.GetImageFormFiles(c)
+		if err != nil {
+			return nil, err
+		}
+		if info.RelayMode == relayconstant.RelayModeImagesVariations {
+			images = images[:1]
+			mask = nil
+			if prompt == "" {
+				prompt = relaycommon.ImageVariationPrompt
+			}
+		}
+		for _, image := range images {
+			parts = append(parts, dto.GeminiPart{
+				InlineData: &dto.GeminiInlineData{MimeType: image.MimeType, Data: image.Base64()},
+			})
+		}
+		if mask != nil {
+			binaryMask, err := mask.BinaryMask()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, dto.GeminiPart{
+				InlineData: &dto.GeminiInlineData{MimeType: binaryMask.MimeType, Data: binaryMask.Base64()},
+			})
+			prompt = relaycommon.ImageMaskPrompt + "\n" + prompt
+		}
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+	parts = append(parts, dto.GeminiPart{Text: prompt})
+
+	return &dto.GeminiChatRequest{
+		Contents: []dto.GeminiChatContent{
+			{
+				Role:  "user",
+				Parts: parts,
+			},
+		},
+		GenerationConfig: dto.GeminiChatGenerationConfig{
+			ResponseModalities: []string{"TEXT", "IMAGE"},
+		},
+	}, nil
+}
+
+// GeminiChatImageHandler 将 generateContent 返回的内联图片转换为 OpenAI 图片响应
+func GeminiChatImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	var geminiResponse dto.GeminiChatResponse
+	if err := common.Unmarshal(responseBody, &geminiResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if geminiResponse.PromptFeedback != nil && geminiResponse.PromptFeedback.BlockReason != nil {
+		return nil, types.NewOpenAIError(errors.New("request blocked by Gemini API: "+*geminiResponse.PromptFeedback.BlockReason), types.ErrorCodePromptBlocked, http.StatusBadRequest)
+	}
+
+	openAIResponse := dto.ImageResponse{
+		Created: common.GetTimestamp(),
+	}
+	var revisedPrompt strings.Builder
+	for _, candidate := range geminiResponse.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "image/") {
+				openAIResponse.Data = append(openAIResponse.Data, dto.ImageData{B64Json: part.InlineData.Data})
+			} else if part.Text != "" && !part.Thought {
+				revisedPrompt.WriteString(part.Text)
+			}
+		}
+	}
+	if len(openAIResponse.Data) == 0 {
+		return nil, types.NewOpenAIError(errors.New("no images generated"), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	for i := range openAIResponse.Data {
+		openAIResponse.Data[i].RevisedPrompt = revisedPrompt.String()
+	}
+
+	jsonResponse, err := common.Marshal(openAIResponse)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(jsonResponse)
+
+	usage := &dto.Usage{
+		PromptTokens:     geminiResponse.UsageMetadata.PromptTokenCount,
+		CompletionTokens: geminiResponse.UsageMetadata.CandidatesTokenCount + geminiResponse.UsageMetadata.ThoughtsTokenCount,
+		TotalTokens:      geminiResponse.UsageMetadata.TotalTokenCount,
+	}
+	usage.CompletionTokenDetails.ReasoningTokens = geminiResponse.UsageMetadata.ThoughtsTokenCount
+	if usage.PromptTokens <= 0 {
+		usage.PromptTokens = info.GetEstimatePromptTokens()
+	}
+	return usage, nil
+}
+
 type GeminiModelsResponse struct {
 	Models        []dto.GeminiModel `json:"models"`
 	NextPageToken string            `json:"nextPageToken"`
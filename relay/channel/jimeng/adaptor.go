@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
@@ -77,6 +79,31 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 	if request.ResponseFormat == "" || request.ResponseFormat == "url" {
 		payload.ReturnURL = true // Default to returning image URLs
 	}
+	if width, height, ok := strings.Cut(request.Size, "x"); ok {
+		payload.Width, _ = strconv.Atoi(width)
+		payload.Height, _ = strconv.Atoi(height)
+	}
+
+	if info.RelayMode == relayconstant.RelayModeImagesEdits || info.RelayMode == relayconstant.RelayModeImagesVariations {
+		// 图生图、局部重绘的原图与蒙版通过 binary_data_base64 按顺序传入
+		images, mask, err := relaycommon.GetImageFormFiles(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			payload.BinaryData = append(payload.BinaryData, image.Base64())
+		}
+		if mask != nil {
+			binaryMask, err := mask.BinaryMask()
+			if err != nil {
+				return nil, err
+			}
+			payload.BinaryData = append(payload.BinaryData, binaryMask.Base64())
+		}
+		if payload.Prompt == "" {
+			payload.Prompt = relaycommon.ImageVariationPrompt
+		}
+	}
 
 	if len(request.ExtraFields) > 0 {
 		if err := json.Unmarshal(request.ExtraFields, &payload); err != nil {
@@ -124,12 +151,15 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
-	if info.RelayMode == relayconstant.RelayModeImagesGenerations {
+	switch info.RelayMode {
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		usage, err = jimengImageHandler(c, resp, info)
-	} else if info.IsStream {
-		usage, err = openai.OaiStreamHandler(c, info, resp)
-	} else {
-		usage, err = openai.OpenaiHandler(c, info, resp)
+	default:
+		if info.IsStream {
+			usage, err = openai.OaiStreamHandler(c, info, resp)
+		} else {
+			usage, err = openai.OpenaiHandler(c, info, resp)
+		}
 	}
 	return
 }
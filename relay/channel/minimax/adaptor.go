@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
@@ -50,6 +51,15 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 		},
 		OutputFormat: outputFormat,
 	}
+	if info.IsStream {
+		// 流式合成只支持 hex 输出及 mp3/pcm/flac 格式，聚合音频在流中已逐段下发，无需重复返回
+		minimaxRequest.Stream = true
+		minimaxRequest.StreamOptions = &StreamOptions{ExcludeAggregatedAudio: true}
+		minimaxRequest.OutputFormat = "hex"
+		if outputFormat != "pcm" && outputFormat != "flac" {
+			minimaxRequest.AudioSetting.Format = "mp3"
+		}
+	}
 
 	// 同步扩展字段的厂商自定义metadata
 	if len(request.Metadata) > 0 {
@@ -116,6 +126,9 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
 	if info.RelayMode == constant.RelayModeAudioSpeech {
+		if info.IsStream && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			return handleTTSStreamResponse(c, resp, info)
+		}
 		return handleTTSResponse(c, resp, info)
 	}
 
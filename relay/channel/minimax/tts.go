@@ -1,6 +1,7 @@
 package minimax
 
 import (
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -9,8 +10,12 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 )
@@ -171,6 +176,64 @@ func handleTTSResponse(c *gin.Context, resp *http.Response, info *relaycommon.Re
 	return usage, nil
 }
 
+// handleTTSStreamResponse 将 MiniMax 流式合成的 hex 音频分片转换为 speech.audio.delta 事件
+func handleTTSStreamResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	var usageCharacters int64
+	var streamErr *types.NewAPIError
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		var chunk MiniMaxTTSResponse
+		if unmarshalErr := common.UnmarshalJsonStr(data, &chunk); unmarshalErr != nil {
+			logger.LogError(c, "failed to unmarshal minimax TTS stream chunk: "+unmarshalErr.Error())
+			return true
+		}
+		if chunk.BaseResp.StatusCode != 0 {
+			streamErr = types.NewErrorWithStatusCode(
+				fmt.Errorf("minimax TTS error: %d - %s", chunk.BaseResp.StatusCode, chunk.BaseResp.StatusMsg),
+				types.ErrorCodeBadResponse,
+				http.StatusBadRequest,
+			)
+			return false
+		}
+		if chunk.ExtraInfo.UsageCharacters > 0 {
+			usageCharacters = chunk.ExtraInfo.UsageCharacters
+		}
+		if chunk.Data.Audio == "" {
+			return true
+		}
+		audioData, decodeErr := hex.DecodeString(chunk.Data.Audio)
+		if decodeErr != nil {
+			logger.LogError(c, "failed to decode minimax TTS stream audio: "+decodeErr.Error())
+			return true
+		}
+		_ = helper.ObjectData(c, dto.AudioStreamEvent{
+			Type:  dto.AudioSpeechStreamEventDelta,
+			Audio: base64.StdEncoding.EncodeToString(audioData),
+		})
+		return true
+	})
+	if streamErr != nil {
+		return nil, streamErr
+	}
+
+	promptTokens := info.GetEstimatePromptTokens()
+	_ = helper.ObjectData(c, dto.AudioStreamEvent{
+		Type: dto.AudioSpeechStreamEventDone,
+		Usage: &dto.AudioStreamUsage{
+			InputTokens: promptTokens,
+			TotalTokens: promptTokens,
+		},
+	})
+
+	usage = &dto.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: 0,
+		TotalTokens:      int(usageCharacters),
+	}
+	return usage, nil
+}
+
 func handleChatCompletionResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
 	body, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
@@ -36,6 +36,8 @@ import (
 type Adaptor struct {
 	ChannelType    int
 	ResponseFormat string
+	// 语音合成需要本地转码时，向上游请求的音频格式
+	AudioUpstreamFormat string
 }
 
 // parseReasoningEffortFromModelSuffix 从模型名称中解析推理级别
@@ -361,6 +363,17 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
 	a.ResponseFormat = request.ResponseFormat
 	if info.RelayMode == relayconstant.RelayModeAudioSpeech {
+		// 流式合成的音频分片无法整体转码，仅非流式请求按渠道声明的输出格式转码
+		if !info.IsStream {
+			responseFormat := request.ResponseFormat
+			if responseFormat == "" {
+				responseFormat = "mp3"
+			}
+			if upstreamFormat := service.GetAudioOutputTranscodeFormat(info.ChannelOtherSettings.AudioOutputFormats, responseFormat); upstreamFormat != "" {
+				a.AudioUpstreamFormat = upstreamFormat
+				request.ResponseFormat = upstreamFormat
+			}
+		}
 		jsonData, err := json.Marshal(request)
 		if err != nil {
 			return nil, fmt.Errorf("error marshalling object: %w", err)
@@ -408,11 +421,31 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 		}
 		defer file.Close()
 
-		part, err := writer.CreateFormFile("file", fileHeader.Filename)
+		var fileReader io.Reader = file
+		filename := fileHeader.Filename
+		uploadFormat := strings.TrimPrefix(filepath.Ext(filename), ".")
+		if uploadFormat == "" {
+			_, uploadFormat, _ = strings.Cut(fileHeader.Header.Get("Content-Type"), "/")
+		}
+		if targetFormat := service.GetAudioInputTranscodeFormat(info.ChannelOtherSettings.AudioInputFormats, uploadFormat); targetFormat != "" {
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading audio file: %v", err)
+			}
+			transcoded, err := common.TranscodeAudio(data, uploadFormat, targetFormat, 0)
+			if err != nil {
+				return nil, fmt.Errorf("error transcoding audio file from %s to %s: %w", uploadFormat, targetFormat, err)
+			}
+			logger.LogDebug(c.Request.Context(), fmt.Sprintf("transcoded audio file from %s to %s (%d -> %d bytes)", uploadFormat, targetFormat, len(data), len(transcoded)))
+			fileReader = bytes.NewReader(transcoded)
+			filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + targetFormat
+		}
+
+		part, err := writer.CreateFormFile("file", filename)
 		if err != nil {
 			return nil, errors.New("create form file failed")
 		}
-		if _, err := io.Copy(part, file); err != nil {
+		if _, err := io.Copy(part, fileReader); err != nil {
 			return nil, errors.New("copy file failed")
 		}
 
@@ -426,7 +459,7 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
 	switch info.RelayMode {
-	case relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 
 		var requestBody bytes.Buffer
 		writer := multipart.NewWriter(&requestBody)
@@ -591,7 +624,8 @@ func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommo
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
 	if info.RelayMode == relayconstant.RelayModeAudioTranscription ||
 		info.RelayMode == relayconstant.RelayModeAudioTranslation ||
-		info.RelayMode == relayconstant.RelayModeImagesEdits {
+		info.RelayMode == relayconstant.RelayModeImagesEdits ||
+		info.RelayMode == relayconstant.RelayModeImagesVariations {
 		return channel.DoFormRequest(a, c, info, requestBody)
 	} else if info.RelayMode == relayconstant.RelayModeRealtime {
 		return channel.DoWssRequest(a, c, info, requestBody)
@@ -605,12 +639,16 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 	case relayconstant.RelayModeRealtime:
 		err, usage = OpenaiRealtimeHandler(c, info)
 	case relayconstant.RelayModeAudioSpeech:
-		usage = OpenaiTTSHandler(c, resp, info)
+		if a.AudioUpstreamFormat != "" {
+			usage, err = OpenaiTTSTranscodeHandler(c, resp, info, a.AudioUpstreamFormat)
+		} else {
+			usage = OpenaiTTSHandler(c, resp, info)
+		}
 	case relayconstant.RelayModeAudioTranslation:
 		fallthrough
 	case relayconstant.RelayModeAudioTranscription:
 		err, usage = OpenaiSTTHandler(c, resp, info, a.ResponseFormat)
-	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		usage, err = OpenaiHandlerWithUsage(c, info, resp)
 	case relayconstant.RelayModeRerank:
 		usage, err = common_handler.RerankHandler(c, info, resp)
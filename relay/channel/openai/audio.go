@@ -2,9 +2,9 @@ package openai
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 
 	"github.com/QuantumNous/new-api/common"
@@ -34,84 +34,115 @@ func OpenaiTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	}
 	c.Writer.WriteHeader(resp.StatusCode)
 
+	audioFormat := "mp3" // 默认格式
+	if audioReq, ok := info.Request.(*dto.AudioRequest); ok && audioReq.ResponseFormat != "" {
+		audioFormat = audioReq.ResponseFormat
+	}
+
 	if info.IsStream {
+		// 累积 speech.audio.delta 中的音频，结束后按时长计费
+		var audio bytes.Buffer
+		var upstreamUsage *dto.AudioStreamUsage
 		helper.StreamScannerHandler(c, resp, info, func(data string) bool {
-			if service.SundaySearch(data, "usage") {
-				var simpleResponse dto.SimpleResponse
-				err := common.Unmarshal([]byte(data), &simpleResponse)
-				if err != nil {
-					logger.LogError(c, err.Error())
-				}
-				if simpleResponse.Usage.TotalTokens != 0 {
-					usage.PromptTokens = simpleResponse.Usage.InputTokens
-					usage.CompletionTokens = simpleResponse.OutputTokens
-					usage.TotalTokens = simpleResponse.TotalTokens
+			var event dto.AudioStreamEvent
+			if err := common.UnmarshalJsonStr(data, &event); err != nil {
+				logger.LogError(c, err.Error())
+			} else if event.Type == dto.AudioSpeechStreamEventDelta {
+				if chunk, err := base64.StdEncoding.DecodeString(event.Audio); err == nil {
+					audio.Write(chunk)
 				}
+			} else if event.Usage != nil && event.Usage.TotalTokens != 0 {
+				upstreamUsage = event.Usage
 			}
 			_ = helper.StringData(c, data)
 			return true
 		})
+		if audio.Len() > 0 {
+			common.SetContextKey(c, constant.ContextKeyLocalCountTokens, true)
+			if err := service.SetSpeechDurationUsage(c.Request.Context(), usage, audio.Bytes(), audioFormat, 0); err != nil {
+				logger.LogWarn(c, fmt.Sprintf("failed to get audio duration: %v", err))
+			}
+		} else if upstreamUsage != nil {
+			usage.PromptTokens = upstreamUsage.InputTokens
+			usage.CompletionTokens = upstreamUsage.OutputTokens
+			usage.TotalTokens = upstreamUsage.TotalTokens
+		}
 	} else {
 		common.SetContextKey(c, constant.ContextKeyLocalCountTokens, true)
-		// 读取响应体到缓冲区
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			logger.LogError(c, fmt.Sprintf("failed to read TTS response body: %v", err))
-			c.Writer.WriteHeaderNow()
-			return usage
-		}
-
-		// 写入响应到客户端
+		// 边读边写，尽早把首段音频交给客户端，同时缓存完整音频用于计算时长
 		c.Writer.WriteHeaderNow()
-		_, err = c.Writer.Write(bodyBytes)
-		if err != nil {
-			logger.LogError(c, fmt.Sprintf("failed to write TTS response: %v", err))
+		var audio bytes.Buffer
+		buf := make([]byte, 32<<10)
+		writeFailed := false
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 {
+				audio.Write(buf[:n])
+				if !writeFailed {
+					if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+						// 客户端断开后继续读完上游音频，按实际合成时长计费
+						logger.LogError(c, fmt.Sprintf("failed to write TTS response: %v", writeErr))
+						writeFailed = true
+					} else {
+						_ = helper.FlushWriter(c)
+					}
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					logger.LogError(c, fmt.Sprintf("failed to read TTS response body: %v", err))
+				}
+				break
+			}
 		}
 
-		// 计算音频时长并更新 usage
-		audioFormat := "mp3" // 默认格式
-		if audioReq, ok := info.Request.(*dto.AudioRequest); ok && audioReq.ResponseFormat != "" {
-			audioFormat = audioReq.ResponseFormat
+		if err := service.SetSpeechDurationUsage(c.Request.Context(), usage, audio.Bytes(), audioFormat, 0); err != nil {
+			logger.LogWarn(c, fmt.Sprintf("failed to get audio duration: %v", err))
 		}
+	}
 
-		var duration float64
-		var durationErr error
-
-		if audioFormat == "pcm" {
-			// PCM 格式没有文件头，根据 OpenAI TTS 的 PCM 参数计算时长
-			// 采样率: 24000 Hz, 位深度: 16-bit (2 bytes), 声道数: 1
-			const sampleRate = 24000
-			const bytesPerSample = 2
-			const channels = 1
-			duration = float64(len(bodyBytes)) / float64(sampleRate*bytesPerSample*channels)
-		} else {
-			ext := "." + audioFormat
-			reader := bytes.NewReader(bodyBytes)
-			duration, durationErr = common.GetAudioDuration(c.Request.Context(), reader, ext)
-		}
+	return usage
+}
 
-		usage.PromptTokensDetails.TextTokens = usage.PromptTokens
-
-		if durationErr != nil {
-			logger.LogWarn(c, fmt.Sprintf("failed to get audio duration: %v", durationErr))
-			// 如果无法获取时长，则设置保底的 CompletionTokens，根据body大小计算
-			sizeInKB := float64(len(bodyBytes)) / 1000.0
-			estimatedTokens := int(math.Ceil(sizeInKB)) // 粗略估算每KB约等于1 token
-			usage.CompletionTokens = estimatedTokens
-			usage.CompletionTokenDetails.AudioTokens = estimatedTokens
-		} else if duration > 0 {
-			// 计算 token: ceil(duration) / 60.0 * 1000，即每分钟 1000 tokens
-			completionTokens := int(math.Round(math.Ceil(duration) / 60.0 * 1000))
-			usage.CompletionTokens = completionTokens
-			usage.CompletionTokenDetails.AudioTokens = completionTokens
-		}
-		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+// OpenaiTTSTranscodeHandler 上游按 upstreamFormat 返回音频，本地转码为客户端请求的格式后再返回，按转码后的音频计费
+func OpenaiTTSTranscodeHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, upstreamFormat string) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	responseFormat := "mp3"
+	if audioReq, ok := info.Request.(*dto.AudioRequest); ok && audioReq.ResponseFormat != "" {
+		responseFormat = audioReq.ResponseFormat
 	}
+	responseFormat = common.NormalizeAudioFormat(responseFormat)
 
-	return usage
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	transcoded, err := common.TranscodeAudio(audio, upstreamFormat, responseFormat, 0)
+	if err != nil {
+		return nil, types.NewOpenAIError(fmt.Errorf("failed to transcode audio from %s to %s: %w", upstreamFormat, responseFormat, err), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	usage := &dto.Usage{}
+	usage.PromptTokens = info.GetEstimatePromptTokens()
+	usage.TotalTokens = info.GetEstimatePromptTokens()
+	common.SetContextKey(c, constant.ContextKeyLocalCountTokens, true)
+	if err := service.SetSpeechDurationUsage(c.Request.Context(), usage, transcoded, responseFormat, 0); err != nil {
+		logger.LogWarn(c, fmt.Sprintf("failed to get audio duration: %v", err))
+	}
+
+	contentType := "audio/" + responseFormat
+	if responseFormat == "pcm" {
+		contentType = "audio/pcm"
+	}
+	c.Data(resp.StatusCode, contentType, transcoded)
+	return usage, nil
 }
 
 func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, responseFormat string) (*types.NewAPIError, *dto.Usage) {
+	if info.IsStream {
+		return openaiSTTStreamHandler(c, resp, info)
+	}
 	defer service.CloseResponseBodyGracefully(resp)
 
 	responseBody, err := io.ReadAll(resp.Body)
@@ -143,3 +174,27 @@ func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 	return nil, usage
 }
+
+// openaiSTTStreamHandler 透传 stream=true 的转写事件，上游未返回用量时按音频时长估算
+func openaiSTTStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.Usage) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	usage := &dto.Usage{}
+	usage.PromptTokens = info.GetEstimatePromptTokens()
+	usage.TotalTokens = usage.PromptTokens
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		if service.SundaySearch(data, "usage") {
+			var event dto.AudioStreamEvent
+			if err := common.UnmarshalJsonStr(data, &event); err != nil {
+				logger.LogError(c, err.Error())
+			} else if event.Usage != nil && event.Usage.TotalTokens > 0 {
+				usage.PromptTokens = event.Usage.InputTokens
+				usage.CompletionTokens = event.Usage.OutputTokens
+				usage.TotalTokens = event.Usage.TotalTokens
+			}
+		}
+		_ = helper.StringData(c, data)
+		return true
+	})
+	return nil, usage
+}
@@ -123,7 +123,7 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 		inputPayload["prompt_upsampling"] = true
 	}
 
-	if info.RelayMode == relayconstant.RelayModeImagesEdits {
+	if info.RelayMode == relayconstant.RelayModeImagesEdits || info.RelayMode == relayconstant.RelayModeImagesVariations {
 		imageURL, err := uploadFileFromForm(c, info, "image", "image[]", "image_prompt")
 		if err != nil {
 			return nil, err
@@ -132,6 +132,21 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 			return nil, errors.New("replicate adaptor: image file is required for edits")
 		}
 		inputPayload["image_prompt"] = imageURL
+		if _, mask, err := relaycommon.GetImageFormFiles(c); err == nil && mask != nil {
+			// flux-fill 等局部重绘模型的 mask 为白色待编辑区域
+			binaryMask, err := mask.BinaryMask()
+			if err != nil {
+				return nil, err
+			}
+			maskURL, err := uploadFile(info, binaryMask.Filename, binaryMask.MimeType, binaryMask.Data)
+			if err != nil {
+				return nil, err
+			}
+			inputPayload["mask"] = maskURL
+		}
+		if prompt, _ := inputPayload["prompt"].(string); prompt == "" {
+			inputPayload["prompt"] = relaycommon.ImageVariationPrompt
+		}
 	}
 
 	if len(request.ExtraFields) > 0 {
@@ -440,13 +455,20 @@ func uploadFileFromForm(c *gin.Context, info *relaycommon.RelayInfo, fieldCandid
 		return "", fmt.Errorf("replicate adaptor: failed to open image file: %w", err)
 	}
 	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("replicate adaptor: copy image content failed: %w", err)
+	}
+	return uploadFile(info, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), data)
+}
 
+// uploadFile 上传文件到 Replicate Files API，返回可用于模型输入的地址
+func uploadFile(info *relaycommon.RelayInfo, filename string, contentType string, data []byte) (string, error) {
 	var body bytes.Buffer
 	writer := multipart.NewWriter(&body)
 
 	hdr := make(textproto.MIMEHeader)
-	hdr.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"content\"; filename=\"%s\"", fileHeader.Filename))
-	contentType := fileHeader.Header.Get("Content-Type")
+	hdr.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"content\"; filename=\"%s\"", filename))
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
@@ -457,7 +479,7 @@ func uploadFileFromForm(c *gin.Context, info *relaycommon.RelayInfo, fieldCandid
 		writer.Close()
 		return "", fmt.Errorf("replicate adaptor: create upload form failed: %w", err)
 	}
-	if _, err := io.Copy(part, file); err != nil {
+	if _, err := part.Write(data); err != nil {
 		writer.Close()
 		return "", fmt.Errorf("replicate adaptor: copy image content failed: %w", err)
 	}
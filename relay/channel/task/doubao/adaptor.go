@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
 	"github.com/QuantumNous/new-api/common"
 
@@ -164,11 +163,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 		return
 	}
 
-	ov := dto.NewOpenAIVideo()
-	ov.ID = dResp.ID
-	ov.TaskID = dResp.ID
-	ov.CreatedAt = time.Now().Unix()
-	ov.Model = info.OriginModelName
+	ov := relaycommon.NewOpenAIVideoSubmitResponse(c, info, dResp.ID)
 
 	c.JSON(http.StatusOK, ov)
 	return dResp.ID, responseBody, nil
@@ -233,6 +228,12 @@ func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*
 		}
 	}
 
+	if req.Duration > 0 {
+		r.Duration = dto.IntValue(req.Duration)
+	}
+	r.Resolution = relaycommon.VideoSizeToResolution(req.Size)
+	r.Ratio = relaycommon.VideoSizeToAspectRatio(req.Size)
+
 	metadata := req.Metadata
 	medaBytes, err := json.Marshal(metadata)
 	if err != nil {
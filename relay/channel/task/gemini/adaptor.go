@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
@@ -40,7 +39,13 @@ type GeminiVideoGenerationConfig struct {
 
 // GeminiVideoRequest represents a single video generation instance
 type GeminiVideoRequest struct {
-	Prompt string `json:"prompt"`
+	Prompt string            `json:"prompt"`
+	Image  *GeminiVideoImage `json:"image,omitempty"` // first frame for image-to-video
+}
+
+type GeminiVideoImage struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded"`
+	MimeType           string `json:"mimeType"`
 }
 
 // GeminiVideoPayload represents the complete video generation request payload
@@ -141,7 +146,22 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 		Instances: []GeminiVideoRequest{
 			{Prompt: req.Prompt},
 		},
-		Parameters: GeminiVideoGenerationConfig{},
+		Parameters: GeminiVideoGenerationConfig{
+			DurationSeconds: float64(req.Duration),
+		},
+	}
+	if ratio := relaycommon.VideoSizeToAspectRatio(req.Size); ratio == "16:9" || ratio == "9:16" {
+		body.Parameters.AspectRatio = ratio
+	}
+	if resolution := relaycommon.VideoSizeToResolution(req.Size); resolution == "720p" || resolution == "1080p" {
+		body.Parameters.Resolution = resolution
+	}
+	if req.HasImage() {
+		mimeType, data, err := service.GetImageBase64(req.Images[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "get input reference failed")
+		}
+		body.Instances[0].Image = &GeminiVideoImage{BytesBase64Encoded: data, MimeType: mimeType}
 	}
 
 	metadata := req.Metadata
@@ -182,11 +202,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 		return "", nil, service.TaskErrorWrapper(fmt.Errorf("missing operation name"), "invalid_response", http.StatusInternalServerError)
 	}
 	taskID = encodeLocalTaskID(s.Name)
-	ov := dto.NewOpenAIVideo()
-	ov.ID = taskID
-	ov.TaskID = taskID
-	ov.CreatedAt = time.Now().Unix()
-	ov.Model = info.OriginModelName
+	ov := relaycommon.NewOpenAIVideoSubmitResponse(c, info, taskID)
 	c.JSON(http.StatusOK, ov)
 	return taskID, responseBody, nil
 }
@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
@@ -100,11 +99,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 		return
 	}
 
-	ov := dto.NewOpenAIVideo()
-	ov.ID = hResp.TaskID
-	ov.TaskID = hResp.TaskID
-	ov.CreatedAt = time.Now().Unix()
-	ov.Model = info.OriginModelName
+	ov := relaycommon.NewOpenAIVideoSubmitResponse(c, info, hResp.TaskID)
 
 	c.JSON(http.StatusOK, ov)
 	return hResp.TaskID, responseBody, nil
@@ -158,6 +153,13 @@ func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*
 		Duration:   &duration,
 		Resolution: resolution,
 	}
+	// 首帧图生视频，两张图片时作为首尾帧
+	if len(req.Images) > 0 {
+		videoRequest.FirstFrameImage = req.Images[0]
+	}
+	if len(req.Images) > 1 {
+		videoRequest.LastFrameImage = req.Images[1]
+	}
 	if err := req.UnmarshalMetadata(&videoRequest); err != nil {
 		return nil, errors.Wrap(err, "unmarshal metadata to video request failed")
 	}
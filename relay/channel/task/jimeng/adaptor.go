@@ -201,11 +201,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 		return
 	}
 
-	ov := dto.NewOpenAIVideo()
-	ov.ID = jResp.Data.TaskID
-	ov.TaskID = jResp.Data.TaskID
-	ov.CreatedAt = time.Now().Unix()
-	ov.Model = info.OriginModelName
+	ov := relaycommon.NewOpenAIVideoSubmitResponse(c, info, jResp.Data.TaskID)
 	c.JSON(http.StatusOK, ov)
 	return jResp.Data.TaskID, responseBody, nil
 }
@@ -390,12 +386,22 @@ func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*
 		r.Frames = 121 // 24*5+1 = 121
 	}
 
+	if ratio := relaycommon.VideoSizeToAspectRatio(req.Size); ratio != "" {
+		r.AspectRatio = ratio
+	}
+
 	// Handle one-of image_urls or binary_data_base64
 	if req.HasImage() {
 		if strings.HasPrefix(req.Images[0], "http") {
 			r.ImageUrls = req.Images
 		} else {
-			r.BinaryDataBase64 = req.Images
+			for _, image := range req.Images {
+				// input_reference 可能是 data URL，去掉前缀
+				if _, data, ok := strings.Cut(image, ";base64,"); ok {
+					image = data
+				}
+				r.BinaryDataBase64 = append(r.BinaryDataBase64, image)
+			}
 		}
 	}
 	metadata := req.Metadata
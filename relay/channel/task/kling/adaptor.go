@@ -189,11 +189,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("%s", kResp.Message), "task_failed", http.StatusBadRequest)
 		return
 	}
-	ov := dto.NewOpenAIVideo()
-	ov.ID = kResp.Data.TaskId
-	ov.TaskID = kResp.Data.TaskId
-	ov.CreatedAt = time.Now().Unix()
-	ov.Model = info.OriginModelName
+	ov := relaycommon.NewOpenAIVideoSubmitResponse(c, info, kResp.Data.TaskId)
 	c.JSON(http.StatusOK, ov)
 	return kResp.Data.TaskId, responseBody, nil
 }
@@ -266,6 +262,10 @@ func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*
 	if r.ModelName == "" {
 		r.ModelName = "kling-v1"
 	}
+	// 可灵的 image 接受 URL 或不带前缀的 base64
+	if _, data, ok := strings.Cut(r.Image, ";base64,"); ok {
+		r.Image = data
+	}
 	metadata := req.Metadata
 	medaBytes, err := json.Marshal(metadata)
 	if err != nil {
@@ -287,6 +287,9 @@ func (a *TaskAdaptor) getAspectRatio(size string) string {
 	case "720x1280", "1080x1920":
 		return "9:16"
 	default:
+		if ratio := relaycommon.VideoSizeToAspectRatio(size); ratio != "" {
+			return ratio
+		}
 		return "1:1"
 	}
 }
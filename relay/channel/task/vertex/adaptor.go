@@ -145,9 +145,28 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 		Instances:  []map[string]any{{"prompt": req.Prompt}},
 		Parameters: map[string]any{},
 	}
+	if ratio := relaycommon.VideoSizeToAspectRatio(req.Size); ratio == "16:9" || ratio == "9:16" {
+		body.Parameters["aspectRatio"] = ratio
+	}
+	if resolution := relaycommon.VideoSizeToResolution(req.Size); resolution == "720p" || resolution == "1080p" {
+		body.Parameters["resolution"] = resolution
+	}
+	if req.Duration > 0 {
+		body.Parameters["durationSeconds"] = req.Duration
+	}
+	if req.HasImage() {
+		mimeType, data, err := service.GetImageBase64(req.Images[0])
+		if err != nil {
+			return nil, fmt.Errorf("get input reference failed: %w", err)
+		}
+		body.Instances[0]["image"] = map[string]any{"bytesBase64Encoded": data, "mimeType": mimeType}
+	}
 	if req.Metadata != nil {
-		if v, ok := req.Metadata["storageUri"]; ok {
-			body.Parameters["storageUri"] = v
+		// metadata 作为 Veo 的扩展 parameters 透传
+		for k, v := range req.Metadata {
+			if k != "sampleCount" {
+				body.Parameters[k] = v
+			}
 		}
 		if v, ok := req.Metadata["sampleCount"]; ok {
 			if i, ok := v.(int); ok {
@@ -166,16 +185,6 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 		return nil, fmt.Errorf("sampleCount must be greater than 0")
 	}
 
-	// if req.Duration > 0 {
-	// 	body.Parameters["durationSeconds"] = req.Duration
-	// } else if req.Seconds != "" {
-	// 	seconds, err := strconv.Atoi(req.Seconds)
-	// 	if err != nil {
-	// 		return nil, errors.Wrap(err, "convert seconds to int failed")
-	// 	}
-	// 	body.Parameters["durationSeconds"] = seconds
-	// }
-
 	info.PriceData.OtherRatios = map[string]float64{
 		"sampleCount": float64(body.Parameters["sampleCount"].(int)),
 	}
@@ -212,7 +221,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 		return "", nil, service.TaskErrorWrapper(fmt.Errorf("missing operation name"), "invalid_response", http.StatusInternalServerError)
 	}
 	localID := encodeLocalTaskID(s.Name)
-	c.JSON(http.StatusOK, gin.H{"task_id": localID})
+	c.JSON(http.StatusOK, relaycommon.NewOpenAIVideoSubmitResponse(c, info, localID))
 	return localID, responseBody, nil
 }
 
@@ -12,6 +12,7 @@ import (
 
 	"github.com/QuantumNous/new-api/dto"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -243,9 +244,23 @@ func handleTTSWebSocketResponse(c *gin.Context, requestURL string, volcRequest V
 		)
 	}
 
-	contentType := getContentTypeByEncoding(encoding)
-	c.Header("Content-Type", contentType)
-	c.Header("Transfer-Encoding", "chunked")
+	// 客户端要求 stream_format=sse 时按 speech.audio.delta 事件下发，否则直接分块写出音频
+	sse := false
+	if audioReq, ok := info.Request.(*dto.AudioRequest); ok && audioReq.StreamFormat == "sse" {
+		sse = true
+	}
+	if sse {
+		helper.SetEventStreamHeaders(c)
+	} else {
+		contentType := getContentTypeByEncoding(encoding)
+		c.Header("Content-Type", contentType)
+		c.Header("Transfer-Encoding", "chunked")
+	}
+	usage = &dto.Usage{
+		PromptTokens:     info.GetEstimatePromptTokens(),
+		CompletionTokens: 0,
+		TotalTokens:      info.GetEstimatePromptTokens(),
+	}
 
 	for {
 		msg, recvErr := ReceiveMessage(conn)
@@ -271,23 +286,32 @@ func handleTTSWebSocketResponse(c *gin.Context, requestURL string, volcRequest V
 			continue
 		case MsgTypeAudioOnlyServer:
 			if len(msg.Payload) > 0 {
-				if _, writeErr := c.Writer.Write(msg.Payload); writeErr != nil {
-					return nil, types.NewErrorWithStatusCode(
-						fmt.Errorf("failed to write audio data: %w", writeErr),
-						types.ErrorCodeBadResponse,
-						http.StatusInternalServerError,
-					)
+				info.SetFirstResponseTime()
+				if sse {
+					if writeErr := helper.ObjectData(c, dto.AudioStreamEvent{
+						Type:  dto.AudioSpeechStreamEventDelta,
+						Audio: base64.StdEncoding.EncodeToString(msg.Payload),
+					}); writeErr != nil {
+						return nil, types.NewErrorWithStatusCode(
+							fmt.Errorf("failed to write audio data: %w", writeErr),
+							types.ErrorCodeBadResponse,
+							http.StatusInternalServerError,
+						)
+					}
+				} else {
+					if _, writeErr := c.Writer.Write(msg.Payload); writeErr != nil {
+						return nil, types.NewErrorWithStatusCode(
+							fmt.Errorf("failed to write audio data: %w", writeErr),
+							types.ErrorCodeBadResponse,
+							http.StatusInternalServerError,
+						)
+					}
+					c.Writer.Flush()
 				}
-				c.Writer.Flush()
 			}
 
 			if msg.Sequence < 0 {
-				c.Status(http.StatusOK)
-				usage = &dto.Usage{
-					PromptTokens:     info.GetEstimatePromptTokens(),
-					CompletionTokens: 0,
-					TotalTokens:      info.GetEstimatePromptTokens(),
-				}
+				finishTTSStream(c, usage.(*dto.Usage), sse)
 				return usage, nil
 			}
 		default:
@@ -295,11 +319,21 @@ func handleTTSWebSocketResponse(c *gin.Context, requestURL string, volcRequest V
 		}
 	}
 
+	finishTTSStream(c, usage.(*dto.Usage), sse)
+	return usage, nil
+}
+
+func finishTTSStream(c *gin.Context, usage *dto.Usage, sse bool) {
 	c.Status(http.StatusOK)
-	usage = &dto.Usage{
-		PromptTokens:     info.GetEstimatePromptTokens(),
-		CompletionTokens: 0,
-		TotalTokens:      info.GetEstimatePromptTokens(),
+	if !sse {
+		return
 	}
-	return usage, nil
+	_ = helper.ObjectData(c, dto.AudioStreamEvent{
+		Type: dto.AudioSpeechStreamEventDone,
+		Usage: &dto.AudioStreamUsage{
+			InputTokens:  usage.PromptTokens,
+			OutputTokens: usage.CompletionTokens,
+			TotalTokens:  usage.TotalTokens,
+		},
+	})
 }
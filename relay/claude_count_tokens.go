@@ -0,0 +1,85 @@
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel/claude"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClaudeCountTokensHelper 处理 /v1/messages/count_tokens，Anthropic 渠道转发上游，其余渠道本地估算，均不计费
+func ClaudeCountTokensHelper(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) *types.NewAPIError {
+	info.InitChannelMeta(c)
+	if err := helper.ModelMappedHelper(c, info, request); err != nil {
+		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
+	}
+
+	if info.ChannelType == constant.ChannelTypeAnthropic {
+		body, err := forwardClaudeCountTokens(c, info)
+		if err == nil {
+			c.Data(http.StatusOK, "application/json", body)
+			return nil
+		}
+		// 上游统计失败时退回本地估算
+		logger.LogWarn(c, fmt.Sprintf("count_tokens: channel #%d failed, fallback to local estimate: %v", info.ChannelId, err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"input_tokens": service.CountClaudeRequestToken(request),
+	})
+	return nil
+}
+
+func forwardClaudeCountTokens(c *gin.Context, info *relaycommon.RelayInfo) ([]byte, error) {
+	request := make(map[string]any)
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return nil, err
+	}
+	request["model"] = info.UpstreamModelName
+	body, err := common.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, info.ChannelBaseUrl+"/v1/messages/count_tokens", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", info.ApiKey)
+	anthropicVersion := c.Request.Header.Get("anthropic-version")
+	if anthropicVersion == "" {
+		anthropicVersion = "2023-06-01"
+	}
+	req.Header.Set("anthropic-version", anthropicVersion)
+	claude.CommonClaudeHeadersOperation(c, &req.Header, info)
+
+	client, err := service.GetHttpClientWithProxy(info.ChannelSetting.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, string(responseBody))
+	}
+	return responseBody, nil
+}
@@ -0,0 +1,133 @@
+package common
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 不支持变体接口的上游，用图生图模拟 /v1/images/variations 时使用的提示词
+const ImageVariationPrompt = "Create a new variation of this image. Keep the main subject, composition and style, but vary the details."
+
+// 不支持蒙版的上游，将蒙版作为第二张图片传入时附加的说明
+const ImageMaskPrompt = "The second image is a mask: only modify the white areas of the first image and keep the black areas unchanged."
+
+// ImageFile 图片编辑、变体请求中上传的图片
+type ImageFile struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+func (f *ImageFile) Base64() string {
+	return base64.StdEncoding.EncodeToString(f.Data)
+}
+
+func (f *ImageFile) DataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", f.MimeType, f.Base64())
+}
+
+// GetImageFormFiles 读取表单中的原图（image、image[]、image[n]）与可选的蒙版（mask）
+func GetImageFormFiles(c *gin.Context) ([]*ImageFile, *ImageFile, error) {
+	mf := c.Request.MultipartForm
+	if mf == nil {
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse multipart form: %w", err)
+		}
+		mf = c.Request.MultipartForm
+	}
+
+	imageHeaders := mf.File["image"]
+	if len(imageHeaders) == 0 {
+		imageHeaders = mf.File["image[]"]
+	}
+	if len(imageHeaders) == 0 {
+		for fieldName, files := range mf.File {
+			if strings.HasPrefix(fieldName, "image[") {
+				imageHeaders = append(imageHeaders, files...)
+			}
+		}
+	}
+	if len(imageHeaders) == 0 {
+		return nil, nil, errors.New("image is required")
+	}
+
+	images := make([]*ImageFile, 0, len(imageHeaders))
+	for _, header := range imageHeaders {
+		file, err := readImageFormFile(header)
+		if err != nil {
+			return nil, nil, err
+		}
+		images = append(images, file)
+	}
+
+	var mask *ImageFile
+	if maskHeaders := mf.File["mask"]; len(maskHeaders) > 0 {
+		var err error
+		if mask, err = readImageFormFile(maskHeaders[0]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return images, mask, nil
+}
+
+func readImageFormFile(header *multipart.FileHeader) (*ImageFile, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image file %s: %w", header.Filename, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image file %s: %w", header.Filename, err)
+	}
+	return &ImageFile{
+		Filename: header.Filename,
+		MimeType: http.DetectContentType(data),
+		Data:     data,
+	}, nil
+}
+
+// BinaryMask 将 OpenAI 风格的蒙版（透明区域为待编辑区域）转换为黑白蒙版（白色为待编辑区域），
+// 不含透明像素的蒙版视为已是黑白蒙版，原样返回
+func (f *ImageFile) BinaryMask() (*ImageFile, error) {
+	img, _, err := image.Decode(bytes.NewReader(f.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mask: %w", err)
+	}
+	bounds := img.Bounds()
+	out := image.NewGray(bounds)
+	hasTransparent := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, a := img.At(x, y).RGBA()
+			if a < 0x8000 {
+				hasTransparent = true
+				out.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	if !hasTransparent {
+		return f, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode mask: %w", err)
+	}
+	return &ImageFile{
+		Filename: strings.TrimSuffix(f.Filename, ".png") + "_mask.png",
+		MimeType: "image/png",
+		Data:     buf.Bytes(),
+	}, nil
+}
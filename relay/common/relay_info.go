@@ -82,6 +82,11 @@ type TokenCountMeta struct {
 type RelayInfo struct {
 	TokenId           int
 	TokenKey          string
+	TokenKeyHash      string
+	DerivedTokenId    string            // 派生令牌 ID，非派生令牌为空
+	DerivedMaxSpend   int               // 派生令牌最大消费额度，0 表示不限
+	DerivedExpiresAt  int64             // 派生令牌过期时间
+	TokenMetadata     map[string]string // 派生令牌携带的元数据，写入日志
 	TokenGroup        string
 	UserId            int
 	UsingGroup        string // 使用的分组，当auto跨分组重试时，会变动
@@ -111,6 +116,7 @@ type RelayInfo struct {
 	UserSetting            dto.UserSetting
 	UserEmail              string
 	UserQuota              int
+	UserCreditLimit        int // 后付费信用额度，余额可透支至 -UserCreditLimit
 	RelayFormat            types.RelayFormat
 	SendResponseCount      int
 	FinalPreConsumedQuota  int  // 最终预消耗的配额
@@ -409,12 +415,17 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 		UserQuota:  common.GetContextKeyInt(c, constant.ContextKeyUserQuota),
 		UserEmail:  common.GetContextKeyString(c, constant.ContextKeyUserEmail),
 
+		UserCreditLimit: common.GetContextKeyInt(c, constant.ContextKeyUserCreditLimit),
+
 		OriginModelName: common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
 
-		TokenId:        common.GetContextKeyInt(c, constant.ContextKeyTokenId),
-		TokenKey:       common.GetContextKeyString(c, constant.ContextKeyTokenKey),
-		TokenUnlimited: common.GetContextKeyBool(c, constant.ContextKeyTokenUnlimited),
-		TokenGroup:     tokenGroup,
+		TokenId:         common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+		TokenKey:        common.GetContextKeyString(c, constant.ContextKeyTokenKey),
+		TokenKeyHash:    common.GetContextKeyString(c, constant.ContextKeyTokenKeyHash),
+		DerivedTokenId:  common.GetContextKeyString(c, constant.ContextKeyDerivedTokenId),
+		DerivedMaxSpend: common.GetContextKeyInt(c, constant.ContextKeyDerivedTokenMaxSpend),
+		TokenUnlimited:  common.GetContextKeyBool(c, constant.ContextKeyTokenUnlimited),
+		TokenGroup:      tokenGroup,
 
 		isFirstResponse: true,
 		RelayMode:       relayconstant.Path2RelayMode(c.Request.URL.Path),
@@ -447,6 +458,12 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 	if ok {
 		info.UserSetting = userSetting
 	}
+	if expiresAt, ok := common.GetContextKeyType[int64](c, constant.ContextKeyDerivedTokenExpiresAt); ok {
+		info.DerivedExpiresAt = expiresAt
+	}
+	if metadata, ok := common.GetContextKeyType[map[string]string](c, constant.ContextKeyDerivedTokenMetadata); ok {
+		info.TokenMetadata = metadata
+	}
 
 	return info
 }
@@ -571,6 +588,8 @@ func (info *RelayInfo) HasSendResponse() bool {
 type TaskRelayInfo struct {
 	Action       string
 	OriginTaskID string
+	// 本次提交生成的任务 id，写入消费日志用于任务对账
+	SubmitTaskID string
 
 	ConsumeQuota bool
 }
@@ -1,10 +1,15 @@
 package common
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
@@ -95,10 +100,15 @@ func validateMultipartTaskRequest(c *gin.Context, info *RelayInfo, action string
 	}
 
 	if durationStr := formData.Get("seconds"); durationStr != "" {
+		req.Seconds = durationStr
 		if duration, err := strconv.Atoi(durationStr); err == nil {
 			req.Duration = duration
 		}
 	}
+	if duration := formData.Get("duration"); duration != "" && req.Duration == 0 {
+		req.Duration, _ = strconv.Atoi(duration)
+	}
+	req.InputReference = formData.Get("input_reference")
 
 	if images := formData["images"]; len(images) > 0 {
 		req.Images = images
@@ -115,9 +125,47 @@ func validateMultipartTaskRequest(c *gin.Context, info *RelayInfo, action string
 			}
 		}
 	}
+	if metadata := formData.Get("metadata"); metadata != "" {
+		// metadata 以 JSON 字符串传入时展开为供应商扩展参数
+		var extra map[string]interface{}
+		if err := common.UnmarshalJsonStr(metadata, &extra); err == nil {
+			delete(req.Metadata, "metadata")
+			for k, v := range extra {
+				req.Metadata[k] = v
+			}
+		}
+	}
+
+	// OpenAI SDK 以文件形式上传 input_reference
+	if files := c.Request.MultipartForm.File["input_reference"]; len(files) > 0 {
+		for _, fileHeader := range files {
+			dataURL, err := readTaskFormFile(fileHeader)
+			if err != nil {
+				return req, err
+			}
+			req.Images = append(req.Images, dataURL)
+		}
+	}
 	return req, nil
 }
 
+func readTaskFormFile(fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", fileHeader.Filename, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", fileHeader.Filename, err)
+	}
+	mimeType := fileHeader.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data)), nil
+}
+
 func ValidateMultipartDirect(c *gin.Context, info *RelayInfo) *dto.TaskError {
 	var prompt string
 	var model string
@@ -196,6 +244,7 @@ func isKnownTaskField(field string) bool {
 		"images":          true,
 		"size":            true,
 		"duration":        true,
+		"seconds":         true,
 		"input_reference": true, // Sora 特有字段
 	}
 	return knownFields[field]
@@ -222,7 +271,102 @@ func ValidateBasicTaskRequest(c *gin.Context, info *RelayInfo, action string) *d
 		// 兼容单图上传
 		req.Images = []string{req.Image}
 	}
+	normalizeOpenAIVideoRequest(&req)
 
 	storeTaskRequest(c, info, action, req)
 	return nil
 }
+
+// normalizeOpenAIVideoRequest 将 OpenAI /v1/videos 的 seconds、input_reference 映射到各供应商通用的 Duration、Images
+func normalizeOpenAIVideoRequest(req *TaskSubmitReq) {
+	if req.Duration == 0 && req.Seconds != "" {
+		req.Duration, _ = strconv.Atoi(req.Seconds)
+	}
+	if req.Seconds == "" && req.Duration > 0 {
+		req.Seconds = strconv.Itoa(req.Duration)
+	}
+	if len(req.Images) == 0 && strings.TrimSpace(req.InputReference) != "" {
+		req.Images = []string{req.InputReference}
+	}
+	if req.Image == "" && len(req.Images) > 0 {
+		req.Image = req.Images[0]
+	}
+}
+
+// VideoSizeToAspectRatio 将 OpenAI 的 WxH 尺寸转换为最接近的常用宽高比，已是宽高比（如 16:9）时原样返回，无法识别时返回空
+func VideoSizeToAspectRatio(size string) string {
+	if strings.Contains(size, ":") {
+		return size
+	}
+	w, h, ok := parseVideoSize(size)
+	if !ok {
+		return ""
+	}
+	ratios := []string{"16:9", "9:16", "1:1", "4:3", "3:4", "21:9", "3:2", "2:3"}
+	target := float64(w) / float64(h)
+	best, bestDiff := "", math.MaxFloat64
+	for _, ratio := range ratios {
+		rw, rh, _ := strings.Cut(ratio, ":")
+		x, _ := strconv.ParseFloat(rw, 64)
+		y, _ := strconv.ParseFloat(rh, 64)
+		if diff := math.Abs(math.Log(target) - math.Log(x/y)); diff < bestDiff {
+			best, bestDiff = ratio, diff
+		}
+	}
+	return best
+}
+
+// VideoSizeToResolution 将 OpenAI 的 WxH 尺寸转换为 480p/720p/1080p 等清晰度，已是清晰度时原样返回，无法识别时返回空
+func VideoSizeToResolution(size string) string {
+	size = strings.ToLower(strings.TrimSpace(size))
+	if strings.HasSuffix(size, "p") || strings.HasSuffix(size, "k") {
+		return size
+	}
+	w, h, ok := parseVideoSize(size)
+	if !ok {
+		return ""
+	}
+	switch short := min(w, h); {
+	case short >= 2160:
+		return "4k"
+	case short >= 1080:
+		return "1080p"
+	case short >= 720:
+		return "720p"
+	case short >= 540:
+		return "540p"
+	default:
+		return "480p"
+	}
+}
+
+func parseVideoSize(size string) (int, int, bool) {
+	width, height, ok := strings.Cut(strings.ToLower(strings.TrimSpace(size)), "x")
+	if !ok {
+		width, height, ok = strings.Cut(size, "*")
+	}
+	if !ok {
+		return 0, 0, false
+	}
+	w, err1 := strconv.Atoi(strings.TrimSpace(width))
+	h, err2 := strconv.Atoi(strings.TrimSpace(height))
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// NewOpenAIVideoSubmitResponse 构造提交任务后返回给客户端的 OpenAI 视频对象
+func NewOpenAIVideoSubmitResponse(c *gin.Context, info *RelayInfo, taskID string) *dto.OpenAIVideo {
+	video := dto.NewOpenAIVideo()
+	video.ID = taskID
+	video.TaskID = taskID
+	video.Status = dto.VideoStatusQueued
+	video.CreatedAt = time.Now().Unix()
+	video.Model = info.OriginModelName
+	if req, err := GetTaskRequest(c); err == nil {
+		video.Seconds = req.Seconds
+		video.Size = req.Size
+	}
+	return video
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/model_setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
@@ -61,6 +64,23 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	var audioRatio float64
 	var audioCompletionRatio float64
 	var freeModel bool
+	var promptTiers []types.PriceTier
+	volumeTier := getUserVolumeTier(c, info)
+	scheduleRatio := ratio_setting.GetScheduleRatio(info.OriginModelName, info.UsingGroup)
+	isBatch := common.GetContextKeyBool(c, constant.ContextKeyMessageBatch)
+	batchRatio := 0.0
+	// 用量阶梯、分时段与批处理倍率叠加在分组倍率之上
+	extraRatio := 1.0
+	if volumeTier != nil {
+		extraRatio *= volumeTier.Ratio
+	}
+	if scheduleRatio != nil {
+		extraRatio *= scheduleRatio.Ratio
+	}
+	if isBatch {
+		batchRatio = model_setting.GetClaudeSettings().GetBatchRatio()
+		extraRatio *= batchRatio
+	}
 	if !usePrice {
 		preConsumedTokens := common.Max(promptTokens, common.PreConsumedQuota)
 		if meta.MaxTokens != 0 {
@@ -87,13 +107,19 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		imageRatio, _ = ratio_setting.GetImageRatio(info.OriginModelName)
 		audioRatio = ratio_setting.GetAudioRatio(info.OriginModelName)
 		audioCompletionRatio = ratio_setting.GetAudioCompletionRatio(info.OriginModelName)
-		ratio := modelRatio * groupRatioInfo.GroupRatio
+		// 按估算的提示词长度选择上下文阶梯，结算时再按实际用量重新选择
+		promptTiers = ratio_setting.GetPromptTiers(info.OriginModelName)
+		preConsumedRatio := modelRatio
+		if tier := types.SelectPromptTier(promptTiers, promptTokens); tier != nil {
+			preConsumedRatio = tier.ModelRatio
+		}
+		ratio := preConsumedRatio * groupRatioInfo.GroupRatio * extraRatio
 		preConsumedQuota = int(float64(preConsumedTokens) * ratio)
 	} else {
 		if meta.ImagePriceRatio != 0 {
 			modelPrice = modelPrice * meta.ImagePriceRatio
 		}
-		preConsumedQuota = int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
+		preConsumedQuota = int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio * extraRatio)
 	}
 
 	// check if free model pre-consume is disabled
@@ -130,7 +156,13 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		CacheCreation5mRatio: cacheCreationRatio5m,
 		CacheCreation1hRatio: cacheCreationRatio1h,
 		QuotaToPreConsume:    preConsumedQuota,
+		PromptTiers:          promptTiers,
+		VolumeTier:           volumeTier,
+		ScheduleRatio:        scheduleRatio,
+		IsBatch:              isBatch,
+		BatchRatio:           batchRatio,
 	}
+	priceData.ApplyPromptTier(promptTokens)
 
 	if common.DebugEnabled {
 		println(fmt.Sprintf("model_price_helper result: %s", priceData.ToSetting()))
@@ -139,6 +171,23 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	return priceData, nil
 }
 
+// getUserVolumeTier 根据用户当月已消费额度返回命中的用量阶梯，未配置阶梯时不查询
+func getUserVolumeTier(c *gin.Context, info *relaycommon.RelayInfo) *types.VolumeTier {
+	if !ratio_setting.HasVolumeTiers() {
+		return nil
+	}
+	monthlyQuota, err := model.GetUserMonthlyConsumeQuota(info.UserId)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to get user monthly quota: %s", err.Error()))
+		return nil
+	}
+	tier, ok := ratio_setting.GetVolumeTier(monthlyQuota)
+	if !ok {
+		return nil
+	}
+	return tier
+}
+
 // ModelPriceHelperPerCall 按次计费的 PriceHelper (MJ、Task)
 func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) types.PerCallPriceData {
 	groupRatioInfo := HandleGroupRatio(c, info)
@@ -153,11 +202,16 @@ func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) types.
 			modelPrice = defaultPrice
 		}
 	}
-	quota := int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
+	quota := modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio
+	scheduleRatio := ratio_setting.GetScheduleRatio(info.OriginModelName, info.UsingGroup)
+	if scheduleRatio != nil {
+		quota *= scheduleRatio.Ratio
+	}
 	priceData := types.PerCallPriceData{
 		ModelPrice:     modelPrice,
-		Quota:          quota,
+		Quota:          int(quota),
 		GroupRatioInfo: groupRatioInfo,
+		ScheduleRatio:  scheduleRatio,
 	}
 	return priceData
 }
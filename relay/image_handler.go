@@ -11,7 +11,9 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
@@ -44,50 +46,169 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 	}
 	adaptor.Init(info)
 
-	var requestBody io.Reader
-
+	var (
+		usage      *dto.Usage
+		imageCount = int(request.N)
+	)
 	if model_setting.GetGlobalSettings().PassThroughRequestEnabled || info.ChannelSetting.PassThroughBodyEnabled {
 		body, err := common.GetRequestBody(c)
 		if err != nil {
 			return types.NewErrorWithStatusCode(err, types.ErrorCodeReadRequestBodyFailed, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
 		}
-		requestBody = bytes.NewBuffer(body)
+		usage, _, newAPIError = doImageRequest(c, info, adaptor, bytes.NewBuffer(body), false)
+		if newAPIError != nil {
+			return newAPIError
+		}
 	} else {
-		convertedRequest, err := adaptor.ConvertImageRequest(c, info, *request)
-		if err != nil {
-			return types.NewError(err, types.ErrorCodeConvertRequestFailed)
+		usage, imageCount, newAPIError = relayNormalizedImageRequest(c, info, adaptor, request)
+		if newAPIError != nil {
+			return newAPIError
 		}
-		relaycommon.AppendRequestConversionFromRequest(info, convertedRequest)
-
-		switch convertedRequest.(type) {
-		case *bytes.Buffer:
-			requestBody = convertedRequest.(io.Reader)
-		default:
-			jsonData, err := common.Marshal(convertedRequest)
-			if err != nil {
-				return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
-			}
+	}
 
-			// apply param override
-			if len(info.ParamOverride) > 0 {
-				jsonData, err = relaycommon.ApplyParamOverride(jsonData, info.ParamOverride, relaycommon.BuildParamOverrideContext(info))
-				if err != nil {
-					return types.NewError(err, types.ErrorCodeChannelParamOverrideInvalid, types.ErrOptionWithSkipRetry())
+	// 按实际生成的图片数量计费，按次计费的价格在预扣时已乘以请求的 n
+	if imageCount > 0 && request.N > 0 && imageCount != int(request.N) && info.PriceData.UsePrice {
+		info.PriceData.ModelPrice = info.PriceData.ModelPrice / float64(request.N) * float64(imageCount)
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = imageCount
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = imageCount
+	}
+
+	quality := "standard"
+	if request.Quality == "hd" {
+		quality = "hd"
+	}
+
+	var logContent []string
+
+	if len(request.Size) > 0 {
+		logContent = append(logContent, fmt.Sprintf("大小 %s", request.Size))
+	}
+	if len(quality) > 0 {
+		logContent = append(logContent, fmt.Sprintf("品质 %s", quality))
+	}
+	if imageCount > 0 {
+		logContent = append(logContent, fmt.Sprintf("生成数量 %d", imageCount))
+	}
+
+	postConsumeQuota(c, info, usage, logContent...)
+	return nil
+}
+
+// relayNormalizedImageRequest 统一各上游的图片请求：映射尺寸、按上游单次上限拆分 n、合并结果并转换 url/b64_json，
+// 返回实际生成的图片数量
+func relayNormalizedImageRequest(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.ImageRequest) (*dto.Usage, int, *types.NewAPIError) {
+	spec := getImageProviderSpec(info)
+	request.Size = spec.normalizeSize(request.Size)
+
+	batches := []uint{request.N}
+	if info.RelayMode == relayconstant.RelayModeImagesGenerations || info.RelayMode == relayconstant.RelayModeImagesVariations {
+		batches = spec.splitN(request.N)
+	}
+
+	usage := &dto.Usage{}
+	var (
+		merged  dto.ImageResponse
+		rawBody []byte
+	)
+	for i, n := range batches {
+		batchRequest := *request
+		batchRequest.N = n
+		requestBody, newAPIError := convertImageRequestBody(c, info, adaptor, batchRequest)
+		if newAPIError == nil {
+			var batchUsage *dto.Usage
+			batchUsage, rawBody, newAPIError = doImageRequest(c, info, adaptor, requestBody, true)
+			if newAPIError == nil {
+				if rawBody == nil {
+					// 流式响应已直接写给客户端
+					return batchUsage, int(request.N), nil
 				}
+				usage.PromptTokens += batchUsage.PromptTokens
+				usage.CompletionTokens += batchUsage.CompletionTokens
+				usage.TotalTokens += batchUsage.TotalTokens
 			}
+		}
+		if newAPIError != nil {
+			if i == 0 {
+				return nil, 0, newAPIError
+			}
+			// 已有批次成功时返回已生成的图片，仅按实际数量计费
+			logger.LogWarn(c, fmt.Sprintf("image batch %d/%d failed: %s", i+1, len(batches), newAPIError.Error()))
+			break
+		}
 
-			if common.DebugEnabled {
-				logger.LogDebug(c, fmt.Sprintf("image request body: %s", string(jsonData)))
+		var batchResponse dto.ImageResponse
+		if err := common.Unmarshal(rawBody, &batchResponse); err != nil {
+			if len(batches) == 1 {
+				// 无法识别的响应原样返回
+				c.Data(http.StatusOK, c.Writer.Header().Get("Content-Type"), rawBody)
+				return usage, int(request.N), nil
 			}
-			requestBody = bytes.NewBuffer(jsonData)
+			return nil, 0, types.NewError(err, types.ErrorCodeBadResponseBody)
+		}
+		if i == 0 {
+			merged = batchResponse
+		} else {
+			merged.Data = append(merged.Data, batchResponse.Data...)
+		}
+	}
+
+	if len(merged.Data) == 0 {
+		// 上游返回成功但没有图片时按失败处理，退还预扣额度
+		return nil, 0, types.NewError(fmt.Errorf("upstream returned no images"), types.ErrorCodeBadResponseBody)
+	}
+	changed := convertImageResponseFormat(c, info, merged.Data, request.ResponseFormat)
+	c.Writer.Header().Del("Content-Length")
+	if len(batches) == 1 && !changed {
+		c.Data(http.StatusOK, "application/json", rawBody)
+	} else {
+		if len(batches) > 1 {
+			merged.Usage = nil
 		}
+		c.JSON(http.StatusOK, merged)
+	}
+	return usage, len(merged.Data), nil
+}
+
+func convertImageRequestBody(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request dto.ImageRequest) (io.Reader, *types.NewAPIError) {
+	convertedRequest, err := adaptor.ConvertImageRequest(c, info, request)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed)
+	}
+	relaycommon.AppendRequestConversionFromRequest(info, convertedRequest)
+
+	if body, ok := convertedRequest.(*bytes.Buffer); ok {
+		return body, nil
+	}
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
 	}
 
+	// apply param override
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverride(jsonData, info.ParamOverride, relaycommon.BuildParamOverrideContext(info))
+		if err != nil {
+			return nil, types.NewError(err, types.ErrorCodeChannelParamOverrideInvalid, types.ErrOptionWithSkipRetry())
+		}
+	}
+
+	if common.DebugEnabled {
+		logger.LogDebug(c, fmt.Sprintf("image request body: %s", string(jsonData)))
+	}
+	return bytes.NewBuffer(jsonData), nil
+}
+
+// doImageRequest 发送一次图片请求，record 为 true 时暂存非流式响应体并返回，由调用方统一写回
+func doImageRequest(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, requestBody io.Reader, record bool) (*dto.Usage, []byte, *types.NewAPIError) {
 	statusCodeMappingStr := c.GetString("status_code_mapping")
 
 	resp, err := adaptor.DoRequest(c, info, requestBody)
 	if err != nil {
-		return types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
+		return nil, nil, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
 	}
 	var httpResp *http.Response
 	if resp != nil {
@@ -98,45 +219,34 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 				// replicate channel returns 201 Created when using Prefer: wait, treat it as success.
 				httpResp.StatusCode = http.StatusOK
 			} else {
-				newAPIError = service.RelayErrorHandler(c.Request.Context(), httpResp, false)
+				newAPIError := service.RelayErrorHandler(c.Request.Context(), httpResp, false)
 				// reset status code 重置状态码
 				service.ResetStatusCode(newAPIError, statusCodeMappingStr)
-				return newAPIError
+				return nil, nil, newAPIError
 			}
 		}
 	}
 
+	var recorder *imageResponseRecorder
+	if record && !info.IsStream {
+		recorder = &imageResponseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+	}
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	if recorder != nil {
+		c.Writer = recorder.ResponseWriter
+	}
 	if newAPIError != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
-		return newAPIError
+		return nil, nil, newAPIError
 	}
-
-	if usage.(*dto.Usage).TotalTokens == 0 {
-		usage.(*dto.Usage).TotalTokens = int(request.N)
-	}
-	if usage.(*dto.Usage).PromptTokens == 0 {
-		usage.(*dto.Usage).PromptTokens = int(request.N)
-	}
-
-	quality := "standard"
-	if request.Quality == "hd" {
-		quality = "hd"
-	}
-
-	var logContent []string
-
-	if len(request.Size) > 0 {
-		logContent = append(logContent, fmt.Sprintf("大小 %s", request.Size))
+	imageUsage, _ := usage.(*dto.Usage)
+	if imageUsage == nil {
+		imageUsage = &dto.Usage{}
 	}
-	if len(quality) > 0 {
-		logContent = append(logContent, fmt.Sprintf("品质 %s", quality))
-	}
-	if request.N > 0 {
-		logContent = append(logContent, fmt.Sprintf("生成数量 %d", request.N))
+	if recorder == nil {
+		return imageUsage, nil, nil
 	}
-
-	postConsumeQuota(c, info, usage.(*dto.Usage), logContent...)
-	return nil
+	return imageUsage, recorder.body.Bytes(), nil
 }
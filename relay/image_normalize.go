@@ -0,0 +1,211 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageProviderSpec 上游图片接口与 OpenAI 的能力差异，用于统一 /v1/images 请求
+type imageProviderSpec struct {
+	// 允许的分辨率（宽x高），请求尺寸映射到宽高比最接近的一项，为空时不处理
+	Sizes []string
+	// 单次请求最多返回的图片数，n 超出时拆分为多次请求，0 表示不限制
+	MaxN uint
+}
+
+var (
+	qwenImageSizes     = []string{"1664x928", "1472x1140", "1328x1328", "1140x1472", "928x1664"}
+	jimengImageSizes   = []string{"1328x1328", "1472x1104", "1104x1472", "1664x936", "936x1664", "1584x1056", "1056x1584", "2016x864"}
+	imagenImageSizes   = []string{"1024x1024", "1536x1024", "1024x1536", "1792x1024", "1024x1792"}
+	seedream3ImageSize = []string{"1024x1024", "864x1152", "1152x864", "1280x720", "720x1280", "832x1248", "1248x832", "1512x648"}
+)
+
+func getImageProviderSpec(info *relaycommon.RelayInfo) *imageProviderSpec {
+	modelName := info.UpstreamModelName
+	switch info.ApiType {
+	case constant.APITypeAli:
+		if strings.HasPrefix(modelName, "qwen-image") {
+			return &imageProviderSpec{Sizes: qwenImageSizes, MaxN: 1}
+		}
+		return &imageProviderSpec{MaxN: 4}
+	case constant.APITypeJimeng:
+		return &imageProviderSpec{Sizes: jimengImageSizes, MaxN: 1}
+	case constant.APITypeGemini:
+		if strings.HasPrefix(modelName, "imagen") {
+			return &imageProviderSpec{Sizes: imagenImageSizes, MaxN: 4}
+		}
+		return &imageProviderSpec{MaxN: 1}
+	case constant.APITypeVolcEngine:
+		if strings.Contains(modelName, "seedream-3") {
+			return &imageProviderSpec{Sizes: seedream3ImageSize, MaxN: 1}
+		}
+		return &imageProviderSpec{MaxN: 1}
+	case constant.APITypeReplicate:
+		if strings.Contains(modelName, "schnell") || strings.Contains(modelName, "flux-dev") {
+			return &imageProviderSpec{MaxN: 4}
+		}
+		return &imageProviderSpec{MaxN: 1}
+	}
+	return nil
+}
+
+// normalizeSize 将 WxH（或 W*H）尺寸映射到上游支持的分辨率，宽高比、1K/2K 等其他写法保持原样
+func (s *imageProviderSpec) normalizeSize(size string) string {
+	if s == nil || len(s.Sizes) == 0 {
+		return size
+	}
+	w, h, ok := parseImageSize(size)
+	if !ok {
+		return size
+	}
+	best := size
+	bestAspect, bestArea := math.MaxFloat64, math.MaxFloat64
+	for _, candidate := range s.Sizes {
+		cw, ch, ok := parseImageSize(candidate)
+		if !ok {
+			continue
+		}
+		if cw == w && ch == h {
+			return candidate
+		}
+		aspect := math.Abs(math.Log(float64(cw)/float64(ch)) - math.Log(float64(w)/float64(h)))
+		area := math.Abs(float64(cw*ch - w*h))
+		if aspect < bestAspect-1e-6 || (math.Abs(aspect-bestAspect) <= 1e-6 && area < bestArea) {
+			best, bestAspect, bestArea = candidate, aspect, area
+		}
+	}
+	return best
+}
+
+// splitN 按单次请求上限拆分图片数量
+func (s *imageProviderSpec) splitN(n uint) []uint {
+	if n == 0 {
+		n = 1
+	}
+	if s == nil || s.MaxN == 0 || n <= s.MaxN {
+		return []uint{n}
+	}
+	batches := make([]uint, 0, (n+s.MaxN-1)/s.MaxN)
+	for n > 0 {
+		batch := min(n, s.MaxN)
+		batches = append(batches, batch)
+		n -= batch
+	}
+	return batches
+}
+
+func parseImageSize(size string) (int, int, bool) {
+	parts := strings.FieldsFunc(strings.ToLower(strings.TrimSpace(size)), func(r rune) bool {
+		return r == 'x' || r == '*'
+	})
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	w, err1 := strconv.Atoi(parts[0])
+	h, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// imageResponseRecorder 暂存适配器写出的图片响应，合并、转换后再统一返回给客户端
+type imageResponseRecorder struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *imageResponseRecorder) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *imageResponseRecorder) WriteHeaderNow() {}
+
+func (w *imageResponseRecorder) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *imageResponseRecorder) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *imageResponseRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *imageResponseRecorder) Size() int {
+	return w.body.Len()
+}
+
+func (w *imageResponseRecorder) Written() bool {
+	return false
+}
+
+func (w *imageResponseRecorder) Flush() {}
+
+// convertImageResponseFormat 按客户端要求的 response_format 在 url 与 b64_json 之间转换，返回是否有改动
+func convertImageResponseFormat(c *gin.Context, info *relaycommon.RelayInfo, data []dto.ImageData, responseFormat string) bool {
+	changed := false
+	for i := range data {
+		switch responseFormat {
+		case "b64_json":
+			if data[i].B64Json != "" || data[i].Url == "" {
+				continue
+			}
+			_, b64, err := service.GetImageFromUrl(data[i].Url)
+			if err != nil {
+				logger.LogWarn(c, fmt.Sprintf("failed to convert image url to base64: %v", err))
+				continue
+			}
+			data[i].B64Json = b64
+			data[i].Url = ""
+			changed = true
+		case "url":
+			if data[i].Url != "" || data[i].B64Json == "" {
+				continue
+			}
+			data[i].Url = imageBase64ToUrl(c, info, data[i].B64Json, i)
+			data[i].B64Json = ""
+			changed = true
+		}
+	}
+	return changed
+}
+
+// imageBase64ToUrl 启用媒体转存时写入存储并返回固定地址，否则返回 data URL
+func imageBase64ToUrl(c *gin.Context, info *relaycommon.RelayInfo, b64 string, index int) string {
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "data:image/png;base64," + b64
+	}
+	contentType := http.DetectContentType(raw)
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "image/png"
+	}
+	sourceId := fmt.Sprintf("%s-%d-%d", c.GetString(common.RequestIdKey), info.ChannelId, index)
+	url, err := service.StoreGeneratedMedia(c.Request.Context(), info.UserId, info.UsingGroup, model.MediaSourceImage, sourceId, raw, contentType)
+	if err != nil {
+		logger.LogDebug(c, fmt.Sprintf("image not stored, returning data url: %v", err))
+		return "data:" + contentType + ";base64," + b64
+	}
+	return url
+}
@@ -0,0 +1,666 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// mjEmulationImageCount 每个任务生成的图片数，与 Midjourney 四宫格一致
+	mjEmulationImageCount = 4
+	// mjEmulationTimeout 后台生成超时时间，需短于任务轮询的 1 小时失败判定
+	mjEmulationTimeout = 30 * time.Minute
+
+	mjEmulationVariationPrompt       = "Create a variation of this image with noticeable changes in composition and details, keeping the same subject and style. %s"
+	mjEmulationSubtleVariationPrompt = "Create a subtle variation of this image, keeping the composition, subject and style almost unchanged. %s"
+	mjEmulationDescribePrompt        = "Describe this image as 4 different Midjourney prompts in English. Output exactly 4 lines, one prompt per line, without numbering or any other text."
+)
+
+var (
+	mjEmulationNumbers = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣"}
+	// 模型输出中可能带有的列表序号
+	mjEmulationListPrefix = regexp.MustCompile(`^\s*(\d+[.)、]|[-*•])\s*`)
+)
+
+// mjEmulationJob Midjourney 模拟任务的后台生成上下文
+type mjEmulationJob struct {
+	ctx    context.Context
+	keys   map[string]any
+	model  string
+	group  string
+	task   *model.Midjourney
+	prompt string
+	size   string
+	// 参考图：URL、data URL 或 base64
+	images []string
+}
+
+// RelayMidjourneyEmulation 使用当前渠道的图片模型模拟 Midjourney 提交接口，生成在后台完成，结果通过任务查询返回
+func RelayMidjourneyEmulation(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dto.MidjourneyResponse {
+	var midjRequest dto.MidjourneyRequest
+	if err := common.UnmarshalBodyReusable(c, &midjRequest); err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "bind_request_body_failed")
+	}
+
+	relayInfo.InitChannelMeta(c)
+
+	callbackUrl, err := service.ResolveTaskCallbackUrl(c, midjRequest.NotifyHook)
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "invalid_callback_url")
+	}
+
+	var images []string
+	switch relayInfo.RelayMode {
+	case relayconstant.RelayModeMidjourneyImagine:
+		if midjRequest.Prompt == "" {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "prompt_is_required")
+		}
+		midjRequest.Action = constant.MjActionImagine
+		images = midjRequest.Base64Array
+	case relayconstant.RelayModeMidjourneyDescribe:
+		if midjRequest.Base64 == "" {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "base64_is_required")
+		}
+		midjRequest.Action = constant.MjActionDescribe
+		images = []string{midjRequest.Base64}
+	case relayconstant.RelayModeMidjourneyAction:
+		if mjErr := service.CoverPlusActionToNormalAction(&midjRequest); mjErr != nil {
+			return mjErr
+		}
+	case relayconstant.RelayModeMidjourneySimpleChange:
+		params := service.ConvertSimpleChangeParams(midjRequest.Content)
+		if params == nil {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "content_parse_failed")
+		}
+		midjRequest.TaskId = params.TaskId
+		midjRequest.Action = params.Action
+		midjRequest.Index = params.Index
+	case relayconstant.RelayModeMidjourneyChange:
+	default:
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "unsupported_action")
+	}
+
+	var originTask *model.Midjourney
+	switch midjRequest.Action {
+	case constant.MjActionImagine, constant.MjActionDescribe:
+	case constant.MjActionUpscale, constant.MjActionVariation, constant.MjActionLowVariation,
+		constant.MjActionHighVariation, constant.MjActionReRoll:
+		if midjRequest.TaskId == "" {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_id_is_required")
+		}
+		originTask = model.GetByMJId(relayInfo.UserId, midjRequest.TaskId)
+		if originTask == nil {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_not_found")
+		}
+		if originTask.Status != "SUCCESS" {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_status_not_success")
+		}
+		var originImages []string
+		_ = json.Unmarshal([]byte(originTask.ImageUrls), &originImages)
+		switch midjRequest.Action {
+		case constant.MjActionUpscale, constant.MjActionVariation:
+			if midjRequest.Index < 1 || midjRequest.Index > len(originImages) {
+				return service.MidjourneyErrorWrapper(constant.MjRequestError, "index_out_of_range")
+			}
+			images = []string{originImages[midjRequest.Index-1]}
+		case constant.MjActionLowVariation, constant.MjActionHighVariation:
+			if len(originImages) == 0 {
+				return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_image_not_found")
+			}
+			images = originImages[:1]
+		}
+		midjRequest.Prompt = originTask.Prompt
+	default:
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "unsupported_action")
+	}
+
+	// 按 Midjourney 操作计费，实际调用的模型仅用于选择渠道
+	emulationModel := relayInfo.OriginModelName
+	modelName := service.CoverActionToModelName(midjRequest.Action)
+	relayInfo.OriginModelName = modelName
+	priceData := helper.ModelPriceHelperPerCall(c, relayInfo)
+
+	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	if err != nil {
+		return &dto.MidjourneyResponse{
+			Code:        4,
+			Description: err.Error(),
+		}
+	}
+	if userQuota+relayInfo.UserCreditLimit-priceData.Quota < 0 || !service.DerivedTokenBudgetEnough(relayInfo, priceData.Quota) {
+		return &dto.MidjourneyResponse{
+			Code:        4,
+			Description: "quota_not_enough",
+		}
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	midjourneyTask := &model.Midjourney{
+		UserId:      relayInfo.UserId,
+		Code:        1,
+		Action:      midjRequest.Action,
+		MjId:        fmt.Sprintf("%d%03d", now, common.GetRandomInt(1000)),
+		Prompt:      midjRequest.Prompt,
+		Description: "提交成功",
+		SubmitTime:  now,
+		Status:      "SUBMITTED",
+		Progress:    "0%",
+		ChannelId:   relayInfo.ChannelId,
+		Quota:       priceData.Quota,
+	}
+	if originTask != nil {
+		midjourneyTask.PromptEn = originTask.PromptEn
+	}
+	if midjRequest.Action == constant.MjActionUpscale {
+		// 放大直接取原四宫格中的单张图片
+		midjourneyTask.Status = "SUCCESS"
+		midjourneyTask.Progress = "100%"
+		midjourneyTask.StartTime = now
+		midjourneyTask.FinishTime = now
+		midjourneyTask.ImageUrl = images[0]
+		imageUrls, _ := json.Marshal(images)
+		midjourneyTask.ImageUrls = string(imageUrls)
+		midjourneyTask.Buttons = mjEmulationButtons(midjourneyTask, 1)
+	}
+	midjourneyTask.SetCallbackUrl(callbackUrl)
+	if err = midjourneyTask.Insert(); err != nil {
+		return &dto.MidjourneyResponse{
+			Code:        4,
+			Description: "insert_midjourney_task_failed",
+		}
+	}
+
+	err = service.PostConsumeQuota(relayInfo, priceData.Quota, 0, true)
+	if err != nil {
+		common.SysLog("error consuming token remain quota: " + err.Error())
+	}
+	logContent := fmt.Sprintf("模型固定价格 %.2f，分组倍率 %.2f，操作 %s，ID %s，模拟模型 %s", priceData.ModelPrice, priceData.GroupRatioInfo.GroupRatio, midjRequest.Action, midjourneyTask.MjId, emulationModel)
+	other := service.GenerateMjOtherInfo(relayInfo, priceData)
+	other["task_id"] = midjourneyTask.MjId
+	other["emulation_model"] = emulationModel
+	model.RecordConsumeLog(c, relayInfo.UserId, model.RecordConsumeLogParams{
+		ChannelId: relayInfo.ChannelId,
+		ModelName: modelName,
+		TokenName: c.GetString("token_name"),
+		Quota:     priceData.Quota,
+		Content:   logContent,
+		TokenId:   relayInfo.TokenId,
+		Group:     relayInfo.UsingGroup,
+		Other:     other,
+	})
+	model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, priceData.Quota)
+	model.UpdateChannelUsedQuota(relayInfo.ChannelId, priceData.Quota)
+
+	if midjourneyTask.Status != "SUCCESS" {
+		// 请求结束后 gin 会复用上下文，需复制渠道与用户信息供后台使用
+		keys := make(map[string]any, len(c.Keys))
+		for key, value := range c.Keys {
+			keys[key] = value
+		}
+		prompt, size := parseMjEmulationPrompt(midjRequest.Prompt)
+		switch midjRequest.Action {
+		case constant.MjActionVariation, constant.MjActionHighVariation:
+			prompt = fmt.Sprintf(mjEmulationVariationPrompt, prompt)
+		case constant.MjActionLowVariation:
+			prompt = fmt.Sprintf(mjEmulationSubtleVariationPrompt, prompt)
+		}
+		job := &mjEmulationJob{
+			keys:   keys,
+			model:  emulationModel,
+			group:  relayInfo.UsingGroup,
+			task:   midjourneyTask,
+			prompt: strings.TrimSpace(prompt),
+			size:   size,
+			images: images,
+		}
+		gopool.Go(job.run)
+	}
+
+	c.JSON(http.StatusOK, dto.MidjourneyResponse{
+		Code:        1,
+		Description: "提交成功",
+		Result:      midjourneyTask.MjId,
+	})
+	return nil
+}
+
+func (job *mjEmulationJob) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), mjEmulationTimeout)
+	defer cancel()
+	job.ctx = ctx
+	task := job.task
+
+	task.Status = "IN_PROGRESS"
+	task.StartTime = time.Now().UnixNano() / int64(time.Millisecond)
+	task.Progress = "10%"
+	if err := task.Update(); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("midjourney emulation: update task %s failed: %v", task.MjId, err))
+	}
+
+	var err error
+	if task.Action == constant.MjActionDescribe {
+		err = job.describe()
+	} else {
+		err = job.generate()
+	}
+
+	task.Progress = "100%"
+	task.FinishTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("midjourney emulation: task %s failed: %v", task.MjId, err))
+		task.Status = "FAILURE"
+		task.FailReason = err.Error()
+	} else {
+		task.Status = "SUCCESS"
+	}
+	if updateErr := task.Update(); updateErr != nil {
+		logger.LogError(ctx, fmt.Sprintf("midjourney emulation: update task %s failed: %v", task.MjId, updateErr))
+		return
+	}
+	if err != nil && task.Quota != 0 {
+		if err := model.IncreaseUserQuota(task.UserId, task.Quota, false); err != nil {
+			logger.LogError(ctx, "fail to increase user quota: "+err.Error())
+		}
+		logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, logger.LogQuota(task.Quota))
+		model.RecordTaskRefundLog(task.UserId, task.MjId, task.Quota, logContent)
+	}
+}
+
+// generate 生成四张图片并拼接为四宫格，上游单次返回不足时补充请求
+func (job *mjEmulationJob) generate() error {
+	task := job.task
+	var results [][]byte
+	for attempt := 0; attempt < mjEmulationImageCount && len(results) < mjEmulationImageCount; attempt++ {
+		images, err := job.requestImages(mjEmulationImageCount - len(results))
+		if err != nil {
+			if len(results) == 0 {
+				return err
+			}
+			logger.LogWarn(job.ctx, fmt.Sprintf("midjourney emulation: task %s got %d images: %v", task.MjId, len(results), err))
+			break
+		}
+		results = append(results, images...)
+		task.Progress = fmt.Sprintf("%d%%", min(len(results), mjEmulationImageCount)*90/mjEmulationImageCount)
+		_ = task.Update()
+	}
+	if len(results) == 0 {
+		return errors.New("no image generated")
+	}
+	if len(results) > mjEmulationImageCount {
+		results = results[:mjEmulationImageCount]
+	}
+
+	grid, err := composeMjGrid(results)
+	if err != nil {
+		return fmt.Errorf("compose grid failed: %w", err)
+	}
+	imageUrls := make([]string, 0, len(results))
+	for i, data := range results {
+		url, err := job.storeImage(fmt.Sprintf("%s-%d", task.MjId, i+1), data)
+		if err != nil {
+			return err
+		}
+		imageUrls = append(imageUrls, url)
+	}
+	gridUrl, err := job.storeImage(task.MjId, grid)
+	if err != nil {
+		return err
+	}
+	urls, _ := json.Marshal(imageUrls)
+	task.ImageUrl = gridUrl
+	task.ImageUrls = string(urls)
+	task.Buttons = mjEmulationButtons(task, len(results))
+	return nil
+}
+
+// requestImages 通过图片接口生成 n 张图片，有参考图时使用编辑接口
+func (job *mjEmulationJob) requestImages(n int) ([][]byte, error) {
+	request := &dto.ImageRequest{
+		Model:  job.model,
+		Prompt: job.prompt,
+		N:      uint(n),
+		Size:   job.size,
+	}
+	var (
+		path        = "/v1/images/generations"
+		contentType = "application/json"
+		body        []byte
+		err         error
+	)
+	if len(job.images) == 0 {
+		body, err = common.Marshal(request)
+	} else {
+		path = "/v1/images/edits"
+		body, contentType, err = buildMjEmulationEditForm(request, job.images)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, recorder, err := job.newContext(path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	info, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAIImage, request, nil)
+	if err != nil {
+		return nil, err
+	}
+	info.InitChannelMeta(c)
+	if err = helper.ModelMappedHelper(c, info, request); err != nil {
+		return nil, err
+	}
+	adaptor := GetAdaptor(info.ApiType)
+	if adaptor == nil {
+		return nil, fmt.Errorf("invalid api type: %d", info.ApiType)
+	}
+	adaptor.Init(info)
+	if _, _, newAPIError := relayNormalizedImageRequest(c, info, adaptor, request); newAPIError != nil {
+		return nil, newAPIError
+	}
+
+	var response dto.ImageResponse
+	if err = common.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("invalid image response: %w", err)
+	}
+	images := make([][]byte, 0, len(response.Data))
+	for _, data := range response.Data {
+		var raw []byte
+		switch {
+		case data.B64Json != "":
+			raw, err = base64.StdEncoding.DecodeString(data.B64Json)
+		case data.Url != "":
+			raw, _, err = loadMjEmulationImage(data.Url)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, raw)
+	}
+	if len(images) == 0 {
+		return nil, errors.New("empty image response")
+	}
+	return images, nil
+}
+
+// describe 调用识图模型生成 4 条提示词
+func (job *mjEmulationJob) describe() error {
+	imageUrl := job.images[0]
+	if !strings.HasPrefix(imageUrl, "http://") && !strings.HasPrefix(imageUrl, "https://") && !strings.HasPrefix(imageUrl, "data:") {
+		mimeType, data, err := service.GetImageBase64(imageUrl)
+		if err != nil {
+			return err
+		}
+		imageUrl = "data:" + mimeType + ";base64," + data
+	}
+	message := dto.Message{Role: "user"}
+	message.SetMediaContent([]dto.MediaContent{
+		{Type: dto.ContentTypeText, Text: mjEmulationDescribePrompt},
+		{Type: dto.ContentTypeImageURL, ImageUrl: &dto.MessageImageUrl{Url: imageUrl, Detail: "auto"}},
+	})
+	request := &dto.GeneralOpenAIRequest{
+		Model:    job.model,
+		Messages: []dto.Message{message},
+	}
+	body, err := common.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	c, recorder, err := job.newContext("/v1/chat/completions", "application/json", body)
+	if err != nil {
+		return err
+	}
+	info, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAI, request, nil)
+	if err != nil {
+		return err
+	}
+	info.InitChannelMeta(c)
+	if err = helper.ModelMappedHelper(c, info, request); err != nil {
+		return err
+	}
+	adaptor := GetAdaptor(info.ApiType)
+	if adaptor == nil {
+		return fmt.Errorf("invalid api type: %d", info.ApiType)
+	}
+	adaptor.Init(info)
+
+	convertedRequest, err := adaptor.ConvertOpenAIRequest(c, info, request)
+	if err != nil {
+		return err
+	}
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return err
+	}
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverride(jsonData, info.ParamOverride, relaycommon.BuildParamOverrideContext(info))
+		if err != nil {
+			return err
+		}
+	}
+	resp, err := adaptor.DoRequest(c, info, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	var httpResp *http.Response
+	if resp != nil {
+		httpResp = resp.(*http.Response)
+		if httpResp.StatusCode != http.StatusOK {
+			return service.RelayErrorHandler(c.Request.Context(), httpResp, false)
+		}
+	}
+	if _, newAPIError := adaptor.DoResponse(c, httpResp, info); newAPIError != nil {
+		return newAPIError
+	}
+
+	var response dto.OpenAITextResponse
+	if err = common.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		return fmt.Errorf("invalid chat response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return errors.New("empty chat response")
+	}
+	var prompts []string
+	for _, line := range strings.Split(response.Choices[0].Message.StringContent(), "\n") {
+		line = strings.TrimSpace(mjEmulationListPrefix.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		prompts = append(prompts, mjEmulationNumbers[len(prompts)]+" "+line)
+		if len(prompts) == len(mjEmulationNumbers) {
+			break
+		}
+	}
+	if len(prompts) == 0 {
+		return errors.New("empty describe result")
+	}
+	job.task.Prompt = strings.Join(prompts, "\n\n")
+	job.task.PromptEn = job.task.Prompt
+	properties, _ := json.Marshal(dto.Properties{FinalPrompt: job.task.Prompt})
+	job.task.Properties = string(properties)
+	return nil
+}
+
+// newContext 构造后台请求上下文，沿用提交时选择的渠道
+func (job *mjEmulationJob) newContext(path string, contentType string, body []byte) (*gin.Context, *httptest.ResponseRecorder, error) {
+	req, err := http.NewRequestWithContext(job.ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = req
+	for key, value := range job.keys {
+		c.Set(key, value)
+	}
+	c.Set(common.KeyBodyStorage, nil)
+	c.Set(common.KeyRequestBody, body)
+	c.Set("relay_mode", relayconstant.Path2RelayMode(path))
+	return c, recorder, nil
+}
+
+// storeImage 将图片写入媒体存储并返回固定地址，图片过大不能以 data URL 写入任务
+func (job *mjEmulationJob) storeImage(sourceId string, data []byte) (string, error) {
+	url, err := service.StoreGeneratedMedia(job.ctx, job.task.UserId, job.group, model.MediaSourceMidjourney, sourceId, data, http.DetectContentType(data))
+	if err != nil {
+		return "", fmt.Errorf("store image failed: %w", err)
+	}
+	return url, nil
+}
+
+// parseMjEmulationPrompt 去掉 Midjourney 参数，并将 --ar 转换为图片尺寸
+func parseMjEmulationPrompt(prompt string) (string, string) {
+	parts := strings.Split(" "+prompt, " --")
+	size := ""
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), " ")
+		if name != "ar" && name != "aspect" {
+			continue
+		}
+		w, h, ok := strings.Cut(strings.TrimSpace(value), ":")
+		if !ok {
+			continue
+		}
+		width, err1 := strconv.ParseFloat(w, 64)
+		height, err2 := strconv.ParseFloat(h, 64)
+		if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+			continue
+		}
+		switch ratio := width / height; {
+		case ratio > 1.2:
+			size = "1536x1024"
+		case ratio < 0.83:
+			size = "1024x1536"
+		default:
+			size = "1024x1024"
+		}
+	}
+	return strings.TrimSpace(parts[0]), size
+}
+
+func buildMjEmulationEditForm(request *dto.ImageRequest, images []string) ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("model", request.Model)
+	_ = writer.WriteField("prompt", request.Prompt)
+	_ = writer.WriteField("n", strconv.Itoa(int(request.N)))
+	if request.Size != "" {
+		_ = writer.WriteField("size", request.Size)
+	}
+	field := "image"
+	if len(images) > 1 {
+		field = "image[]"
+	}
+	for i, image := range images {
+		data, mimeType, err := loadMjEmulationImage(image)
+		if err != nil {
+			return nil, "", err
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="image_%d.%s"`, field, i+1, strings.TrimPrefix(mimeType, "image/")))
+		header.Set("Content-Type", mimeType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err = part.Write(data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// loadMjEmulationImage 读取 URL、data URL 或 base64 图片
+func loadMjEmulationImage(image string) ([]byte, string, error) {
+	mimeType, data, err := service.GetImageBase64(image)
+	if err != nil {
+		return nil, "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return raw, mimeType, nil
+}
+
+// composeMjGrid 将图片按 Midjourney 四宫格拼接，单元格尺寸以第一张图片为准
+func composeMjGrid(images [][]byte) ([]byte, error) {
+	decoded := make([]image.Image, 0, len(images))
+	for _, data := range images {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, img)
+	}
+	cell := decoded[0].Bounds().Size()
+	grid := image.NewRGBA(image.Rect(0, 0, cell.X*2, cell.Y*2))
+	draw.Draw(grid, grid.Bounds(), image.White, image.Point{}, draw.Src)
+	for i, img := range decoded {
+		x, y := (i%2)*cell.X, (i/2)*cell.Y
+		draw.CatmullRom.Scale(grid, image.Rect(x, y, x+cell.X, y+cell.Y), img, img.Bounds(), draw.Over, nil)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, grid, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mjEmulationButtons 生成与 Midjourney 一致的操作按钮，customId 可直接用于 /mj/submit/action
+func mjEmulationButtons(task *model.Midjourney, count int) string {
+	var buttons []dto.ActionButton
+	if task.Action == constant.MjActionUpscale {
+		buttons = append(buttons,
+			dto.ActionButton{CustomId: fmt.Sprintf("MJ::JOB::low_variation::1::%s::SOLO", task.MjId), Emoji: "🪄", Label: "Vary (Subtle)", Type: 2, Style: 2},
+			dto.ActionButton{CustomId: fmt.Sprintf("MJ::JOB::high_variation::1::%s::SOLO", task.MjId), Emoji: "🪄", Label: "Vary (Strong)", Type: 2, Style: 2},
+		)
+	} else {
+		for i := 1; i <= count; i++ {
+			buttons = append(buttons, dto.ActionButton{CustomId: fmt.Sprintf("MJ::JOB::upsample::%d::%s", i, task.MjId), Emoji: "", Label: fmt.Sprintf("U%d", i), Type: 2, Style: 2})
+		}
+		buttons = append(buttons, dto.ActionButton{CustomId: fmt.Sprintf("MJ::JOB::reroll::0::%s::SOLO", task.MjId), Emoji: "🔄", Label: "", Type: 2, Style: 2})
+		for i := 1; i <= count; i++ {
+			buttons = append(buttons, dto.ActionButton{CustomId: fmt.Sprintf("MJ::JOB::variation::%d::%s", i, task.MjId), Emoji: "", Label: fmt.Sprintf("V%d", i), Type: 2, Style: 2})
+		}
+	}
+	data, _ := json.Marshal(buttons)
+	return string(data)
+}
@@ -2,6 +2,7 @@ package relay
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,7 +21,6 @@ import (
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
-	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -34,6 +34,27 @@ func RelayMidjourneyImage(c *gin.Context) {
 		})
 		return
 	}
+	// 已转存的图片直接跳转到签名链接
+	if obj := model.GetStoredMediaObject(model.MediaSourceMidjourney, midjourneyTask.MjId); obj != nil {
+		if signedURL, err := service.GetMediaObjectSignedUrl(c.Request.Context(), obj); err == nil {
+			c.Redirect(http.StatusFound, signedURL)
+			return
+		}
+	}
+	// 未开启媒体转存时模拟任务的图片以 data URL 保存
+	if strings.HasPrefix(midjourneyTask.ImageUrl, "data:") {
+		mimeType, data, err := service.DecodeBase64FileData(midjourneyTask.ImageUrl)
+		if err == nil {
+			if raw, err := base64.StdEncoding.DecodeString(data); err == nil {
+				c.Data(http.StatusOK, mimeType, raw)
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "decode_image_failed",
+		})
+		return
+	}
 	var httpClient *http.Client
 	if channel, err := model.CacheGetChannel(midjourneyTask.ChannelId); err == nil {
 		proxy := channel.GetSetting().Proxy
@@ -123,55 +144,6 @@ func RelayMidjourneyNotify(c *gin.Context) *dto.MidjourneyResponse {
 	return nil
 }
 
-func coverMidjourneyTaskDto(c *gin.Context, originTask *model.Midjourney) (midjourneyTask dto.MidjourneyDto) {
-	midjourneyTask.MjId = originTask.MjId
-	midjourneyTask.Progress = originTask.Progress
-	midjourneyTask.PromptEn = originTask.PromptEn
-	midjourneyTask.State = originTask.State
-	midjourneyTask.SubmitTime = originTask.SubmitTime
-	midjourneyTask.StartTime = originTask.StartTime
-	midjourneyTask.FinishTime = originTask.FinishTime
-	midjourneyTask.ImageUrl = ""
-	if originTask.ImageUrl != "" && setting.MjForwardUrlEnabled {
-		midjourneyTask.ImageUrl = system_setting.ServerAddress + "/mj/image/" + originTask.MjId
-		if originTask.Status != "SUCCESS" {
-			midjourneyTask.ImageUrl += "?rand=" + strconv.FormatInt(time.Now().UnixNano(), 10)
-		}
-	} else {
-		midjourneyTask.ImageUrl = originTask.ImageUrl
-	}
-	if originTask.VideoUrl != "" {
-		midjourneyTask.VideoUrl = originTask.VideoUrl
-	}
-	midjourneyTask.Status = originTask.Status
-	midjourneyTask.FailReason = originTask.FailReason
-	midjourneyTask.Action = originTask.Action
-	midjourneyTask.Description = originTask.Description
-	midjourneyTask.Prompt = originTask.Prompt
-	if originTask.Buttons != "" {
-		var buttons []dto.ActionButton
-		err := json.Unmarshal([]byte(originTask.Buttons), &buttons)
-		if err == nil {
-			midjourneyTask.Buttons = buttons
-		}
-	}
-	if originTask.VideoUrls != "" {
-		var videoUrls []dto.ImgUrls
-		err := json.Unmarshal([]byte(originTask.VideoUrls), &videoUrls)
-		if err == nil {
-			midjourneyTask.VideoUrls = videoUrls
-		}
-	}
-	if originTask.Properties != "" {
-		var properties dto.Properties
-		err := json.Unmarshal([]byte(originTask.Properties), &properties)
-		if err == nil {
-			midjourneyTask.Properties = &properties
-		}
-	}
-	return
-}
-
 func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyResponse {
 	var swapFaceRequest dto.SwapFaceRequest
 	err := common.UnmarshalBodyReusable(c, &swapFaceRequest)
@@ -184,6 +156,10 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 	if swapFaceRequest.SourceBase64 == "" || swapFaceRequest.TargetBase64 == "" {
 		return service.MidjourneyErrorWrapper(constant.MjRequestError, "sour_base64_and_target_base64_is_required")
 	}
+	callbackUrl, err := service.ResolveTaskCallbackUrl(c, "")
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "invalid_callback_url")
+	}
 	modelName := service.CoverActionToModelName(constant.MjActionSwapFace)
 
 	priceData := helper.ModelPriceHelperPerCall(c, info)
@@ -196,7 +172,7 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 		}
 	}
 
-	if userQuota-priceData.Quota < 0 {
+	if userQuota+info.UserCreditLimit-priceData.Quota < 0 || !service.DerivedTokenBudgetEnough(info, priceData.Quota) {
 		return &dto.MidjourneyResponse{
 			Code:        4,
 			Description: "quota_not_enough",
@@ -219,6 +195,7 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 			tokenName := c.GetString("token_name")
 			logContent := fmt.Sprintf("模型固定价格 %.2f，分组倍率 %.2f，操作 %s", priceData.ModelPrice, priceData.GroupRatioInfo.GroupRatio, constant.MjActionSwapFace)
 			other := service.GenerateMjOtherInfo(info, priceData)
+			other["task_id"] = mjResp.Response.Result
 			model.RecordConsumeLog(c, info.UserId, model.RecordConsumeLogParams{
 				ChannelId: info.ChannelId,
 				ModelName: modelName,
@@ -253,6 +230,7 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 		ChannelId:   c.GetInt("channel_id"),
 		Quota:       priceData.Quota,
 	}
+	midjourneyTask.SetCallbackUrl(callbackUrl)
 	err = midjourneyTask.Insert()
 	if err != nil {
 		return service.MidjourneyErrorWrapper(constant.MjRequestError, "insert_midjourney_task_failed")
@@ -316,7 +294,7 @@ func RelayMidjourneyTask(c *gin.Context, relayMode int) *dto.MidjourneyResponse
 				Description: "task_no_found",
 			}
 		}
-		midjourneyTask := coverMidjourneyTaskDto(c, originTask)
+		midjourneyTask := service.CoverMidjourneyTaskDto(originTask)
 		respBody, err = json.Marshal(midjourneyTask)
 		if err != nil {
 			return &dto.MidjourneyResponse{
@@ -339,7 +317,7 @@ func RelayMidjourneyTask(c *gin.Context, relayMode int) *dto.MidjourneyResponse
 		if len(condition.IDs) != 0 {
 			originTasks := model.GetByMJIds(userId, condition.IDs)
 			for _, originTask := range originTasks {
-				midjourneyTask := coverMidjourneyTaskDto(c, originTask)
+				midjourneyTask := service.CoverMidjourneyTaskDto(originTask)
 				tasks = append(tasks, midjourneyTask)
 			}
 		}
@@ -377,6 +355,16 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 
 	relayInfo.InitChannelMeta(c)
 
+	// 开启上游回调时 notifyHook 由上游直接通知，否则由本系统在任务完成后投递
+	notifyHook := ""
+	if !setting.MjNotifyEnabled {
+		notifyHook = midjRequest.NotifyHook
+	}
+	callbackUrl, err := service.ResolveTaskCallbackUrl(c, notifyHook)
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "invalid_callback_url")
+	}
+
 	if relayInfo.RelayMode == relayconstant.RelayModeMidjourneyAction { // midjourney plus，需要从customId中获取任务信息
 		mjErr := service.CoverPlusActionToNormalAction(&midjRequest)
 		if mjErr != nil {
@@ -497,7 +485,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		}
 	}
 
-	if consumeQuota && userQuota-priceData.Quota < 0 {
+	if consumeQuota && (userQuota+relayInfo.UserCreditLimit-priceData.Quota < 0 || !service.DerivedTokenBudgetEnough(relayInfo, priceData.Quota)) {
 		return &dto.MidjourneyResponse{
 			Code:        4,
 			Description: "quota_not_enough",
@@ -519,6 +507,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 			tokenName := c.GetString("token_name")
 			logContent := fmt.Sprintf("模型固定价格 %.2f，分组倍率 %.2f，操作 %s，ID %s", priceData.ModelPrice, priceData.GroupRatioInfo.GroupRatio, midjRequest.Action, midjResponse.Result)
 			other := service.GenerateMjOtherInfo(relayInfo, priceData)
+			other["task_id"] = midjResponse.Result
 			model.RecordConsumeLog(c, relayInfo.UserId, model.RecordConsumeLogParams{
 				ChannelId: relayInfo.ChannelId,
 				ModelName: modelName,
@@ -603,6 +592,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		midjourneyTask.Progress = "100%"
 		midjourneyTask.Status = "SUCCESS"
 	}
+	midjourneyTask.SetCallbackUrl(callbackUrl)
 	err = midjourneyTask.Insert()
 	if err != nil {
 		return &dto.MidjourneyResponse{
@@ -0,0 +1,256 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// SDP offer 通常只有几 KB
+const realtimeMaxSdpSize = 64 << 10
+
+// RealtimeSessionHelper 向上游申请 WebRTC 临时密钥，返回绑定到当前令牌的派生令牌代替上游密钥
+func RealtimeSessionHelper(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
+	info.InitChannelMeta(c)
+	if info.ChannelType != constant.ChannelTypeOpenAI {
+		return types.NewError(fmt.Errorf("channel type %d does not support realtime webrtc", info.ChannelType), types.ErrorCodeInvalidApiType, types.ErrOptionWithSkipRetry())
+	}
+	if err := helper.ModelMappedHelper(c, info, nil); err != nil {
+		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
+	}
+
+	request := make(map[string]any)
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+	}
+	request["model"] = info.UpstreamModelName
+	body, err := common.Marshal(request)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+	}
+
+	resp, err := doRealtimeHttpRequest(c, info, info.ChannelBaseUrl+"/v1/realtime/sessions", "application/json", info.ApiKey, body)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeDoRequestFailed)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return service.RelayErrorHandler(c, resp, false)
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	service.CloseResponseBodyGracefully(resp)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+	}
+	session := make(map[string]any)
+	if err := common.Unmarshal(responseBody, &session); err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	clientSecret, _ := session["client_secret"].(map[string]any)
+	secret, _ := clientSecret["value"].(string)
+	if secret == "" {
+		return types.NewError(fmt.Errorf("upstream response missing client_secret"), types.ErrorCodeBadResponseBody)
+	}
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	if v, ok := clientSecret["expires_at"].(float64); ok && v > 0 {
+		expiresAt = int64(v)
+	}
+
+	parent, err := model.GetTokenByKeyHash(info.TokenKeyHash, false)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+	}
+	derived, err := service.IssueDerivedToken(parent, service.DerivedTokenRequest{
+		ExpiresIn: int(common.Max(int(expiresAt-time.Now().Unix()), 1)),
+		Models:    []string{info.OriginModelName},
+		Scopes:    []string{constant.TokenScopeRealtime},
+	})
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+	}
+	err = service.SaveRealtimeEphemeralSession(derived.Id, &service.RealtimeEphemeralSession{
+		ChannelId:     info.ChannelId,
+		KeyIndex:      common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex),
+		OriginModel:   info.OriginModelName,
+		UpstreamModel: info.UpstreamModelName,
+		ClientSecret:  secret,
+		ExpiresAt:     derived.ExpiresAt,
+	})
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+	}
+
+	clientSecret["value"] = derived.Key
+	clientSecret["expires_at"] = derived.ExpiresAt
+	session["model"] = info.OriginModelName
+	c.JSON(http.StatusOK, session)
+	return nil
+}
+
+// RealtimeCallHelper 使用会话的上游临时密钥转发 SDP offer，并通过旁路连接按 response.done 的用量计费
+func RealtimeCallHelper(c *gin.Context, info *relaycommon.RelayInfo, session *service.RealtimeEphemeralSession) *types.NewAPIError {
+	info.InitChannelMeta(c)
+	info.UpstreamModelName = session.UpstreamModel
+	info.IsStream = true
+
+	sdp, err := io.ReadAll(io.LimitReader(c.Request.Body, realtimeMaxSdpSize+1))
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+	if len(sdp) == 0 || len(sdp) > realtimeMaxSdpSize {
+		return types.NewErrorWithStatusCode(fmt.Errorf("invalid sdp offer"), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+
+	requestURL := info.ChannelBaseUrl + c.Request.URL.Path + "?model=" + url.QueryEscape(session.UpstreamModel)
+	resp, err := doRealtimeHttpRequest(c, info, requestURL, "application/sdp", session.ClientSecret, sdp)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeDoRequestFailed)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return service.RelayErrorHandler(c, resp, false)
+	}
+	answer, err := io.ReadAll(resp.Body)
+	service.CloseResponseBodyGracefully(resp)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+	}
+
+	// 上游通过 Location 返回通话地址，如 /v1/realtime/calls/rtc_xxx，没有通话地址时无法计费，不返回 SDP answer
+	callId := path.Base(resp.Header.Get("Location"))
+	if callId == "" || callId == "." || callId == "/" {
+		return types.NewError(fmt.Errorf("upstream did not return call id, usage cannot be billed"), types.ErrorCodeBadResponseBody, types.ErrOptionWithSkipRetry())
+	}
+	// 旁路连接必须在返回 SDP answer 前建立，失败时挂断通话
+	conn, err := dialRealtimeSideband(c, info, callId)
+	if err != nil {
+		hangupRealtimeCall(c, info, callId)
+		return types.NewError(fmt.Errorf("connect sideband for call %s failed, usage cannot be billed: %w", callId, err), types.ErrorCodeDoRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	info.SetFirstResponseTime()
+	c.Data(resp.StatusCode, "application/sdp", answer)
+
+	ctx := c.Copy()
+	gopool.Go(func() {
+		realtimeSidebandBilling(ctx, info, conn, callId)
+	})
+	return nil
+}
+
+func doRealtimeHttpRequest(c *gin.Context, info *relaycommon.RelayInfo, requestURL string, contentType string, apiKey string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if info.Organization != "" {
+		req.Header.Set("OpenAI-Organization", info.Organization)
+	}
+	client, err := service.GetHttpClientWithProxy(info.ChannelSetting.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+func dialRealtimeSideband(c *gin.Context, info *relaycommon.RelayInfo, callId string) (*websocket.Conn, error) {
+	baseUrl := info.ChannelBaseUrl
+	if strings.HasPrefix(baseUrl, "https://") {
+		baseUrl = "wss://" + strings.TrimPrefix(baseUrl, "https://")
+	} else if strings.HasPrefix(baseUrl, "http://") {
+		baseUrl = "ws://" + strings.TrimPrefix(baseUrl, "http://")
+	}
+	dialer, err := service.GetWebsocketDialerWithProxy(info.ChannelSetting.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+info.ApiKey)
+	conn, _, err := dialer.DialContext(ctx, baseUrl+"/v1/realtime?call_id="+url.QueryEscape(callId), header)
+	return conn, err
+}
+
+// realtimeSidebandBilling 通过旁路 WebSocket 接收 WebRTC 通话的服务端事件，额度不足时挂断通话
+func realtimeSidebandBilling(c *gin.Context, info *relaycommon.RelayInfo, conn *websocket.Conn, callId string) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.LogError(c, fmt.Sprintf("realtime webrtc sideband panic: %v", r))
+		}
+	}()
+	defer conn.Close()
+
+	sumUsage := &dto.RealtimeUsage{}
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.LogWarn(c, fmt.Sprintf("realtime webrtc: sideband for call %s closed: %v", callId, err))
+			}
+			break
+		}
+		event := &dto.RealtimeEvent{}
+		if err := common.Unmarshal(message, event); err != nil {
+			continue
+		}
+		if event.Type != dto.RealtimeEventTypeResponseDone || event.Response == nil || event.Response.Usage == nil {
+			continue
+		}
+		usage := event.Response.Usage
+		sumUsage.TotalTokens += usage.TotalTokens
+		sumUsage.InputTokens += usage.InputTokens
+		sumUsage.OutputTokens += usage.OutputTokens
+		sumUsage.InputTokenDetails.CachedTokens += usage.InputTokenDetails.CachedTokens
+		sumUsage.InputTokenDetails.TextTokens += usage.InputTokenDetails.TextTokens
+		sumUsage.InputTokenDetails.AudioTokens += usage.InputTokenDetails.AudioTokens
+		sumUsage.OutputTokenDetails.TextTokens += usage.OutputTokenDetails.TextTokens
+		sumUsage.OutputTokenDetails.AudioTokens += usage.OutputTokenDetails.AudioTokens
+		if err := service.PreWssConsumeQuota(c, info, usage); err != nil {
+			logger.LogError(c, fmt.Sprintf("realtime webrtc: consume quota for call %s failed, hang up: %v", callId, err))
+			hangupRealtimeCall(c, info, callId)
+			break
+		}
+	}
+	service.PostWssConsumeQuota(c, info, info.UpstreamModelName, sumUsage, "WebRTC")
+}
+
+func hangupRealtimeCall(c *gin.Context, info *relaycommon.RelayInfo, callId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	requestURL := info.ChannelBaseUrl + "/v1/realtime/calls/" + url.PathEscape(callId) + "/hangup"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	client, err := service.GetHttpClientWithProxy(info.ChannelSetting.Proxy)
+	if err != nil {
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("realtime webrtc: hang up call %s failed: %v", callId, err))
+		return
+	}
+	service.CloseResponseBodyGracefully(resp)
+}
@@ -46,6 +46,11 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		info.OriginTaskID = videoID
 	}
 
+	callbackUrl, err := service.ResolveTaskCallbackUrl(c, "")
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_callback_url", http.StatusBadRequest)
+	}
+
 	platform := constant.TaskPlatform(c.GetString("platform"))
 
 	// 获取原始任务信息
@@ -177,35 +182,24 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 			}
 		}
 	}
-	println(fmt.Sprintf("model: %s, model_price: %.4f, group: %s, group_ratio: %.4f, final_ratio: %.4f", modelName, modelPrice, info.UsingGroup, groupRatio, ratio))
-	userQuota, err := model.GetUserQuota(info.UserId, false)
-	if err != nil {
-		taskErr = service.TaskErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
-		return
+	scheduleRatio := ratio_setting.GetScheduleRatio(modelName, info.UsingGroup)
+	if scheduleRatio != nil {
+		ratio *= scheduleRatio.Ratio
 	}
+	println(fmt.Sprintf("model: %s, model_price: %.4f, group: %s, group_ratio: %.4f, final_ratio: %.4f", modelName, modelPrice, info.UsingGroup, groupRatio, ratio))
 	quota := int(ratio * common.QuotaPerUnit)
-	if userQuota-quota < 0 {
-		taskErr = service.TaskErrorWrapperLocal(errors.New("user quota is not enough"), "quota_not_enough", http.StatusForbidden)
-		return
-	}
-
-	// build body
-	requestBody, err := adaptor.BuildRequestBody(c, info)
-	if err != nil {
-		taskErr = service.TaskErrorWrapper(err, "build_request_failed", http.StatusInternalServerError)
-		return
-	}
-	// do request
-	resp, err := adaptor.DoRequest(c, info, requestBody)
-	if err != nil {
-		taskErr = service.TaskErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
-		return
-	}
-	// handle response
-	if resp != nil && resp.StatusCode != http.StatusOK {
-		responseBody, _ := io.ReadAll(resp.Body)
-		taskErr = service.TaskErrorWrapper(fmt.Errorf("%s", string(responseBody)), "fail_to_fetch_task", resp.StatusCode)
-		return
+	// 调度排队任务时额度已在入队时扣除
+	queuedTask, _ := common.GetContextKeyType[*model.Task](c, constant.ContextKeyQueuedTask)
+	if queuedTask == nil {
+		userQuota, err := model.GetUserQuota(info.UserId, false)
+		if err != nil {
+			taskErr = service.TaskErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+			return
+		}
+		if userQuota+info.UserCreditLimit-quota < 0 || !service.DerivedTokenBudgetEnough(info, quota) {
+			taskErr = service.TaskErrorWrapperLocal(errors.New("user quota is not enough"), "quota_not_enough", http.StatusForbidden)
+			return
+		}
 	}
 
 	defer func() {
@@ -248,6 +242,13 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 				if hasUserGroupRatio {
 					other["user_group_ratio"] = userGroupRatio
 				}
+				if scheduleRatio != nil {
+					other["schedule_ratio"] = scheduleRatio.Ratio
+					other["schedule_ratio_name"] = scheduleRatio.Name
+				}
+				if info.SubmitTaskID != "" {
+					other["task_id"] = info.SubmitTaskID
+				}
 				model.RecordConsumeLog(c, info.UserId, model.RecordConsumeLogParams{
 					ChannelId: info.ChannelId,
 					ModelName: modelName,
@@ -264,17 +265,47 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		}
 	}()
 
+	// 渠道并发已满时进入本地队列，由后台在槽位空闲后提交上游
+	if queuedTask == nil && shouldQueueTask(info, platform) {
+		return enqueueTask(c, info, platform, quota, callbackUrl)
+	}
+
+	// build body
+	requestBody, err := adaptor.BuildRequestBody(c, info)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "build_request_failed", http.StatusInternalServerError)
+		return
+	}
+	// do request
+	resp, err := adaptor.DoRequest(c, info, requestBody)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		return
+	}
+	// handle response
+	if resp != nil && resp.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(resp.Body)
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("%s", string(responseBody)), "fail_to_fetch_task", resp.StatusCode)
+		return
+	}
+
 	taskID, taskData, taskErr := adaptor.DoResponse(c, resp, info)
 	if taskErr != nil {
 		return
 	}
-	info.ConsumeQuota = true
 	// insert task
 	task := model.InitTask(platform, info)
 	task.TaskID = taskID
 	task.Quota = quota
 	task.Data = taskData
 	task.Action = info.Action
+	if queuedTask != nil {
+		task.ID = queuedTask.ID
+		return markQueuedTaskSubmitted(c, task)
+	}
+	info.ConsumeQuota = true
+	info.SubmitTaskID = taskID
+	task.SetCallbackUrl(callbackUrl)
 	err = task.Insert()
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError)
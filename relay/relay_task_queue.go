@@ -0,0 +1,135 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskQueueRequest 排队任务的原始请求与上下文，调度时据此重建请求
+type TaskQueueRequest struct {
+	Method      string            `json:"method"`
+	Url         string            `json:"url"`
+	ContentType string            `json:"content_type"`
+	Params      map[string]string `json:"params,omitempty"`
+	Platform    string            `json:"platform"`
+	Action      string            `json:"action,omitempty"`
+	RelayMode   int               `json:"relay_mode,omitempty"`
+	OriginModel string            `json:"origin_model"`
+	TokenId     int               `json:"token_id"` // 仅保存令牌 id，调度时从数据库重新加载令牌
+	TokenName   string            `json:"token_name"`
+	TokenGroup  string            `json:"token_group"`
+	UserGroup   string            `json:"user_group"`
+	UsingGroup  string            `json:"using_group"`
+}
+
+// shouldQueueTask 渠道配置了并发上限且已占满（或已有任务在排队）时需要排队
+func shouldQueueTask(info *relaycommon.RelayInfo, platform constant.TaskPlatform) bool {
+	if !operation_setting.GetTaskQueueSetting().Enabled || platform == constant.TaskPlatformSuno {
+		return false
+	}
+	limit := info.ChannelOtherSettings.TaskConcurrency
+	if limit <= 0 {
+		return false
+	}
+	queued, err := model.CountChannelQueuedTasks(info.ChannelId)
+	if err != nil {
+		return false
+	}
+	if queued > 0 {
+		return true
+	}
+	active, err := model.CountChannelActiveTasks(info.ChannelId)
+	return err == nil && active >= int64(limit)
+}
+
+// enqueueTask 创建排队任务并按正常提交扣费，返回本地任务 id
+func enqueueTask(c *gin.Context, info *relaycommon.RelayInfo, platform constant.TaskPlatform, quota int, callbackUrl string) *dto.TaskError {
+	setting := operation_setting.GetTaskQueueSetting()
+	if setting.MaxQueuedPerUser > 0 {
+		cnt, err := model.CountUserQueuedTasks(info.UserId)
+		if err != nil {
+			return service.TaskErrorWrapper(err, "count_queued_tasks_failed", http.StatusInternalServerError)
+		}
+		if cnt >= int64(setting.MaxQueuedPerUser) {
+			return service.TaskErrorWrapperLocal(errors.New("too many queued tasks, please try again later"), "too_many_queued_tasks", http.StatusForbidden)
+		}
+	}
+
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
+	}
+	req := TaskQueueRequest{
+		Method:      c.Request.Method,
+		Url:         c.Request.URL.RequestURI(),
+		ContentType: c.Request.Header.Get("Content-Type"),
+		Platform:    string(platform),
+		Action:      c.GetString("action"),
+		RelayMode:   c.GetInt("relay_mode"),
+		OriginModel: info.OriginModelName,
+		TokenId:     info.TokenId,
+		TokenName:   c.GetString("token_name"),
+		TokenGroup:  info.TokenGroup,
+		UserGroup:   info.UserGroup,
+		UsingGroup:  info.UsingGroup,
+	}
+	if len(c.Params) > 0 {
+		req.Params = make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			req.Params[p.Key] = p.Value
+		}
+	}
+	reqBytes, err := common.Marshal(req)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "marshal_queue_request_failed", http.StatusInternalServerError)
+	}
+
+	task := model.InitTask(platform, info)
+	task.QueueId = "queue_" + common.GetUUID()
+	task.TaskID = task.QueueId
+	task.Status = model.TaskStatusWaiting
+	task.Quota = quota
+	task.Action = info.Action
+	task.SetCallbackUrl(callbackUrl)
+	item := &model.TaskQueueItem{
+		UserId:    info.UserId,
+		ChannelId: info.ChannelId,
+		Priority:  setting.GetGroupPriority(info.UsingGroup),
+		Request:   string(reqBytes),
+		Body:      body,
+	}
+	if err = model.EnqueueTask(task, item); err != nil {
+		return service.TaskErrorWrapper(err, "enqueue_task_failed", http.StatusInternalServerError)
+	}
+	info.ConsumeQuota = true
+	info.SubmitTaskID = task.TaskID
+
+	ov := task.ToOpenAIVideo()
+	ov.TaskID = task.TaskID
+	c.JSON(http.StatusOK, ov)
+	return nil
+}
+
+// markQueuedTaskSubmitted 排队任务提交上游成功后更新任务，调度期间任务已被取消时仅记录日志
+func markQueuedTaskSubmitted(c *gin.Context, task *model.Task) *dto.TaskError {
+	updated, err := model.MarkQueuedTaskSubmitted(task)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "update_task_failed", http.StatusInternalServerError)
+	}
+	if !updated {
+		logger.LogWarn(c, fmt.Sprintf("queued task #%d was cancelled during dispatch, upstream task %s is orphaned", task.ID, task.TaskID))
+	}
+	return nil
+}
@@ -31,7 +31,11 @@ func WssHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types.
 	}
 
 	if resp != nil {
-		info.TargetWs = resp.(*websocket.Conn)
+		targetWs, ok := resp.(*websocket.Conn)
+		if !ok || targetWs == nil {
+			return types.NewError(fmt.Errorf("channel type %d does not support realtime", info.ChannelType), types.ErrorCodeInvalidApiType, types.ErrOptionWithSkipRetry())
+		}
+		info.TargetWs = targetWs
 		defer info.TargetWs.Close()
 	}
 
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/controller"
 	"github.com/QuantumNous/new-api/middleware"
 
@@ -13,6 +14,22 @@ func SetApiRouter(router *gin.Engine) {
 	apiRouter.Use(gzip.Gzip(gzip.DefaultCompression))
 	apiRouter.Use(middleware.BodyStorageCleanup()) // 清理请求体存储
 	apiRouter.Use(middleware.GlobalAPIRateLimit())
+
+	// 管理接口按资源权限鉴权，见 constant.Permissions
+	channelRead := middleware.PermissionAuth(constant.PermissionChannelRead)
+	channelWrite := middleware.PermissionAuth(constant.PermissionChannelWrite)
+	userRead := middleware.PermissionAuth(constant.PermissionUserRead)
+	userWrite := middleware.PermissionAuth(constant.PermissionUserWrite)
+	billingRead := middleware.PermissionAuth(constant.PermissionBillingRead)
+	billingWrite := middleware.PermissionAuth(constant.PermissionBillingWrite)
+	modelRead := middleware.PermissionAuth(constant.PermissionModelRead)
+	modelWrite := middleware.PermissionAuth(constant.PermissionModelWrite)
+	optionRead := middleware.PermissionAuth(constant.PermissionOptionRead)
+	optionWrite := middleware.PermissionAuth(constant.PermissionOptionWrite)
+	logRead := middleware.PermissionAuth(constant.PermissionLogRead)
+	logWrite := middleware.PermissionAuth(constant.PermissionLogWrite)
+	deploymentRead := middleware.PermissionAuth(constant.PermissionDeploymentRead)
+	deploymentWrite := middleware.PermissionAuth(constant.PermissionDeploymentWrite)
 	{
 		apiRouter.GET("/setup", controller.GetSetup)
 		apiRouter.POST("/setup", controller.PostSetup)
@@ -54,6 +71,7 @@ func SetApiRouter(router *gin.Engine) {
 			userRoute.POST("/register", middleware.CriticalRateLimit(), middleware.TurnstileCheck(), controller.Register)
 			userRoute.POST("/login", middleware.CriticalRateLimit(), middleware.TurnstileCheck(), controller.Login)
 			userRoute.POST("/login/2fa", middleware.CriticalRateLimit(), controller.Verify2FALogin)
+			userRoute.POST("/login/ldap", middleware.CriticalRateLimit(), middleware.TurnstileCheck(), controller.LdapLogin)
 			userRoute.POST("/passkey/login/begin", middleware.CriticalRateLimit(), controller.PasskeyLoginBegin)
 			userRoute.POST("/passkey/login/finish", middleware.CriticalRateLimit(), controller.PasskeyLoginFinish)
 			//userRoute.POST("/tokenlog", middleware.CriticalRateLimit(), controller.TokenLog)
@@ -79,6 +97,8 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/aff", controller.GetAffCode)
 				selfRoute.GET("/topup/info", controller.GetTopUpInfo)
 				selfRoute.GET("/topup/self", controller.GetUserTopUps)
+				selfRoute.GET("/self/statement", controller.GetSelfStatement)
+				selfRoute.GET("/self/postpaid/bills", controller.GetSelfPostpaidBills)
 				selfRoute.POST("/topup", middleware.CriticalRateLimit(), controller.TopUp)
 				selfRoute.POST("/pay", middleware.CriticalRateLimit(), controller.RequestEpay)
 				selfRoute.POST("/amount", controller.RequestAmount)
@@ -101,33 +121,36 @@ func SetApiRouter(router *gin.Engine) {
 			}
 
 			adminRoute := userRoute.Group("/")
-			adminRoute.Use(middleware.AdminAuth())
 			{
-				adminRoute.GET("/", controller.GetAllUsers)
-				adminRoute.GET("/topup", controller.GetAllTopUps)
-				adminRoute.POST("/topup/complete", controller.AdminCompleteTopUp)
-				adminRoute.GET("/search", controller.SearchUsers)
-				adminRoute.GET("/:id", controller.GetUser)
-				adminRoute.POST("/", controller.CreateUser)
-				adminRoute.POST("/manage", controller.ManageUser)
-				adminRoute.PUT("/", controller.UpdateUser)
-				adminRoute.DELETE("/:id", controller.DeleteUser)
-				adminRoute.DELETE("/:id/reset_passkey", controller.AdminResetPasskey)
+				adminRoute.GET("/", userRead, controller.GetAllUsers)
+				adminRoute.GET("/topup", billingRead, controller.GetAllTopUps)
+				adminRoute.POST("/topup/complete", billingWrite, controller.AdminCompleteTopUp)
+				adminRoute.GET("/search", userRead, controller.SearchUsers)
+				adminRoute.GET("/:id", userRead, controller.GetUser)
+				adminRoute.POST("/", userWrite, controller.CreateUser)
+				adminRoute.POST("/manage", userWrite, controller.ManageUser)
+				adminRoute.PUT("/", userWrite, controller.UpdateUser)
+				adminRoute.PUT("/:id/admin_role", middleware.RootAuth(), controller.SetUserAdminRole)
+				adminRoute.DELETE("/:id", userWrite, controller.DeleteUser)
+				adminRoute.DELETE("/:id/reset_passkey", userWrite, controller.AdminResetPasskey)
+				adminRoute.GET("/:id/statement", billingRead, controller.GetUserStatement)
+				adminRoute.POST("/:id/statement/email", billingWrite, controller.SendUserStatement)
+				adminRoute.GET("/postpaid/bills", billingRead, controller.GetAllPostpaidBills)
+				adminRoute.POST("/postpaid/bills/:bill_id/pay", billingWrite, controller.PayPostpaidBill)
 
 				// Admin 2FA routes
-				adminRoute.GET("/2fa/stats", controller.Admin2FAStats)
-				adminRoute.DELETE("/:id/2fa", controller.AdminDisable2FA)
+				adminRoute.GET("/2fa/stats", userRead, controller.Admin2FAStats)
+				adminRoute.DELETE("/:id/2fa", userWrite, controller.AdminDisable2FA)
 			}
 		}
 		optionRoute := apiRouter.Group("/option")
-		optionRoute.Use(middleware.RootAuth())
 		{
-			optionRoute.GET("/", controller.GetOptions)
-			optionRoute.PUT("/", controller.UpdateOption)
-			optionRoute.GET("/channel_affinity_cache", controller.GetChannelAffinityCacheStats)
-			optionRoute.DELETE("/channel_affinity_cache", controller.ClearChannelAffinityCache)
-			optionRoute.POST("/rest_model_ratio", controller.ResetModelRatio)
-			optionRoute.POST("/migrate_console_setting", controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
+			optionRoute.GET("/", optionRead, controller.GetOptions)
+			optionRoute.PUT("/", optionWrite, controller.UpdateOption)
+			optionRoute.GET("/channel_affinity_cache", optionRead, controller.GetChannelAffinityCacheStats)
+			optionRoute.DELETE("/channel_affinity_cache", optionWrite, controller.ClearChannelAffinityCache)
+			optionRoute.POST("/rest_model_ratio", optionWrite, controller.ResetModelRatio)
+			optionRoute.POST("/migrate_console_setting", optionWrite, controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
 		}
 		performanceRoute := apiRouter.Group("/performance")
 		performanceRoute.Use(middleware.RootAuth())
@@ -143,45 +166,54 @@ func SetApiRouter(router *gin.Engine) {
 			ratioSyncRoute.GET("/channels", controller.GetSyncableChannels)
 			ratioSyncRoute.POST("/fetch", controller.FetchUpstreamRatios)
 		}
+		adminRoleRoute := apiRouter.Group("/admin_role")
+		adminRoleRoute.Use(middleware.RootAuth())
+		{
+			adminRoleRoute.GET("/", controller.GetAdminRoles)
+			adminRoleRoute.GET("/permissions", controller.GetAdminPermissions)
+			adminRoleRoute.POST("/", controller.CreateAdminRole)
+			adminRoleRoute.PUT("/", controller.UpdateAdminRole)
+			adminRoleRoute.DELETE("/:id", controller.DeleteAdminRole)
+		}
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.AdminAuth())
 		{
-			channelRoute.GET("/", controller.GetAllChannels)
-			channelRoute.GET("/search", controller.SearchChannels)
-			channelRoute.GET("/models", controller.ChannelListModels)
-			channelRoute.GET("/models_enabled", controller.EnabledListModels)
-			channelRoute.GET("/:id", controller.GetChannel)
-			channelRoute.POST("/:id/key", middleware.RootAuth(), middleware.CriticalRateLimit(), middleware.DisableCache(), middleware.SecureVerificationRequired(), controller.GetChannelKey)
-			channelRoute.GET("/test", controller.TestAllChannels)
-			channelRoute.GET("/test/:id", controller.TestChannel)
-			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
-			channelRoute.GET("/update_balance/:id", controller.UpdateChannelBalance)
-			channelRoute.POST("/", controller.AddChannel)
-			channelRoute.PUT("/", controller.UpdateChannel)
-			channelRoute.DELETE("/disabled", controller.DeleteDisabledChannel)
-			channelRoute.POST("/tag/disabled", controller.DisableTagChannels)
-			channelRoute.POST("/tag/enabled", controller.EnableTagChannels)
-			channelRoute.PUT("/tag", controller.EditTagChannels)
-			channelRoute.DELETE("/:id", controller.DeleteChannel)
-			channelRoute.POST("/batch", controller.DeleteChannelBatch)
-			channelRoute.POST("/fix", controller.FixChannelsAbilities)
-			channelRoute.GET("/fetch_models/:id", controller.FetchUpstreamModels)
-			channelRoute.POST("/fetch_models", controller.FetchModels)
-			channelRoute.POST("/codex/oauth/start", controller.StartCodexOAuth)
-			channelRoute.POST("/codex/oauth/complete", controller.CompleteCodexOAuth)
-			channelRoute.POST("/:id/codex/oauth/start", controller.StartCodexOAuthForChannel)
-			channelRoute.POST("/:id/codex/oauth/complete", controller.CompleteCodexOAuthForChannel)
-			channelRoute.POST("/:id/codex/refresh", controller.RefreshCodexChannelCredential)
-			channelRoute.GET("/:id/codex/usage", controller.GetCodexChannelUsage)
-			channelRoute.POST("/ollama/pull", controller.OllamaPullModel)
-			channelRoute.POST("/ollama/pull/stream", controller.OllamaPullModelStream)
-			channelRoute.DELETE("/ollama/delete", controller.OllamaDeleteModel)
-			channelRoute.GET("/ollama/version/:id", controller.OllamaVersion)
-			channelRoute.POST("/batch/tag", controller.BatchSetChannelTag)
-			channelRoute.GET("/tag/models", controller.GetTagModels)
-			channelRoute.POST("/copy/:id", controller.CopyChannel)
-			channelRoute.POST("/multi_key/manage", controller.ManageMultiKeys)
+			channelRoute.GET("/", channelRead, controller.GetAllChannels)
+			channelRoute.GET("/search", channelRead, controller.SearchChannels)
+			channelRoute.GET("/models", channelRead, controller.ChannelListModels)
+			channelRoute.GET("/models_enabled", channelRead, controller.EnabledListModels)
+			channelRoute.GET("/:id", channelRead, controller.GetChannel)
+			channelRoute.POST("/:id/key", middleware.PermissionAuth(constant.PermissionChannelKey), middleware.CriticalRateLimit(), middleware.DisableCache(), middleware.SecureVerificationRequired(), controller.GetChannelKey)
+			channelRoute.GET("/test", channelWrite, controller.TestAllChannels)
+			channelRoute.GET("/test/:id", channelWrite, controller.TestChannel)
+			channelRoute.GET("/update_balance", channelWrite, controller.UpdateAllChannelsBalance)
+			channelRoute.GET("/update_balance/:id", channelWrite, controller.UpdateChannelBalance)
+			channelRoute.POST("/", channelWrite, controller.AddChannel)
+			channelRoute.PUT("/", channelWrite, controller.UpdateChannel)
+			channelRoute.DELETE("/disabled", channelWrite, controller.DeleteDisabledChannel)
+			channelRoute.POST("/tag/disabled", channelWrite, controller.DisableTagChannels)
+			channelRoute.POST("/tag/enabled", channelWrite, controller.EnableTagChannels)
+			channelRoute.PUT("/tag", channelWrite, controller.EditTagChannels)
+			channelRoute.DELETE("/:id", channelWrite, controller.DeleteChannel)
+			channelRoute.POST("/batch", channelWrite, controller.DeleteChannelBatch)
+			channelRoute.POST("/fix", channelWrite, controller.FixChannelsAbilities)
+			channelRoute.GET("/fetch_models/:id", channelWrite, controller.FetchUpstreamModels)
+			channelRoute.POST("/fetch_models", channelWrite, controller.FetchModels)
+			channelRoute.POST("/codex/oauth/start", channelWrite, controller.StartCodexOAuth)
+			channelRoute.POST("/codex/oauth/complete", channelWrite, controller.CompleteCodexOAuth)
+			channelRoute.POST("/:id/codex/oauth/start", channelWrite, controller.StartCodexOAuthForChannel)
+			channelRoute.POST("/:id/codex/oauth/complete", channelWrite, controller.CompleteCodexOAuthForChannel)
+			channelRoute.POST("/:id/codex/refresh", channelWrite, controller.RefreshCodexChannelCredential)
+			channelRoute.GET("/:id/codex/usage", channelRead, controller.GetCodexChannelUsage)
+			channelRoute.POST("/ollama/pull", channelWrite, controller.OllamaPullModel)
+			channelRoute.POST("/ollama/pull/stream", channelWrite, controller.OllamaPullModelStream)
+			channelRoute.DELETE("/ollama/delete", channelWrite, controller.OllamaDeleteModel)
+			channelRoute.GET("/ollama/version/:id", channelRead, controller.OllamaVersion)
+			channelRoute.POST("/batch/tag", channelWrite, controller.BatchSetChannelTag)
+			channelRoute.GET("/tag/models", channelRead, controller.GetTagModels)
+			channelRoute.POST("/copy/:id", channelWrite, controller.CopyChannel)
+			channelRoute.POST("/multi_key/manage", channelWrite, controller.ManageMultiKeys)
 		}
+		apiRouter.POST("/token/exchange", middleware.CriticalRateLimit(), middleware.TokenAuth(), controller.ExchangeToken)
 		tokenRoute := apiRouter.Group("/token")
 		tokenRoute.Use(middleware.UserAuth())
 		{
@@ -192,41 +224,41 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
 			tokenRoute.POST("/batch", controller.DeleteTokenBatch)
+			tokenRoute.POST("/:id/exchange", controller.ExchangeUserToken)
 		}
 
 		usageRoute := apiRouter.Group("/usage")
 		usageRoute.Use(middleware.CriticalRateLimit())
 		{
 			tokenUsageRoute := usageRoute.Group("/token")
-			tokenUsageRoute.Use(middleware.TokenAuth())
+			tokenUsageRoute.Use(middleware.TokenAuth(), middleware.TokenScope(constant.TokenScopeUsageRead))
 			{
 				tokenUsageRoute.GET("/", controller.GetTokenUsage)
 			}
 		}
 
 		redemptionRoute := apiRouter.Group("/redemption")
-		redemptionRoute.Use(middleware.AdminAuth())
 		{
-			redemptionRoute.GET("/", controller.GetAllRedemptions)
-			redemptionRoute.GET("/search", controller.SearchRedemptions)
-			redemptionRoute.GET("/:id", controller.GetRedemption)
-			redemptionRoute.POST("/", controller.AddRedemption)
-			redemptionRoute.PUT("/", controller.UpdateRedemption)
-			redemptionRoute.DELETE("/invalid", controller.DeleteInvalidRedemption)
-			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
+			redemptionRoute.GET("/", billingRead, controller.GetAllRedemptions)
+			redemptionRoute.GET("/search", billingRead, controller.SearchRedemptions)
+			redemptionRoute.GET("/:id", billingRead, controller.GetRedemption)
+			redemptionRoute.POST("/", billingWrite, controller.AddRedemption)
+			redemptionRoute.PUT("/", billingWrite, controller.UpdateRedemption)
+			redemptionRoute.DELETE("/invalid", billingWrite, controller.DeleteInvalidRedemption)
+			redemptionRoute.DELETE("/:id", billingWrite, controller.DeleteRedemption)
 		}
 		logRoute := apiRouter.Group("/log")
-		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
-		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
-		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/", logRead, controller.GetAllLogs)
+		logRoute.DELETE("/", logWrite, controller.DeleteHistoryLogs)
+		logRoute.GET("/stat", logRead, controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
-		logRoute.GET("/channel_affinity_usage_cache", middleware.AdminAuth(), controller.GetChannelAffinityUsageCacheStats)
-		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/channel_affinity_usage_cache", logRead, controller.GetChannelAffinityUsageCacheStats)
+		logRoute.GET("/search", logRead, controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
 
 		dataRoute := apiRouter.Group("/data")
-		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
+		dataRoute.GET("/", logRead, controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
 
 		logRoute.Use(middleware.CORS())
@@ -234,79 +266,77 @@ func SetApiRouter(router *gin.Engine) {
 			logRoute.GET("/token", controller.GetLogByKey)
 		}
 		groupRoute := apiRouter.Group("/group")
-		groupRoute.Use(middleware.AdminAuth())
 		{
-			groupRoute.GET("/", controller.GetGroups)
+			groupRoute.GET("/", middleware.PermissionAuth(constant.PermissionUserRead, constant.PermissionChannelRead, constant.PermissionBillingRead), controller.GetGroups)
 		}
 
 		prefillGroupRoute := apiRouter.Group("/prefill_group")
-		prefillGroupRoute.Use(middleware.AdminAuth())
 		{
-			prefillGroupRoute.GET("/", controller.GetPrefillGroups)
-			prefillGroupRoute.POST("/", controller.CreatePrefillGroup)
-			prefillGroupRoute.PUT("/", controller.UpdatePrefillGroup)
-			prefillGroupRoute.DELETE("/:id", controller.DeletePrefillGroup)
+			prefillGroupRoute.GET("/", modelRead, controller.GetPrefillGroups)
+			prefillGroupRoute.POST("/", modelWrite, controller.CreatePrefillGroup)
+			prefillGroupRoute.PUT("/", modelWrite, controller.UpdatePrefillGroup)
+			prefillGroupRoute.DELETE("/:id", modelWrite, controller.DeletePrefillGroup)
 		}
 
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
-		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+		mjRoute.GET("/", logRead, controller.GetAllMidjourney)
 
 		taskRoute := apiRouter.Group("/task")
 		{
 			taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserTask)
-			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+			taskRoute.POST("/:task_id/cancel", middleware.UserAuth(), controller.CancelTask)
+			taskRoute.GET("/", logRead, controller.GetAllTask)
+			taskRoute.GET("/reconcile/report", billingRead, controller.GetTaskReconcileReport)
+			taskRoute.POST("/reconcile", billingWrite, controller.RunTaskReconcile)
 		}
 
 		vendorRoute := apiRouter.Group("/vendors")
-		vendorRoute.Use(middleware.AdminAuth())
 		{
-			vendorRoute.GET("/", controller.GetAllVendors)
-			vendorRoute.GET("/search", controller.SearchVendors)
-			vendorRoute.GET("/:id", controller.GetVendorMeta)
-			vendorRoute.POST("/", controller.CreateVendorMeta)
-			vendorRoute.PUT("/", controller.UpdateVendorMeta)
-			vendorRoute.DELETE("/:id", controller.DeleteVendorMeta)
+			vendorRoute.GET("/", modelRead, controller.GetAllVendors)
+			vendorRoute.GET("/search", modelRead, controller.SearchVendors)
+			vendorRoute.GET("/:id", modelRead, controller.GetVendorMeta)
+			vendorRoute.POST("/", modelWrite, controller.CreateVendorMeta)
+			vendorRoute.PUT("/", modelWrite, controller.UpdateVendorMeta)
+			vendorRoute.DELETE("/:id", modelWrite, controller.DeleteVendorMeta)
 		}
 
 		modelsRoute := apiRouter.Group("/models")
-		modelsRoute.Use(middleware.AdminAuth())
 		{
-			modelsRoute.GET("/sync_upstream/preview", controller.SyncUpstreamPreview)
-			modelsRoute.POST("/sync_upstream", controller.SyncUpstreamModels)
-			modelsRoute.GET("/missing", controller.GetMissingModels)
-			modelsRoute.GET("/", controller.GetAllModelsMeta)
-			modelsRoute.GET("/search", controller.SearchModelsMeta)
-			modelsRoute.GET("/:id", controller.GetModelMeta)
-			modelsRoute.POST("/", controller.CreateModelMeta)
-			modelsRoute.PUT("/", controller.UpdateModelMeta)
-			modelsRoute.DELETE("/:id", controller.DeleteModelMeta)
+			modelsRoute.GET("/sync_upstream/preview", modelRead, controller.SyncUpstreamPreview)
+			modelsRoute.POST("/sync_upstream", modelWrite, controller.SyncUpstreamModels)
+			modelsRoute.GET("/missing", modelRead, controller.GetMissingModels)
+			modelsRoute.GET("/", modelRead, controller.GetAllModelsMeta)
+			modelsRoute.GET("/search", modelRead, controller.SearchModelsMeta)
+			modelsRoute.GET("/:id", modelRead, controller.GetModelMeta)
+			modelsRoute.POST("/", modelWrite, controller.CreateModelMeta)
+			modelsRoute.PUT("/", modelWrite, controller.UpdateModelMeta)
+			modelsRoute.DELETE("/:id", modelWrite, controller.DeleteModelMeta)
 		}
 
 		// Deployments (model deployment management)
 		deploymentsRoute := apiRouter.Group("/deployments")
-		deploymentsRoute.Use(middleware.AdminAuth())
 		{
-			deploymentsRoute.GET("/settings", controller.GetModelDeploymentSettings)
-			deploymentsRoute.POST("/settings/test-connection", controller.TestIoNetConnection)
-			deploymentsRoute.GET("/", controller.GetAllDeployments)
-			deploymentsRoute.GET("/search", controller.SearchDeployments)
-			deploymentsRoute.POST("/test-connection", controller.TestIoNetConnection)
-			deploymentsRoute.GET("/hardware-types", controller.GetHardwareTypes)
-			deploymentsRoute.GET("/locations", controller.GetLocations)
-			deploymentsRoute.GET("/available-replicas", controller.GetAvailableReplicas)
-			deploymentsRoute.POST("/price-estimation", controller.GetPriceEstimation)
-			deploymentsRoute.GET("/check-name", controller.CheckClusterNameAvailability)
-			deploymentsRoute.POST("/", controller.CreateDeployment)
+			deploymentsRoute.GET("/settings", deploymentRead, controller.GetModelDeploymentSettings)
+			deploymentsRoute.POST("/settings/test-connection", deploymentWrite, controller.TestIoNetConnection)
+			deploymentsRoute.GET("/", deploymentRead, controller.GetAllDeployments)
+			deploymentsRoute.GET("/search", deploymentRead, controller.SearchDeployments)
+			deploymentsRoute.POST("/test-connection", deploymentWrite, controller.TestIoNetConnection)
+			deploymentsRoute.GET("/hardware-types", deploymentRead, controller.GetHardwareTypes)
+			deploymentsRoute.GET("/locations", deploymentRead, controller.GetLocations)
+			deploymentsRoute.GET("/available-replicas", deploymentRead, controller.GetAvailableReplicas)
+			deploymentsRoute.POST("/price-estimation", deploymentWrite, controller.GetPriceEstimation)
+			deploymentsRoute.GET("/check-name", deploymentRead, controller.CheckClusterNameAvailability)
+			deploymentsRoute.POST("/", deploymentWrite, controller.CreateDeployment)
 
-			deploymentsRoute.GET("/:id", controller.GetDeployment)
-			deploymentsRoute.GET("/:id/logs", controller.GetDeploymentLogs)
-			deploymentsRoute.GET("/:id/containers", controller.ListDeploymentContainers)
-			deploymentsRoute.GET("/:id/containers/:container_id", controller.GetContainerDetails)
-			deploymentsRoute.PUT("/:id", controller.UpdateDeployment)
-			deploymentsRoute.PUT("/:id/name", controller.UpdateDeploymentName)
-			deploymentsRoute.POST("/:id/extend", controller.ExtendDeployment)
-			deploymentsRoute.DELETE("/:id", controller.DeleteDeployment)
+			deploymentsRoute.GET("/:id", deploymentRead, controller.GetDeployment)
+			deploymentsRoute.GET("/:id/logs", deploymentRead, controller.GetDeploymentLogs)
+			deploymentsRoute.GET("/:id/containers", deploymentRead, controller.ListDeploymentContainers)
+			deploymentsRoute.GET("/:id/containers/:container_id", deploymentRead, controller.GetContainerDetails)
+			deploymentsRoute.PUT("/:id", deploymentWrite, controller.UpdateDeployment)
+			deploymentsRoute.PUT("/:id/name", deploymentWrite, controller.UpdateDeploymentName)
+			deploymentsRoute.POST("/:id/extend", deploymentWrite, controller.ExtendDeployment)
+			deploymentsRoute.DELETE("/:id", deploymentWrite, controller.DeleteDeployment)
 		}
 	}
 }
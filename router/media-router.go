@@ -0,0 +1,18 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetMediaRouter 转存媒体文件的访问地址：固定地址仅文件所属用户可访问，访问时跳转到带有效期的签名链接
+func SetMediaRouter(router *gin.Engine) {
+	mediaRouter := router.Group("/media")
+	mediaRouter.Use(middleware.GlobalAPIRateLimit())
+	{
+		mediaRouter.GET("/:id", middleware.MediaAuth(), controller.GetMedia)
+		mediaRouter.GET("/:id/raw", controller.GetMediaRaw)
+	}
+}
@@ -17,7 +17,7 @@ func SetRelayRouter(router *gin.Engine) {
 	router.Use(middleware.StatsMiddleware())
 	// https://platform.openai.com/docs/api-reference/introduction
 	modelsRouter := router.Group("/v1/models")
-	modelsRouter.Use(middleware.TokenAuth())
+	modelsRouter.Use(middleware.TokenAuth(), middleware.TokenScope(constant.TokenScopeModelsList))
 	{
 		modelsRouter.GET("", func(c *gin.Context) {
 			switch {
@@ -41,7 +41,7 @@ func SetRelayRouter(router *gin.Engine) {
 	}
 
 	geminiRouter := router.Group("/v1beta/models")
-	geminiRouter.Use(middleware.TokenAuth())
+	geminiRouter.Use(middleware.TokenAuth(), middleware.TokenScope(constant.TokenScopeModelsList))
 	{
 		geminiRouter.GET("", func(c *gin.Context) {
 			controller.ListModels(c, constant.ChannelTypeGemini)
@@ -49,7 +49,7 @@ func SetRelayRouter(router *gin.Engine) {
 	}
 
 	geminiCompatibleRouter := router.Group("/v1beta/openai/models")
-	geminiCompatibleRouter.Use(middleware.TokenAuth())
+	geminiCompatibleRouter.Use(middleware.TokenAuth(), middleware.TokenScope(constant.TokenScopeModelsList))
 	{
 		geminiCompatibleRouter.GET("", func(c *gin.Context) {
 			controller.ListModels(c, constant.ChannelTypeOpenAI)
@@ -68,9 +68,27 @@ func SetRelayRouter(router *gin.Engine) {
 		// WebSocket 路由（统一到 Relay）
 		wsRouter := relayV1Router.Group("")
 		wsRouter.Use(middleware.Distribute())
-		wsRouter.GET("/realtime", func(c *gin.Context) {
+		wsRouter.GET("/realtime", middleware.TokenScope(constant.TokenScopeRealtime), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIRealtime)
 		})
+		wsRouter.POST("/realtime/sessions", middleware.TokenScope(constant.TokenScopeRealtime), controller.RelayRealtimeSession)
+	}
+	{
+		// WebRTC SDP 交换，使用 /v1/realtime/sessions 签发的临时密钥，渠道由临时密钥确定
+		webrtcRouter := relayV1Router.Group("")
+		webrtcRouter.POST("/realtime", middleware.TokenScope(constant.TokenScopeRealtime), controller.RelayRealtimeCall)
+		webrtcRouter.POST("/realtime/calls", middleware.TokenScope(constant.TokenScopeRealtime), controller.RelayRealtimeCall)
+	}
+	{
+		// Claude 消息批处理，请求由后台执行，不在提交时选择渠道
+		batchRouter := relayV1Router.Group("/messages/batches")
+		batchRouter.Use(middleware.TokenScope(constant.TokenScopeChat))
+		batchRouter.POST("", controller.CreateMessageBatch)
+		batchRouter.GET("", controller.ListMessageBatches)
+		batchRouter.GET("/:id", controller.GetMessageBatch)
+		batchRouter.POST("/:id/cancel", controller.CancelMessageBatch)
+		batchRouter.GET("/:id/results", controller.GetMessageBatchResults)
+		batchRouter.DELETE("/:id", controller.DeleteMessageBatch)
 	}
 	{
 		//http router
@@ -78,73 +96,76 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.Use(middleware.Distribute())
 
 		// claude related routes
-		httpRouter.POST("/messages", func(c *gin.Context) {
+		httpRouter.POST("/messages", middleware.TokenScope(constant.TokenScopeChat), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatClaude)
 		})
+		httpRouter.POST("/messages/count_tokens", middleware.TokenScope(constant.TokenScopeChat), controller.RelayClaudeCountTokens)
 
 		// chat related routes
-		httpRouter.POST("/completions", func(c *gin.Context) {
+		httpRouter.POST("/completions", middleware.TokenScope(constant.TokenScopeChat), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAI)
 		})
-		httpRouter.POST("/chat/completions", func(c *gin.Context) {
+		httpRouter.POST("/chat/completions", middleware.TokenScope(constant.TokenScopeChat), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAI)
 		})
 
 		// response related routes
-		httpRouter.POST("/responses", func(c *gin.Context) {
+		httpRouter.POST("/responses", middleware.TokenScope(constant.TokenScopeChat), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIResponses)
 		})
-		httpRouter.POST("/responses/compact", func(c *gin.Context) {
+		httpRouter.POST("/responses/compact", middleware.TokenScope(constant.TokenScopeChat), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIResponsesCompaction)
 		})
 
 		// image related routes
-		httpRouter.POST("/edits", func(c *gin.Context) {
+		httpRouter.POST("/edits", middleware.TokenScope(constant.TokenScopeImages), func(c *gin.Context) {
+			controller.Relay(c, types.RelayFormatOpenAIImage)
+		})
+		httpRouter.POST("/images/generations", middleware.TokenScope(constant.TokenScopeImages), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIImage)
 		})
-		httpRouter.POST("/images/generations", func(c *gin.Context) {
+		httpRouter.POST("/images/edits", middleware.TokenScope(constant.TokenScopeImages), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIImage)
 		})
-		httpRouter.POST("/images/edits", func(c *gin.Context) {
+		httpRouter.POST("/images/variations", middleware.TokenScope(constant.TokenScopeImages), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIImage)
 		})
 
 		// embedding related routes
-		httpRouter.POST("/embeddings", func(c *gin.Context) {
+		httpRouter.POST("/embeddings", middleware.TokenScope(constant.TokenScopeEmbeddings), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatEmbedding)
 		})
 
 		// audio related routes
-		httpRouter.POST("/audio/transcriptions", func(c *gin.Context) {
+		httpRouter.POST("/audio/transcriptions", middleware.TokenScope(constant.TokenScopeAudio), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIAudio)
 		})
-		httpRouter.POST("/audio/translations", func(c *gin.Context) {
+		httpRouter.POST("/audio/translations", middleware.TokenScope(constant.TokenScopeAudio), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIAudio)
 		})
-		httpRouter.POST("/audio/speech", func(c *gin.Context) {
+		httpRouter.POST("/audio/speech", middleware.TokenScope(constant.TokenScopeAudio), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIAudio)
 		})
 
 		// rerank related routes
-		httpRouter.POST("/rerank", func(c *gin.Context) {
+		httpRouter.POST("/rerank", middleware.TokenScope(constant.TokenScopeEmbeddings), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatRerank)
 		})
 
 		// gemini relay routes
-		httpRouter.POST("/engines/:model/embeddings", func(c *gin.Context) {
+		httpRouter.POST("/engines/:model/embeddings", middleware.TokenScope(constant.TokenScopeEmbeddings), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatGemini)
 		})
-		httpRouter.POST("/models/*path", func(c *gin.Context) {
+		httpRouter.POST("/models/*path", middleware.GeminiTokenScope(), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatGemini)
 		})
 
 		// other relay routes
-		httpRouter.POST("/moderations", func(c *gin.Context) {
+		httpRouter.POST("/moderations", middleware.TokenScope(constant.TokenScopeChat), func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAI)
 		})
 
 		// not implemented
-		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
 		httpRouter.GET("/files", controller.RelayNotImplemented)
 		httpRouter.POST("/files", controller.RelayNotImplemented)
 		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
@@ -166,7 +187,7 @@ func SetRelayRouter(router *gin.Engine) {
 	//relayMjRouter.Use()
 
 	relaySunoRouter := router.Group("/suno")
-	relaySunoRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.TokenAuth(), middleware.TokenScope(constant.TokenScopeTasks), middleware.Distribute())
 	{
 		relaySunoRouter.POST("/submit/:action", controller.RelayTask)
 		relaySunoRouter.POST("/fetch", controller.RelayTask)
@@ -175,6 +196,7 @@ func SetRelayRouter(router *gin.Engine) {
 
 	relayGeminiRouter := router.Group("/v1beta")
 	relayGeminiRouter.Use(middleware.TokenAuth())
+	relayGeminiRouter.Use(middleware.GeminiTokenScope())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
 	relayGeminiRouter.Use(middleware.Distribute())
 	{
@@ -187,7 +209,7 @@ func SetRelayRouter(router *gin.Engine) {
 
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", relay.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.TokenAuth(), middleware.MidjourneyTokenScope(), middleware.Distribute())
 	{
 		relayMjRouter.POST("/submit/action", controller.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", controller.RelayMidjourney)
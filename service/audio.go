@@ -1,9 +1,15 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
+	"math"
 	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
 )
 
 func parseAudio(audioBase64 string, format string) (duration float64, err error) {
@@ -46,3 +52,69 @@ func DecodeBase64AudioData(audioBase64 string) (string, error) {
 
 	return audioBase64, nil
 }
+
+// GetSpeechAudioDuration 计算合成音频时长，pcm 没有文件头，按 16-bit 单声道和采样率推算
+func GetSpeechAudioDuration(ctx context.Context, audio []byte, format string, sampleRate int) (float64, error) {
+	if format == "pcm" {
+		if sampleRate <= 0 {
+			sampleRate = 24000
+		}
+		return float64(len(audio)) / float64(sampleRate*2), nil
+	}
+	return common.GetAudioDuration(ctx, bytes.NewReader(audio), "."+format)
+}
+
+// SetSpeechDurationUsage 按合成音频时长计算补全 tokens：ceil(duration) / 60 * 1000，即每分钟 1000 tokens；
+// 无法获取时长时按音频体积保底估算（每 KB 约 1 token）
+func SetSpeechDurationUsage(ctx context.Context, usage *dto.Usage, audio []byte, format string, sampleRate int) error {
+	duration, err := GetSpeechAudioDuration(ctx, audio, format, sampleRate)
+	completionTokens := 0
+	if err != nil {
+		completionTokens = int(math.Ceil(float64(len(audio)) / 1000.0))
+	} else if duration > 0 {
+		completionTokens = int(math.Round(math.Ceil(duration) / 60.0 * 1000))
+	}
+	usage.PromptTokensDetails.TextTokens = usage.PromptTokens
+	if completionTokens > 0 {
+		usage.CompletionTokens = completionTokens
+		usage.CompletionTokenDetails.AudioTokens = completionTokens
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return err
+}
+
+func containsAudioFormat(formats []string, format string) bool {
+	format = common.NormalizeAudioFormat(format)
+	for _, f := range formats {
+		if common.NormalizeAudioFormat(f) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAudioInputTranscodeFormat 上传格式不在渠道声明的输入格式中时，返回需要转码成的格式；无需或无法转码时返回空
+func GetAudioInputTranscodeFormat(inputFormats []string, uploadFormat string) string {
+	if len(inputFormats) == 0 || containsAudioFormat(inputFormats, uploadFormat) || !common.CanDecodeAudio(uploadFormat) {
+		return ""
+	}
+	for _, f := range inputFormats {
+		if common.CanEncodeAudio(f) {
+			return common.NormalizeAudioFormat(f)
+		}
+	}
+	return ""
+}
+
+// GetAudioOutputTranscodeFormat 请求的格式不在渠道声明的输出格式中时，返回应向上游请求的可解码格式；无需或无法转码时返回空
+func GetAudioOutputTranscodeFormat(outputFormats []string, responseFormat string) string {
+	if len(outputFormats) == 0 || containsAudioFormat(outputFormats, responseFormat) || !common.CanEncodeAudio(responseFormat) {
+		return ""
+	}
+	for _, f := range outputFormats {
+		if common.CanDecodeAudio(f) {
+			return common.NormalizeAudioFormat(f)
+		}
+	}
+	return ""
+}
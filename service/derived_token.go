@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 派生令牌：由父令牌签发的短期无状态 JWT，格式为 sk-dt.<jwt>
+const (
+	DerivedTokenPrefix        = "dt."
+	DerivedTokenDefaultTTL    = 15 * time.Minute
+	DerivedTokenMaxTTL        = time.Hour
+	derivedTokenMaxMetadata   = 16
+	derivedTokenSpendKeyLabel = "derived_token_spend:"
+)
+
+// DerivedTokenClaims 派生令牌载荷，ParentHash 为父令牌的哈希（非凭证，仅用于定位父令牌缓存）
+type DerivedTokenClaims struct {
+	ParentHash string            `json:"ph"`
+	ParentId   int               `json:"pid"`
+	UserId     int               `json:"uid"`
+	MaxSpend   int               `json:"max,omitempty"`
+	Models     []string          `json:"models,omitempty"`
+	Scopes     []string          `json:"scopes,omitempty"`
+	Metadata   map[string]string `json:"meta,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// DerivedTokenRequest 派生令牌申请参数
+type DerivedTokenRequest struct {
+	ExpiresIn int               `json:"expires_in"` // 有效期（秒），默认 15 分钟
+	MaxSpend  int               `json:"max_spend"`  // 最大可消费额度，0 表示以父令牌剩余额度为上限
+	Models    []string          `json:"models"`     // 可用模型子集，留空继承父令牌
+	Scopes    []string          `json:"scopes"`     // 权限范围子集，留空继承父令牌
+	Metadata  map[string]string `json:"metadata"`   // 附加到每次请求日志中的元数据
+}
+
+// DerivedToken 签发结果
+type DerivedToken struct {
+	Id        string `json:"-"`
+	Key       string `json:"key"`
+	ExpiresAt int64  `json:"expires_at"`
+	MaxSpend  int    `json:"max_spend"`
+}
+
+func IsDerivedTokenKey(key string) bool {
+	return strings.HasPrefix(key, DerivedTokenPrefix)
+}
+
+func isSubset(items []string, allowed []string) (string, bool) {
+	allowedMap := make(map[string]bool, len(allowed))
+	for _, item := range allowed {
+		allowedMap[item] = true
+	}
+	for _, item := range items {
+		if !allowedMap[item] {
+			return item, false
+		}
+	}
+	return "", true
+}
+
+// IssueDerivedToken 基于父令牌签发短期派生令牌，额度与模型、权限范围均不超过父令牌
+func IssueDerivedToken(parent *model.Token, req DerivedTokenRequest) (*DerivedToken, error) {
+	if parent.KeyHash == "" {
+		return nil, errors.New("父令牌无效")
+	}
+	if parent.Status != common.TokenStatusEnabled {
+		return nil, errors.New("父令牌状态不可用")
+	}
+	now := time.Now()
+	ttl := DerivedTokenDefaultTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > DerivedTokenMaxTTL {
+		return nil, fmt.Errorf("有效期不能超过 %d 秒", int(DerivedTokenMaxTTL.Seconds()))
+	}
+	expiresAt := now.Add(ttl)
+	if parent.ExpiredTime != -1 && parent.ExpiredTime < expiresAt.Unix() {
+		expiresAt = time.Unix(parent.ExpiredTime, 0)
+	}
+	if !expiresAt.After(now) {
+		return nil, errors.New("父令牌已过期")
+	}
+
+	if req.MaxSpend < 0 {
+		return nil, errors.New("最大消费额度不能为负数")
+	}
+	maxSpend := req.MaxSpend
+	if !parent.UnlimitedQuota {
+		if parent.RemainQuota <= 0 {
+			return nil, errors.New("父令牌额度已用尽")
+		}
+		if maxSpend == 0 || maxSpend > parent.RemainQuota {
+			maxSpend = parent.RemainQuota
+		}
+	}
+
+	if len(req.Models) > 0 && parent.ModelLimitsEnabled {
+		if m, ok := isSubset(req.Models, parent.GetModelLimits()); !ok {
+			return nil, fmt.Errorf("父令牌无权访问模型 %s", m)
+		}
+	}
+	if len(req.Scopes) > 0 {
+		for _, scope := range req.Scopes {
+			if !constant.IsValidTokenScope(scope) {
+				return nil, fmt.Errorf("无效的令牌权限范围: %s", scope)
+			}
+		}
+		if parent.Scopes != "" {
+			if scope, ok := isSubset(req.Scopes, parent.GetScopes()); !ok {
+				return nil, fmt.Errorf("父令牌没有权限范围 %s", scope)
+			}
+		}
+	}
+	if len(req.Metadata) > derivedTokenMaxMetadata {
+		return nil, fmt.Errorf("元数据最多 %d 项", derivedTokenMaxMetadata)
+	}
+
+	claims := DerivedTokenClaims{
+		ParentHash: parent.KeyHash,
+		ParentId:   parent.Id,
+		UserId:     parent.UserId,
+		MaxSpend:   maxSpend,
+		Models:     req.Models,
+		Scopes:     req.Scopes,
+		Metadata:   req.Metadata,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        common.GetUUID(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(model.DerivedTokenSecret())
+	if err != nil {
+		return nil, err
+	}
+	return &DerivedToken{
+		Id:        claims.ID,
+		Key:       "sk-" + DerivedTokenPrefix + signed,
+		ExpiresAt: expiresAt.Unix(),
+		MaxSpend:  maxSpend,
+	}, nil
+}
+
+// ValidateDerivedToken 校验派生令牌签名与有效期，返回应用了派生限制的父令牌副本
+func ValidateDerivedToken(c *gin.Context, key string) (*model.Token, error) {
+	var claims DerivedTokenClaims
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(key, DerivedTokenPrefix), &claims, func(t *jwt.Token) (interface{}, error) {
+		return model.DerivedTokenSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("派生令牌已过期")
+		}
+		return nil, errors.New("无效的派生令牌")
+	}
+
+	parent, err := model.GetTokenByKeyHash(claims.ParentHash, false)
+	if err != nil || parent.Id != claims.ParentId || parent.UserId != claims.UserId {
+		return nil, errors.New("派生令牌的父令牌不存在")
+	}
+	if parent.Status != common.TokenStatusEnabled {
+		return nil, errors.New("父令牌状态不可用")
+	}
+	if parent.ExpiredTime != -1 && parent.ExpiredTime < common.GetTimestamp() {
+		return nil, errors.New("父令牌已过期")
+	}
+	if !parent.UnlimitedQuota && parent.RemainQuota <= 0 {
+		return nil, errors.New("父令牌额度已用尽")
+	}
+
+	token := *parent
+	if claims.MaxSpend > 0 {
+		remain := claims.MaxSpend - GetDerivedTokenSpend(claims.ID)
+		if remain <= 0 {
+			return nil, errors.New("派生令牌额度已用尽")
+		}
+		if token.UnlimitedQuota || token.RemainQuota > remain {
+			token.RemainQuota = remain
+		}
+		token.UnlimitedQuota = false
+	}
+	if len(claims.Models) > 0 {
+		token.ModelLimitsEnabled = true
+		token.ModelLimits = strings.Join(claims.Models, ",")
+	}
+	if len(claims.Scopes) > 0 {
+		token.Scopes = strings.Join(claims.Scopes, ",")
+	}
+
+	common.SetContextKey(c, constant.ContextKeyDerivedTokenId, claims.ID)
+	common.SetContextKey(c, constant.ContextKeyDerivedTokenMaxSpend, claims.MaxSpend)
+	common.SetContextKey(c, constant.ContextKeyDerivedTokenExpiresAt, claims.ExpiresAt.Unix())
+	if len(claims.Metadata) > 0 {
+		common.SetContextKey(c, constant.ContextKeyDerivedTokenMetadata, claims.Metadata)
+	}
+	return &token, nil
+}
+
+func derivedTokenSpendKey(id string) string {
+	return derivedTokenSpendKeyLabel + id
+}
+
+// 额度充足时累加并返回 1，否则返回 0
+var reserveDerivedTokenSpendScript = redis.NewScript(`
+local spend = tonumber(redis.call('GET', KEYS[1]) or '0')
+if spend + tonumber(ARGV[1]) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// GetDerivedTokenSpend 获取派生令牌已消费额度，启用 Redis 时记录在 Redis，否则记录在数据库，多节点共享
+func GetDerivedTokenSpend(id string) int {
+	if common.RedisEnabled {
+		value, err := common.RedisGet(derivedTokenSpendKey(id))
+		if err != nil {
+			return 0
+		}
+		spend, _ := strconv.Atoi(value)
+		return spend
+	}
+	return model.GetDerivedTokenSpend(id)
+}
+
+// ReserveDerivedTokenSpend 预扣费时占用派生令牌额度，超出 max_spend 时返回错误，
+// 占用的额度在结算或返还时通过 AddDerivedTokenSpend 调整
+func ReserveDerivedTokenSpend(id string, maxSpend int, quota int, expiresAt int64) error {
+	if id == "" || quota <= 0 {
+		return nil
+	}
+	if maxSpend <= 0 {
+		AddDerivedTokenSpend(id, quota, expiresAt)
+		return nil
+	}
+	var ok bool
+	if common.RedisEnabled {
+		result, err := reserveDerivedTokenSpendScript.Run(context.Background(), common.RDB,
+			[]string{derivedTokenSpendKey(id)}, quota, maxSpend, int(DerivedTokenMaxTTL.Seconds())).Int()
+		if err != nil {
+			return err
+		}
+		ok = result == 1
+	} else {
+		var err error
+		ok, err = model.ReserveDerivedTokenSpend(id, maxSpend, quota, expiresAt)
+		if err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return fmt.Errorf("派生令牌额度不足, 剩余额度: %s, 需要预扣费额度: %s",
+			logger.FormatQuota(maxSpend-GetDerivedTokenSpend(id)), logger.FormatQuota(quota))
+	}
+	return nil
+}
+
+// DerivedTokenBudgetEnough 按次计费且不预扣费的请求（任务、Midjourney）在提交前检查派生令牌剩余额度
+func DerivedTokenBudgetEnough(relayInfo *relaycommon.RelayInfo, quota int) bool {
+	if relayInfo.DerivedTokenId == "" || relayInfo.DerivedMaxSpend <= 0 {
+		return true
+	}
+	return relayInfo.DerivedMaxSpend-GetDerivedTokenSpend(relayInfo.DerivedTokenId) >= quota
+}
+
+// AddDerivedTokenSpend 累加派生令牌消费额度，quota 为负数时返还，记录保留至派生令牌最长有效期
+func AddDerivedTokenSpend(id string, quota int, expiresAt int64) {
+	if id == "" || quota == 0 {
+		return
+	}
+	if common.RedisEnabled {
+		ctx := context.Background()
+		pipe := common.RDB.TxPipeline()
+		pipe.IncrBy(ctx, derivedTokenSpendKey(id), int64(quota))
+		pipe.Expire(ctx, derivedTokenSpendKey(id), DerivedTokenMaxTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			common.SysLog("failed to record derived token spend: " + err.Error())
+		}
+		return
+	}
+	if err := model.AddDerivedTokenSpend(id, quota, expiresAt); err != nil {
+		common.SysLog("failed to record derived token spend: " + err.Error())
+	}
+}
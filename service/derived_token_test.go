@@ -0,0 +1,216 @@
+package service
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupServiceTestDB 使用内存 SQLite 替换全局 DB，测试结束后恢复
+func setupServiceTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// 内存库每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	origDB, origRedis := model.DB, common.RedisEnabled
+	t.Cleanup(func() {
+		model.DB = origDB
+		common.RedisEnabled = origRedis
+	})
+	model.DB = db
+	common.RedisEnabled = false
+}
+
+func newTestGinContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+func newDerivedTokenParent() *model.Token {
+	parent := &model.Token{
+		Id:                 1,
+		UserId:             7,
+		Status:             common.TokenStatusEnabled,
+		ExpiredTime:        -1,
+		RemainQuota:        1000,
+		ModelLimitsEnabled: true,
+		ModelLimits:        "gpt-4o,gpt-4o-mini",
+	}
+	parent.SetKey("parent-token-key-0123456789")
+	return parent
+}
+
+func signDerivedTokenClaims(t *testing.T, claims DerivedTokenClaims, secret []byte) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	require.NoError(t, err)
+	return DerivedTokenPrefix + signed
+}
+
+func TestIssueDerivedToken_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(parent *model.Token)
+		req     DerivedTokenRequest
+		wantErr string
+	}{
+		{name: "disabled parent", mutate: func(p *model.Token) { p.Status = common.TokenStatusDisabled }, wantErr: "父令牌状态不可用"},
+		{name: "ttl above max", req: DerivedTokenRequest{ExpiresIn: int(DerivedTokenMaxTTL.Seconds()) + 1}, wantErr: "有效期不能超过"},
+		{name: "expired parent", mutate: func(p *model.Token) { p.ExpiredTime = time.Now().Add(-time.Minute).Unix() }, wantErr: "父令牌已过期"},
+		{name: "negative max spend", req: DerivedTokenRequest{MaxSpend: -1}, wantErr: "最大消费额度不能为负数"},
+		{name: "exhausted parent", mutate: func(p *model.Token) { p.RemainQuota = 0 }, wantErr: "父令牌额度已用尽"},
+		{name: "model outside parent", req: DerivedTokenRequest{Models: []string{"o1"}}, wantErr: "父令牌无权访问模型 o1"},
+		{name: "invalid scope", req: DerivedTokenRequest{Scopes: []string{"not-a-scope"}}, wantErr: "无效的令牌权限范围"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := newDerivedTokenParent()
+			if tt.mutate != nil {
+				tt.mutate(parent)
+			}
+			_, err := IssueDerivedToken(parent, tt.req)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestIssueDerivedToken_CapsToParent(t *testing.T) {
+	parent := newDerivedTokenParent()
+	parent.ExpiredTime = time.Now().Add(10 * time.Minute).Unix()
+
+	token, err := IssueDerivedToken(parent, DerivedTokenRequest{ExpiresIn: 3600, MaxSpend: 5000})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token.Key, "sk-"+DerivedTokenPrefix))
+	// 额度与有效期都不能超过父令牌
+	require.Equal(t, parent.RemainQuota, token.MaxSpend)
+	require.Equal(t, parent.ExpiredTime, token.ExpiresAt)
+}
+
+func TestValidateDerivedToken(t *testing.T) {
+	setupServiceTestDB(t, &model.Token{}, &model.DerivedTokenSpend{})
+	parent := newDerivedTokenParent()
+	require.NoError(t, model.DB.Create(parent).Error)
+
+	issued, err := IssueDerivedToken(parent, DerivedTokenRequest{MaxSpend: 100, Models: []string{"gpt-4o"}})
+	require.NoError(t, err)
+	key := strings.TrimPrefix(issued.Key, "sk-")
+
+	validClaims := func() DerivedTokenClaims {
+		return DerivedTokenClaims{
+			ParentHash: parent.KeyHash,
+			ParentId:   parent.Id,
+			UserId:     parent.UserId,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        common.GetUUID(),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+	}
+
+	t.Run("valid token applies restrictions", func(t *testing.T) {
+		token, err := ValidateDerivedToken(newTestGinContext(), key)
+		require.NoError(t, err)
+		require.Equal(t, parent.Id, token.Id)
+		require.Equal(t, 100, token.RemainQuota)
+		require.True(t, token.ModelLimitsEnabled)
+		require.Equal(t, "gpt-4o", token.ModelLimits)
+	})
+
+	tests := []struct {
+		name    string
+		key     func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name: "tampered signature",
+			key: func(t *testing.T) string {
+				// 修改签名中间的字符，末位字符可能只包含填充位
+				i := strings.LastIndex(key, ".") + 10
+				replacement := byte('A')
+				if key[i] == 'A' {
+					replacement = 'B'
+				}
+				return key[:i] + string(replacement) + key[i+1:]
+			},
+			wantErr: "无效的派生令牌",
+		},
+		{
+			name: "tampered payload",
+			key: func(t *testing.T) string {
+				claims := validClaims()
+				claims.MaxSpend = 1 << 30
+				forged := strings.Split(signDerivedTokenClaims(t, claims, []byte("other")), ".")
+				parts := strings.Split(key, ".")
+				// 替换载荷但保留原签名
+				return strings.Join([]string{parts[0], parts[1], forged[2], parts[3]}, ".")
+			},
+			wantErr: "无效的派生令牌",
+		},
+		{
+			name: "wrong secret",
+			key: func(t *testing.T) string {
+				return signDerivedTokenClaims(t, validClaims(), []byte("other-secret"))
+			},
+			wantErr: "无效的派生令牌",
+		},
+		{
+			name: "none algorithm",
+			key: func(t *testing.T) string {
+				signed, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				require.NoError(t, err)
+				return DerivedTokenPrefix + signed
+			},
+			wantErr: "无效的派生令牌",
+		},
+		{
+			name: "expired",
+			key: func(t *testing.T) string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return signDerivedTokenClaims(t, claims, model.DerivedTokenSecret())
+			},
+			wantErr: "派生令牌已过期",
+		},
+		{
+			name: "missing expiry",
+			key: func(t *testing.T) string {
+				claims := validClaims()
+				claims.ExpiresAt = nil
+				return signDerivedTokenClaims(t, claims, model.DerivedTokenSecret())
+			},
+			wantErr: "无效的派生令牌",
+		},
+		{
+			name: "parent of another user",
+			key: func(t *testing.T) string {
+				claims := validClaims()
+				claims.UserId = parent.UserId + 1
+				return signDerivedTokenClaims(t, claims, model.DerivedTokenSecret())
+			},
+			wantErr: "派生令牌的父令牌不存在",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateDerivedToken(newTestGinContext(), tt.key(t))
+			require.Error(t, err)
+			require.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
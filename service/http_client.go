@@ -12,6 +12,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/setting/system_setting"
 
+	"github.com/gorilla/websocket"
 	"golang.org/x/net/proxy"
 )
 
@@ -70,6 +71,24 @@ func GetHttpClientWithProxy(proxyURL string) (*http.Client, error) {
 	return NewProxyHttpClient(proxyURL)
 }
 
+// GetWebsocketDialerWithProxy 返回与渠道 HTTP 客户端使用相同代理与 TLS 配置的 WebSocket 拨号器
+func GetWebsocketDialerWithProxy(proxyURL string) (*websocket.Dialer, error) {
+	client, err := GetHttpClientWithProxy(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	dialer := *websocket.DefaultDialer
+	if client == nil {
+		return &dialer, nil
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok && transport != nil {
+		dialer.Proxy = transport.Proxy
+		dialer.NetDialContext = transport.DialContext
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	return &dialer, nil
+}
+
 // ResetProxyClientCache 清空代理客户端缓存，确保下次使用时重新初始化
 func ResetProxyClientCache() {
 	proxyClientLock.Lock()
@@ -65,6 +65,14 @@ func DecodeBase64FileData(base64String string) (string, string, error) {
 	return mimeType, base64String, nil
 }
 
+// GetImageBase64 获取 URL、data URL 或 base64 图片的类型和base64编码的数据
+func GetImageBase64(image string) (mimeType string, data string, err error) {
+	if strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		return GetImageFromUrl(image)
+	}
+	return DecodeBase64FileData(image)
+}
+
 // GetImageFromUrl 获取图片的类型和base64编码的数据
 func GetImageFromUrl(url string) (mimeType string, data string, err error) {
 	resp, err := DoDownloadRequest(url)
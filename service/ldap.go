@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	ldapSyncBatchSize = 100
+	// 单轮同步待禁用用户超过该数量或超过总数的 10% 时中止，避免目录配置错误导致批量误禁用
+	ldapSyncMaxDisableCount   = 20
+	ldapSyncMaxDisablePercent = 10
+)
+
+var (
+	ldapSyncOnce    sync.Once
+	ldapSyncRunning atomic.Bool
+)
+
+// LdapEntry 目录中的用户信息
+type LdapEntry struct {
+	DN          string
+	Username    string
+	Email       string
+	DisplayName string
+	Groups      []string
+}
+
+func dialLdap(setting *system_setting.LDAPSettings) (*ldap.Conn, error) {
+	if setting.URL == "" {
+		return nil, errors.New("未配置 LDAP 服务器地址")
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: setting.InsecureSkipVerify}
+	if u, err := url.Parse(setting.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+	conn, err := ldap.DialURL(setting.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(10 * time.Second)
+	if setting.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if setting.BindDN != "" {
+		err = conn.Bind(setting.BindDN, setting.BindSecret)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ldapUserFilter 登录时按用户过滤器查找用户
+func ldapUserFilter(setting *system_setting.LDAPSettings, username string) string {
+	return strings.ReplaceAll(setting.UserFilter, "%s", ldap.EscapeFilter(username))
+}
+
+// ldapIdFilter 同步时按 LdapId 来源的用户名属性精确查找用户，LdapId 不一定能匹配用户过滤器
+func ldapIdFilter(setting *system_setting.LDAPSettings, ldapId string) string {
+	if setting.UsernameAttribute == "" {
+		return ldapUserFilter(setting, ldapId)
+	}
+	return "(" + ldap.EscapeFilter(setting.UsernameAttribute) + "=" + ldap.EscapeFilter(ldapId) + ")"
+}
+
+func searchLdapUser(conn *ldap.Conn, setting *system_setting.LDAPSettings, filter string, username string) (*LdapEntry, error) {
+	req := ldap.NewSearchRequest(setting.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		filter, []string{setting.UsernameAttribute, setting.EmailAttribute, setting.DisplayNameAttr, setting.GroupAttribute}, nil)
+	// Base DN 不存在（NoSuchObject）属于配置或目录异常，不能当作用户不存在
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Entries) == 0 {
+		return nil, nil
+	}
+	if len(res.Entries) > 1 {
+		return nil, errors.New("LDAP 用户过滤器匹配到多个用户")
+	}
+	entry := res.Entries[0]
+	ldapEntry := &LdapEntry{
+		DN:          entry.DN,
+		Username:    entry.GetAttributeValue(setting.UsernameAttribute),
+		Email:       entry.GetAttributeValue(setting.EmailAttribute),
+		DisplayName: entry.GetAttributeValue(setting.DisplayNameAttr),
+		Groups:      entry.GetAttributeValues(setting.GroupAttribute),
+	}
+	if ldapEntry.Username == "" {
+		ldapEntry.Username = username
+	}
+	return ldapEntry, nil
+}
+
+// LdapAuthenticate 使用服务账号查找用户后，以用户 DN 和密码绑定校验身份
+func LdapAuthenticate(username string, password string) (*LdapEntry, error) {
+	setting := system_setting.GetLDAPSettings()
+	conn, err := dialLdap(setting)
+	if err != nil {
+		common.SysLog("ldap: connect failed: " + err.Error())
+		return nil, errors.New("无法连接至 LDAP 服务器，请稍后重试！")
+	}
+	defer conn.Close()
+	entry, err := searchLdapUser(conn, setting, ldapUserFilter(setting, username), username)
+	if err != nil {
+		common.SysLog("ldap: search user failed: " + err.Error())
+		return nil, errors.New("LDAP 查询用户失败")
+	}
+	if entry == nil {
+		return nil, errors.New("用户名或密码错误")
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, errors.New("用户名或密码错误")
+	}
+	return entry, nil
+}
+
+// ldapGroupCN 取出组 DN 中的 CN，便于按组名配置映射
+func ldapGroupCN(group string) string {
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return group
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return attr.Value
+		}
+	}
+	return group
+}
+
+// ResolveLdapGroup 按配置的分组映射将目录组转换为本系统分组，未命中时返回空
+func ResolveLdapGroup(groups []string) string {
+	mapping := system_setting.GetLDAPSettings().GroupMapping
+	for _, group := range groups {
+		if mapped, ok := mapping[group]; ok {
+			return mapped
+		}
+		if mapped, ok := mapping[ldapGroupCN(group)]; ok {
+			return mapped
+		}
+	}
+	return ""
+}
+
+// ResolveLdapRole 属于管理员目录组的用户为管理员，返回新角色以及管理员角色是否由目录授予。
+// 未配置管理员目录组时不改变角色；只降级由目录授予的管理员，手动提升的管理员与超级管理员不受目录影响
+func ResolveLdapRole(groups []string, currentRole int, ldapAdmin bool) (int, bool) {
+	adminGroups := system_setting.GetLDAPSettings().AdminGroups
+	if currentRole == common.RoleRootUser || len(adminGroups) == 0 {
+		return currentRole, ldapAdmin
+	}
+	for _, admin := range adminGroups {
+		for _, group := range groups {
+			if strings.EqualFold(group, admin) || strings.EqualFold(ldapGroupCN(group), admin) {
+				if currentRole >= common.RoleAdminUser {
+					return currentRole, ldapAdmin
+				}
+				return common.RoleAdminUser, true
+			}
+		}
+	}
+	if ldapAdmin && currentRole == common.RoleAdminUser {
+		return common.RoleCommonUser, false
+	}
+	return currentRole, ldapAdmin
+}
+
+// StartLdapSyncTask 定期同步 LDAP 用户，禁用目录中已删除的用户并更新分组与角色
+func StartLdapSyncTask() {
+	ldapSyncOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+
+			var lastSync time.Time
+			for range ticker.C {
+				setting := system_setting.GetLDAPSettings()
+				if !setting.Enabled || !setting.SyncEnabled {
+					continue
+				}
+				interval := time.Duration(setting.SyncInterval) * time.Minute
+				if interval < time.Minute {
+					interval = time.Minute
+				}
+				if time.Since(lastSync) < interval {
+					continue
+				}
+				lastSync = time.Now()
+				runLdapSyncOnce()
+			}
+		})
+	})
+}
+
+// ldapSyncDisableExceeded 待禁用用户数量是否超过单轮同步的安全阈值，只禁用一个用户时不受比例限制
+func ldapSyncDisableExceeded(toDisable int, total int) bool {
+	return toDisable > ldapSyncMaxDisableCount || (toDisable > 1 && toDisable*100 > total*ldapSyncMaxDisablePercent)
+}
+
+func runLdapSyncOnce() {
+	if !ldapSyncRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer ldapSyncRunning.Store(false)
+
+	ctx := context.Background()
+	setting := system_setting.GetLDAPSettings()
+	conn, err := dialLdap(setting)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("ldap sync: connect failed: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	// 先收集全部变更，确认待禁用比例正常后再统一写入
+	type ldapSyncChange struct {
+		user      *model.User
+		status    int
+		group     string
+		role      int
+		ldapAdmin bool
+	}
+	var changes []ldapSyncChange
+	var total, toDisable int
+	offset := 0
+	for {
+		users, err := model.GetLdapUsers(offset, ldapSyncBatchSize)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("ldap sync: query users failed: %v", err))
+			return
+		}
+		if len(users) == 0 {
+			break
+		}
+		offset += ldapSyncBatchSize
+		total += len(users)
+
+		for _, user := range users {
+			entry, err := searchLdapUser(conn, setting, ldapIdFilter(setting, user.LdapId), user.LdapId)
+			if err != nil {
+				if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+					// Base DN 不存在时所有查询都会失败，中止本轮同步
+					logger.LogError(ctx, fmt.Sprintf("ldap sync: base dn %s not found, abort: %v", setting.BaseDN, err))
+					return
+				}
+				// 查询出错时不做处理，避免目录服务异常导致误禁用
+				logger.LogWarn(ctx, fmt.Sprintf("ldap sync: search user %s failed: %v", user.LdapId, err))
+				continue
+			}
+			status, group, role, ldapAdmin := user.Status, user.Group, user.Role, user.LdapAdmin
+			if entry == nil {
+				if user.Role != common.RoleRootUser {
+					status = common.UserStatusDisabled
+				}
+			} else {
+				if mapped := ResolveLdapGroup(entry.Groups); mapped != "" {
+					group = mapped
+				}
+				role, ldapAdmin = ResolveLdapRole(entry.Groups, user.Role, user.LdapAdmin)
+			}
+			if status == user.Status && group == user.Group && role == user.Role && ldapAdmin == user.LdapAdmin {
+				continue
+			}
+			if status != user.Status {
+				toDisable++
+			}
+			changes = append(changes, ldapSyncChange{user: user, status: status, group: group, role: role, ldapAdmin: ldapAdmin})
+		}
+	}
+	if ldapSyncDisableExceeded(toDisable, total) {
+		logger.LogError(ctx, fmt.Sprintf("ldap sync: %d of %d users would be disabled, abort without changes", toDisable, total))
+		return
+	}
+
+	var updated, disabled int
+	for _, change := range changes {
+		user := change.user
+		if err := model.UpdateUserDirectoryAttrs(user.Id, change.status, change.group, change.role, change.ldapAdmin); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("ldap sync: update user %d failed: %v", user.Id, err))
+			continue
+		}
+		if change.status != user.Status {
+			disabled++
+			model.RecordLog(user.Id, model.LogTypeSystem, "LDAP 目录中已不存在该用户，账户已禁用")
+		} else {
+			updated++
+		}
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("ldap sync finished: updated=%d disabled=%d", updated, disabled))
+}
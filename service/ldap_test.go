@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveLdapRole(t *testing.T) {
+	setting := system_setting.GetLDAPSettings()
+	orig := setting.AdminGroups
+	t.Cleanup(func() { setting.AdminGroups = orig })
+
+	adminGroup := []string{"cn=admins,ou=groups,dc=example,dc=com"}
+	tests := []struct {
+		name          string
+		adminGroups   []string
+		groups        []string
+		role          int
+		ldapAdmin     bool
+		wantRole      int
+		wantLdapAdmin bool
+	}{
+		{name: "no admin groups keeps admin", groups: nil, role: common.RoleAdminUser, wantRole: common.RoleAdminUser},
+		{name: "no admin groups keeps ldap admin", groups: nil, role: common.RoleAdminUser, ldapAdmin: true, wantRole: common.RoleAdminUser, wantLdapAdmin: true},
+		{name: "member promoted", adminGroups: []string{"admins"}, groups: adminGroup, role: common.RoleCommonUser, wantRole: common.RoleAdminUser, wantLdapAdmin: true},
+		{name: "manual admin stays manual", adminGroups: []string{"admins"}, groups: adminGroup, role: common.RoleAdminUser, wantRole: common.RoleAdminUser},
+		{name: "ldap admin demoted", adminGroups: []string{"admins"}, role: common.RoleAdminUser, ldapAdmin: true, wantRole: common.RoleCommonUser},
+		{name: "manual admin not demoted", adminGroups: []string{"admins"}, role: common.RoleAdminUser, wantRole: common.RoleAdminUser},
+		{name: "root untouched", adminGroups: []string{"admins"}, role: common.RoleRootUser, wantRole: common.RoleRootUser},
+		{name: "common user stays common", adminGroups: []string{"admins"}, role: common.RoleCommonUser, wantRole: common.RoleCommonUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting.AdminGroups = tt.adminGroups
+			role, ldapAdmin := ResolveLdapRole(tt.groups, tt.role, tt.ldapAdmin)
+			require.Equal(t, tt.wantRole, role)
+			require.Equal(t, tt.wantLdapAdmin, ldapAdmin)
+		})
+	}
+}
+
+func TestLdapSyncDisableExceeded(t *testing.T) {
+	tests := []struct {
+		name      string
+		toDisable int
+		total     int
+		want      bool
+	}{
+		{name: "nothing to disable", toDisable: 0, total: 100, want: false},
+		{name: "single user always allowed", toDisable: 1, total: 2, want: false},
+		{name: "within ratio", toDisable: 10, total: 100, want: false},
+		{name: "over ratio", toDisable: 11, total: 100, want: true},
+		{name: "over count", toDisable: ldapSyncMaxDisableCount + 1, total: 10000, want: true},
+		{name: "whole directory gone", toDisable: 5, total: 5, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ldapSyncDisableExceeded(tt.toDisable, tt.total))
+		})
+	}
+}
@@ -31,6 +31,14 @@ func appendRequestPath(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, other
 	}
 }
 
+func appendScheduleRatioInfo(scheduleRatio *types.ScheduleRatioInfo, other map[string]interface{}) {
+	if scheduleRatio == nil || other == nil {
+		return
+	}
+	other["schedule_ratio"] = scheduleRatio.Ratio
+	other["schedule_ratio_name"] = scheduleRatio.Name
+}
+
 func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, modelRatio, groupRatio, completionRatio float64,
 	cacheTokens int, cacheRatio float64, modelPrice float64, userGroupRatio float64) map[string]interface{} {
 	other := make(map[string]interface{})
@@ -42,9 +50,23 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 	other["model_price"] = modelPrice
 	other["user_group_ratio"] = userGroupRatio
 	other["frt"] = float64(relayInfo.FirstResponseTime.UnixMilli() - relayInfo.StartTime.UnixMilli())
+	if len(relayInfo.TokenMetadata) > 0 {
+		other["token_metadata"] = relayInfo.TokenMetadata
+	}
 	if relayInfo.ReasoningEffort != "" {
 		other["reasoning_effort"] = relayInfo.ReasoningEffort
 	}
+	if tier := relayInfo.PriceData.PromptTier; tier != nil {
+		other["prompt_tier"] = tier.MinPromptTokens
+	}
+	if tier := relayInfo.PriceData.VolumeTier; tier != nil {
+		other["volume_tier"] = tier.MinMonthlyQuota
+		other["volume_ratio"] = tier.Ratio
+	}
+	appendScheduleRatioInfo(relayInfo.PriceData.ScheduleRatio, other)
+	if relayInfo.PriceData.IsBatch {
+		other["batch_ratio"] = relayInfo.PriceData.BatchRatio
+	}
 	if relayInfo.IsModelMapped {
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
@@ -156,6 +178,10 @@ func GenerateMjOtherInfo(relayInfo *relaycommon.RelayInfo, priceData types.PerCa
 	if priceData.GroupRatioInfo.HasSpecialRatio {
 		other["user_group_ratio"] = priceData.GroupRatioInfo.GroupSpecialRatio
 	}
+	appendScheduleRatioInfo(priceData.ScheduleRatio, other)
 	appendRequestPath(nil, relayInfo, other)
+	if len(relayInfo.TokenMetadata) > 0 {
+		other["token_metadata"] = relayInfo.TokenMetadata
+	}
 	return other
 }
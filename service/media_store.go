@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// MediaStore 生成结果的持久化存储
+type MediaStore interface {
+	Put(ctx context.Context, key string, contentType string, body io.ReadSeeker, size int64) error
+	Delete(ctx context.Context, key string) error
+	// SignedURL 生成带有效期的访问链接
+	SignedURL(ctx context.Context, obj *model.MediaObject, expire time.Duration) (string, error)
+}
+
+// GetMediaStore 根据驱动获取存储实现，驱动为空时使用当前配置
+func GetMediaStore(driver string) (MediaStore, error) {
+	setting := system_setting.GetMediaStorageSettings()
+	if driver == "" {
+		driver = setting.Driver
+	}
+	switch driver {
+	case system_setting.MediaStorageDriverLocal:
+		if setting.LocalPath == "" {
+			return nil, errors.New("未配置本地存储目录")
+		}
+		return &localMediaStore{root: setting.LocalPath}, nil
+	case system_setting.MediaStorageDriverS3:
+		if setting.S3Endpoint == "" || setting.S3Bucket == "" {
+			return nil, errors.New("未配置 S3 存储地址或存储桶")
+		}
+		return &s3MediaStore{setting: setting}, nil
+	default:
+		return nil, fmt.Errorf("不支持的存储驱动：%s", driver)
+	}
+}
+
+// MediaObjectStableUrl 转存对象对外的固定访问地址
+func MediaObjectStableUrl(obj *model.MediaObject) string {
+	return strings.TrimSuffix(system_setting.ServerAddress, "/") + "/media/" + obj.PublicId
+}
+
+// GetMediaObjectSignedUrl 获取转存对象的临时访问链接，配置 CDN 时返回 CDN 地址
+func GetMediaObjectSignedUrl(ctx context.Context, obj *model.MediaObject) (string, error) {
+	setting := system_setting.GetMediaStorageSettings()
+	if setting.PublicBaseURL != "" {
+		return strings.TrimSuffix(setting.PublicBaseURL, "/") + "/" + obj.StorageKey, nil
+	}
+	store, err := GetMediaStore(obj.Driver)
+	if err != nil {
+		return "", err
+	}
+	expire := time.Duration(setting.LinkExpireMinutes) * time.Minute
+	if expire <= 0 {
+		expire = time.Hour
+	}
+	return store.SignedURL(ctx, obj, expire)
+}
+
+// StoreGeneratedMedia 将同步接口直接返回的文件写入媒体存储，返回固定访问地址
+func StoreGeneratedMedia(ctx context.Context, userId int, group string, sourceType string, sourceId string, data []byte, contentType string) (string, error) {
+	setting := system_setting.GetMediaStorageSettings()
+	if !setting.Enabled {
+		return "", errors.New("未启用媒体转存")
+	}
+	if maxSize := int64(setting.MaxFileSizeMB) << 20; maxSize > 0 && int64(len(data)) > maxSize {
+		return "", fmt.Errorf("file exceeds %d MB", setting.MaxFileSizeMB)
+	}
+	store, err := GetMediaStore("")
+	if err != nil {
+		return "", err
+	}
+
+	obj := &model.MediaObject{
+		PublicId:    common.GetUUID(),
+		UserId:      userId,
+		Group:       group,
+		SourceType:  sourceType,
+		SourceId:    sourceId,
+		Driver:      setting.Driver,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Status:      model.MediaObjectStatusStored,
+		Attempts:    1,
+	}
+	ext := ""
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	obj.StorageKey = time.Now().Format("2006/01/02/") + obj.PublicId + ext
+	if err = store.Put(ctx, obj.StorageKey, contentType, bytes.NewReader(data), obj.Size); err != nil {
+		return "", err
+	}
+	if days := setting.GetRetentionDays(group); days > 0 {
+		obj.ExpiresAt = time.Now().Unix() + int64(days)*86400
+	}
+	if err = obj.Insert(); err != nil {
+		_ = store.Delete(ctx, obj.StorageKey)
+		return "", err
+	}
+	return MediaObjectStableUrl(obj), nil
+}
+
+func mediaLinkSignature(publicId string, expires int64) string {
+	h := hmac.New(sha256.New, []byte(common.CryptoSecret))
+	h.Write([]byte(publicId + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyMediaLinkSignature 校验本地存储访问链接的签名与有效期
+func VerifyMediaLinkSignature(publicId string, expiresStr string, signature string) bool {
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || expires < time.Now().Unix() {
+		return false
+	}
+	return hmac.Equal([]byte(mediaLinkSignature(publicId, expires)), []byte(signature))
+}
+
+type localMediaStore struct {
+	root string
+}
+
+// LocalMediaPath 获取本地存储文件路径
+func LocalMediaPath(key string) string {
+	return filepath.Join(system_setting.GetMediaStorageSettings().LocalPath, filepath.FromSlash(key))
+}
+
+func (s *localMediaStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *localMediaStore) Put(ctx context.Context, key string, contentType string, body io.ReadSeeker, size int64) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(p)
+		return err
+	}
+	return f.Close()
+}
+
+func (s *localMediaStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *localMediaStore) SignedURL(ctx context.Context, obj *model.MediaObject, expire time.Duration) (string, error) {
+	expires := time.Now().Add(expire).Unix()
+	return fmt.Sprintf("%s/raw?expires=%d&signature=%s", MediaObjectStableUrl(obj), expires, mediaLinkSignature(obj.PublicId, expires)), nil
+}
+
+// s3MediaStore S3 兼容存储，使用 SigV4 签名直接调用 REST 接口
+type s3MediaStore struct {
+	setting *system_setting.MediaStorageSettings
+}
+
+func (s *s3MediaStore) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.setting.S3Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if s.setting.S3PathStyle {
+		u.Path = "/" + s.setting.S3Bucket + "/" + key
+	} else {
+		u.Host = s.setting.S3Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+func (s *s3MediaStore) credentials() aws.Credentials {
+	return aws.Credentials{
+		AccessKeyID:     s.setting.S3AccessKeyId,
+		SecretAccessKey: s.setting.S3AccessSecret,
+	}
+}
+
+func (s *s3MediaStore) region() string {
+	if s.setting.S3Region == "" {
+		return "us-east-1"
+	}
+	return s.setting.S3Region
+}
+
+func (s *s3MediaStore) do(ctx context.Context, method string, key string, body io.ReadSeeker, size int64, contentType string) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Body = io.NopCloser(body)
+		req.ContentLength = size
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	err = v4.NewSigner().SignHTTP(ctx, s.credentials(), req, "UNSIGNED-PAYLOAD", "s3", s.region(), time.Now(), func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 %s %s failed: status %d, %s", method, key, resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func (s *s3MediaStore) Put(ctx context.Context, key string, contentType string, body io.ReadSeeker, size int64) error {
+	return s.do(ctx, http.MethodPut, key, body, size, contentType)
+}
+
+func (s *s3MediaStore) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, http.MethodDelete, key, nil, 0, "")
+}
+
+func (s *s3MediaStore) SignedURL(ctx context.Context, obj *model.MediaObject, expire time.Duration) (string, error) {
+	u, err := s.objectURL(obj.StorageKey)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expire/time.Second), 10))
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	signedURL, _, err := v4.NewSigner().PresignHTTP(ctx, s.credentials(), req, "UNSIGNED-PAYLOAD", "s3", s.region(), time.Now(), func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	return signedURL, err
+}
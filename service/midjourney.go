@@ -13,8 +13,10 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -74,6 +76,44 @@ func GetMjRequestModel(relayMode int, midjRequest *dto.MidjourneyRequest) (strin
 	return modelName, nil, true
 }
 
+// GetMjEmulationModel 开启 Midjourney 模拟时返回处理该请求的图片或识图模型，不支持的操作返回空
+func GetMjEmulationModel(userId int, mjModel string, midjRequest *dto.MidjourneyRequest) string {
+	// 模拟生成的图片需要转存，未启用媒体转存时不使用模拟
+	if !setting.MjEmulationEnabled || !system_setting.GetMediaStorageSettings().Enabled {
+		return ""
+	}
+	switch constant.MidjourneyModel2Action[mjModel] {
+	case constant.MjActionImagine:
+		return setting.MjEmulationImageModel
+	case constant.MjActionDescribe:
+		return setting.MjEmulationDescribeModel
+	case constant.MjActionUpscale, constant.MjActionVariation, constant.MjActionLowVariation,
+		constant.MjActionHighVariation, constant.MjActionReRoll:
+		// 原任务由 Midjourney 渠道生成时仍交给原渠道处理
+		taskId := midjRequest.TaskId
+		if taskId == "" && midjRequest.Content != "" {
+			if params := ConvertSimpleChangeParams(midjRequest.Content); params != nil {
+				taskId = params.TaskId
+			}
+		}
+		originTask := model.GetByMJId(userId, taskId)
+		if originTask == nil || IsMidjourneyChannel(originTask.ChannelId) {
+			return ""
+		}
+		return setting.MjEmulationImageModel
+	}
+	return ""
+}
+
+// IsMidjourneyChannel 渠道是否为 Midjourney Proxy 渠道，其余渠道上的任务均为模拟生成
+func IsMidjourneyChannel(channelId int) bool {
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		return true
+	}
+	return channel.Type == constant.ChannelTypeMidjourney || channel.Type == constant.ChannelTypeMidjourneyPlus
+}
+
 func CoverPlusActionToNormalAction(midjRequest *dto.MidjourneyRequest) *dto.MidjourneyResponse {
 	// "customId": "MJ::JOB::upsample::2::3dbbd469-36af-4a0f-8f02-df6c579e7011"
 	customId := midjRequest.CustomId
@@ -257,3 +297,53 @@ func DoMidjourneyHttpRequest(c *gin.Context, timeout time.Duration, fullRequestU
 		Response:   midjResponse,
 	}, responseBody, nil
 }
+
+// CoverMidjourneyTaskDto 将任务记录转换为 Midjourney 查询接口的返回格式
+func CoverMidjourneyTaskDto(originTask *model.Midjourney) (midjourneyTask dto.MidjourneyDto) {
+	midjourneyTask.MjId = originTask.MjId
+	midjourneyTask.Progress = originTask.Progress
+	midjourneyTask.PromptEn = originTask.PromptEn
+	midjourneyTask.State = originTask.State
+	midjourneyTask.SubmitTime = originTask.SubmitTime
+	midjourneyTask.StartTime = originTask.StartTime
+	midjourneyTask.FinishTime = originTask.FinishTime
+	midjourneyTask.ImageUrl = ""
+	if originTask.ImageUrl != "" && setting.MjForwardUrlEnabled {
+		midjourneyTask.ImageUrl = system_setting.ServerAddress + "/mj/image/" + originTask.MjId
+		if originTask.Status != "SUCCESS" {
+			midjourneyTask.ImageUrl += "?rand=" + strconv.FormatInt(time.Now().UnixNano(), 10)
+		}
+	} else {
+		midjourneyTask.ImageUrl = originTask.ImageUrl
+	}
+	if originTask.VideoUrl != "" {
+		midjourneyTask.VideoUrl = originTask.VideoUrl
+	}
+	midjourneyTask.Status = originTask.Status
+	midjourneyTask.FailReason = originTask.FailReason
+	midjourneyTask.Action = originTask.Action
+	midjourneyTask.Description = originTask.Description
+	midjourneyTask.Prompt = originTask.Prompt
+	if originTask.Buttons != "" {
+		var buttons []dto.ActionButton
+		err := json.Unmarshal([]byte(originTask.Buttons), &buttons)
+		if err == nil {
+			midjourneyTask.Buttons = buttons
+		}
+	}
+	if originTask.VideoUrls != "" {
+		var videoUrls []dto.ImgUrls
+		err := json.Unmarshal([]byte(originTask.VideoUrls), &videoUrls)
+		if err == nil {
+			midjourneyTask.VideoUrls = videoUrls
+		}
+	}
+	if originTask.Properties != "" {
+		var properties dto.Properties
+		err := json.Unmarshal([]byte(originTask.Properties), &properties)
+		if err == nil {
+			midjourneyTask.Properties = &properties
+		}
+	}
+	return
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcRelayJWKSCacheTTL      = time.Hour
+	oidcRelayJWKSRefreshMinGap = time.Minute
+	oidcRelayBindingTTL        = 5 * time.Minute
+)
+
+var oidcRelayAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+var oidcRelayJWKS = struct {
+	sync.RWMutex
+	url       string
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}{}
+
+// oidcRelayBinding JWT 主体到用户及其系统令牌的映射缓存，避免每次请求都查询数据库
+type oidcRelayBinding struct {
+	userId   int
+	keyHash  string
+	group    string
+	budget   string
+	expireAt time.Time
+}
+
+var (
+	oidcRelayBindings  sync.Map
+	oidcRelayProvision sync.Mutex
+)
+
+// IsOIDCRelayToken 判断中继请求携带的凭证是否为 JWT
+func IsOIDCRelayToken(key string) bool {
+	if !system_setting.GetOIDCRelaySettings().Enabled {
+		return false
+	}
+	return strings.HasPrefix(key, "eyJ") && strings.Count(key, ".") == 2
+}
+
+func decodeBase64URLInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBase64URLInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URLInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func fetchOIDCRelayJWKS(jwksURL string) (map[string]interface{}, error) {
+	client := http.Client{
+		Timeout: 5 * time.Second,
+	}
+	res, err := client.Get(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned status %d", res.StatusCode)
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			common.SysLog(fmt.Sprintf("oidc relay: skip jwk %s: %v", k.Kid, err))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no usable keys")
+	}
+	return keys, nil
+}
+
+// getOIDCRelayKey 按 kid 获取签名公钥，遇到未知 kid 时刷新 JWKS 以支持 IdP 轮换密钥
+func getOIDCRelayKey(kid string) (interface{}, error) {
+	jwksURL := system_setting.GetOIDCRelaySettings().JWKSURL
+	if jwksURL == "" {
+		return nil, errors.New("未配置 JWKS 地址")
+	}
+	oidcRelayJWKS.RLock()
+	fresh := oidcRelayJWKS.url == jwksURL && time.Since(oidcRelayJWKS.fetchedAt) < oidcRelayJWKSCacheTTL
+	key, ok := oidcRelayJWKS.keys[kid]
+	canRefresh := oidcRelayJWKS.url != jwksURL || time.Since(oidcRelayJWKS.fetchedAt) > oidcRelayJWKSRefreshMinGap
+	oidcRelayJWKS.RUnlock()
+	if fresh && ok {
+		return key, nil
+	}
+	if !fresh || canRefresh {
+		keys, err := fetchOIDCRelayJWKS(jwksURL)
+		if err != nil {
+			common.SysLog("oidc relay: fetch jwks failed: " + err.Error())
+			return nil, errors.New("无法获取 JWKS")
+		}
+		oidcRelayJWKS.Lock()
+		oidcRelayJWKS.url = jwksURL
+		oidcRelayJWKS.keys = keys
+		oidcRelayJWKS.fetchedAt = time.Now()
+		oidcRelayJWKS.Unlock()
+		key, ok = keys[kid]
+	}
+	if !ok {
+		// 只有一个密钥且 JWT 未声明 kid 时直接使用
+		oidcRelayJWKS.RLock()
+		defer oidcRelayJWKS.RUnlock()
+		if kid == "" && len(oidcRelayJWKS.keys) == 1 {
+			for _, k := range oidcRelayJWKS.keys {
+				return k, nil
+			}
+		}
+		return nil, errors.New("未找到 JWT 签名密钥")
+	}
+	return key, nil
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	if name == "" {
+		return ""
+	}
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	if name == "" {
+		return nil
+	}
+	switch v := claims[name].(type) {
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// resolveOIDCRelayGroup 将 IdP 分组映射为本系统分组，只认可显式配置的映射，未命中时返回空并沿用用户的默认分组
+func resolveOIDCRelayGroup(setting *system_setting.OIDCRelaySettings, claims jwt.MapClaims) string {
+	for _, g := range claimStrings(claims, setting.GroupClaim) {
+		if mapped, ok := setting.GroupMapping[g]; ok {
+			return mapped
+		}
+	}
+	return ""
+}
+
+// ValidateOIDCRelayToken 校验 IdP 签发的 JWT，并返回对应用户的系统令牌
+func ValidateOIDCRelayToken(raw string) (*model.Token, error) {
+	token, err := resolveOIDCRelayToken(raw)
+	if err != nil {
+		return nil, err
+	}
+	if token.Status != common.TokenStatusEnabled {
+		return nil, errors.New("该用户的 OIDC 令牌已被禁用")
+	}
+	if !token.UnlimitedQuota && token.RemainQuota <= 0 {
+		return nil, errors.New("JWT 预算已用尽")
+	}
+	return token, nil
+}
+
+func resolveOIDCRelayToken(raw string) (*model.Token, error) {
+	setting := system_setting.GetOIDCRelaySettings()
+	// 未限定 iss 与 aud 时，IdP 为其他客户端签发的 JWT 也能通过校验
+	if setting.Issuer == "" || setting.Audience == "" {
+		return nil, errors.New("未配置 JWT 的 Issuer 与 Audience")
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(oidcRelayAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+		jwt.WithIssuer(setting.Issuer),
+		jwt.WithAudience(setting.Audience),
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return getOIDCRelayKey(kid)
+	}, options...)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("JWT 已过期")
+		}
+		return nil, fmt.Errorf("无效的 JWT: %v", err)
+	}
+
+	subject := claimString(claims, setting.SubjectClaim)
+	if subject == "" {
+		return nil, errors.New("JWT 缺少用户标识")
+	}
+	group := resolveOIDCRelayGroup(setting, claims)
+	budget := claimString(claims, setting.BudgetClaim)
+
+	bindingKey := setting.Issuer + "\n" + subject
+	if v, ok := oidcRelayBindings.Load(bindingKey); ok {
+		binding := v.(*oidcRelayBinding)
+		if binding.group == group && binding.budget == budget && time.Now().Before(binding.expireAt) {
+			token, err := model.GetTokenByKeyHash(binding.keyHash, false)
+			if err == nil {
+				return token, nil
+			}
+		}
+	}
+
+	oidcRelayProvision.Lock()
+	defer oidcRelayProvision.Unlock()
+	user, err := getOrProvisionOIDCRelayUser(setting, claims, setting.Issuer, subject, group)
+	if err != nil {
+		return nil, err
+	}
+	token, err := syncOIDCRelayToken(user, budget)
+	if err != nil {
+		return nil, err
+	}
+	oidcRelayBindings.Store(bindingKey, &oidcRelayBinding{
+		userId:   user.Id,
+		keyHash:  token.KeyHash,
+		group:    group,
+		budget:   budget,
+		expireAt: time.Now().Add(oidcRelayBindingTTL),
+	})
+	return token, nil
+}
+
+func getOrProvisionOIDCRelayUser(setting *system_setting.OIDCRelaySettings, claims jwt.MapClaims, issuer string, subject string, group string) (*model.User, error) {
+	userId, err := model.GetOIDCRelayIdentityUserId(issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+	if userId != 0 {
+		user, err := model.GetUserById(userId, false)
+		if err != nil {
+			return nil, err
+		}
+		if group != "" && user.Group != group {
+			if err := model.UpdateUserGroup(user.Id, group); err != nil {
+				return nil, err
+			}
+			user.Group = group
+		}
+		return user, nil
+	}
+	if !setting.AutoProvision {
+		return nil, errors.New("JWT 对应的用户不存在")
+	}
+	user := model.User{
+		Username: claimString(claims, setting.UsernameClaim),
+	}
+	if user.Username != "" {
+		if exist, err := model.CheckUserExistOrDeleted(user.Username, ""); err != nil || exist {
+			user.Username = ""
+		}
+	}
+	if user.Username == "" {
+		user.Username = "oidc_" + strconv.Itoa(model.GetMaxUserId()+1)
+	}
+	user.Email = claimString(claims, setting.EmailClaim)
+	user.DisplayName = claimString(claims, "name")
+	if user.DisplayName == "" {
+		user.DisplayName = "OIDC User"
+	}
+	if group != "" {
+		user.Group = group
+	}
+	if err := user.Insert(0); err != nil {
+		return nil, err
+	}
+	if err := model.CreateOIDCRelayIdentity(issuer, subject, user.Id); err != nil {
+		return nil, err
+	}
+	common.SysLog(fmt.Sprintf("oidc relay: provisioned user %s for subject %s of %s", user.Username, subject, issuer))
+	return &user, nil
+}
+
+// syncOIDCRelayToken 获取或创建用户的系统令牌，并按 JWT 中的预算声明同步令牌额度
+func syncOIDCRelayToken(user *model.User, budget string) (*model.Token, error) {
+	token, err := model.GetUserTokenByName(user.Id, model.OIDCRelayTokenName)
+	if err != nil {
+		return nil, err
+	}
+	created := token == nil
+	if created {
+		key, err := common.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		token = &model.Token{
+			UserId:         user.Id,
+			Name:           model.OIDCRelayTokenName,
+			CreatedTime:    common.GetTimestamp(),
+			AccessedTime:   common.GetTimestamp(),
+			ExpiredTime:    -1,
+			UnlimitedQuota: true,
+		}
+		token.SetKey(key)
+	}
+
+	unlimited := true
+	remain := token.RemainQuota
+	if budget != "" {
+		amount, err := strconv.ParseFloat(budget, 64)
+		if err != nil || amount < 0 {
+			return nil, errors.New("JWT 预算声明无效")
+		}
+		unlimited = false
+		remain = int(amount*common.QuotaPerUnit) - token.UsedQuota
+		if remain < 0 {
+			remain = 0
+		}
+	}
+
+	if created {
+		token.UnlimitedQuota = unlimited
+		token.RemainQuota = remain
+		if err := token.Insert(); err != nil {
+			return nil, err
+		}
+		return token, nil
+	}
+	if token.UnlimitedQuota != unlimited || token.RemainQuota != remain {
+		token.UnlimitedQuota = unlimited
+		token.RemainQuota = remain
+		if err := token.Update(); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOIDCRelayIssuer   = "https://idp.example.com"
+	testOIDCRelayAudience = "new-api"
+	testOIDCRelayKid      = "test-key"
+)
+
+// setupOIDCRelayTest 启动提供 JWKS 的测试服务并配置中继认证，返回签名私钥
+func setupOIDCRelayTest(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testOIDCRelayKid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	setting := system_setting.GetOIDCRelaySettings()
+	orig := *setting
+	t.Cleanup(func() {
+		*setting = orig
+		oidcRelayJWKS.Lock()
+		oidcRelayJWKS.url, oidcRelayJWKS.keys = "", nil
+		oidcRelayJWKS.Unlock()
+	})
+	setting.Enabled = true
+	setting.Issuer = testOIDCRelayIssuer
+	setting.Audience = testOIDCRelayAudience
+	setting.JWKSURL = server.URL
+	setting.SubjectClaim = "sub"
+	setting.AutoProvision = false
+	return key
+}
+
+func validOIDCRelayClaims(subject string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss": testOIDCRelayIssuer,
+		"aud": testOIDCRelayAudience,
+		"sub": subject,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func signOIDCRelayClaims(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testOIDCRelayKid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestResolveOIDCRelayToken_Rejects(t *testing.T) {
+	key := setupOIDCRelayTest(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		raw     func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name: "expired",
+			raw: func(t *testing.T) string {
+				claims := validOIDCRelayClaims("alice")
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return signOIDCRelayClaims(t, key, claims)
+			},
+			wantErr: "JWT 已过期",
+		},
+		{
+			name: "tampered payload",
+			raw: func(t *testing.T) string {
+				parts := strings.Split(signOIDCRelayClaims(t, key, validOIDCRelayClaims("alice")), ".")
+				forged := strings.Split(signOIDCRelayClaims(t, key, validOIDCRelayClaims("admin")), ".")
+				return parts[0] + "." + forged[1] + "." + parts[2]
+			},
+			wantErr: "无效的 JWT",
+		},
+		{
+			name: "signed by unknown key",
+			raw: func(t *testing.T) string {
+				return signOIDCRelayClaims(t, otherKey, validOIDCRelayClaims("alice"))
+			},
+			wantErr: "无效的 JWT",
+		},
+		{
+			name: "hmac algorithm",
+			raw: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, validOIDCRelayClaims("alice"))
+				token.Header["kid"] = testOIDCRelayKid
+				signed, err := token.SignedString([]byte("secret"))
+				require.NoError(t, err)
+				return signed
+			},
+			wantErr: "无效的 JWT",
+		},
+		{
+			name: "wrong issuer",
+			raw: func(t *testing.T) string {
+				claims := validOIDCRelayClaims("alice")
+				claims["iss"] = "https://evil.example.com"
+				return signOIDCRelayClaims(t, key, claims)
+			},
+			wantErr: "无效的 JWT",
+		},
+		{
+			name: "wrong audience",
+			raw: func(t *testing.T) string {
+				claims := validOIDCRelayClaims("alice")
+				claims["aud"] = "other-client"
+				return signOIDCRelayClaims(t, key, claims)
+			},
+			wantErr: "无效的 JWT",
+		},
+		{
+			name: "missing expiry",
+			raw: func(t *testing.T) string {
+				claims := validOIDCRelayClaims("alice")
+				delete(claims, "exp")
+				return signOIDCRelayClaims(t, key, claims)
+			},
+			wantErr: "无效的 JWT",
+		},
+		{
+			name: "missing subject",
+			raw: func(t *testing.T) string {
+				claims := validOIDCRelayClaims("alice")
+				delete(claims, "sub")
+				return signOIDCRelayClaims(t, key, claims)
+			},
+			wantErr: "JWT 缺少用户标识",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveOIDCRelayToken(tt.raw(t))
+			require.Error(t, err)
+			require.True(t, strings.HasPrefix(err.Error(), tt.wantErr), err.Error())
+		})
+	}
+}
+
+func TestResolveOIDCRelayToken_RequiresIssuerAndAudience(t *testing.T) {
+	key := setupOIDCRelayTest(t)
+	raw := signOIDCRelayClaims(t, key, validOIDCRelayClaims("alice"))
+
+	system_setting.GetOIDCRelaySettings().Audience = ""
+	_, err := resolveOIDCRelayToken(raw)
+	require.EqualError(t, err, "未配置 JWT 的 Issuer 与 Audience")
+}
+
+func TestResolveOIDCRelayToken_Binding(t *testing.T) {
+	key := setupOIDCRelayTest(t)
+	setupServiceTestDB(t, &model.User{}, &model.Token{}, &model.OIDCRelayIdentity{})
+
+	token := &model.Token{
+		UserId:         1,
+		Name:           model.OIDCRelayTokenName,
+		Status:         common.TokenStatusEnabled,
+		ExpiredTime:    -1,
+		UnlimitedQuota: true,
+	}
+	token.SetKey("oidc-relay-token-key-0123456789")
+	require.NoError(t, model.DB.Create(token).Error)
+
+	bindingKey := testOIDCRelayIssuer + "\n" + "alice"
+	oidcRelayBindings.Store(bindingKey, &oidcRelayBinding{
+		userId:   token.UserId,
+		keyHash:  token.KeyHash,
+		expireAt: time.Now().Add(time.Minute),
+	})
+	t.Cleanup(func() { oidcRelayBindings.Delete(bindingKey) })
+
+	resolved, err := resolveOIDCRelayToken(signOIDCRelayClaims(t, key, validOIDCRelayClaims("alice")))
+	require.NoError(t, err)
+	require.Equal(t, token.Id, resolved.Id)
+
+	// 未绑定且未开启自动创建的主体不能通过
+	_, err = resolveOIDCRelayToken(signOIDCRelayClaims(t, key, validOIDCRelayClaims("bob")))
+	require.EqualError(t, err, "JWT 对应的用户不存在")
+}
+
+func TestResolveOIDCRelayGroup(t *testing.T) {
+	setting := &system_setting.OIDCRelaySettings{GroupClaim: "groups"}
+	claims := jwt.MapClaims{"groups": []any{"engineering", "vip"}}
+
+	// 未配置映射时不按同名分组匹配，沿用默认分组
+	require.Equal(t, "", resolveOIDCRelayGroup(setting, claims))
+
+	setting.GroupMapping = map[string]string{"engineering": "default", "admins": "vip"}
+	require.Equal(t, "default", resolveOIDCRelayGroup(setting, claims))
+	require.Equal(t, "", resolveOIDCRelayGroup(setting, jwt.MapClaims{"groups": []any{"vip"}}))
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	postpaidTickInterval = time.Hour
+	postpaidBatchSize    = 100
+)
+
+var (
+	postpaidOnce    sync.Once
+	postpaidRunning atomic.Bool
+)
+
+// StartPostpaidBillingTask 每月初为后付费用户生成上月账单，并定期暂停账单逾期用户的服务
+func StartPostpaidBillingTask() {
+	postpaidOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(postpaidTickInterval)
+			defer ticker.Stop()
+
+			runPostpaidBillingOnce()
+			for range ticker.C {
+				runPostpaidBillingOnce()
+			}
+		})
+	})
+}
+
+func runPostpaidBillingOnce() {
+	setting := operation_setting.GetPostpaidSetting()
+	if !setting.Enabled {
+		return
+	}
+	if !postpaidRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer postpaidRunning.Store(false)
+
+	ctx := context.Background()
+	month := model.PreviousStatementMonth(time.Now())
+	if setting.LastBilledMonth != month {
+		// 先记录出账月份，避免出账过程中重启导致重复通知
+		if err := model.UpdateOption("postpaid_setting.last_billed_month", month); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("postpaid billing: update last billed month failed: %v", err))
+			return
+		}
+		generatePostpaidBills(ctx, month, setting.BillDueDays)
+	}
+	if setting.AutoSuspend {
+		suspendOverdueUsers(ctx)
+	}
+}
+
+func generatePostpaidBills(ctx context.Context, month string, dueDays int) {
+	dueTime := time.Now().AddDate(0, 0, dueDays).Unix()
+	var created, failed int
+	offset := 0
+	for {
+		users, err := model.GetPostpaidUsers(offset, postpaidBatchSize)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("postpaid billing: query users failed: %v", err))
+			return
+		}
+		if len(users) == 0 {
+			break
+		}
+		offset += postpaidBatchSize
+
+		for _, user := range users {
+			bill, err := model.CreatePostpaidBill(user, month, dueTime)
+			if err != nil {
+				failed++
+				logger.LogWarn(ctx, fmt.Sprintf("postpaid billing: create bill for user %d failed: %v", user.Id, err))
+				continue
+			}
+			if bill == nil || bill.Status != model.PostpaidBillStatusUnpaid {
+				continue
+			}
+			created++
+			notifyPostpaidBill(user, bill)
+		}
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("postpaid billing for %s finished: created=%d failed=%d", month, created, failed))
+}
+
+func notifyPostpaidBill(user *model.User, bill *model.PostpaidBill) {
+	prompt := fmt.Sprintf("您的 %s 后付费账单已生成", bill.Month)
+	content := "{{value}}，当月消费 {{value}}，应付欠款 {{value}}，请于 {{value}} 前结清，逾期将暂停服务。"
+	values := []interface{}{prompt, logger.FormatQuota(int(bill.UsageQuota)), logger.FormatQuota(bill.OwedQuota),
+		time.Unix(bill.DueTime, 0).Format("2006-01-02 15:04")}
+	err := NotifyUser(user.Id, user.Email, user.GetSetting(), dto.NewNotify(dto.NotifyTypePostpaidBill, prompt, content, values))
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to send postpaid bill notify to user %d: %s", user.Id, err.Error()))
+	}
+}
+
+func suspendOverdueUsers(ctx context.Context) {
+	userIds, err := model.GetOverdueBillUserIds(common.GetTimestamp())
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("postpaid billing: query overdue bills failed: %v", err))
+		return
+	}
+	for _, userId := range userIds {
+		user, err := model.GetUserById(userId, false)
+		if err != nil || user.BillingSuspended {
+			continue
+		}
+		if err := model.SetUserBillingSuspended(userId, true); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("postpaid billing: suspend user %d failed: %v", userId, err))
+			continue
+		}
+		model.RecordLog(userId, model.LogTypeSystem, "后付费账单逾期未付，服务已暂停")
+		prompt := "您的后付费账单已逾期，服务已暂停"
+		content := "{{value}}，请尽快结清欠款，结清后服务将自动恢复。"
+		err = NotifyUser(userId, user.Email, user.GetSetting(), dto.NewNotify(dto.NotifyTypePostpaidBill, prompt, content, []interface{}{prompt}))
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to send billing suspended notify to user %d: %s", userId, err.Error()))
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
@@ -35,17 +36,25 @@ func PreConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
 	}
-	if userQuota <= 0 {
+	// 后付费用户允许余额透支至 -CreditLimit
+	availableQuota := userQuota + relayInfo.UserCreditLimit
+	if availableQuota <= 0 {
+		if relayInfo.UserCreditLimit > 0 {
+			notifyCreditLimitReached(relayInfo, userQuota)
+			return types.NewErrorWithStatusCode(fmt.Errorf("信用额度已用尽, 当前余额: %s, 信用额度: %s", logger.FormatQuota(userQuota), logger.FormatQuota(relayInfo.UserCreditLimit)), types.ErrorCodeInsufficientUserQuota, http.StatusForbidden, types.ErrOptionWithSkipRetry(), types.ErrOptionWithNoRecordErrorLog())
+		}
 		return types.NewErrorWithStatusCode(fmt.Errorf("用户额度不足, 剩余额度: %s", logger.FormatQuota(userQuota)), types.ErrorCodeInsufficientUserQuota, http.StatusForbidden, types.ErrOptionWithSkipRetry(), types.ErrOptionWithNoRecordErrorLog())
 	}
-	if userQuota-preConsumedQuota < 0 {
-		return types.NewErrorWithStatusCode(fmt.Errorf("预扣费额度失败, 用户剩余额度: %s, 需要预扣费额度: %s", logger.FormatQuota(userQuota), logger.FormatQuota(preConsumedQuota)), types.ErrorCodeInsufficientUserQuota, http.StatusForbidden, types.ErrOptionWithSkipRetry(), types.ErrOptionWithNoRecordErrorLog())
+	if availableQuota-preConsumedQuota < 0 {
+		return types.NewErrorWithStatusCode(fmt.Errorf("预扣费额度失败, 用户可用额度: %s, 需要预扣费额度: %s", logger.FormatQuota(availableQuota), logger.FormatQuota(preConsumedQuota)), types.ErrorCodeInsufficientUserQuota, http.StatusForbidden, types.ErrOptionWithSkipRetry(), types.ErrOptionWithNoRecordErrorLog())
 	}
 
 	trustQuota := common.GetTrustQuota()
 
 	relayInfo.UserQuota = userQuota
-	if userQuota > trustQuota {
+	// 设置了 max_spend 的派生令牌不信任额度，始终预扣费以占用派生令牌额度
+	derivedBudget := relayInfo.DerivedTokenId != "" && relayInfo.DerivedMaxSpend > 0
+	if availableQuota > trustQuota && !derivedBudget {
 		// 用户额度充足，判断令牌额度是否充足
 		if !relayInfo.TokenUnlimited {
 			// 非无限令牌，判断令牌额度是否充足
@@ -72,8 +81,21 @@ func PreConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		if err != nil {
 			return types.NewError(err, types.ErrorCodeUpdateDataError, types.ErrOptionWithSkipRetry())
 		}
-		logger.LogInfo(c, fmt.Sprintf("用户 %d 预扣费 %s, 预扣费后剩余额度: %s", relayInfo.UserId, logger.FormatQuota(preConsumedQuota), logger.FormatQuota(userQuota-preConsumedQuota)))
+		logger.LogInfo(c, fmt.Sprintf("用户 %d 预扣费 %s, 预扣费后剩余额度: %s", relayInfo.UserId, logger.FormatQuota(preConsumedQuota), logger.FormatQuota(availableQuota-preConsumedQuota)))
 	}
 	relayInfo.FinalPreConsumedQuota = preConsumedQuota
 	return nil
 }
+
+// notifyCreditLimitReached 通知后付费用户信用额度已用尽，服务已暂停
+func notifyCreditLimitReached(relayInfo *relaycommon.RelayInfo, userQuota int) {
+	gopool.Go(func() {
+		prompt := "您的信用额度已用尽，服务已暂停"
+		content := "{{value}}，当前余额为 {{value}}，信用额度为 {{value}}，请结清欠款或联系管理员调整信用额度。"
+		values := []interface{}{prompt, logger.FormatQuota(userQuota), logger.FormatQuota(relayInfo.UserCreditLimit)}
+		err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NewNotify(dto.NotifyTypeCreditLimit, prompt, content, values))
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to send credit limit notify to user %d: %s", relayInfo.UserId, err.Error()))
+		}
+	})
+}
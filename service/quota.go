@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"strings"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
@@ -37,6 +36,8 @@ type QuotaInfo struct {
 	ModelPrice    float64
 	ModelRatio    float64
 	GroupRatio    float64
+	// PriceMultiplier 用量阶梯、分时段、批处理等叠加倍率，0 表示不叠加
+	PriceMultiplier float64
 }
 
 func hasCustomModelRatio(modelName string, currentRatio float64) bool {
@@ -53,7 +54,7 @@ func calculateAudioQuota(info QuotaInfo) int {
 		quotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
 		groupRatio := decimal.NewFromFloat(info.GroupRatio)
 
-		quota := modelPrice.Mul(quotaPerUnit).Mul(groupRatio)
+		quota := modelPrice.Mul(quotaPerUnit).Mul(groupRatio).Mul(priceMultiplier(info))
 		return int(quota.IntPart())
 	}
 
@@ -63,7 +64,7 @@ func calculateAudioQuota(info QuotaInfo) int {
 
 	groupRatio := decimal.NewFromFloat(info.GroupRatio)
 	modelRatio := decimal.NewFromFloat(info.ModelRatio)
-	ratio := groupRatio.Mul(modelRatio)
+	ratio := groupRatio.Mul(modelRatio).Mul(priceMultiplier(info))
 
 	inputTextTokens := decimal.NewFromInt(int64(info.InputDetails.TextTokens))
 	outputTextTokens := decimal.NewFromInt(int64(info.OutputDetails.TextTokens))
@@ -86,6 +87,13 @@ func calculateAudioQuota(info QuotaInfo) int {
 	return int(quota.Round(0).IntPart())
 }
 
+func priceMultiplier(info QuotaInfo) decimal.Decimal {
+	if info.PriceMultiplier <= 0 {
+		return decimal.NewFromInt(1)
+	}
+	return decimal.NewFromFloat(info.PriceMultiplier)
+}
+
 func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.RealtimeUsage) error {
 	if relayInfo.UsePrice {
 		return nil
@@ -95,7 +103,7 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 		return err
 	}
 
-	token, err := model.GetTokenByKey(strings.TrimPrefix(relayInfo.TokenKey, "sk-"), false)
+	token, err := model.GetTokenByKeyHash(relayInfo.TokenKeyHash, false)
 	if err != nil {
 		return err
 	}
@@ -130,15 +138,16 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 			TextTokens:  textOutTokens,
 			AudioTokens: audioOutTokens,
 		},
-		ModelName:  modelName,
-		UsePrice:   relayInfo.UsePrice,
-		ModelRatio: modelRatio,
-		GroupRatio: actualGroupRatio,
+		ModelName:       modelName,
+		UsePrice:        relayInfo.UsePrice,
+		ModelRatio:      modelRatio,
+		GroupRatio:      actualGroupRatio,
+		PriceMultiplier: relayInfo.PriceData.GetPriceMultiplier(),
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
 
-	if userQuota < quota {
+	if userQuota+relayInfo.UserCreditLimit < quota {
 		return fmt.Errorf("user quota is not enough, user quota: %s, need quota: %s", logger.FormatQuota(userQuota), logger.FormatQuota(quota))
 	}
 
@@ -183,10 +192,11 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 			TextTokens:  textOutTokens,
 			AudioTokens: audioOutTokens,
 		},
-		ModelName:  modelName,
-		UsePrice:   usePrice,
-		ModelRatio: modelRatio,
-		GroupRatio: groupRatio,
+		ModelName:       modelName,
+		UsePrice:        usePrice,
+		ModelRatio:      modelRatio,
+		GroupRatio:      groupRatio,
+		PriceMultiplier: relayInfo.PriceData.GetPriceMultiplier(),
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
@@ -269,6 +279,13 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		promptTokens -= cacheCreationTokens
 	}
 
+	// 按实际提示词长度重新选择上下文阶梯
+	relayInfo.PriceData.ApplyPromptTier(promptTokens + cacheTokens + cacheCreationTokens)
+	completionRatio = relayInfo.PriceData.CompletionRatio
+	modelRatio = relayInfo.PriceData.ModelRatio
+	cacheRatio = relayInfo.PriceData.CacheRatio
+	extraRatio := relayInfo.PriceData.GetPriceMultiplier()
+
 	calculateQuota := 0.0
 	if !relayInfo.PriceData.UsePrice {
 		calculateQuota = float64(promptTokens)
@@ -280,9 +297,9 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			calculateQuota += float64(remainingCacheCreationTokens) * cacheCreationRatio
 		}
 		calculateQuota += float64(completionTokens) * completionRatio
-		calculateQuota = calculateQuota * groupRatio * modelRatio
+		calculateQuota = calculateQuota * groupRatio * modelRatio * extraRatio
 	} else {
-		calculateQuota = modelPrice * common.QuotaPerUnit * groupRatio
+		calculateQuota = modelPrice * common.QuotaPerUnit * groupRatio * extraRatio
 	}
 
 	if modelRatio != 0 && calculateQuota <= 0 {
@@ -402,10 +419,11 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 			TextTokens:  textOutTokens,
 			AudioTokens: audioOutTokens,
 		},
-		ModelName:  relayInfo.OriginModelName,
-		UsePrice:   usePrice,
-		ModelRatio: modelRatio,
-		GroupRatio: groupRatio,
+		ModelName:       relayInfo.OriginModelName,
+		UsePrice:        usePrice,
+		ModelRatio:      modelRatio,
+		GroupRatio:      groupRatio,
+		PriceMultiplier: relayInfo.PriceData.GetPriceMultiplier(),
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
@@ -487,15 +505,20 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	//if relayInfo.TokenUnlimited {
 	//	return nil
 	//}
-	token, err := model.GetTokenByKey(relayInfo.TokenKey, false)
+	token, err := model.GetTokenByKeyHash(relayInfo.TokenKeyHash, false)
 	if err != nil {
 		return err
 	}
 	if !relayInfo.TokenUnlimited && token.RemainQuota < quota {
 		return fmt.Errorf("token quota is not enough, token remain quota: %s, need quota: %s", logger.FormatQuota(token.RemainQuota), logger.FormatQuota(quota))
 	}
-	err = model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, quota)
+	// 派生令牌先占用自身额度，返还预扣费时经 PostConsumeQuota 释放
+	if err := ReserveDerivedTokenSpend(relayInfo.DerivedTokenId, relayInfo.DerivedMaxSpend, quota, relayInfo.DerivedExpiresAt); err != nil {
+		return err
+	}
+	err = model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKeyHash, quota)
 	if err != nil {
+		AddDerivedTokenSpend(relayInfo.DerivedTokenId, -quota, relayInfo.DerivedExpiresAt)
 		return err
 	}
 	return nil
@@ -514,13 +537,14 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 
 	if !relayInfo.IsPlayground {
 		if quota > 0 {
-			err = model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, quota)
+			err = model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKeyHash, quota)
 		} else {
-			err = model.IncreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, -quota)
+			err = model.IncreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKeyHash, -quota)
 		}
 		if err != nil {
 			return err
 		}
+		AddDerivedTokenSpend(relayInfo.DerivedTokenId, quota, relayInfo.DerivedExpiresAt)
 	}
 
 	if sendEmail {
@@ -543,11 +567,15 @@ func checkAndSendQuotaNotify(relayInfo *relaycommon.RelayInfo, quota int, preCon
 		//noMoreQuota := userCache.Quota-(quota+preConsumedQuota) <= 0
 		quotaTooLow := false
 		consumeQuota := quota + preConsumedQuota
-		if relayInfo.UserQuota-consumeQuota < threshold {
+		// 后付费用户以剩余信用额度（余额 + 信用额度）判断
+		if relayInfo.UserQuota+relayInfo.UserCreditLimit-consumeQuota < threshold {
 			quotaTooLow = true
 		}
 		if quotaTooLow {
 			prompt := "您的额度即将用尽"
+			if relayInfo.UserCreditLimit > 0 {
+				prompt = "您的信用额度即将用尽"
+			}
 			topUpLink := fmt.Sprintf("%s/console/topup", system_setting.ServerAddress)
 
 			// 根据通知方式生成不同的内容格式
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const realtimeSessionKeyLabel = "realtime_session:"
+
+// RealtimeEphemeralSession WebRTC 临时密钥对应的上游会话，以派生令牌 ID 为键保存在服务端，上游临时密钥不下发给客户端
+type RealtimeEphemeralSession struct {
+	ChannelId     int    `json:"channel_id"`
+	KeyIndex      int    `json:"key_index"`
+	OriginModel   string `json:"origin_model"`
+	UpstreamModel string `json:"upstream_model"`
+	ClientSecret  string `json:"client_secret"`
+	ExpiresAt     int64  `json:"expires_at"`
+}
+
+var realtimeSessions = struct {
+	sync.Mutex
+	sessions map[string]*RealtimeEphemeralSession
+}{sessions: map[string]*RealtimeEphemeralSession{}}
+
+func realtimeSessionKey(id string) string {
+	return realtimeSessionKeyLabel + id
+}
+
+// SaveRealtimeEphemeralSession 保存临时密钥会话，启用 Redis 时多节点共享
+func SaveRealtimeEphemeralSession(id string, session *RealtimeEphemeralSession) error {
+	ttl := time.Until(time.Unix(session.ExpiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	if common.RedisEnabled {
+		data, err := common.Marshal(session)
+		if err != nil {
+			return err
+		}
+		return common.RedisSet(realtimeSessionKey(id), string(data), ttl)
+	}
+	now := time.Now().Unix()
+	realtimeSessions.Lock()
+	defer realtimeSessions.Unlock()
+	for key, s := range realtimeSessions.sessions {
+		if s.ExpiresAt < now {
+			delete(realtimeSessions.sessions, key)
+		}
+	}
+	realtimeSessions.sessions[id] = session
+	return nil
+}
+
+// GetRealtimeEphemeralSession 获取未过期的临时密钥会话
+func GetRealtimeEphemeralSession(id string) (*RealtimeEphemeralSession, bool) {
+	if id == "" {
+		return nil, false
+	}
+	if common.RedisEnabled {
+		value, err := common.RedisGet(realtimeSessionKey(id))
+		if err != nil {
+			return nil, false
+		}
+		session := &RealtimeEphemeralSession{}
+		if err := common.UnmarshalJsonStr(value, session); err != nil {
+			return nil, false
+		}
+		return session, true
+	}
+	realtimeSessions.Lock()
+	defer realtimeSessions.Unlock()
+	session, ok := realtimeSessions.sessions[id]
+	if !ok || session.ExpiresAt < time.Now().Unix() {
+		return nil, false
+	}
+	return session, true
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// StatementSummary 账单金额汇总，金额单位为美元
+type StatementSummary struct {
+	Usage    float64 `json:"usage"`
+	Refund   float64 `json:"refund"`
+	Subtotal float64 `json:"subtotal"`
+	TaxRate  float64 `json:"tax_rate"`
+	Tax      float64 `json:"tax"`
+	Total    float64 `json:"total"`
+}
+
+func quotaToMoney(quota int64) float64 {
+	if common.QuotaPerUnit == 0 {
+		return 0
+	}
+	return float64(quota) / common.QuotaPerUnit
+}
+
+func formatMoney(amount float64) string {
+	return fmt.Sprintf("%.4f", amount)
+}
+
+func formatStatementTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
+}
+
+// SummarizeStatement 根据账单配置的税率计算应付金额
+func SummarizeStatement(statement *model.UserStatement) StatementSummary {
+	taxRate := operation_setting.GetStatementSetting().TaxRate
+	summary := StatementSummary{
+		Usage:   quotaToMoney(statement.UsageQuota),
+		Refund:  quotaToMoney(statement.RefundQuota),
+		TaxRate: taxRate,
+	}
+	summary.Subtotal = summary.Usage - summary.Refund
+	if summary.Subtotal < 0 {
+		summary.Subtotal = 0
+	}
+	summary.Tax = summary.Subtotal * taxRate
+	summary.Total = summary.Subtotal + summary.Tax
+	return summary
+}
+
+// statementRows 将账单展开为行，CSV 与 PDF 共用，空行用于分隔区块
+func statementRows(statement *model.UserStatement) [][]string {
+	setting := operation_setting.GetStatementSetting()
+	summary := SummarizeStatement(statement)
+	rows := make([][]string, 0, len(statement.Items)+32)
+
+	rows = append(rows, []string{"Statement", statement.Month})
+	if setting.CompanyName != "" {
+		rows = append(rows, []string{"Company", setting.CompanyName})
+	}
+	if setting.CompanyAddress != "" {
+		rows = append(rows, []string{"Address", setting.CompanyAddress})
+	}
+	if setting.CompanyTaxId != "" {
+		rows = append(rows, []string{"Tax ID", setting.CompanyTaxId})
+	}
+	if setting.CompanyEmail != "" {
+		rows = append(rows, []string{"Contact", setting.CompanyEmail})
+	}
+	rows = append(rows, []string{"Customer", statement.Username, statement.Email})
+	rows = append(rows, []string{"Period", formatStatementTime(statement.StartTime), formatStatementTime(statement.EndTime - 1)})
+	rows = append(rows, []string{})
+
+	rows = append(rows, []string{"Model", "Requests", "Prompt Tokens", "Completion Tokens", "Amount"})
+	for _, item := range statement.Items {
+		rows = append(rows, []string{
+			item.ModelName,
+			fmt.Sprintf("%d", item.Requests),
+			fmt.Sprintf("%d", item.PromptTokens),
+			fmt.Sprintf("%d", item.CompletionTokens),
+			formatMoney(quotaToMoney(item.Quota)),
+		})
+	}
+	rows = append(rows, []string{})
+
+	if len(statement.TopUps) > 0 {
+		rows = append(rows, []string{"Top-up", "Payment Method", "Amount", "Paid", "Time"})
+		for _, topUp := range statement.TopUps {
+			rows = append(rows, []string{
+				topUp.TradeNo,
+				topUp.PaymentMethod,
+				fmt.Sprintf("%d", topUp.Amount),
+				fmt.Sprintf("%.2f", topUp.Money),
+				formatStatementTime(topUp.CompleteTime),
+			})
+		}
+		rows = append(rows, []string{})
+	}
+	if len(statement.Redemptions) > 0 {
+		rows = append(rows, []string{"Redemption", "Name", "Amount", "Time"})
+		for _, redemption := range statement.Redemptions {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", redemption.Id),
+				redemption.Name,
+				formatMoney(quotaToMoney(int64(redemption.Quota))),
+				formatStatementTime(redemption.RedeemedTime),
+			})
+		}
+		rows = append(rows, []string{})
+	}
+	if len(statement.Checkins) > 0 {
+		rows = append(rows, []string{"Check-in Rewards", fmt.Sprintf("%d", len(statement.Checkins)), formatMoney(quotaToMoney(statement.CheckinQuota))})
+		rows = append(rows, []string{})
+	}
+
+	rows = append(rows, []string{"Usage", formatMoney(summary.Usage)})
+	rows = append(rows, []string{"Refunds", formatMoney(summary.Refund)})
+	rows = append(rows, []string{"Subtotal", formatMoney(summary.Subtotal)})
+	rows = append(rows, []string{fmt.Sprintf("Tax (%.2f%%)", summary.TaxRate*100), formatMoney(summary.Tax)})
+	rows = append(rows, []string{"Total", formatMoney(summary.Total)})
+	return rows
+}
+
+// RenderStatementCSV 生成 CSV 格式账单
+func RenderStatementCSV(statement *model.UserStatement) ([]byte, error) {
+	var buf bytes.Buffer
+	// 写入 BOM，方便 Excel 正确识别 UTF-8
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(statementRows(statement)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderStatementPDF 生成 PDF 格式账单
+func RenderStatementPDF(statement *model.UserStatement) []byte {
+	rows := statementRows(statement)
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, "    "))
+	}
+	return renderSimplePDF(lines)
+}
+
+// RenderStatementHTML 生成邮件正文
+func RenderStatementHTML(statement *model.UserStatement) string {
+	var b strings.Builder
+	b.WriteString("<table border=\"1\" cellpadding=\"4\" cellspacing=\"0\" style=\"border-collapse:collapse\">")
+	for _, row := range statementRows(statement) {
+		if len(row) == 0 {
+			b.WriteString("<tr><td colspan=\"5\">&nbsp;</td></tr>")
+			continue
+		}
+		b.WriteString("<tr>")
+		for _, cell := range row {
+			b.WriteString("<td>")
+			b.WriteString(html.EscapeString(cell))
+			b.WriteString("</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// 简单的纯文本 PDF 生成。默认使用阅读器内置的 Adobe 标准中文字体 STSong-Light，
+// 配置 STATEMENT_PDF_FONT_FILE（TrueType 字体文件）时将该字体嵌入 PDF，保证在任何阅读器中都能显示中文
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 40
+	pdfFontSize   = 9
+	pdfLineHeight = 13
+	// 每行最大宽度，单位为字号的千分之一
+	pdfMaxLineWidth = (pdfPageWidth - 2*pdfMargin) * 1000 / pdfFontSize
+)
+
+// pdfEmbeddedFont 嵌入的 TrueType 字体，按字形编号（Identity-H）写入文本
+type pdfEmbeddedFont struct {
+	data       []byte
+	font       *sfnt.Font
+	unitsPerEm int
+}
+
+var (
+	pdfFontOnce     sync.Once
+	pdfFontEmbedded *pdfEmbeddedFont
+)
+
+func getPDFEmbeddedFont() *pdfEmbeddedFont {
+	pdfFontOnce.Do(func() {
+		path := os.Getenv("STATEMENT_PDF_FONT_FILE")
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			common.SysError("failed to read STATEMENT_PDF_FONT_FILE: " + err.Error())
+			return
+		}
+		// 只支持 TrueType 轮廓，CFF 轮廓的 OpenType 字体不能作为 FontFile2 嵌入
+		if len(data) < 4 || !(bytes.Equal(data[:4], []byte{0, 1, 0, 0}) || string(data[:4]) == "true") {
+			common.SysError("STATEMENT_PDF_FONT_FILE must be a TrueType (.ttf) font")
+			return
+		}
+		f, err := sfnt.Parse(data)
+		if err != nil {
+			common.SysError("failed to parse STATEMENT_PDF_FONT_FILE: " + err.Error())
+			return
+		}
+		pdfFontEmbedded = &pdfEmbeddedFont{data: data, font: f, unitsPerEm: int(f.UnitsPerEm())}
+	})
+	return pdfFontEmbedded
+}
+
+// pdfTextEncoder 将文本编码为 PDF 十六进制字符串，并记录用到的字形宽度
+type pdfTextEncoder struct {
+	embedded *pdfEmbeddedFont
+	buf      sfnt.Buffer
+	widths   map[uint16]int
+	unicode  map[uint16]rune
+}
+
+func newPDFTextEncoder() *pdfTextEncoder {
+	return &pdfTextEncoder{
+		embedded: getPDFEmbeddedFont(),
+		widths:   make(map[uint16]int),
+		unicode:  make(map[uint16]rune),
+	}
+}
+
+// code 返回字符的 2 字节编码：嵌入字体时为字形编号，否则为 UCS-2 码位
+func (e *pdfTextEncoder) code(r rune) (uint16, int) {
+	if r < 32 {
+		r = ' '
+	}
+	if e.embedded == nil {
+		if r > 0xFFFF {
+			r = '?'
+		}
+		// STSong-Light 中 ASCII 为半角
+		if r < 0x7F {
+			return uint16(r), 500
+		}
+		return uint16(r), 1000
+	}
+	gid, err := e.embedded.font.GlyphIndex(&e.buf, r)
+	if err != nil || gid == 0 {
+		gid, _ = e.embedded.font.GlyphIndex(&e.buf, '?')
+	}
+	width, ok := e.widths[uint16(gid)]
+	if !ok {
+		advance, err := e.embedded.font.GlyphAdvance(&e.buf, gid, fixed.I(e.embedded.unitsPerEm), font.HintingNone)
+		if err == nil {
+			width = advance.Round() * 1000 / e.embedded.unitsPerEm
+		}
+		e.widths[uint16(gid)] = width
+		e.unicode[uint16(gid)] = r
+	}
+	return uint16(gid), width
+}
+
+func (e *pdfTextEncoder) encode(text string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range text {
+		c, _ := e.code(r)
+		b.WriteString(fmt.Sprintf("%04X", c))
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// fontObjects 返回字体相关对象，第一个为 Type0 字体，first 为第一个对象的编号
+func (e *pdfTextEncoder) fontObjects(first int) []string {
+	if e.embedded == nil {
+		return []string{
+			fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [%d 0 R] >>", first+1),
+			fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor %d 0 R /DW 1000 /W [1 95 500] >>", first+2),
+			"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+		}
+	}
+	gids := make([]int, 0, len(e.widths))
+	for gid := range e.widths {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+	var w, toUnicode strings.Builder
+	for _, gid := range gids {
+		w.WriteString(fmt.Sprintf("%d [%d] ", gid, e.widths[uint16(gid)]))
+		r := e.unicode[uint16(gid)]
+		if r > 0xFFFF {
+			continue
+		}
+		toUnicode.WriteString(fmt.Sprintf("<%04X> <%04X>\n", gid, r))
+	}
+	cmap := "/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n" +
+		fmt.Sprintf("%d beginbfchar\n%sendbfchar\n", strings.Count(toUnicode.String(), "\n"), toUnicode.String()) +
+		"endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend"
+	name := "StatementFont"
+	if n, err := e.embedded.font.Name(&e.buf, sfnt.NameIDPostScript); err == nil && n != "" {
+		name = strings.Map(func(r rune) rune {
+			if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%", r) {
+				return -1
+			}
+			return r
+		}, n)
+	}
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, first+1, first+4),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>", name, first+2, w.String()),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [0 -200 1000 900] /ItalicAngle 0 /Ascent 900 /Descent -200 /CapHeight 700 /StemV 80 /FontFile2 %d 0 R >>", name, first+3),
+		fmt.Sprintf("<< /Length %d /Length1 %d >>\nstream\n%s\nendstream", len(e.embedded.data), len(e.embedded.data), e.embedded.data),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(cmap), cmap),
+	}
+}
+
+// wrapPDFLines 按字符宽度折行
+func wrapPDFLines(e *pdfTextEncoder, lines []string) []string {
+	wrapped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var current strings.Builder
+		width := 0
+		for _, r := range line {
+			_, w := e.code(r)
+			if width+w > pdfMaxLineWidth && current.Len() > 0 {
+				wrapped = append(wrapped, current.String())
+				current.Reset()
+				width = 0
+			}
+			current.WriteRune(r)
+			width += w
+		}
+		wrapped = append(wrapped, current.String())
+	}
+	return wrapped
+}
+
+func renderSimplePDF(lines []string) []byte {
+	encoder := newPDFTextEncoder()
+	lines = wrapPDFLines(encoder, lines)
+	linesPerPage := (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	var pages [][]string
+	for len(lines) > 0 {
+		n := linesPerPage
+		if n > len(lines) {
+			n = len(lines)
+		}
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+	if len(pages) == 0 {
+		pages = append(pages, []string{})
+	}
+
+	// 对象编号：1 catalog, 2 pages, 之后每页占用 page 与 content 两个对象，最后是字体对象
+	objects := make([]string, 2+2*len(pages))
+	fontObj := len(objects) + 1
+	kids := make([]string, len(pages))
+	for i, pageLines := range pages {
+		pageObj := 3 + 2*i
+		contentObj := pageObj + 1
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj)
+
+		var content bytes.Buffer
+		content.WriteString(fmt.Sprintf("BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin))
+		for _, line := range pageLines {
+			content.WriteString(encoder.encode(line) + " Tj T*\n")
+		}
+		content.WriteString("ET")
+
+		objects[pageObj-1] = fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, fontObj, contentObj)
+		objects[contentObj-1] = fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String())
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	objects = append(objects, encoder.fontObjects(fontObj)...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, obj))
+	}
+	xrefOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1))
+	for _, offset := range offsets {
+		buf.WriteString(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset))
+	return buf.Bytes()
+}
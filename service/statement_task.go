@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	statementEmailTickInterval = time.Hour
+	statementEmailBatchSize    = 100
+)
+
+var (
+	statementEmailOnce    sync.Once
+	statementEmailRunning atomic.Bool
+)
+
+// StartStatementEmailTask 每月初向用户发送上月账单邮件
+func StartStatementEmailTask() {
+	statementEmailOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(statementEmailTickInterval)
+			defer ticker.Stop()
+
+			runStatementEmailOnce()
+			for range ticker.C {
+				runStatementEmailOnce()
+			}
+		})
+	})
+}
+
+func runStatementEmailOnce() {
+	setting := operation_setting.GetStatementSetting()
+	if !setting.EmailEnabled {
+		return
+	}
+	month := model.PreviousStatementMonth(time.Now())
+	if setting.LastSentMonth == month {
+		return
+	}
+	if !statementEmailRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer statementEmailRunning.Store(false)
+
+	ctx := context.Background()
+	// 先记录发送月份，避免发送过程中重启导致重复发送
+	if err := model.UpdateOption("statement_setting.last_sent_month", month); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("statement email: update last sent month failed: %v", err))
+		return
+	}
+
+	var sent, failed int
+	offset := 0
+	for {
+		users, err := model.GetStatementRecipients(offset, statementEmailBatchSize)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("statement email: query users failed: %v", err))
+			return
+		}
+		if len(users) == 0 {
+			break
+		}
+		offset += statementEmailBatchSize
+
+		for _, user := range users {
+			if err := SendUserStatementEmail(user.Id, month); err != nil {
+				failed++
+				logger.LogWarn(ctx, fmt.Sprintf("statement email: send to user %d failed: %v", user.Id, err))
+				continue
+			}
+			sent++
+		}
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("statement email for %s finished: sent=%d failed=%d", month, sent, failed))
+}
+
+// SendUserStatementEmail 发送用户指定月份的账单邮件，无消费和充值记录时不发送
+func SendUserStatementEmail(userId int, month string) error {
+	statement, err := model.GetUserStatement(userId, month)
+	if err != nil {
+		return err
+	}
+	if statement.Email == "" {
+		return fmt.Errorf("user %d has no email", userId)
+	}
+	if len(statement.Items) == 0 && len(statement.TopUps) == 0 && len(statement.Redemptions) == 0 {
+		return nil
+	}
+	subject := fmt.Sprintf("%s %s 账单", common.SystemName, month)
+	content := fmt.Sprintf("<p>您好，%s：</p><p>以下是您 %s 的账单，完整账单可在控制台下载 PDF / CSV 版本。</p>%s",
+		statement.Username, month, RenderStatementHTML(statement))
+	return common.SendEmail(subject, statement.Email, content)
+}
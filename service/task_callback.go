@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const taskCallbackBatchSize = 50
+
+// 回调失败后的重试间隔（秒），用尽后标记为失败
+var taskCallbackRetryDelays = []int64{10, 30, 60, 300, 900, 3600}
+
+var (
+	taskCallbackOnce    sync.Once
+	taskCallbackRunning atomic.Bool
+)
+
+// TaskCallbackPayload 任务回调的负载数据
+type TaskCallbackPayload struct {
+	Event      string `json:"event"`
+	TaskId     string `json:"task_id"`
+	Platform   string `json:"platform"`
+	Action     string `json:"action"`
+	Status     string `json:"status"`
+	FailReason string `json:"fail_reason,omitempty"`
+	Timestamp  int64  `json:"timestamp"`
+	Data       any    `json:"data,omitempty"`
+}
+
+// ValidateTaskCallbackUrl 校验回调地址格式，投递时另行做 SSRF 校验
+func ValidateTaskCallbackUrl(callbackUrl string) error {
+	if callbackUrl == "" {
+		return nil
+	}
+	if len(callbackUrl) > 512 {
+		return errors.New("回调地址过长")
+	}
+	u, err := url.Parse(callbackUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("回调地址必须是有效的 http(s) 地址")
+	}
+	return nil
+}
+
+// ResolveTaskCallbackUrl 获取任务回调地址，请求中指定的地址优先，其次为令牌上配置的地址
+func ResolveTaskCallbackUrl(c *gin.Context, requestUrl string) (string, error) {
+	if requestUrl == "" {
+		var req struct {
+			CallbackUrl string `json:"callback_url" form:"callback_url"`
+		}
+		if err := common.UnmarshalBodyReusable(c, &req); err == nil {
+			requestUrl = req.CallbackUrl
+		}
+	}
+	if requestUrl != "" {
+		if err := ValidateTaskCallbackUrl(requestUrl); err != nil {
+			return "", err
+		}
+		return requestUrl, nil
+	}
+	return common.GetContextKeyString(c, constant.ContextKeyTokenCallbackUrl), nil
+}
+
+// StartTaskCallbackTask 定期投递已完成任务的回调通知
+func StartTaskCallbackTask() {
+	taskCallbackOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(5 * time.Second)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				runTaskCallbackOnce()
+			}
+		})
+	})
+}
+
+func runTaskCallbackOnce() {
+	if !taskCallbackRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer taskCallbackRunning.Store(false)
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	tasks, err := model.GetPendingCallbackTasks(now, taskCallbackBatchSize)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("task callback: query tasks failed: %v", err))
+	}
+	for _, task := range tasks {
+		payload := TaskCallbackPayload{
+			TaskId:     task.TaskID,
+			Platform:   string(task.Platform),
+			Action:     task.Action,
+			Status:     string(task.Status),
+			FailReason: task.FailReason,
+		}
+		if task.Platform == constant.TaskPlatformSuno {
+			payload.Data = task.Data
+		} else {
+			payload.Data = task.ToOpenAIVideo()
+		}
+		deliverTaskCallback(ctx, task.UserId, &task.TaskCallback, payload)
+		if err := task.UpdateCallback(); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("task callback: update task %s failed: %v", task.TaskID, err))
+		}
+	}
+
+	mjTasks, err := model.GetPendingCallbackMidjourneys(now, taskCallbackBatchSize)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("task callback: query midjourney tasks failed: %v", err))
+	}
+	for _, task := range mjTasks {
+		payload := TaskCallbackPayload{
+			TaskId:     task.MjId,
+			Platform:   constant.TaskPlatformMidjourney,
+			Action:     task.Action,
+			Status:     task.Status,
+			FailReason: task.FailReason,
+			Data:       CoverMidjourneyTaskDto(task),
+		}
+		deliverTaskCallback(ctx, task.UserId, &task.TaskCallback, payload)
+		if err := task.UpdateCallback(); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("task callback: update midjourney task %s failed: %v", task.MjId, err))
+		}
+	}
+}
+
+// deliverTaskCallback 投递一次回调并更新投递状态，签名复用用户通知设置中的 webhook 密钥，
+// 用户未设置密钥时自动生成，回调始终带有签名
+func deliverTaskCallback(ctx context.Context, userId int, cb *model.TaskCallback, payload TaskCallbackPayload) {
+	if payload.Status == model.TaskStatusSuccess {
+		payload.Event = "task.succeeded"
+	} else {
+		payload.Event = "task.failed"
+	}
+	payload.Timestamp = time.Now().Unix()
+
+	payloadBytes, err := json.Marshal(payload)
+	if err == nil {
+		var secret string
+		var generated bool
+		secret, generated, err = model.EnsureUserWebhookSecret(userId)
+		if generated {
+			model.RecordLog(userId, model.LogTypeSystem, "已自动生成 Webhook 密钥用于签名任务回调，可在个人设置的通知配置中查看")
+		}
+		if err == nil {
+			err = postSignedWebhook(cb.CallbackUrl, secret, payloadBytes)
+		}
+	}
+
+	cb.CallbackAttempts++
+	if err == nil {
+		cb.CallbackStatus = model.TaskCallbackStatusSuccess
+		cb.CallbackError = ""
+		return
+	}
+	errMsg := []rune(err.Error())
+	if len(errMsg) > 200 {
+		errMsg = errMsg[:200]
+	}
+	cb.CallbackError = string(errMsg)
+	if cb.CallbackAttempts > len(taskCallbackRetryDelays) {
+		cb.CallbackStatus = model.TaskCallbackStatusFailed
+		logger.LogWarn(ctx, fmt.Sprintf("task callback: task %s delivery failed after %d attempts: %v", payload.TaskId, cb.CallbackAttempts, err))
+		return
+	}
+	cb.CallbackNextAt = time.Now().Unix() + taskCallbackRetryDelays[cb.CallbackAttempts-1]
+}
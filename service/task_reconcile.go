@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const taskReconcileBatchSize = 100
+
+var (
+	taskReconcileOnce    sync.Once
+	taskReconcileRunning atomic.Bool
+)
+
+// TaskReconcileResult 一次对账处理的任务数量
+type TaskReconcileResult struct {
+	FailedTasks       int `json:"failed_tasks"`
+	FailedMidjourneys int `json:"failed_midjourneys"`
+	RefundedQuota     int `json:"refunded_quota"`
+}
+
+// StartTaskReconcileTask 定期将超时未完成的异步任务标记为失败并退还额度
+func StartTaskReconcileTask() {
+	taskReconcileOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+
+		gopool.Go(func() {
+			ticker := time.NewTicker(5 * time.Minute)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if !operation_setting.GetTaskReconcileSetting().Enabled {
+					continue
+				}
+				if _, err := ReconcileStuckTasks(context.Background()); err != nil {
+					logger.LogError(context.Background(), fmt.Sprintf("task reconcile: %v", err))
+				}
+			}
+		})
+	})
+}
+
+// ReconcileStuckTasks 处理超过平台超时时间仍未完成的任务
+func ReconcileStuckTasks(ctx context.Context) (*TaskReconcileResult, error) {
+	if !taskReconcileRunning.CompareAndSwap(false, true) {
+		return nil, fmt.Errorf("对账任务正在运行中")
+	}
+	defer taskReconcileRunning.Store(false)
+
+	setting := operation_setting.GetTaskReconcileSetting()
+	result := &TaskReconcileResult{}
+	now := time.Now()
+
+	platforms, err := model.GetUnfinishedTaskPlatforms()
+	if err != nil {
+		return result, err
+	}
+	for _, platform := range platforms {
+		timeout := setting.GetTimeoutMinutes(platform)
+		if timeout <= 0 {
+			continue
+		}
+		before := now.Add(-time.Duration(timeout) * time.Minute).Unix()
+		tasks, err := model.GetStuckTasks(platform, before, taskReconcileBatchSize)
+		if err != nil {
+			return result, err
+		}
+		for _, task := range tasks {
+			reason := fmt.Sprintf("任务超过 %d 分钟未完成，已自动标记失败", timeout)
+			updated, err := model.FailStuckTask(task, reason)
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("task reconcile: fail task %s error: %v", task.TaskID, err))
+				continue
+			}
+			if !updated {
+				continue
+			}
+			result.FailedTasks++
+			if task.Quota != 0 {
+				refundStuckTask(ctx, task.UserId, task.TaskID, task.Quota)
+				result.RefundedQuota += task.Quota
+			}
+		}
+	}
+
+	timeout := setting.GetTimeoutMinutes(constant.TaskPlatformMidjourney)
+	if timeout > 0 {
+		before := now.Add(-time.Duration(timeout) * time.Minute).UnixMilli()
+		mjTasks, err := model.GetStuckMidjourneys(before, taskReconcileBatchSize)
+		if err != nil {
+			return result, err
+		}
+		for _, task := range mjTasks {
+			reason := fmt.Sprintf("任务超过 %d 分钟未完成，已自动标记失败", timeout)
+			updated, err := model.FailStuckMidjourney(task, reason)
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("task reconcile: fail midjourney task %s error: %v", task.MjId, err))
+				continue
+			}
+			if !updated {
+				continue
+			}
+			result.FailedMidjourneys++
+			if task.Quota != 0 {
+				refundStuckTask(ctx, task.UserId, task.MjId, task.Quota)
+				result.RefundedQuota += task.Quota
+			}
+		}
+	}
+
+	if result.FailedTasks > 0 || result.FailedMidjourneys > 0 {
+		logger.LogInfo(ctx, fmt.Sprintf("task reconcile: failed %d tasks and %d midjourney tasks, refund %s",
+			result.FailedTasks, result.FailedMidjourneys, logger.LogQuota(result.RefundedQuota)))
+	}
+	return result, nil
+}
+
+func refundStuckTask(ctx context.Context, userId int, taskId string, quota int) {
+	if err := model.IncreaseUserQuota(userId, quota, false); err != nil {
+		logger.LogWarn(ctx, "Failed to increase user quota: "+err.Error())
+	}
+	logContent := fmt.Sprintf("异步任务超时未完成 %s，退还 %s", taskId, logger.LogQuota(quota))
+	model.RecordTaskRefundLog(userId, taskId, quota, logContent)
+}
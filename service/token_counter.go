@@ -450,3 +450,38 @@ func CountTextToken(text string, model string) int {
 		return EstimateTokenByModel(model, text)
 	}
 }
+
+// Claude 单张图片的 token 上限，长边超过 1568 像素时会被缩放
+const (
+	claudeImageMaxEdge   = 1568
+	claudeImageMaxTokens = 1600
+)
+
+// CountClaudeRequestToken 估算 Claude 请求的输入 token 数，用于 count_tokens 接口本地应答
+func CountClaudeRequestToken(request *dto.ClaudeRequest) int {
+	meta := request.GetTokenCountMeta()
+	tokens := EstimateToken(Claude, meta.CombineText)
+	// 消息与工具定义的格式开销
+	tokens += meta.MessagesCount*3 + meta.ToolsCount*8
+	for _, file := range meta.Files {
+		tokens += getClaudeImageToken(file.OriginData)
+	}
+	return tokens
+}
+
+// getClaudeImageToken 按 Claude 的规则（宽×高/750）估算图片 token，无法解析尺寸时按上限计算
+func getClaudeImageToken(data string) int {
+	if strings.HasPrefix(data, "http") {
+		return claudeImageMaxTokens
+	}
+	config, _, _, err := DecodeBase64ImageData(data)
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return claudeImageMaxTokens
+	}
+	width, height := float64(config.Width), float64(config.Height)
+	if scale := claudeImageMaxEdge / math.Max(width, height); scale < 1 {
+		width *= scale
+		height *= scale
+	}
+	return min(int(math.Ceil(width*height/750)), claudeImageMaxTokens)
+}
@@ -54,6 +54,12 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 		return fmt.Errorf("failed to marshal webhook payload: %v", err)
 	}
 
+	return postSignedWebhook(webhookURL, secret, payloadBytes)
+}
+
+// postSignedWebhook 发送 webhook 请求，secret 非空时附带 HMAC-SHA256 签名
+func postSignedWebhook(webhookURL string, secret string, payloadBytes []byte) error {
+	var err error
 	// 创建 HTTP 请求
 	var req *http.Request
 	var resp *http.Response
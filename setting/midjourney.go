@@ -5,3 +5,8 @@ var MjAccountFilterEnabled = false
 var MjModeClearEnabled = false
 var MjForwardUrlEnabled = true
 var MjActionCheckSuccessEnabled = true
+
+// Midjourney 模拟：使用其他图片模型处理 /mj/submit 请求
+var MjEmulationEnabled = false
+var MjEmulationImageModel = "gpt-image-1"
+var MjEmulationDescribeModel = "gpt-4o-mini"
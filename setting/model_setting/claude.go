@@ -1,7 +1,9 @@
 package model_setting
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/QuantumNous/new-api/setting/config"
 )
@@ -18,6 +20,8 @@ type ClaudeSettings struct {
 	DefaultMaxTokens                      map[string]int                 `json:"default_max_tokens"`
 	ThinkingAdapterEnabled                bool                           `json:"thinking_adapter_enabled"`
 	ThinkingAdapterBudgetTokensPercentage float64                        `json:"thinking_adapter_budget_tokens_percentage"`
+	BatchRatio                            float64                        `json:"batch_ratio"`       // 消息批处理价格倍率，批处理逐个按普通接口转发，上游按原价收费，默认不打折
+	BatchConcurrency                      int                            `json:"batch_concurrency"` // 消息批处理同时执行的请求数
 }
 
 // 默认配置
@@ -28,6 +32,8 @@ var defaultClaudeSettings = ClaudeSettings{
 		"default": 8192,
 	},
 	ThinkingAdapterBudgetTokensPercentage: 0.8,
+	BatchRatio:                            1,
+	BatchConcurrency:                      5,
 }
 
 // 全局实例
@@ -73,3 +79,23 @@ func (c *ClaudeSettings) GetDefaultMaxTokens(model string) int {
 	}
 	return c.DefaultMaxTokens["default"]
 }
+
+// GetBatchRatio 返回有效的批处理倍率，未配置或配置非法时按原价计费
+func (c *ClaudeSettings) GetBatchRatio() float64 {
+	if c.BatchRatio <= 0 {
+		return 1
+	}
+	return c.BatchRatio
+}
+
+// CheckBatchRatio 校验批处理倍率
+func CheckBatchRatio(value string) error {
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errors.New("批处理价格倍率必须为数字")
+	}
+	if ratio <= 0 {
+		return errors.New("批处理价格倍率必须大于 0")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// PostpaidSetting 后付费（信用额度）账户配置
+type PostpaidSetting struct {
+	Enabled         bool   `json:"enabled"`       // 每月初自动为后付费用户生成上月账单
+	BillDueDays     int    `json:"bill_due_days"` // 账单生成后的付款期限（天），逾期自动暂停服务
+	AutoSuspend     bool   `json:"auto_suspend"`  // 账单逾期是否自动暂停服务
+	LastBilledMonth string `json:"last_billed_month"`
+}
+
+// 默认配置
+var postpaidSetting = PostpaidSetting{
+	Enabled:     false,
+	BillDueDays: 15,
+	AutoSuspend: true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("postpaid_setting", &postpaidSetting)
+}
+
+// GetPostpaidSetting 获取后付费配置
+func GetPostpaidSetting() *PostpaidSetting {
+	return &postpaidSetting
+}
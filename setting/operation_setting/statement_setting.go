@@ -0,0 +1,30 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// StatementSetting 月度账单配置
+type StatementSetting struct {
+	CompanyName    string  `json:"company_name"`
+	CompanyAddress string  `json:"company_address"`
+	CompanyTaxId   string  `json:"company_tax_id"`
+	CompanyEmail   string  `json:"company_email"`
+	TaxRate        float64 `json:"tax_rate"`      // 税率，如 0.06 表示 6%
+	EmailEnabled   bool    `json:"email_enabled"` // 每月初自动向用户发送上月账单
+	LastSentMonth  string  `json:"last_sent_month"`
+}
+
+// 默认配置
+var statementSetting = StatementSetting{
+	TaxRate:      0,
+	EmailEnabled: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("statement_setting", &statementSetting)
+}
+
+// GetStatementSetting 获取账单配置
+func GetStatementSetting() *StatementSetting {
+	return &statementSetting
+}
@@ -0,0 +1,34 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// TaskQueueSetting 异步任务排队配置，渠道并发上限在渠道设置 task_concurrency 中配置
+type TaskQueueSetting struct {
+	Enabled            bool           `json:"enabled"`
+	GroupPriority      map[string]int `json:"group_priority"`       // 分组 -> 优先级，数值越大越先调度
+	MaxQueuedPerUser   int            `json:"max_queued_per_user"`  // 单个用户最多排队任务数，0 表示不限制
+	QueueTimeoutMinute int            `json:"queue_timeout_minute"` // 排队超时后任务失败并退还额度
+}
+
+// 默认配置
+var taskQueueSetting = TaskQueueSetting{
+	Enabled:            false,
+	GroupPriority:      map[string]int{},
+	MaxQueuedPerUser:   20,
+	QueueTimeoutMinute: 60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("task_queue_setting", &taskQueueSetting)
+}
+
+// GetTaskQueueSetting 获取任务排队配置
+func GetTaskQueueSetting() *TaskQueueSetting {
+	return &taskQueueSetting
+}
+
+// GetGroupPriority 获取分组的调度优先级
+func (s *TaskQueueSetting) GetGroupPriority(group string) int {
+	return s.GroupPriority[group]
+}
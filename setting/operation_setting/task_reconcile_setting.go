@@ -0,0 +1,35 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// TaskReconcileSetting 异步任务对账配置，超时未完成的任务将被标记为失败并退还额度
+type TaskReconcileSetting struct {
+	Enabled                bool           `json:"enabled"`
+	TimeoutMinutes         int            `json:"timeout_minutes"`          // 默认超时时间
+	PlatformTimeoutMinutes map[string]int `json:"platform_timeout_minutes"` // 平台 -> 超时时间，平台为 suno、mj 或渠道类型编号
+}
+
+// 默认配置
+var taskReconcileSetting = TaskReconcileSetting{
+	Enabled:                false,
+	TimeoutMinutes:         1440,
+	PlatformTimeoutMinutes: map[string]int{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("task_reconcile_setting", &taskReconcileSetting)
+}
+
+// GetTaskReconcileSetting 获取任务对账配置
+func GetTaskReconcileSetting() *TaskReconcileSetting {
+	return &taskReconcileSetting
+}
+
+// GetTimeoutMinutes 获取平台的任务超时时间，未单独配置时使用默认值
+func (s *TaskReconcileSetting) GetTimeoutMinutes(platform string) int {
+	if minutes, ok := s.PlatformTimeoutMinutes[platform]; ok && minutes > 0 {
+		return minutes
+	}
+	return s.TimeoutMinutes
+}
@@ -46,6 +46,7 @@ func GetExposedData() gin.H {
 		"completion_ratio": GetCompletionRatioCopy(),
 		"cache_ratio":      GetCacheRatioCopy(),
 		"model_price":      GetModelPriceCopy(),
+		"prompt_tier":      GetPromptTierRatioCopy(),
 	}
 	exposedData.Store(&exposedCache{
 		data:      newData,
@@ -374,6 +374,9 @@ func InitRatioSettings() {
 	audioCompletionRatioMapMutex.Lock()
 	audioCompletionRatioMap = defaultAudioCompletionRatio
 	audioCompletionRatioMapMutex.Unlock()
+
+	// initialize prompt tier and volume tier ratios
+	initTierRatioSettings()
 }
 
 func GetModelPriceMap() map[string]float64 {
@@ -0,0 +1,156 @@
+package ratio_setting
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+	"github.com/QuantumNous/new-api/types"
+)
+
+// ScheduleRatioRule 分时段价格倍率规则
+// Start/End 为 HH:MM 格式，End 小于等于 Start 时表示跨天（如 22:00-06:00）
+type ScheduleRatioRule struct {
+	Name   string   `json:"name"`
+	Start  string   `json:"start"`
+	End    string   `json:"end"`
+	Ratio  float64  `json:"ratio"`
+	Models []string `json:"models,omitempty"` // 为空时对所有模型生效
+	Groups []string `json:"groups,omitempty"` // 为空时对所有分组生效
+}
+
+// ScheduleRatioSetting 分时段（如闲时）价格倍率配置，在分组倍率之上叠加
+type ScheduleRatioSetting struct {
+	Enabled  bool                `json:"enabled"`
+	Timezone string              `json:"timezone"` // IANA 时区，如 Asia/Shanghai，为空时使用服务器时区
+	Rules    []ScheduleRatioRule `json:"rules"`
+}
+
+var scheduleRatioSetting = ScheduleRatioSetting{
+	Enabled:  false,
+	Timezone: "",
+	Rules: []ScheduleRatioRule{
+		{
+			Name:  "off_peak",
+			Start: "00:00",
+			End:   "08:00",
+			Ratio: 0.5,
+		},
+	},
+}
+
+var scheduleLocationCache sync.Map
+
+func init() {
+	config.GlobalConfig.Register("schedule_ratio_setting", &scheduleRatioSetting)
+}
+
+func GetScheduleRatioSetting() *ScheduleRatioSetting {
+	return &scheduleRatioSetting
+}
+
+func getScheduleLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.Local
+	}
+	if loc, ok := scheduleLocationCache.Load(timezone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		common.SysError("invalid schedule ratio timezone: " + timezone)
+		return time.Local
+	}
+	scheduleLocationCache.Store(timezone, loc)
+	return loc
+}
+
+func parseClockMinutes(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func (rule *ScheduleRatioRule) matchTime(minutes int) bool {
+	start, ok := parseClockMinutes(rule.Start)
+	if !ok {
+		return false
+	}
+	end, ok := parseClockMinutes(rule.End)
+	if !ok {
+		return false
+	}
+	if start < end {
+		return minutes >= start && minutes < end
+	}
+	// 跨天时段
+	return minutes >= start || minutes < end
+}
+
+func (rule *ScheduleRatioRule) matchTarget(modelName, group string) bool {
+	if len(rule.Models) > 0 && !common.StringsContains(rule.Models, modelName) &&
+		!common.StringsContains(rule.Models, FormatMatchingModelName(modelName)) {
+		return false
+	}
+	if len(rule.Groups) > 0 && !common.StringsContains(rule.Groups, group) {
+		return false
+	}
+	return true
+}
+
+// GetScheduleRatio 返回当前时间命中的分时段倍率，规则按配置顺序匹配，未启用或未命中时返回 nil
+func GetScheduleRatio(modelName, group string) *types.ScheduleRatioInfo {
+	setting := GetScheduleRatioSetting()
+	if !setting.Enabled || len(setting.Rules) == 0 {
+		return nil
+	}
+	now := time.Now().In(getScheduleLocation(setting.Timezone))
+	minutes := now.Hour()*60 + now.Minute()
+	for i := range setting.Rules {
+		rule := &setting.Rules[i]
+		if rule.Ratio <= 0 || !rule.matchTarget(modelName, group) || !rule.matchTime(minutes) {
+			continue
+		}
+		return &types.ScheduleRatioInfo{
+			Name:  rule.Name,
+			Ratio: rule.Ratio,
+		}
+	}
+	return nil
+}
+
+// CheckScheduleRatioRules 校验分时段倍率规则，时间须为 HH:MM 且倍率大于 0
+func CheckScheduleRatioRules(value string) error {
+	var rules []ScheduleRatioRule
+	if err := common.UnmarshalJsonStr(value, &rules); err != nil {
+		return errors.New("分时段倍率规则不是合法的 JSON")
+	}
+	for i, rule := range rules {
+		if _, ok := parseClockMinutes(rule.Start); !ok {
+			return fmt.Errorf("第 %d 条规则的开始时间 %q 格式错误，应为 HH:MM", i+1, rule.Start)
+		}
+		if _, ok := parseClockMinutes(rule.End); !ok {
+			return fmt.Errorf("第 %d 条规则的结束时间 %q 格式错误，应为 HH:MM", i+1, rule.End)
+		}
+		if rule.Ratio <= 0 {
+			return fmt.Errorf("第 %d 条规则的倍率必须大于 0", i+1)
+		}
+	}
+	return nil
+}
+
+// CheckScheduleRatioTimezone 校验时区，空值表示使用服务器时区
+func CheckScheduleRatioTimezone(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(value); err != nil {
+		return fmt.Errorf("无效的时区 %s", value)
+	}
+	return nil
+}
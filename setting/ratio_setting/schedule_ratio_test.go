@@ -0,0 +1,66 @@
+package ratio_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleRatioRuleMatchTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   string
+		end     string
+		minutes int
+		want    bool
+	}{
+		{name: "same day inside", start: "00:00", end: "08:00", minutes: 7*60 + 59, want: true},
+		{name: "same day start inclusive", start: "00:00", end: "08:00", minutes: 0, want: true},
+		{name: "same day end exclusive", start: "00:00", end: "08:00", minutes: 8 * 60, want: false},
+		{name: "overnight before midnight", start: "22:00", end: "06:00", minutes: 23 * 60, want: true},
+		{name: "overnight after midnight", start: "22:00", end: "06:00", minutes: 5*60 + 59, want: true},
+		{name: "overnight end exclusive", start: "22:00", end: "06:00", minutes: 6 * 60, want: false},
+		{name: "overnight daytime", start: "22:00", end: "06:00", minutes: 12 * 60, want: false},
+		{name: "equal start and end covers whole day", start: "08:00", end: "08:00", minutes: 3 * 60, want: true},
+		{name: "invalid start", start: "8am", end: "10:00", minutes: 9 * 60, want: false},
+		{name: "invalid end", start: "08:00", end: "25:00", minutes: 9 * 60, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := ScheduleRatioRule{Start: tt.start, End: tt.end, Ratio: 0.5}
+			require.Equal(t, tt.want, rule.matchTime(tt.minutes))
+		})
+	}
+}
+
+func TestCheckScheduleRatioRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "valid overnight", value: `[{"start":"22:00","end":"06:00","ratio":0.5}]`},
+		{name: "empty list", value: `[]`},
+		{name: "invalid json", value: `{`, wantErr: true},
+		{name: "bad start", value: `[{"start":"22","end":"06:00","ratio":0.5}]`, wantErr: true},
+		{name: "bad end", value: `[{"start":"22:00","end":"24:00","ratio":0.5}]`, wantErr: true},
+		{name: "zero ratio", value: `[{"start":"22:00","end":"06:00","ratio":0}]`, wantErr: true},
+		{name: "negative ratio", value: `[{"start":"22:00","end":"06:00","ratio":-1}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckScheduleRatioRules(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckScheduleRatioTimezone(t *testing.T) {
+	require.NoError(t, CheckScheduleRatioTimezone(""))
+	require.NoError(t, CheckScheduleRatioTimezone("Asia/Shanghai"))
+	require.Error(t, CheckScheduleRatioTimezone("Mars/Olympus"))
+}
@@ -0,0 +1,182 @@
+package ratio_setting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/types"
+)
+
+// 长上下文阶梯价格，价格来源于各家官方定价页（超过 200k 提示词后输入输出价格提高）
+var defaultPromptTierRatio = map[string][]types.PriceTier{
+	"gemini-2.5-pro": {
+		{MinPromptTokens: 200000, ModelRatio: 1.25, CompletionRatio: 6},
+	},
+	"claude-sonnet-4-20250514": {
+		{MinPromptTokens: 200000, ModelRatio: 3, CompletionRatio: 3.75},
+	},
+	"claude-sonnet-4-5-20250929": {
+		{MinPromptTokens: 200000, ModelRatio: 3, CompletionRatio: 3.75},
+	},
+}
+
+var (
+	promptTierRatioMap      map[string][]types.PriceTier
+	promptTierRatioMapMutex sync.RWMutex
+)
+
+var (
+	volumeTiers      []types.VolumeTier
+	volumeTiersMutex sync.RWMutex
+)
+
+func initTierRatioSettings() {
+	promptTierRatioMapMutex.Lock()
+	promptTierRatioMap = make(map[string][]types.PriceTier, len(defaultPromptTierRatio))
+	for k, v := range defaultPromptTierRatio {
+		promptTierRatioMap[k] = append([]types.PriceTier(nil), v...)
+	}
+	promptTierRatioMapMutex.Unlock()
+
+	volumeTiersMutex.Lock()
+	volumeTiers = make([]types.VolumeTier, 0)
+	volumeTiersMutex.Unlock()
+}
+
+func PromptTierRatio2JSONString() string {
+	promptTierRatioMapMutex.RLock()
+	defer promptTierRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(promptTierRatioMap)
+	if err != nil {
+		common.SysLog("error marshalling prompt tier ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdatePromptTierRatioByJSONString(jsonStr string) error {
+	newMap := make(map[string][]types.PriceTier)
+	if err := json.Unmarshal([]byte(jsonStr), &newMap); err != nil {
+		return err
+	}
+	for _, tiers := range newMap {
+		sort.Slice(tiers, func(i, j int) bool {
+			return tiers[i].MinPromptTokens < tiers[j].MinPromptTokens
+		})
+	}
+	promptTierRatioMapMutex.Lock()
+	promptTierRatioMap = newMap
+	promptTierRatioMapMutex.Unlock()
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func CheckPromptTierRatio(jsonStr string) error {
+	checkMap := make(map[string][]types.PriceTier)
+	if err := json.Unmarshal([]byte(jsonStr), &checkMap); err != nil {
+		return err
+	}
+	for name, tiers := range checkMap {
+		for _, tier := range tiers {
+			if tier.MinPromptTokens <= 0 {
+				return fmt.Errorf("prompt tier threshold must be greater than 0: %s", name)
+			}
+			if tier.ModelRatio < 0 || tier.CompletionRatio < 0 || tier.CacheRatio < 0 {
+				return fmt.Errorf("prompt tier ratio must be not less than 0: %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// GetPromptTiers 返回模型配置的上下文长度阶梯，按阈值升序
+func GetPromptTiers(name string) []types.PriceTier {
+	promptTierRatioMapMutex.RLock()
+	defer promptTierRatioMapMutex.RUnlock()
+	tiers, ok := promptTierRatioMap[name]
+	if !ok {
+		tiers, ok = promptTierRatioMap[FormatMatchingModelName(name)]
+		if !ok {
+			return nil
+		}
+	}
+	return append([]types.PriceTier(nil), tiers...)
+}
+
+func GetPromptTierRatioCopy() map[string][]types.PriceTier {
+	promptTierRatioMapMutex.RLock()
+	defer promptTierRatioMapMutex.RUnlock()
+	copyMap := make(map[string][]types.PriceTier, len(promptTierRatioMap))
+	for k, v := range promptTierRatioMap {
+		copyMap[k] = append([]types.PriceTier(nil), v...)
+	}
+	return copyMap
+}
+
+func VolumeTierRatio2JSONString() string {
+	volumeTiersMutex.RLock()
+	defer volumeTiersMutex.RUnlock()
+	jsonBytes, err := json.Marshal(volumeTiers)
+	if err != nil {
+		common.SysLog("error marshalling volume tier ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateVolumeTierRatioByJSONString(jsonStr string) error {
+	newTiers := make([]types.VolumeTier, 0)
+	if err := json.Unmarshal([]byte(jsonStr), &newTiers); err != nil {
+		return err
+	}
+	sort.Slice(newTiers, func(i, j int) bool {
+		return newTiers[i].MinMonthlyQuota < newTiers[j].MinMonthlyQuota
+	})
+	volumeTiersMutex.Lock()
+	volumeTiers = newTiers
+	volumeTiersMutex.Unlock()
+	return nil
+}
+
+func CheckVolumeTierRatio(jsonStr string) error {
+	checkTiers := make([]types.VolumeTier, 0)
+	if err := json.Unmarshal([]byte(jsonStr), &checkTiers); err != nil {
+		return err
+	}
+	for _, tier := range checkTiers {
+		if tier.MinMonthlyQuota < 0 {
+			return errors.New("volume tier quota must be not less than 0")
+		}
+		if tier.Ratio < 0 {
+			return errors.New("volume tier ratio must be not less than 0")
+		}
+	}
+	return nil
+}
+
+func HasVolumeTiers() bool {
+	volumeTiersMutex.RLock()
+	defer volumeTiersMutex.RUnlock()
+	return len(volumeTiers) > 0
+}
+
+func GetVolumeTiersCopy() []types.VolumeTier {
+	volumeTiersMutex.RLock()
+	defer volumeTiersMutex.RUnlock()
+	return append([]types.VolumeTier(nil), volumeTiers...)
+}
+
+// GetVolumeTier 根据用户当月已消耗额度返回命中的用量阶梯
+func GetVolumeTier(monthlyQuota int) (*types.VolumeTier, bool) {
+	volumeTiersMutex.RLock()
+	defer volumeTiersMutex.RUnlock()
+	for i := len(volumeTiers) - 1; i >= 0; i-- {
+		if monthlyQuota >= volumeTiers[i].MinMonthlyQuota {
+			tier := volumeTiers[i]
+			return &tier, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,44 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type LDAPSettings struct {
+	Enabled            bool              `json:"enabled"`
+	URL                string            `json:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool              `json:"start_tls"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"`
+	BindDN             string            `json:"bind_dn"`     // 用于搜索用户的服务账号
+	BindSecret         string            `json:"bind_secret"` // 服务账号密码，以 secret 结尾不会下发到前端
+	BaseDN             string            `json:"base_dn"`
+	UserFilter         string            `json:"user_filter"` // %s 会被替换为转义后的用户名
+	UsernameAttribute  string            `json:"username_attribute"`
+	EmailAttribute     string            `json:"email_attribute"`
+	DisplayNameAttr    string            `json:"display_name_attribute"`
+	GroupAttribute     string            `json:"group_attribute"` // 用户所属组的属性，如 memberOf
+	GroupMapping       map[string]string `json:"group_mapping"`   // 目录组（DN 或 CN）-> 本系统分组
+	AdminGroups        []string          `json:"admin_groups"`    // 属于这些目录组的用户为管理员
+	AutoRegister       bool              `json:"auto_register"`
+	SyncEnabled        bool              `json:"sync_enabled"`
+	SyncInterval       int               `json:"sync_interval"` // 同步间隔（分钟）
+}
+
+// 默认配置，过滤器与属性名适配 Active Directory
+var defaultLDAPSettings = LDAPSettings{
+	UserFilter:        "(&(objectClass=user)(sAMAccountName=%s))",
+	UsernameAttribute: "sAMAccountName",
+	EmailAttribute:    "mail",
+	DisplayNameAttr:   "displayName",
+	GroupAttribute:    "memberOf",
+	GroupMapping:      map[string]string{},
+	AdminGroups:       []string{},
+	SyncInterval:      60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("ldap", &defaultLDAPSettings)
+}
+
+func GetLDAPSettings() *LDAPSettings {
+	return &defaultLDAPSettings
+}
@@ -0,0 +1,61 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+const (
+	MediaStorageDriverLocal = "local"
+	MediaStorageDriverS3    = "s3"
+)
+
+// MediaStorageSettings 生成结果（视频、图片）转存配置
+type MediaStorageSettings struct {
+	Enabled bool   `json:"enabled"`
+	Driver  string `json:"driver"` // local 或 s3
+	// 本地存储目录
+	LocalPath string `json:"local_path"`
+	// S3 兼容存储
+	S3Endpoint     string `json:"s3_endpoint"`
+	S3Region       string `json:"s3_region"`
+	S3Bucket       string `json:"s3_bucket"`
+	S3AccessKeyId  string `json:"s3_access_key_id"`
+	S3AccessSecret string `json:"s3_access_secret"` // 以 secret 结尾不会下发到前端
+	S3PathStyle    bool   `json:"s3_path_style"`
+	// CDN 域名，配置后直接跳转到 CDN 地址而不再签名
+	PublicBaseURL string `json:"public_base_url"`
+	// 签名访问链接有效期（分钟）
+	LinkExpireMinutes int `json:"link_expire_minutes"`
+	// 单个文件大小上限（MB）
+	MaxFileSizeMB int `json:"max_file_size_mb"`
+	// 默认保留天数，0 表示永久保留
+	RetentionDays int `json:"retention_days"`
+	// 按分组覆盖保留天数
+	GroupRetentionDays map[string]int `json:"group_retention_days"`
+}
+
+// 默认配置
+var defaultMediaStorageSettings = MediaStorageSettings{
+	Driver:             MediaStorageDriverLocal,
+	LocalPath:          "data/media",
+	S3Region:           "us-east-1",
+	LinkExpireMinutes:  60,
+	MaxFileSizeMB:      500,
+	RetentionDays:      30,
+	GroupRetentionDays: map[string]int{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("media_storage", &defaultMediaStorageSettings)
+}
+
+func GetMediaStorageSettings() *MediaStorageSettings {
+	return &defaultMediaStorageSettings
+}
+
+// GetRetentionDays 获取分组的保留天数，0 表示永久保留
+func (s *MediaStorageSettings) GetRetentionDays(group string) int {
+	if days, ok := s.GroupRetentionDays[group]; ok {
+		return days
+	}
+	return s.RetentionDays
+}
@@ -0,0 +1,35 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// OIDCRelaySettings 中继接口的 OIDC/JWT Bearer 认证配置，使用 IdP 签发的 JWT 直接调用中继接口
+type OIDCRelaySettings struct {
+	Enabled       bool              `json:"enabled"`
+	Issuer        string            `json:"issuer"`         // 期望的 iss，启用时必填
+	Audience      string            `json:"audience"`       // 期望的 aud，启用时必填
+	JWKSURL       string            `json:"jwks_url"`       // 用于校验签名的 JWKS 地址
+	SubjectClaim  string            `json:"subject_claim"`  // 用户唯一标识，与 iss 共同确定中继身份
+	UsernameClaim string            `json:"username_claim"` // 自动创建用户时使用的用户名
+	EmailClaim    string            `json:"email_claim"`
+	GroupClaim    string            `json:"group_claim"`   // 分组声明，可为字符串或字符串数组
+	GroupMapping  map[string]string `json:"group_mapping"` // IdP 分组 -> 本系统分组，未命中映射时使用默认分组
+	BudgetClaim   string            `json:"budget_claim"`  // 预算声明，单位为美元，留空不限制
+	AutoProvision bool              `json:"auto_provision"`
+}
+
+// 默认配置
+var defaultOIDCRelaySettings = OIDCRelaySettings{
+	SubjectClaim:  "sub",
+	UsernameClaim: "preferred_username",
+	EmailClaim:    "email",
+	GroupMapping:  map[string]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("oidc_relay", &defaultOIDCRelaySettings)
+}
+
+func GetOIDCRelaySettings() *OIDCRelaySettings {
+	return &defaultOIDCRelaySettings
+}
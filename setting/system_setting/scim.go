@@ -0,0 +1,21 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// ScimSettings SCIM 2.0 用户同步接口配置
+type ScimSettings struct {
+	Enabled      bool   `json:"enabled"`
+	BearerSecret string `json:"bearer_secret"` // IdP 调用 /scim/v2 时使用的 Bearer 令牌，以 secret 结尾不会下发到前端
+}
+
+// 默认配置
+var defaultScimSettings = ScimSettings{}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("scim", &defaultScimSettings)
+}
+
+func GetScimSettings() *ScimSettings {
+	return &defaultScimSettings
+}
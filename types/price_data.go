@@ -8,6 +8,26 @@ type GroupRatioInfo struct {
 	HasSpecialRatio   bool
 }
 
+// PriceTier 上下文长度阶梯价格，提示词 tokens 超过 MinPromptTokens 时使用该阶梯的倍率
+type PriceTier struct {
+	MinPromptTokens int     `json:"min_prompt_tokens"`
+	ModelRatio      float64 `json:"model_ratio"`
+	CompletionRatio float64 `json:"completion_ratio,omitempty"`
+	CacheRatio      float64 `json:"cache_ratio,omitempty"`
+}
+
+// VolumeTier 用户月用量阶梯，当月已消耗额度达到 MinMonthlyQuota 后按 Ratio 计费
+type VolumeTier struct {
+	MinMonthlyQuota int     `json:"min_monthly_quota"`
+	Ratio           float64 `json:"ratio"`
+}
+
+// ScheduleRatioInfo 命中的分时段价格倍率
+type ScheduleRatioInfo struct {
+	Name  string  `json:"name"`
+	Ratio float64 `json:"ratio"`
+}
+
 type PriceData struct {
 	FreeModel            bool
 	ModelPrice           float64
@@ -24,6 +44,87 @@ type PriceData struct {
 	UsePrice             bool
 	QuotaToPreConsume    int // 预消耗额度
 	GroupRatioInfo       GroupRatioInfo
+	PromptTiers          []PriceTier        // 模型配置的上下文长度阶梯
+	PromptTier           *PriceTier         // 当前命中的上下文长度阶梯
+	VolumeTier           *VolumeTier        // 当前命中的用户月用量阶梯
+	ScheduleRatio        *ScheduleRatioInfo // 请求开始时命中的分时段倍率，结算沿用该值
+	IsBatch              bool               // 是否为消息批处理中的请求
+	BatchRatio           float64            // 批处理价格倍率，仅 IsBatch 时生效
+
+	basePromptRatios *PriceTier
+}
+
+// ApplyPromptTier 根据提示词 tokens 选择上下文长度阶梯并更新倍率。
+// 预扣费时使用估算值，结算时使用实际用量再次调用，会先恢复基础倍率再重新选择。
+func (p *PriceData) ApplyPromptTier(promptTokens int) {
+	if len(p.PromptTiers) == 0 || p.UsePrice {
+		return
+	}
+	if p.basePromptRatios == nil {
+		p.basePromptRatios = &PriceTier{
+			ModelRatio:      p.ModelRatio,
+			CompletionRatio: p.CompletionRatio,
+			CacheRatio:      p.CacheRatio,
+		}
+	}
+	p.ModelRatio = p.basePromptRatios.ModelRatio
+	p.CompletionRatio = p.basePromptRatios.CompletionRatio
+	p.CacheRatio = p.basePromptRatios.CacheRatio
+	p.PromptTier = SelectPromptTier(p.PromptTiers, promptTokens)
+	if p.PromptTier == nil {
+		return
+	}
+	p.ModelRatio = p.PromptTier.ModelRatio
+	if p.PromptTier.CompletionRatio > 0 {
+		p.CompletionRatio = p.PromptTier.CompletionRatio
+	}
+	if p.PromptTier.CacheRatio > 0 {
+		p.CacheRatio = p.PromptTier.CacheRatio
+	}
+}
+
+// SelectPromptTier 返回提示词 tokens 命中的最高阶梯，未命中返回 nil
+func SelectPromptTier(tiers []PriceTier, promptTokens int) *PriceTier {
+	var selected *PriceTier
+	for i := range tiers {
+		if promptTokens <= tiers[i].MinPromptTokens {
+			continue
+		}
+		if selected == nil || tiers[i].MinPromptTokens > selected.MinPromptTokens {
+			tier := tiers[i]
+			selected = &tier
+		}
+	}
+	return selected
+}
+
+// GetVolumeRatio 返回用户月用量阶梯倍率，未命中时为 1
+func (p *PriceData) GetVolumeRatio() float64 {
+	if p.VolumeTier == nil {
+		return 1
+	}
+	return p.VolumeTier.Ratio
+}
+
+// GetScheduleRatio 返回分时段倍率，未命中时为 1
+func (p *PriceData) GetScheduleRatio() float64 {
+	if p.ScheduleRatio == nil {
+		return 1
+	}
+	return p.ScheduleRatio.Ratio
+}
+
+// GetBatchRatio 返回批处理倍率，非批处理请求为 1
+func (p *PriceData) GetBatchRatio() float64 {
+	if !p.IsBatch {
+		return 1
+	}
+	return p.BatchRatio
+}
+
+// GetPriceMultiplier 返回叠加在分组倍率之上的额外倍率（用量阶梯、分时段、批处理）
+func (p *PriceData) GetPriceMultiplier() float64 {
+	return p.GetVolumeRatio() * p.GetScheduleRatio() * p.GetBatchRatio()
 }
 
 func (p *PriceData) AddOtherRatio(key string, ratio float64) {
@@ -40,8 +141,9 @@ type PerCallPriceData struct {
 	ModelPrice     float64
 	Quota          int
 	GroupRatioInfo GroupRatioInfo
+	ScheduleRatio  *ScheduleRatioInfo
 }
 
 func (p *PriceData) ToSetting() string {
-	return fmt.Sprintf("ModelPrice: %f, ModelRatio: %f, CompletionRatio: %f, CacheRatio: %f, GroupRatio: %f, UsePrice: %t, CacheCreationRatio: %f, CacheCreation5mRatio: %f, CacheCreation1hRatio: %f, QuotaToPreConsume: %d, ImageRatio: %f, AudioRatio: %f, AudioCompletionRatio: %f", p.ModelPrice, p.ModelRatio, p.CompletionRatio, p.CacheRatio, p.GroupRatioInfo.GroupRatio, p.UsePrice, p.CacheCreationRatio, p.CacheCreation5mRatio, p.CacheCreation1hRatio, p.QuotaToPreConsume, p.ImageRatio, p.AudioRatio, p.AudioCompletionRatio)
+	return fmt.Sprintf("ModelPrice: %f, ModelRatio: %f, CompletionRatio: %f, CacheRatio: %f, GroupRatio: %f, UsePrice: %t, CacheCreationRatio: %f, CacheCreation5mRatio: %f, CacheCreation1hRatio: %f, QuotaToPreConsume: %d, ImageRatio: %f, AudioRatio: %f, AudioCompletionRatio: %f, VolumeRatio: %f, ScheduleRatio: %f, BatchRatio: %f", p.ModelPrice, p.ModelRatio, p.CompletionRatio, p.CacheRatio, p.GroupRatioInfo.GroupRatio, p.UsePrice, p.CacheCreationRatio, p.CacheCreation5mRatio, p.CacheCreation1hRatio, p.QuotaToPreConsume, p.ImageRatio, p.AudioRatio, p.AudioCompletionRatio, p.GetVolumeRatio(), p.GetScheduleRatio(), p.GetBatchRatio())
 }
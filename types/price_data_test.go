@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPromptTier(t *testing.T) {
+	// 故意乱序，选择结果不应依赖配置顺序
+	tiers := []PriceTier{
+		{MinPromptTokens: 200000, ModelRatio: 3},
+		{MinPromptTokens: 32000, ModelRatio: 2},
+	}
+
+	tests := []struct {
+		name         string
+		promptTokens int
+		wantRatio    float64
+	}{
+		{name: "below first tier", promptTokens: 1000, wantRatio: 0},
+		{name: "exactly at first boundary", promptTokens: 32000, wantRatio: 0},
+		{name: "just above first boundary", promptTokens: 32001, wantRatio: 2},
+		{name: "exactly at second boundary", promptTokens: 200000, wantRatio: 2},
+		{name: "above second boundary", promptTokens: 200001, wantRatio: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier := SelectPromptTier(tiers, tt.promptTokens)
+			if tt.wantRatio == 0 {
+				require.Nil(t, tier)
+				return
+			}
+			require.NotNil(t, tier)
+			require.Equal(t, tt.wantRatio, tier.ModelRatio)
+		})
+	}
+}
+
+func TestSelectPromptTier_ReturnsCopy(t *testing.T) {
+	tiers := []PriceTier{{MinPromptTokens: 10, ModelRatio: 2}}
+	tier := SelectPromptTier(tiers, 11)
+	require.NotNil(t, tier)
+	tier.ModelRatio = 5
+	require.Equal(t, 2.0, tiers[0].ModelRatio)
+}
+
+func TestSelectPromptTier_Empty(t *testing.T) {
+	require.Nil(t, SelectPromptTier(nil, 100000))
+}
@@ -104,7 +104,7 @@ function App() {
         <Route
           path='/console/models'
           element={
-            <AdminRoute>
+            <AdminRoute permission='model.read'>
               <ModelPage />
             </AdminRoute>
           }
@@ -112,7 +112,7 @@ function App() {
         <Route
           path='/console/deployment'
           element={
-            <AdminRoute>
+            <AdminRoute permission='deployment.read'>
               <ModelDeploymentPage />
             </AdminRoute>
           }
@@ -120,7 +120,7 @@ function App() {
         <Route
           path='/console/channel'
           element={
-            <AdminRoute>
+            <AdminRoute permission='channel.read'>
               <Channel />
             </AdminRoute>
           }
@@ -144,7 +144,7 @@ function App() {
         <Route
           path='/console/redemption'
           element={
-            <AdminRoute>
+            <AdminRoute permission='billing.read'>
               <Redemption />
             </AdminRoute>
           }
@@ -152,7 +152,7 @@ function App() {
         <Route
           path='/console/user'
           element={
-            <AdminRoute>
+            <AdminRoute permission='user.read'>
               <User />
             </AdminRoute>
           }
@@ -228,7 +228,7 @@ function App() {
         <Route
           path='/console/setting'
           element={
-            <AdminRoute>
+            <AdminRoute permission='option.read'>
               <Suspense fallback={<Loading></Loading>} key={location.pathname}>
                 <Setting />
               </Suspense>
@@ -92,6 +92,7 @@ const LoginForm = () => {
   const [githubLoading, setGithubLoading] = useState(false);
   const [discordLoading, setDiscordLoading] = useState(false);
   const [oidcLoading, setOidcLoading] = useState(false);
+  const [useLdap, setUseLdap] = useState(false);
   const [linuxdoLoading, setLinuxdoLoading] = useState(false);
   const [emailLoginLoading, setEmailLoginLoading] = useState(false);
   const [loginLoading, setLoginLoading] = useState(false);
@@ -214,8 +215,11 @@ const LoginForm = () => {
     setLoginLoading(true);
     try {
       if (username && password) {
+        const loginPath = useLdap
+          ? '/api/user/login/ldap'
+          : '/api/user/login';
         const res = await API.post(
-          `/api/user/login?turnstile=${turnstileToken}`,
+          `${loginPath}?turnstile=${turnstileToken}`,
           {
             username,
             password,
@@ -716,6 +720,15 @@ const LoginForm = () => {
                   prefix={<IconLock />}
                 />
 
+                {status.ldap_enabled && (
+                  <Checkbox
+                    checked={useLdap}
+                    onChange={(e) => setUseLdap(e.target.checked)}
+                  >
+                    <Text size='small'>{t('使用 LDAP 目录账户登录')}</Text>
+                  </Checkbox>
+                )}
+
                 {(hasUserAgreement || hasPrivacyPolicy) && (
                   <div className='pt-4'>
                     <Checkbox
@@ -0,0 +1,212 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useRef, useState } from 'react';
+import {
+  Button,
+  Card,
+  Checkbox,
+  Form,
+  Modal,
+  Popconfirm,
+  Space,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import { useTranslation } from 'react-i18next';
+import { API, showError, showSuccess } from '../../helpers';
+
+const { Text } = Typography;
+
+const PERMISSION_LABELS = {
+  'channel.read': '查看渠道',
+  'channel.write': '编辑渠道',
+  'channel.key': '查看渠道密钥',
+  'user.read': '查看用户',
+  'user.write': '编辑用户',
+  'billing.read': '查看账单与兑换码',
+  'billing.write': '管理账单与兑换码',
+  'model.read': '查看模型',
+  'model.write': '编辑模型',
+  'option.read': '查看系统设置',
+  'option.write': '修改系统设置',
+  'log.read': '查看日志',
+  'log.write': '清理日志',
+  'deployment.read': '查看模型部署',
+  'deployment.write': '管理模型部署',
+};
+
+const AdminRoleSetting = () => {
+  const { t } = useTranslation();
+  const [roles, setRoles] = useState([]);
+  const [permissions, setPermissions] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [editingRole, setEditingRole] = useState(null);
+  const formApiRef = useRef(null);
+
+  const loadRoles = async () => {
+    setLoading(true);
+    try {
+      const [rolesRes, permsRes] = await Promise.all([
+        API.get('/api/admin_role/'),
+        API.get('/api/admin_role/permissions'),
+      ]);
+      if (rolesRes.data.success) {
+        setRoles(rolesRes.data.data || []);
+      } else {
+        showError(rolesRes.data.message);
+      }
+      if (permsRes.data.success) {
+        setPermissions(permsRes.data.data || []);
+      }
+    } catch (e) {
+      showError(e.message);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    loadRoles();
+  }, []);
+
+  const submitRole = async (values) => {
+    const payload = {
+      id: editingRole?.id || 0,
+      name: values.name,
+      description: values.description || '',
+      permissions: values.permissions || [],
+    };
+    const res = payload.id
+      ? await API.put('/api/admin_role/', payload)
+      : await API.post('/api/admin_role/', payload);
+    if (res.data.success) {
+      showSuccess(t('保存成功'));
+      setEditingRole(null);
+      loadRoles();
+    } else {
+      showError(res.data.message);
+    }
+  };
+
+  const deleteRole = async (id) => {
+    const res = await API.delete(`/api/admin_role/${id}`);
+    if (res.data.success) {
+      showSuccess(t('删除成功'));
+      loadRoles();
+    } else {
+      showError(res.data.message);
+    }
+  };
+
+  const columns = [
+    { title: t('角色名称'), dataIndex: 'name' },
+    { title: t('描述'), dataIndex: 'description' },
+    {
+      title: t('权限'),
+      dataIndex: 'permissions',
+      render: (perms) => (
+        <Space wrap>
+          {(perms || []).map((p) => (
+            <Tag key={p} color='blue'>
+              {t(PERMISSION_LABELS[p] || p)}
+            </Tag>
+          ))}
+        </Space>
+      ),
+    },
+    {
+      title: t('操作'),
+      dataIndex: 'operate',
+      render: (_, record) => (
+        <Space>
+          <Button size='small' onClick={() => setEditingRole(record)}>
+            {t('编辑')}
+          </Button>
+          <Popconfirm
+            title={t('确定删除该角色？已分配的用户将失去对应权限')}
+            onConfirm={() => deleteRole(record.id)}
+          >
+            <Button size='small' type='danger'>
+              {t('删除')}
+            </Button>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <Card style={{ marginTop: '10px' }}>
+      <div className='flex justify-between items-center mb-2'>
+        <Text type='secondary'>
+          {t(
+            '自定义管理角色可分配给任意用户，分配后该用户仅拥有角色中勾选的管理权限',
+          )}
+        </Text>
+        <Button theme='solid' onClick={() => setEditingRole({})}>
+          {t('新建角色')}
+        </Button>
+      </div>
+      <Table
+        columns={columns}
+        dataSource={roles}
+        rowKey='id'
+        loading={loading}
+        pagination={false}
+      />
+      <Modal
+        title={editingRole?.id ? t('编辑角色') : t('新建角色')}
+        visible={editingRole !== null}
+        onOk={() => formApiRef.current?.submitForm()}
+        onCancel={() => setEditingRole(null)}
+        destroyOnClose
+      >
+        {editingRole !== null && (
+          <Form
+            initValues={{
+              name: editingRole.name || '',
+              description: editingRole.description || '',
+              permissions: editingRole.permissions || [],
+            }}
+            getFormApi={(api) => (formApiRef.current = api)}
+            onSubmit={submitRole}
+          >
+            <Form.Input
+              field='name'
+              label={t('角色名称')}
+              rules={[{ required: true, message: t('请输入角色名称') }]}
+            />
+            <Form.Input field='description' label={t('描述')} />
+            <Form.CheckboxGroup field='permissions' label={t('权限')}>
+              {permissions.map((p) => (
+                <Checkbox key={p} value={p}>
+                  {t(PERMISSION_LABELS[p] || p)}
+                </Checkbox>
+              ))}
+            </Form.CheckboxGroup>
+          </Form>
+        )}
+      </Modal>
+    </Card>
+  );
+};
+
+export default AdminRoleSetting;
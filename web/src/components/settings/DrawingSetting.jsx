@@ -31,6 +31,9 @@ const DrawingSetting = () => {
     MjForwardUrlEnabled: false,
     MjModeClearEnabled: false,
     MjActionCheckSuccessEnabled: false,
+    MjEmulationEnabled: false,
+    MjEmulationImageModel: '',
+    MjEmulationDescribeModel: '',
   });
 
   let [loading, setLoading] = useState(false);
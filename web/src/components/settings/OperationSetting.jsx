@@ -27,6 +27,8 @@ import SettingsLog from '../../pages/Setting/Operation/SettingsLog';
 import SettingsMonitoring from '../../pages/Setting/Operation/SettingsMonitoring';
 import SettingsCreditLimit from '../../pages/Setting/Operation/SettingsCreditLimit';
 import SettingsCheckin from '../../pages/Setting/Operation/SettingsCheckin';
+import SettingsTaskQueue from '../../pages/Setting/Operation/SettingsTaskQueue';
+import SettingsTaskReconcile from '../../pages/Setting/Operation/SettingsTaskReconcile';
 import { API, showError, toBoolean } from '../../helpers';
 
 const OperationSetting = () => {
@@ -78,6 +80,15 @@ const OperationSetting = () => {
     'checkin_setting.enabled': false,
     'checkin_setting.min_quota': 1000,
     'checkin_setting.max_quota': 10000,
+    /* 任务排队设置 */
+    'task_queue_setting.enabled': false,
+    'task_queue_setting.group_priority': '',
+    'task_queue_setting.max_queued_per_user': 20,
+    'task_queue_setting.queue_timeout_minute': 60,
+    /* 任务对账设置 */
+    'task_reconcile_setting.enabled': false,
+    'task_reconcile_setting.timeout_minutes': 1440,
+    'task_reconcile_setting.platform_timeout_minutes': '',
   });
 
   let [loading, setLoading] = useState(false);
@@ -151,6 +162,14 @@ const OperationSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <SettingsCheckin options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 任务排队设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsTaskQueue options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 任务对账设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsTaskReconcile options={inputs} refresh={onRefresh} />
+        </Card>
       </Spin>
     </>
   );
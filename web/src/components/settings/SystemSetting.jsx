@@ -39,6 +39,7 @@ import {
   showError,
   showSuccess,
   toBoolean,
+  verifyJSON,
 } from '../../helpers';
 import axios from 'axios';
 import { useTranslation } from 'react-i18next';
@@ -62,6 +63,50 @@ const SystemSetting = () => {
     'oidc.authorization_endpoint': '',
     'oidc.token_endpoint': '',
     'oidc.user_info_endpoint': '',
+    'oidc_relay.enabled': '',
+    'oidc_relay.issuer': '',
+    'oidc_relay.audience': '',
+    'oidc_relay.jwks_url': '',
+    'oidc_relay.subject_claim': '',
+    'oidc_relay.username_claim': '',
+    'oidc_relay.email_claim': '',
+    'oidc_relay.group_claim': '',
+    'oidc_relay.group_mapping': '',
+    'oidc_relay.budget_claim': '',
+    'oidc_relay.auto_provision': '',
+    'ldap.enabled': '',
+    'ldap.url': '',
+    'ldap.start_tls': '',
+    'ldap.insecure_skip_verify': '',
+    'ldap.bind_dn': '',
+    'ldap.bind_secret': '',
+    'ldap.base_dn': '',
+    'ldap.user_filter': '',
+    'ldap.username_attribute': '',
+    'ldap.email_attribute': '',
+    'ldap.display_name_attribute': '',
+    'ldap.group_attribute': '',
+    'ldap.group_mapping': '',
+    'ldap.admin_groups': '',
+    'ldap.auto_register': '',
+    'ldap.sync_enabled': '',
+    'ldap.sync_interval': '',
+    'scim.enabled': '',
+    'scim.bearer_secret': '',
+    'media_storage.enabled': '',
+    'media_storage.driver': 'local',
+    'media_storage.local_path': '',
+    'media_storage.s3_endpoint': '',
+    'media_storage.s3_region': '',
+    'media_storage.s3_bucket': '',
+    'media_storage.s3_access_key_id': '',
+    'media_storage.s3_access_secret': '',
+    'media_storage.s3_path_style': '',
+    'media_storage.public_base_url': '',
+    'media_storage.link_expire_minutes': '',
+    'media_storage.max_file_size_mb': '',
+    'media_storage.retention_days': '',
+    'media_storage.group_retention_days': '',
     Notice: '',
     SMTPServer: '',
     SMTPPort: '',
@@ -184,11 +229,29 @@ const SystemSetting = () => {
           case 'LinuxDOOAuthEnabled':
           case 'discord.enabled':
           case 'oidc.enabled':
+          case 'oidc_relay.enabled':
+          case 'oidc_relay.auto_provision':
+          case 'ldap.enabled':
+          case 'ldap.start_tls':
+          case 'ldap.insecure_skip_verify':
+          case 'ldap.auto_register':
+          case 'ldap.sync_enabled':
+          case 'scim.enabled':
+          case 'media_storage.enabled':
+          case 'media_storage.s3_path_style':
           case 'passkey.enabled':
           case 'passkey.allow_insecure_origin':
           case 'WorkerAllowHttpImageRequestEnabled':
             item.value = toBoolean(item.value);
             break;
+          case 'ldap.admin_groups':
+            // 管理员组以逗号分隔的形式编辑
+            try {
+              item.value = (JSON.parse(item.value || '[]') || []).join(',');
+            } catch (e) {
+              item.value = '';
+            }
+            break;
           case 'passkey.origins':
             // origins是逗号分隔的字符串，直接使用
             item.value = item.value || '';
@@ -577,6 +640,181 @@ const SystemSetting = () => {
     }
   };
 
+  const submitOIDCRelaySettings = async () => {
+    const jwksUrl = inputs['oidc_relay.jwks_url'];
+    if (
+      jwksUrl &&
+      !jwksUrl.startsWith('http://') &&
+      !jwksUrl.startsWith('https://')
+    ) {
+      showError(t('JWKS URL 必须以 http:// 或 https:// 开头'));
+      return;
+    }
+    if (
+      inputs['oidc_relay.enabled'] &&
+      (!inputs['oidc_relay.issuer'] || !inputs['oidc_relay.audience'])
+    ) {
+      showError(t('启用中继认证时必须填写 Issuer 与 Audience'));
+      return;
+    }
+    const groupMapping = inputs['oidc_relay.group_mapping'] || '{}';
+    if (!verifyJSON(groupMapping)) {
+      showError(t('分组映射不是合法的 JSON 字符串'));
+      return;
+    }
+    const options = [];
+    [
+      'oidc_relay.issuer',
+      'oidc_relay.audience',
+      'oidc_relay.jwks_url',
+      'oidc_relay.subject_claim',
+      'oidc_relay.username_claim',
+      'oidc_relay.email_claim',
+      'oidc_relay.group_claim',
+      'oidc_relay.budget_claim',
+    ].forEach((key) => {
+      if (originInputs[key] !== inputs[key]) {
+        options.push({ key, value: inputs[key] || '' });
+      }
+    });
+    if (originInputs['oidc_relay.group_mapping'] !== groupMapping) {
+      options.push({ key: 'oidc_relay.group_mapping', value: groupMapping });
+    }
+    if (options.length > 0) {
+      await updateOptions(options);
+    }
+  };
+
+  const submitLDAPSettings = async () => {
+    const ldapUrl = inputs['ldap.url'];
+    if (
+      ldapUrl &&
+      !ldapUrl.startsWith('ldap://') &&
+      !ldapUrl.startsWith('ldaps://')
+    ) {
+      showError(t('LDAP 地址必须以 ldap:// 或 ldaps:// 开头'));
+      return;
+    }
+    const groupMapping = inputs['ldap.group_mapping'] || '{}';
+    if (!verifyJSON(groupMapping)) {
+      showError(t('分组映射不是合法的 JSON 字符串'));
+      return;
+    }
+    const options = [];
+    [
+      'ldap.url',
+      'ldap.bind_dn',
+      'ldap.base_dn',
+      'ldap.user_filter',
+      'ldap.username_attribute',
+      'ldap.email_attribute',
+      'ldap.display_name_attribute',
+      'ldap.group_attribute',
+    ].forEach((key) => {
+      if (originInputs[key] !== inputs[key]) {
+        options.push({ key, value: inputs[key] || '' });
+      }
+    });
+    if (
+      originInputs['ldap.bind_secret'] !== inputs['ldap.bind_secret'] &&
+      inputs['ldap.bind_secret'] !== ''
+    ) {
+      options.push({
+        key: 'ldap.bind_secret',
+        value: inputs['ldap.bind_secret'],
+      });
+    }
+    if (originInputs['ldap.group_mapping'] !== groupMapping) {
+      options.push({ key: 'ldap.group_mapping', value: groupMapping });
+    }
+    if (originInputs['ldap.admin_groups'] !== inputs['ldap.admin_groups']) {
+      const adminGroups = (inputs['ldap.admin_groups'] || '')
+        .split(',')
+        .map((g) => g.trim())
+        .filter((g) => g !== '');
+      options.push({
+        key: 'ldap.admin_groups',
+        value: JSON.stringify(adminGroups),
+      });
+    }
+    if (
+      String(originInputs['ldap.sync_interval']) !==
+      String(inputs['ldap.sync_interval'])
+    ) {
+      options.push({
+        key: 'ldap.sync_interval',
+        value: String(inputs['ldap.sync_interval'] || 60),
+      });
+    }
+    if (options.length > 0) {
+      await updateOptions(options);
+    }
+  };
+
+  const submitScimSettings = async () => {
+    if (
+      originInputs['scim.bearer_secret'] !== inputs['scim.bearer_secret'] &&
+      inputs['scim.bearer_secret'] !== ''
+    ) {
+      await updateOptions([
+        { key: 'scim.bearer_secret', value: inputs['scim.bearer_secret'] },
+      ]);
+    }
+  };
+
+  const submitMediaStorageSettings = async () => {
+    const groupRetention =
+      inputs['media_storage.group_retention_days'] || '{}';
+    if (!verifyJSON(groupRetention)) {
+      showError(t('分组保留天数不是合法的 JSON 字符串'));
+      return;
+    }
+    const options = [];
+    [
+      'media_storage.driver',
+      'media_storage.local_path',
+      'media_storage.s3_endpoint',
+      'media_storage.s3_region',
+      'media_storage.s3_bucket',
+      'media_storage.s3_access_key_id',
+      'media_storage.public_base_url',
+    ].forEach((key) => {
+      if (originInputs[key] !== inputs[key]) {
+        options.push({ key, value: inputs[key] || '' });
+      }
+    });
+    [
+      'media_storage.link_expire_minutes',
+      'media_storage.max_file_size_mb',
+      'media_storage.retention_days',
+    ].forEach((key) => {
+      if (String(originInputs[key]) !== String(inputs[key])) {
+        options.push({ key, value: String(inputs[key] || 0) });
+      }
+    });
+    if (
+      originInputs['media_storage.s3_access_secret'] !==
+        inputs['media_storage.s3_access_secret'] &&
+      inputs['media_storage.s3_access_secret'] !== ''
+    ) {
+      options.push({
+        key: 'media_storage.s3_access_secret',
+        value: inputs['media_storage.s3_access_secret'],
+      });
+    }
+    if (
+      originInputs['media_storage.group_retention_days'] !== groupRetention
+    ) {
+      options.push({
+        key: 'media_storage.group_retention_days',
+        value: groupRetention,
+      });
+    }
+    if (options.length > 0) {
+      await updateOptions(options);
+    }
+  };
+
   const submitTelegramSettings = async () => {
     const options = [
       { key: 'TelegramBotToken', value: inputs.TelegramBotToken },
@@ -981,6 +1219,139 @@ const SystemSetting = () => {
                 </Form.Section>
               </Card>
 
+              <Card>
+                <Form.Section text={t('生成结果转存')}>
+                  <Text>
+                    {t(
+                      '任务成功后将生成的视频和图片转存到本地磁盘或 S3 兼容存储，任务结果中的地址替换为本站的固定地址，访问时跳转到带有效期的签名链接',
+                    )}
+                  </Text>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                    style={{ marginTop: 16 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Checkbox
+                        field="['media_storage.enabled']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('media_storage.enabled', e)
+                        }
+                      >
+                        {t('启用生成结果转存')}
+                      </Form.Checkbox>
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Checkbox
+                        field="['media_storage.s3_path_style']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('media_storage.s3_path_style', e)
+                        }
+                      >
+                        {t('S3 使用路径风格访问')}
+                      </Form.Checkbox>
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Select
+                        field="['media_storage.driver']"
+                        label={t('存储类型')}
+                        optionList={[
+                          { label: t('本地磁盘'), value: 'local' },
+                          { label: t('S3 兼容存储'), value: 's3' },
+                        ]}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.local_path']"
+                        label={t('本地存储目录')}
+                        placeholder='data/media'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.s3_endpoint']"
+                        label={t('S3 地址')}
+                        placeholder='https://s3.us-east-1.amazonaws.com'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.s3_region']"
+                        label={t('S3 区域')}
+                        placeholder='us-east-1'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.s3_bucket']"
+                        label={t('S3 存储桶')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.s3_access_key_id']"
+                        label={t('S3 Access Key ID')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.s3_access_secret']"
+                        label={t('S3 Secret Access Key')}
+                        type='password'
+                        placeholder={t('敏感信息不会发送到前端显示')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['media_storage.public_base_url']"
+                        label={t('CDN 地址')}
+                        extraText={t(
+                          '配置后直接跳转到 CDN 地址，访问控制由 CDN 负责',
+                        )}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.InputNumber
+                        field="['media_storage.link_expire_minutes']"
+                        label={t('签名链接有效期（分钟）')}
+                        min={1}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.InputNumber
+                        field="['media_storage.max_file_size_mb']"
+                        label={t('单个文件大小上限（MB）')}
+                        min={1}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.InputNumber
+                        field="['media_storage.retention_days']"
+                        label={t('默认保留天数')}
+                        extraText={t('0 表示永久保留')}
+                        min={0}
+                      />
+                    </Col>
+                  </Row>
+                  <Form.TextArea
+                    field="['media_storage.group_retention_days']"
+                    label={t('分组保留天数')}
+                    extraText={t('按分组覆盖默认保留天数的 JSON 映射')}
+                    placeholder='{"vip": 90, "default": 7}'
+                    autosize
+                  />
+                  <Button onClick={submitMediaStorageSettings}>
+                    {t('保存转存设置')}
+                  </Button>
+                </Form.Section>
+              </Card>
+
               <Card>
                 <Form.Section text={t('配置登录注册')}>
                   <Row
@@ -1417,6 +1788,350 @@ const SystemSetting = () => {
                 </Form.Section>
               </Card>
 
+              <Card>
+                <Form.Section text={t('配置 OIDC/JWT 中继认证')}>
+                  <Text>
+                    {t(
+                      '允许中继接口直接使用 IdP 签发的 JWT 作为 Bearer 凭证，签名通过 JWKS 校验，JWT 声明映射到用户、分组与预算',
+                    )}
+                  </Text>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                    style={{ marginTop: 16 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Checkbox
+                        field="['oidc_relay.enabled']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('oidc_relay.enabled', e)
+                        }
+                      >
+                        {t('允许使用 JWT 访问中继接口')}
+                      </Form.Checkbox>
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Checkbox
+                        field="['oidc_relay.auto_provision']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('oidc_relay.auto_provision', e)
+                        }
+                      >
+                        {t('自动创建不存在的用户')}
+                      </Form.Checkbox>
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.jwks_url']"
+                        label={t('JWKS URL')}
+                        placeholder='https://idp.example.com/.well-known/jwks.json'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.issuer']"
+                        label={t('Issuer')}
+                        placeholder={t('必填，JWT 的 iss 需与之一致')}
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.audience']"
+                        label={t('Audience')}
+                        placeholder={t('必填，JWT 的 aud 需包含该值')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.subject_claim']"
+                        label={t('用户标识声明')}
+                        placeholder='sub'
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.username_claim']"
+                        label={t('用户名声明')}
+                        placeholder='preferred_username'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.email_claim']"
+                        label={t('邮箱声明')}
+                        placeholder='email'
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.group_claim']"
+                        label={t('分组声明')}
+                        placeholder='groups'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['oidc_relay.budget_claim']"
+                        label={t('预算声明')}
+                        extraText={t('预算单位为美元，留空则不限制')}
+                      />
+                    </Col>
+                  </Row>
+                  <Form.TextArea
+                    field="['oidc_relay.group_mapping']"
+                    label={t('分组映射')}
+                    extraText={t(
+                      'IdP 分组到本系统分组的 JSON 映射，未命中映射的用户使用默认分组',
+                    )}
+                    placeholder='{"engineering": "vip"}'
+                    autosize
+                  />
+                  <Button onClick={submitOIDCRelaySettings}>
+                    {t('保存 OIDC/JWT 中继认证设置')}
+                  </Button>
+                </Form.Section>
+              </Card>
+
+              <Card>
+                <Form.Section text={t('配置 LDAP / Active Directory')}>
+                  <Text>
+                    {t(
+                      '用以支持通过 LDAP 目录账户登录，并按目录组同步用户分组与角色',
+                    )}
+                  </Text>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                    style={{ marginTop: 16 }}
+                  >
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.Checkbox
+                        field="['ldap.enabled']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('ldap.enabled', e)
+                        }
+                      >
+                        {t('允许通过 LDAP 进行登录')}
+                      </Form.Checkbox>
+                    </Col>
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.Checkbox
+                        field="['ldap.auto_register']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('ldap.auto_register', e)
+                        }
+                      >
+                        {t('首次登录时自动创建用户')}
+                      </Form.Checkbox>
+                    </Col>
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.Checkbox
+                        field="['ldap.sync_enabled']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('ldap.sync_enabled', e)
+                        }
+                      >
+                        {t('定期同步目录用户')}
+                      </Form.Checkbox>
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.Checkbox
+                        field="['ldap.start_tls']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('ldap.start_tls', e)
+                        }
+                      >
+                        {t('使用 StartTLS')}
+                      </Form.Checkbox>
+                    </Col>
+                    <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                      <Form.Checkbox
+                        field="['ldap.insecure_skip_verify']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('ldap.insecure_skip_verify', e)
+                        }
+                      >
+                        {t('跳过 TLS 证书校验')}
+                      </Form.Checkbox>
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.url']"
+                        label={t('LDAP 地址')}
+                        placeholder='ldaps://ad.example.com:636'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.base_dn']"
+                        label={t('Base DN')}
+                        placeholder='DC=example,DC=com'
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.bind_dn']"
+                        label={t('Bind DN')}
+                        placeholder='CN=svc-newapi,OU=Service,DC=example,DC=com'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.bind_secret']"
+                        label={t('Bind 密码')}
+                        type='password'
+                        placeholder={t('敏感信息不会发送到前端显示')}
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.user_filter']"
+                        label={t('用户过滤器')}
+                        extraText={t('%s 会被替换为登录用户名')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.username_attribute']"
+                        label={t('用户名属性')}
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.email_attribute']"
+                        label={t('邮箱属性')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.display_name_attribute']"
+                        label={t('显示名称属性')}
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.group_attribute']"
+                        label={t('组属性')}
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['ldap.admin_groups']"
+                        label={t('管理员组')}
+                        extraText={t('多个组以逗号分隔，可填写组 DN 或 CN')}
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.InputNumber
+                        field="['ldap.sync_interval']"
+                        label={t('同步间隔（分钟）')}
+                        min={1}
+                      />
+                    </Col>
+                  </Row>
+                  <Form.TextArea
+                    field="['ldap.group_mapping']"
+                    label={t('分组映射')}
+                    extraText={t('目录组（DN 或 CN）到本系统分组的 JSON 映射')}
+                    placeholder='{"Engineering": "vip"}'
+                    autosize
+                  />
+                  <Button onClick={submitLDAPSettings}>
+                    {t('保存 LDAP 设置')}
+                  </Button>
+                </Form.Section>
+              </Card>
+
+              <Card>
+                <Form.Section text={t('配置 SCIM 用户同步')}>
+                  <Text>
+                    {t(
+                      '用以支持 Okta、Azure AD 等 IdP 通过 SCIM 2.0 自动创建、停用和删除用户并分配分组',
+                    )}
+                  </Text>
+                  <Banner
+                    type='info'
+                    description={`${t('SCIM 地址填')} ${inputs.ServerAddress ? inputs.ServerAddress : t('网站地址')}/scim/v2`}
+                    style={{ marginBottom: 20, marginTop: 16 }}
+                  />
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Checkbox
+                        field="['scim.enabled']"
+                        noLabel
+                        onChange={(e) =>
+                          handleCheckboxChange('scim.enabled', e)
+                        }
+                      >
+                        {t('启用 SCIM 用户同步')}
+                      </Form.Checkbox>
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field="['scim.bearer_secret']"
+                        label={t('SCIM Bearer 令牌')}
+                        type='password'
+                        placeholder={t('敏感信息不会发送到前端显示')}
+                      />
+                    </Col>
+                  </Row>
+                  <Button onClick={submitScimSettings}>
+                    {t('保存 SCIM 设置')}
+                  </Button>
+                </Form.Section>
+              </Card>
+
               <Card>
                 <Form.Section text={t('配置 GitHub OAuth App')}>
                   <Text>{t('用以支持通过 GitHub 进行登录注册')}</Text>
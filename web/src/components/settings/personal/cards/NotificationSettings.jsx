@@ -508,18 +508,6 @@ const NotificationSettings = ({
                       ]}
                     />
 
-                    <Form.Input
-                      field='webhookSecret'
-                      label={t('接口凭证')}
-                      placeholder={t('请输入密钥')}
-                      onChange={(val) => handleFormChange('webhookSecret', val)}
-                      prefix={<IconKey />}
-                      extraText={t(
-                        '密钥将以Bearer方式添加到请求头中，用于验证webhook请求的合法性',
-                      )}
-                      showClear
-                    />
-
                     <Form.Slot label={t('Webhook请求结构说明')}>
                       <div>
                         <div style={{ height: '200px', marginBottom: '12px' }}>
@@ -561,6 +549,18 @@ const NotificationSettings = ({
                   </>
                 )}
 
+                {/* Webhook 密钥同时用于签名异步任务回调，任意通知方式下均可查看 */}
+                <Form.Input
+                  field='webhookSecret'
+                  label={t('接口凭证')}
+                  placeholder={t('请输入密钥')}
+                  onChange={(val) => handleFormChange('webhookSecret', val)}
+                  prefix={<IconKey />}
+                  extraText={t(
+                    '密钥将以Bearer方式添加到Webhook通知与任务回调的请求头中并用于签名，未设置时投递任务回调会自动生成',
+                  )}
+                />
+
                 {/* Bark推送设置 */}
                 {notificationSettings.warningType === 'bark' && (
                   <>
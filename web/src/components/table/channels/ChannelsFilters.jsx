@@ -90,7 +90,7 @@ const ChannelsFilters = ({
               size='small'
               field='searchKeyword'
               prefix={<IconSearch />}
-              placeholder={t('渠道ID，名称，密钥，API地址')}
+              placeholder={t('渠道ID，名称，API地址（不支持按密钥搜索）')}
               showClear
               pure
             />
@@ -99,6 +99,11 @@ const MODEL_FETCHABLE_TYPES = new Set([
   1, 4, 14, 34, 17, 26, 27, 24, 47, 25, 20, 23, 31, 40, 42, 48, 43,
 ]);
 
+// 音频本地转码仅支持解码 wav/pcm/flac/ogg/aiff，编码 wav/pcm
+const AUDIO_FORMAT_OPTIONS = ['mp3', 'wav', 'pcm', 'flac', 'opus', 'aac'].map(
+  (format) => ({ label: format, value: format }),
+);
+
 function type2secretPrompt(type) {
   // inputs.type === 15 ? '按照如下格式输入：APIKey|SecretKey' : (inputs.type === 18 ? '按照如下格式输入：APPID|APISecret|APIKey' : '请输入渠道对应的鉴权密钥')
   switch (type) {
@@ -170,6 +175,11 @@ const EditChannelModal = (props) => {
     allow_service_tier: false,
     disable_store: false, // false = 允许透传（默认开启）
     allow_safety_identifier: false,
+    // 异步任务并发上限，0 表示不限制
+    task_concurrency: 0,
+    // 上游接受/返回的音频格式，与请求不一致时本地转码
+    audio_input_formats: [],
+    audio_output_formats: [],
   };
   const [batch, setBatch] = useState(false);
   const [multiToSingle, setMultiToSingle] = useState(false);
@@ -633,6 +643,9 @@ const EditChannelModal = (props) => {
           data.disable_store = parsedSettings.disable_store || false;
           data.allow_safety_identifier =
             parsedSettings.allow_safety_identifier || false;
+          data.task_concurrency = parsedSettings.task_concurrency || 0;
+          data.audio_input_formats = parsedSettings.audio_input_formats || [];
+          data.audio_output_formats = parsedSettings.audio_output_formats || [];
         } catch (error) {
           console.error('解析其他设置失败:', error);
           data.azure_responses_version = '';
@@ -643,6 +656,9 @@ const EditChannelModal = (props) => {
           data.allow_service_tier = false;
           data.disable_store = false;
           data.allow_safety_identifier = false;
+          data.task_concurrency = 0;
+          data.audio_input_formats = [];
+          data.audio_output_formats = [];
         }
       } else {
         // 兼容历史数据：老渠道没有 settings 时，默认按 json 展示
@@ -652,6 +668,7 @@ const EditChannelModal = (props) => {
         data.allow_service_tier = false;
         data.disable_store = false;
         data.allow_safety_identifier = false;
+        data.task_concurrency = 0;
       }
 
       if (
@@ -1414,6 +1431,9 @@ const EditChannelModal = (props) => {
     delete localInputs.allow_service_tier;
     delete localInputs.disable_store;
     delete localInputs.allow_safety_identifier;
+    delete localInputs.task_concurrency;
+    delete localInputs.audio_input_formats;
+    delete localInputs.audio_output_formats;
 
     let res;
     localInputs.auto_ban = localInputs.auto_ban ? 1 : 0;
@@ -3178,6 +3198,68 @@ const EditChannelModal = (props) => {
                       )}
                     />
 
+                    <Form.InputNumber
+                      field='task_concurrency'
+                      label={t('异步任务并发上限')}
+                      placeholder={t('0 表示不限制')}
+                      min={0}
+                      onNumberChange={(value) =>
+                        handleChannelOtherSettingsChange(
+                          'task_concurrency',
+                          value || 0,
+                        )
+                      }
+                      extraText={t(
+                        '视频等异步任务同时处理中的数量达到上限后，新任务进入排队，需在运营设置中启用任务排队',
+                      )}
+                      style={{ width: '100%' }}
+                    />
+
+                    {/* 音频转码 - OpenAI 渠道 */}
+                    {inputs.type === 1 && (
+                      <>
+                        <Form.Select
+                          field='audio_input_formats'
+                          label={t('语音识别输入格式')}
+                          placeholder={t('留空表示不转码')}
+                          multiple
+                          allowCreate
+                          filter
+                          optionList={AUDIO_FORMAT_OPTIONS}
+                          onChange={(value) =>
+                            handleChannelOtherSettingsChange(
+                              'audio_input_formats',
+                              value || [],
+                            )
+                          }
+                          extraText={t(
+                            '上游仅接受这些格式时，wav/pcm/flac/ogg/aiff 上传会在本地转码为 wav/pcm 后再转发，mp3 等其他格式原样转发',
+                          )}
+                          style={{ width: '100%' }}
+                        />
+
+                        <Form.Select
+                          field='audio_output_formats'
+                          label={t('语音合成输出格式')}
+                          placeholder={t('留空表示不转码')}
+                          multiple
+                          allowCreate
+                          filter
+                          optionList={AUDIO_FORMAT_OPTIONS}
+                          onChange={(value) =>
+                            handleChannelOtherSettingsChange(
+                              'audio_output_formats',
+                              value || [],
+                            )
+                          }
+                          extraText={t(
+                            '请求的 wav/pcm 不在这些格式中时，向上游请求其中的 wav/pcm/flac 格式并在本地转码，仅对非流式请求生效',
+                          )}
+                          style={{ width: '100%' }}
+                        />
+                      </>
+                    )}
+
                     {/* 字段透传控制 - OpenAI 渠道 */}
                     {inputs.type === 1 && (
                       <>
@@ -18,7 +18,7 @@ For commercial licensing, please contact support@quantumnous.com
 */
 
 import React from 'react';
-import { Progress, Tag, Typography } from '@douyinfe/semi-ui';
+import { Popconfirm, Progress, Tag, Typography } from '@douyinfe/semi-ui';
 import {
   Music,
   FileText,
@@ -210,6 +210,12 @@ const renderStatus = (type, t) => {
           {t('排队中')}
         </Tag>
       );
+    case 'WAITING':
+      return (
+        <Tag color='amber' shape='circle' prefixIcon={<Clock size={14} />}>
+          {t('等待调度')}
+        </Tag>
+      );
     case 'UNKNOWN':
       return (
         <Tag color='white' shape='circle' prefixIcon={<HelpCircle size={14} />}>
@@ -238,6 +244,7 @@ export const getTaskLogsColumns = ({
   openContentModal,
   isAdminUser,
   openVideoModal,
+  cancelTask,
 }) => {
   return [
     {
@@ -383,6 +390,18 @@ export const getTaskLogsColumns = ({
             </a>
           );
         }
+        if (record.status === 'WAITING') {
+          return (
+            <Popconfirm
+              title={t('确定取消该排队任务？预扣额度将退还')}
+              onConfirm={() => cancelTask(record.task_id)}
+            >
+              <a href='#' onClick={(e) => e.preventDefault()}>
+                {t('取消排队')}
+              </a>
+            </Popconfirm>
+          );
+        }
         if (!text) {
           return t('无');
         }
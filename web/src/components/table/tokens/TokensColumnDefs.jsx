@@ -108,11 +108,12 @@ const renderGroupColumn = (text, record, t) => {
 };
 
 // Render token key column with show/hide and copy functionality
+// Only the key prefix is stored on the server, the full key is returned once on creation
 const renderTokenKey = (text, record, showKeys, setShowKeys, copyText) => {
-  const fullKey = 'sk-' + record.key;
-  const maskedKey =
-    'sk-' + record.key.slice(0, 4) + '**********' + record.key.slice(-4);
-  const revealed = !!showKeys[record.id];
+  const hasFullKey = !!record.key;
+  const fullKey = hasFullKey ? 'sk-' + record.key : '';
+  const maskedKey = 'sk-' + (record.key_prefix || '') + '**********';
+  const revealed = hasFullKey && !!showKeys[record.id];
 
   return (
     <div className='w-[200px]'>
@@ -128,6 +129,7 @@ const renderTokenKey = (text, record, showKeys, setShowKeys, copyText) => {
               type='tertiary'
               icon={revealed ? <IconEyeClosed /> : <IconEyeOpened />}
               aria-label='toggle token visibility'
+              disabled={!hasFullKey}
               onClick={(e) => {
                 e.stopPropagation();
                 setShowKeys((prev) => ({ ...prev, [record.id]: !revealed }));
@@ -139,6 +141,7 @@ const renderTokenKey = (text, record, showKeys, setShowKeys, copyText) => {
               type='tertiary'
               icon={<IconCopy />}
               aria-label='copy token key'
+              disabled={!hasFullKey}
               onClick={async (e) => {
                 e.stopPropagation();
                 await copyText(fullKey);
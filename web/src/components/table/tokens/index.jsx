@@ -41,12 +41,13 @@ import EditTokenModal from './modals/EditTokenModal';
 import { useTokensData } from '../../../hooks/tokens/useTokensData';
 import { useIsMobile } from '../../../hooks/common/useIsMobile';
 import { createCardProPagination } from '../../../helpers/utils';
+import { getTokenApiKey } from '../../../helpers/token';
 
 function TokensPage() {
   // Define the function first, then pass it into the hook to avoid TDZ errors
   const openFluentNotificationRef = useRef(null);
-  const tokensData = useTokensData((key) =>
-    openFluentNotificationRef.current?.(key),
+  const tokensData = useTokensData((token) =>
+    openFluentNotificationRef.current?.(token),
   );
   const isMobile = useIsMobile();
   const latestRef = useRef({
@@ -54,12 +55,12 @@ function TokensPage() {
     selectedKeys: [],
     t: (k) => k,
     selectedModel: '',
-    prefillKey: '',
+    prefillToken: null,
   });
   const [modelOptions, setModelOptions] = useState([]);
   const [selectedModel, setSelectedModel] = useState('');
   const [fluentNoticeOpen, setFluentNoticeOpen] = useState(false);
-  const [prefillKey, setPrefillKey] = useState('');
+  const [prefillToken, setPrefillToken] = useState(null);
 
   // Keep latest data for handlers inside notifications
   useEffect(() => {
@@ -68,14 +69,14 @@ function TokensPage() {
       selectedKeys: tokensData.selectedKeys,
       t: tokensData.t,
       selectedModel,
-      prefillKey,
+      prefillToken,
     };
   }, [
     tokensData.tokens,
     tokensData.selectedKeys,
     tokensData.t,
     selectedModel,
-    prefillKey,
+    prefillToken,
   ]);
 
   const loadModels = async () => {
@@ -111,20 +112,20 @@ function TokensPage() {
     }
   };
 
-  function openFluentNotification(key) {
+  function openFluentNotification(token) {
     const { t } = latestRef.current;
     const SUPPRESS_KEY = 'fluent_notify_suppressed';
     if (modelOptions.length === 0) {
       // fire-and-forget; a later effect will refresh the notice content
       loadModels();
     }
-    if (!key && localStorage.getItem(SUPPRESS_KEY) === '1') return;
+    if (!token && localStorage.getItem(SUPPRESS_KEY) === '1') return;
     const container = document.getElementById('fluent-new-api-container');
     if (!container) {
       Toast.warning(t('未检测到 FluentRead（流畅阅读），请确认扩展已启用'));
       return;
     }
-    setPrefillKey(key || '');
+    setPrefillToken(token || null);
     setFluentNoticeOpen(true);
     Notification.info({
       id: 'fluent-detected',
@@ -132,7 +133,7 @@ function TokensPage() {
       content: (
         <div>
           <div style={{ marginBottom: 8 }}>
-            {key
+            {token
               ? t('请选择模型。')
               : t('选择模型后可一键填充当前选中令牌（或本页第一个令牌）。')}
           </div>
@@ -156,7 +157,7 @@ function TokensPage() {
             >
               {t('一键填充到 FluentRead')}
             </Button>
-            {!key && (
+            {!token && (
               <Button
                 type='warning'
                 onClick={() => {
@@ -184,13 +185,13 @@ function TokensPage() {
   openFluentNotificationRef.current = openFluentNotification;
 
   // Prefill to Fluent handler
-  const handlePrefillToFluent = () => {
+  const handlePrefillToFluent = async () => {
     const {
       tokens,
       selectedKeys,
       t,
       selectedModel: chosenModel,
-      prefillKey: overrideKey,
+      prefillToken: overrideToken,
     } = latestRef.current;
     const container = document.getElementById('fluent-new-api-container');
     if (!container) {
@@ -213,21 +214,23 @@ function TokensPage() {
     }
     if (!serverAddress) serverAddress = window.location.origin;
 
+    const token =
+      overrideToken ||
+      (selectedKeys && selectedKeys.length === 1
+        ? selectedKeys[0]
+        : tokens && tokens.length > 0
+          ? tokens[0]
+          : null);
+    if (!token) {
+      Toast.warning(t('没有可用令牌用于填充'));
+      return;
+    }
     let apiKeyToUse = '';
-    if (overrideKey) {
-      apiKeyToUse = 'sk-' + overrideKey;
-    } else {
-      const token =
-        selectedKeys && selectedKeys.length === 1
-          ? selectedKeys[0]
-          : tokens && tokens.length > 0
-            ? tokens[0]
-            : null;
-      if (!token) {
-        Toast.warning(t('没有可用令牌用于填充'));
-        return;
-      }
-      apiKeyToUse = 'sk-' + token.key;
+    try {
+      apiKeyToUse = await getTokenApiKey(token);
+    } catch (e) {
+      showError(e.message);
+      return;
     }
 
     const payload = {
@@ -19,13 +19,25 @@ For commercial licensing, please contact support@quantumnous.com
 
 import React from 'react';
 import { Modal, Button, Space } from '@douyinfe/semi-ui';
+import { showError } from '../../../../helpers';
 
 const CopyTokensModal = ({ visible, onCancel, selectedKeys, copyText, t }) => {
+  // 完整密钥仅在创建时返回，列表中的令牌只能复制本次创建的
+  const getCopyableTokens = () => {
+    const copyable = selectedKeys.filter((token) => token.key);
+    if (copyable.length < selectedKeys.length) {
+      showError(t('令牌密钥仅在创建时显示，已跳过无法复制的令牌'));
+    }
+    return copyable;
+  };
+
   // Handle copy with name and key format
   const handleCopyWithName = async () => {
+    const copyable = getCopyableTokens();
+    if (copyable.length === 0) return;
     let content = '';
-    for (let i = 0; i < selectedKeys.length; i++) {
-      content += selectedKeys[i].name + '    sk-' + selectedKeys[i].key + '\n';
+    for (let i = 0; i < copyable.length; i++) {
+      content += copyable[i].name + '    sk-' + copyable[i].key + '\n';
     }
     await copyText(content);
     onCancel();
@@ -33,9 +45,11 @@ const CopyTokensModal = ({ visible, onCancel, selectedKeys, copyText, t }) => {
 
   // Handle copy with key only format
   const handleCopyKeyOnly = async () => {
+    const copyable = getCopyableTokens();
+    if (copyable.length === 0) return;
     let content = '';
-    for (let i = 0; i < selectedKeys.length; i++) {
-      content += 'sk-' + selectedKeys[i].key + '\n';
+    for (let i = 0; i < copyable.length; i++) {
+      content += 'sk-' + copyable[i].key + '\n';
     }
     await copyText(content);
     onCancel();
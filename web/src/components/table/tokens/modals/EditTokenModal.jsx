@@ -20,6 +20,7 @@ For commercial licensing, please contact support@quantumnous.com
 import React, { useEffect, useState, useContext, useRef } from 'react';
 import {
   API,
+  copy,
   showError,
   showSuccess,
   timestamp2string,
@@ -41,6 +42,7 @@ import {
   Form,
   Col,
   Row,
+  Modal,
 } from '@douyinfe/semi-ui';
 import {
   IconCreditCard,
@@ -64,6 +66,18 @@ const EditTokenModal = (props) => {
   const [groups, setGroups] = useState([]);
   const isEdit = props.editingToken.id !== undefined;
 
+  const tokenScopeOptions = [
+    { label: t('对话'), value: 'chat' },
+    { label: t('向量嵌入'), value: 'embeddings' },
+    { label: t('图像'), value: 'images' },
+    { label: t('音频'), value: 'audio' },
+    { label: t('实时对话'), value: 'realtime' },
+    { label: t('异步任务/视频'), value: 'tasks' },
+    { label: 'Midjourney', value: 'midjourney' },
+    { label: t('模型列表'), value: 'models.list' },
+    { label: t('用量查询'), value: 'usage.read' },
+  ];
+
   const getInitValues = () => ({
     name: '',
     remain_quota: 0,
@@ -71,9 +85,11 @@ const EditTokenModal = (props) => {
     unlimited_quota: true,
     model_limits_enabled: false,
     model_limits: [],
+    scopes: [],
     allow_ips: '',
     group: '',
     cross_group_retry: false,
+    callback_url: '',
     tokenCount: 1,
   });
 
@@ -162,6 +178,7 @@ const EditTokenModal = (props) => {
       } else {
         data.model_limits = [];
       }
+      data.scopes = data.scopes ? data.scopes.split(',') : [];
       if (formApiRef.current) {
         formApiRef.current.setValues({ ...getInitValues(), ...data });
       }
@@ -221,6 +238,7 @@ const EditTokenModal = (props) => {
       }
       localInputs.model_limits = localInputs.model_limits.join(',');
       localInputs.model_limits_enabled = localInputs.model_limits.length > 0;
+      localInputs.scopes = localInputs.scopes.join(',');
       let res = await API.put(`/api/token/`, {
         ...localInputs,
         id: parseInt(props.editingToken.id),
@@ -236,6 +254,7 @@ const EditTokenModal = (props) => {
     } else {
       const count = parseInt(values.tokenCount, 10) || 1;
       let successCount = 0;
+      const createdKeys = [];
       for (let i = 0; i < count; i++) {
         let { tokenCount: _tc, ...localInputs } = values;
         const baseName =
@@ -258,17 +277,30 @@ const EditTokenModal = (props) => {
         }
         localInputs.model_limits = localInputs.model_limits.join(',');
         localInputs.model_limits_enabled = localInputs.model_limits.length > 0;
+        localInputs.scopes = localInputs.scopes.join(',');
         let res = await API.post(`/api/token/`, localInputs);
-        const { success, message } = res.data;
+        const { success, message, data } = res.data;
         if (success) {
           successCount++;
+          if (data && data.key) {
+            createdKeys.push(`${data.name}    sk-${data.key}`);
+          }
         } else {
           showError(t(message));
           break;
         }
       }
       if (successCount > 0) {
-        showSuccess(t('令牌创建成功，请在列表页面点击复制获取令牌！'));
+        showSuccess(t('令牌创建成功！'));
+        const keysText = createdKeys.join('\n');
+        Modal.info({
+          title: t('请立即复制并妥善保存令牌，关闭后将无法再次查看'),
+          content: (
+            <pre className='whitespace-pre-wrap break-all'>{keysText}</pre>
+          ),
+          okText: t('复制'),
+          onOk: () => copy(keysText),
+        });
         props.refresh();
         props.handleClose();
       }
@@ -559,6 +591,17 @@ const EditTokenModal = (props) => {
                       style={{ width: '100%' }}
                     />
                   </Col>
+                  <Col span={24}>
+                    <Form.Select
+                      field='scopes'
+                      label={t('权限范围')}
+                      placeholder={t('请选择该令牌可访问的接口类型，留空允许全部')}
+                      multiple
+                      optionList={tokenScopeOptions}
+                      showClear
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
                   <Col span={24}>
                     <Form.TextArea
                       field='allow_ips'
@@ -573,6 +616,18 @@ const EditTokenModal = (props) => {
                       style={{ width: '100%' }}
                     />
                   </Col>
+                  <Col span={24}>
+                    <Form.Input
+                      field='callback_url'
+                      label={t('任务回调地址')}
+                      placeholder={t('异步任务完成后通知的地址，留空则不回调')}
+                      extraText={t(
+                        '请求中指定 callback_url 时优先使用请求中的地址，回调签名使用通知设置中的 Webhook 密钥',
+                      )}
+                      showClear
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
                 </Row>
               </Card>
             </div>
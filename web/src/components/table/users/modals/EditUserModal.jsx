@@ -21,6 +21,7 @@ import React, { useEffect, useState, useRef } from 'react';
 import { useTranslation } from 'react-i18next';
 import {
   API,
+  isRoot,
   showError,
   showSuccess,
   renderQuota,
@@ -62,6 +63,8 @@ const EditUserModal = (props) => {
   const [addQuotaLocal, setAddQuotaLocal] = useState('');
   const isMobile = useIsMobile();
   const [groupOptions, setGroupOptions] = useState([]);
+  const [adminRoleOptions, setAdminRoleOptions] = useState([]);
+  const initialAdminRoleIdRef = useRef(0);
   const formApiRef = useRef(null);
 
   const isEdit = Boolean(userId);
@@ -79,6 +82,7 @@ const EditUserModal = (props) => {
     quota: 0,
     group: 'default',
     remark: '',
+    admin_role_id: 0,
   });
 
   const fetchGroups = async () => {
@@ -90,6 +94,21 @@ const EditUserModal = (props) => {
     }
   };
 
+  const fetchAdminRoles = async () => {
+    try {
+      const res = await API.get(`/api/admin_role/`);
+      const { success, data } = res.data;
+      if (success) {
+        setAdminRoleOptions([
+          { label: t('不分配'), value: 0 },
+          ...(data || []).map((r) => ({ label: r.name, value: r.id })),
+        ]);
+      }
+    } catch (e) {
+      showError(e.message);
+    }
+  };
+
   const handleCancel = () => props.handleClose();
 
   const loadUser = async () => {
@@ -99,6 +118,7 @@ const EditUserModal = (props) => {
     const { success, message, data } = res.data;
     if (success) {
       data.password = '';
+      initialAdminRoleIdRef.current = data.admin_role_id || 0;
       formApiRef.current?.setValues({ ...getInitValues(), ...data });
     } else {
       showError(message);
@@ -109,6 +129,7 @@ const EditUserModal = (props) => {
   useEffect(() => {
     loadUser();
     if (userId) fetchGroups();
+    if (userId && isRoot()) fetchAdminRoles();
   }, [props.editingUser.id]);
 
   /* ----------------------- submit ----------------------- */
@@ -123,6 +144,21 @@ const EditUserModal = (props) => {
     const url = userId ? `/api/user/` : `/api/user/self`;
     const res = await API.put(url, payload);
     const { success, message } = res.data;
+    if (
+      success &&
+      userId &&
+      isRoot() &&
+      (values.admin_role_id || 0) !== initialAdminRoleIdRef.current
+    ) {
+      const roleRes = await API.put(`/api/user/${userId}/admin_role`, {
+        admin_role_id: values.admin_role_id || 0,
+      });
+      if (!roleRes.data.success) {
+        showError(roleRes.data.message);
+        setLoading(false);
+        return;
+      }
+    }
     if (success) {
       showSuccess(t('用户信息更新成功！'));
       props.refresh();
@@ -286,6 +322,17 @@ const EditUserModal = (props) => {
                         />
                       </Col>
 
+                      {isRoot() && (
+                        <Col span={24}>
+                          <Form.Select
+                            field='admin_role_id'
+                            label={t('管理角色')}
+                            optionList={adminRoleOptions}
+                            extraText={t('分配后该用户仅拥有角色中的管理权限')}
+                          />
+                        </Col>
+                      )}
+
                       <Col span={10}>
                         <Form.InputNumber
                           field='quota'
@@ -34,7 +34,7 @@ import {
 import { Coins } from 'lucide-react';
 import { IconSearch } from '@douyinfe/semi-icons';
 import { API, timestamp2string } from '../../../helpers';
-import { isAdmin } from '../../../helpers/utils';
+import { hasPermission } from '../../../helpers/utils';
 import { useIsMobile } from '../../../hooks/common/useIsMobile';
 
 const { Text } = Typography;
@@ -66,7 +66,9 @@ const TopupHistoryModal = ({ visible, onCancel, t }) => {
   const loadTopups = async (currentPage, currentPageSize) => {
     setLoading(true);
     try {
-      const base = isAdmin() ? '/api/user/topup' : '/api/user/topup/self';
+      const base = hasPermission('billing.read')
+        ? '/api/user/topup'
+        : '/api/user/topup/self';
       const qs =
         `p=${currentPage}&page_size=${currentPageSize}` +
         (keyword ? `&keyword=${encodeURIComponent(keyword)}` : '');
@@ -151,7 +153,7 @@ const TopupHistoryModal = ({ visible, onCancel, t }) => {
   };
 
   // 检查是否为管理员
-  const userIsAdmin = useMemo(() => isAdmin(), []);
+  const userIsAdmin = useMemo(() => hasPermission('billing.read'), []);
 
   const columns = useMemo(() => {
     const baseColumns = [
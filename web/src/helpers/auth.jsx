@@ -20,6 +20,7 @@ For commercial licensing, please contact support@quantumnous.com
 import React from 'react';
 import { Navigate } from 'react-router-dom';
 import { history } from './history';
+import { hasPermission, isAdmin } from './utils';
 
 export function authHeader() {
   // return authorization header with jwt token
@@ -49,14 +50,13 @@ function PrivateRoute({ children }) {
   return children;
 }
 
-export function AdminRoute({ children }) {
+export function AdminRoute({ children, permission }) {
   const raw = localStorage.getItem('user');
   if (!raw) {
     return <Navigate to='/login' state={{ from: history.location }} />;
   }
   try {
-    const user = JSON.parse(raw);
-    if (user && typeof user.role === 'number' && user.role >= 10) {
+    if (permission ? hasPermission(permission) : isAdmin()) {
       return children;
     }
   } catch (e) {
@@ -19,9 +19,27 @@ For commercial licensing, please contact support@quantumnous.com
 
 import { API } from './api';
 
+/**
+ * 获取令牌可用于调用接口的密钥
+ * 服务端只保存令牌哈希，完整密钥仅在创建时返回，其余情况签发 1 小时有效的派生令牌
+ * @param {object} token 令牌
+ * @returns {Promise<string>} 带 sk- 前缀的密钥
+ */
+export async function getTokenApiKey(token) {
+  if (token.key) {
+    return 'sk-' + token.key;
+  }
+  const res = await API.post(`/api/token/${token.id}/exchange`, {
+    expires_in: 3600,
+  });
+  const { success, message, data } = res.data;
+  if (!success) throw new Error(message);
+  return data.key;
+}
+
 /**
  * 获取可用的token keys
- * @returns {Promise<string[]>} 返回active状态的token key数组
+ * @returns {Promise<string[]>} 返回第一个active状态令牌的密钥（带 sk- 前缀）
  */
 export async function fetchTokenKeys() {
   try {
@@ -30,8 +48,9 @@ export async function fetchTokenKeys() {
     if (!success) throw new Error('Failed to fetch token keys');
 
     const tokenItems = Array.isArray(data) ? data : data.items || [];
-    const activeTokens = tokenItems.filter((token) => token.status === 1);
-    return activeTokens.map((token) => token.key);
+    const activeToken = tokenItems.find((token) => token.status === 1);
+    if (!activeToken) return [];
+    return [await getTokenApiKey(activeToken)];
   } catch (error) {
     console.error('Error fetching token keys:', error);
     return [];
@@ -36,9 +36,27 @@ export function isAdmin() {
   let user = localStorage.getItem('user');
   if (!user) return false;
   user = JSON.parse(user);
+  if (Array.isArray(user.admin_permissions)) {
+    return user.role >= 100 || user.admin_permissions.length > 0;
+  }
   return user.role >= 10;
 }
 
+// 仅限超级管理员的权限，未分配自定义角色的管理员不具备
+const ROOT_ONLY_PERMISSIONS = ['channel.key', 'option.read', 'option.write'];
+
+export function hasPermission(permission) {
+  let user = localStorage.getItem('user');
+  if (!user) return false;
+  user = JSON.parse(user);
+  if (user.role >= 100) return true;
+  if (Array.isArray(user.admin_permissions)) {
+    return user.admin_permissions.includes(permission);
+  }
+  // 兼容旧的登录信息
+  return user.role >= 10 && !ROOT_ONLY_PERMISSIONS.includes(permission);
+}
+
 export function isRoot() {
   let user = localStorage.getItem('user');
   if (!user) return false;
@@ -246,6 +246,18 @@ export const useTaskLogsData = () => {
     await loadLogs(1, pageSize);
   };
 
+  // 取消排队中的任务
+  const cancelTask = async (taskId) => {
+    const res = await API.post(`/api/task/${taskId}/cancel`);
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess(t('任务已取消'));
+      await refresh();
+    } else {
+      showError(message);
+    }
+  };
+
   // Copy text function
   const copyText = async (text) => {
     if (await copy(text)) {
@@ -321,6 +333,7 @@ export const useTaskLogsData = () => {
     copyText,
     openContentModal,
     openVideoModal, // 新增
+    cancelTask,
     enrichLogs,
     syncPageData,
 
@@ -27,6 +27,7 @@ import {
   showSuccess,
   encodeToBase64,
 } from '../../helpers';
+import { getTokenApiKey } from '../../helpers/token';
 import { ITEMS_PER_PAGE } from '../../constants';
 import { useTableCompactMode } from '../common/useTableCompactMode';
 
@@ -123,7 +124,14 @@ export const useTokensData = (openFluentNotification) => {
   // Open link function for chat integrations
   const onOpenLink = async (type, url, record) => {
     if (url && url.startsWith('fluent')) {
-      openFluentNotification(record.key);
+      openFluentNotification(record);
+      return;
+    }
+    let apiKey = '';
+    try {
+      apiKey = await getTokenApiKey(record);
+    } catch (e) {
+      showError(e.message);
       return;
     }
     let status = localStorage.getItem('status');
@@ -139,7 +147,7 @@ export const useTokensData = (openFluentNotification) => {
       let cherryConfig = {
         id: 'new-api',
         baseUrl: serverAddress,
-        apiKey: 'sk-' + record.key,
+        apiKey: apiKey,
       };
       let encodedConfig = encodeURIComponent(
         encodeToBase64(JSON.stringify(cherryConfig)),
@@ -148,7 +156,7 @@ export const useTokensData = (openFluentNotification) => {
     } else {
       let encodedServerAddress = encodeURIComponent(serverAddress);
       url = url.replaceAll('{address}', encodedServerAddress);
-      url = url.replaceAll('{key}', 'sk-' + record.key);
+      url = url.replaceAll('{key}', apiKey);
     }
 
     window.open(url, '_blank');
@@ -292,6 +300,15 @@ export const useTokensData = (openFluentNotification) => {
       return;
     }
 
+    // 完整密钥仅在创建时返回，列表中的令牌只能复制本次创建的
+    const copyable = selectedKeys.filter((token) => token.key);
+    if (copyable.length < selectedKeys.length) {
+      showError(t('令牌密钥仅在创建时显示，已跳过无法复制的令牌'));
+    }
+    if (copyable.length === 0) {
+      return;
+    }
+
     Modal.info({
       title: t('复制令牌'),
       icon: null,
@@ -302,9 +319,8 @@ export const useTokensData = (openFluentNotification) => {
             className='px-3 py-1 bg-gray-200 rounded'
             onClick={async () => {
               let content = '';
-              for (let i = 0; i < selectedKeys.length; i++) {
-                content +=
-                  selectedKeys[i].name + '    sk-' + selectedKeys[i].key + '\n';
+              for (let i = 0; i < copyable.length; i++) {
+                content += copyable[i].name + '    sk-' + copyable[i].key + '\n';
               }
               await copyText(content);
               Modal.destroyAll();
@@ -316,8 +332,8 @@ export const useTokensData = (openFluentNotification) => {
             className='px-3 py-1 bg-blue-500 text-white rounded'
             onClick={async () => {
               let content = '';
-              for (let i = 0; i < selectedKeys.length; i++) {
-                content += 'sk-' + selectedKeys[i].key + '\n';
+              for (let i = 0; i < copyable.length; i++) {
+                content += 'sk-' + copyable[i].key + '\n';
               }
               await copyText(content);
               Modal.destroyAll();
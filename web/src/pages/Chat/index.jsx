@@ -43,7 +43,7 @@ const ChatPage = () => {
               '{address}',
               encodeURIComponent(serverAddress),
             );
-            link = link.replaceAll('{key}', 'sk-' + key);
+            link = link.replaceAll('{key}', key);
           }
         }
       }
@@ -25,7 +25,7 @@ const chat2page = () => {
 
   const comLink = (key) => {
     if (!chatLink || !serverAddress || !key) return '';
-    return `${chatLink}/#/?settings={"key":"sk-${key}","url":"${encodeURIComponent(serverAddress)}"}`;
+    return `${chatLink}/#/?settings={"key":"${key}","url":"${encodeURIComponent(serverAddress)}"}`;
   };
 
   if (keys.length > 0) {
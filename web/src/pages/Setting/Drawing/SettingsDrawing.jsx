@@ -38,6 +38,9 @@ export default function SettingsDrawing(props) {
     MjForwardUrlEnabled: false,
     MjModeClearEnabled: false,
     MjActionCheckSuccessEnabled: false,
+    MjEmulationEnabled: false,
+    MjEmulationImageModel: '',
+    MjEmulationDescribeModel: '',
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -197,6 +200,52 @@ export default function SettingsDrawing(props) {
                 />
               </Col>
             </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'MjEmulationEnabled'}
+                  label={t('使用图片模型模拟 Midjourney')}
+                  extraText={t(
+                    '开启后 Midjourney 接口的绘图、变换、放大与识图请求将由下方模型处理，无需 Midjourney 渠道，需先启用媒体转存',
+                  )}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      MjEmulationEnabled: value,
+                    })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'MjEmulationImageModel'}
+                  label={t('模拟绘图模型')}
+                  placeholder='gpt-image-1'
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      MjEmulationImageModel: value,
+                    })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'MjEmulationDescribeModel'}
+                  label={t('模拟识图模型')}
+                  placeholder='gpt-4o-mini'
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      MjEmulationDescribeModel: value,
+                    })
+                  }
+                />
+              </Col>
+            </Row>
             <Row>
               <Button size='default' onClick={onSubmit}>
                 {t('保存绘图设置')}
@@ -55,6 +55,8 @@ export default function SettingClaudeModel(props) {
     'claude.thinking_adapter_enabled': true,
     'claude.default_max_tokens': '',
     'claude.thinking_adapter_budget_tokens_percentage': 0.8,
+    'claude.batch_ratio': 1,
+    'claude.batch_concurrency': 5,
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -213,6 +215,41 @@ export default function SettingClaudeModel(props) {
                 />
               </Col>
             </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('消息批处理价格倍率')}
+                  field={'claude.batch_ratio'}
+                  initValue={''}
+                  extraText={t(
+                    '/v1/messages/batches 中的请求按此倍率计费，必须大于 0。批处理请求逐个按普通接口转发，上游按原价收费，低于 1 会产生亏损',
+                  )}
+                  min={0.01}
+                  step={0.1}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'claude.batch_ratio': value,
+                    })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('消息批处理并发数')}
+                  field={'claude.batch_concurrency'}
+                  initValue={''}
+                  extraText={t('后台同时执行的批处理请求数')}
+                  min={1}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'claude.batch_concurrency': value,
+                    })
+                  }
+                />
+              </Col>
+            </Row>
 
             <Row>
               <Button size='default' onClick={onSubmit}>
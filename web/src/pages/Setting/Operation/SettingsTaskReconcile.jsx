@@ -0,0 +1,292 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState, useRef } from 'react';
+import {
+  Button,
+  Col,
+  Form,
+  Modal,
+  Row,
+  Space,
+  Spin,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  renderQuota,
+  showError,
+  showSuccess,
+  showWarning,
+  timestamp2string,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+export default function SettingsTaskReconcile(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'task_reconcile_setting.enabled': false,
+    'task_reconcile_setting.timeout_minutes': 1440,
+    'task_reconcile_setting.platform_timeout_minutes': '',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+  const [reportVisible, setReportVisible] = useState(false);
+  const [reportLoading, setReportLoading] = useState(false);
+  const [report, setReport] = useState(null);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    const platformTimeout =
+      inputs['task_reconcile_setting.platform_timeout_minutes'] || '{}';
+    if (!verifyJSON(platformTimeout)) {
+      return showError(t('平台超时时间不是合法的 JSON 字符串'));
+    }
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      let value = String(inputs[item.key]);
+      if (item.key === 'task_reconcile_setting.platform_timeout_minutes') {
+        value = platformTimeout;
+      }
+      return API.put('/api/option/', {
+        key: item.key,
+        value,
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (requestQueue.length === 1) {
+          if (res.includes(undefined)) return;
+        } else if (requestQueue.length > 1) {
+          if (res.includes(undefined))
+            return showError(t('部分保存失败，请重试'));
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  async function runReconcile() {
+    setLoading(true);
+    try {
+      const res = await API.post('/api/task/reconcile');
+      const { success, message, data } = res.data;
+      if (success) {
+        showSuccess(
+          t('已处理 {{tasks}} 个超时任务，退还 {{quota}}', {
+            tasks: data.failed_tasks + data.failed_midjourneys,
+            quota: renderQuota(data.refunded_quota),
+          }),
+        );
+      } else {
+        showError(message);
+      }
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  async function loadReport() {
+    setReportVisible(true);
+    setReportLoading(true);
+    try {
+      const res = await API.get('/api/task/reconcile/report');
+      const { success, message, data } = res.data;
+      if (success) {
+        setReport(data);
+      } else {
+        showError(message);
+      }
+    } finally {
+      setReportLoading(false);
+    }
+  }
+
+  const issueLabels = {
+    missing_consume_log: t('缺少消费日志'),
+    quota_mismatch: t('额度不一致'),
+    missing_refund: t('失败未退款'),
+    over_refund: t('退款超出消费'),
+  };
+
+  const reportColumns = [
+    { title: t('任务 ID'), dataIndex: 'task_id' },
+    { title: t('平台'), dataIndex: 'platform' },
+    { title: t('用户 ID'), dataIndex: 'user_id' },
+    { title: t('状态'), dataIndex: 'status' },
+    {
+      title: t('提交时间'),
+      dataIndex: 'submit_time',
+      render: (text) => timestamp2string(text),
+    },
+    {
+      title: t('任务额度'),
+      dataIndex: 'task_quota',
+      render: (text) => renderQuota(text),
+    },
+    {
+      title: t('消费'),
+      dataIndex: 'consumed_quota',
+      render: (text) => renderQuota(text),
+    },
+    {
+      title: t('退款'),
+      dataIndex: 'refunded_quota',
+      render: (text) => renderQuota(text),
+    },
+    {
+      title: t('问题'),
+      dataIndex: 'issue',
+      render: (text) => <Tag color='red'>{issueLabels[text] || text}</Tag>,
+    },
+  ];
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('任务对账设置')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '超过超时时间仍未完成的异步任务将被标记为失败并退还额度，对账报告用于核对任务额度与消费、退款日志',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'task_reconcile_setting.enabled'}
+                  label={t('启用超时任务自动退款')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('task_reconcile_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'task_reconcile_setting.timeout_minutes'}
+                  label={t('默认超时时间（分钟）')}
+                  onChange={handleFieldChange(
+                    'task_reconcile_setting.timeout_minutes',
+                  )}
+                  min={0}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={24} md={16} lg={16} xl={16}>
+                <Form.TextArea
+                  field={'task_reconcile_setting.platform_timeout_minutes'}
+                  label={t('平台超时时间')}
+                  extraText={t(
+                    '平台到超时分钟数的 JSON 映射，平台为 suno、mj 或渠道类型编号',
+                  )}
+                  placeholder='{"suno": 60, "mj": 120, "50": 180}'
+                  autosize
+                  onChange={handleFieldChange(
+                    'task_reconcile_setting.platform_timeout_minutes',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Space>
+                <Button size='default' onClick={onSubmit}>
+                  {t('保存任务对账设置')}
+                </Button>
+                <Button size='default' onClick={runReconcile}>
+                  {t('立即处理超时任务')}
+                </Button>
+                <Button size='default' onClick={loadReport}>
+                  {t('查看对账报告')}
+                </Button>
+              </Space>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+      <Modal
+        title={t('任务对账报告')}
+        visible={reportVisible}
+        onCancel={() => setReportVisible(false)}
+        footer={null}
+        width={1000}
+      >
+        {report && !report.consume_log_enabled && (
+          <Typography.Text
+            type='warning'
+            style={{ marginBottom: 12, display: 'block' }}
+          >
+            {t('未启用消费日志，无法核对任务消费')}
+          </Typography.Text>
+        )}
+        <Typography.Text
+          type='tertiary'
+          style={{ marginBottom: 12, display: 'block' }}
+        >
+          {t('检查最近 7 天提交的任务，最多显示 1000 条')}
+        </Typography.Text>
+        <Table
+          columns={reportColumns}
+          dataSource={report?.items || []}
+          rowKey='task_id'
+          loading={reportLoading}
+          size='small'
+        />
+      </Modal>
+    </>
+  );
+}
@@ -34,10 +34,11 @@ import {
   CreditCard,
   Server,
   Activity,
+  ShieldCheck,
 } from 'lucide-react';
 
 import SystemSetting from '../../components/settings/SystemSetting';
-import { isRoot } from '../../helpers';
+import { hasPermission, isRoot } from '../../helpers';
 import OtherSetting from '../../components/settings/OtherSetting';
 import OperationSetting from '../../components/settings/OperationSetting';
 import RateLimitSetting from '../../components/settings/RateLimitSetting';
@@ -49,6 +50,7 @@ import DrawingSetting from '../../components/settings/DrawingSetting';
 import PaymentSetting from '../../components/settings/PaymentSetting';
 import ModelDeploymentSetting from '../../components/settings/ModelDeploymentSetting';
 import PerformanceSetting from '../../components/settings/PerformanceSetting';
+import AdminRoleSetting from '../../components/settings/AdminRoleSetting';
 
 const Setting = () => {
   const { t } = useTranslation();
@@ -57,7 +59,7 @@ const Setting = () => {
   const [tabActiveKey, setTabActiveKey] = useState('1');
   let panes = [];
 
-  if (isRoot()) {
+  if (hasPermission('option.read')) {
     panes.push({
       tab: (
         <span style={{ display: 'flex', alignItems: 'center', gap: '5px' }}>
@@ -148,16 +150,18 @@ const Setting = () => {
       content: <ModelDeploymentSetting />,
       itemKey: 'model-deployment',
     });
-    panes.push({
-      tab: (
-        <span style={{ display: 'flex', alignItems: 'center', gap: '5px' }}>
-          <Activity size={18} />
-          {t('性能设置')}
-        </span>
-      ),
-      content: <PerformanceSetting />,
-      itemKey: 'performance',
-    });
+    if (isRoot()) {
+      panes.push({
+        tab: (
+          <span style={{ display: 'flex', alignItems: 'center', gap: '5px' }}>
+            <Activity size={18} />
+            {t('性能设置')}
+          </span>
+        ),
+        content: <PerformanceSetting />,
+        itemKey: 'performance',
+      });
+    }
     panes.push({
       tab: (
         <span style={{ display: 'flex', alignItems: 'center', gap: '5px' }}>
@@ -179,6 +183,18 @@ const Setting = () => {
       itemKey: 'other',
     });
   }
+  if (isRoot()) {
+    panes.push({
+      tab: (
+        <span style={{ display: 'flex', alignItems: 'center', gap: '5px' }}>
+          <ShieldCheck size={18} />
+          {t('管理角色')}
+        </span>
+      ),
+      content: <AdminRoleSetting />,
+      itemKey: 'admin-role',
+    });
+  }
   const onChangeTab = (key) => {
     setTabActiveKey(key);
     navigate(`?tab=${key}`);